@@ -0,0 +1,99 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+// HubFieldsAnnotation preserves the v1beta1 fields that cannot be represented in v1alpha1,
+// so that a v1beta1 object converted to v1alpha1 and back is not altered
+const HubFieldsAnnotation = "fdo.redhat.com/v1beta1-fields"
+
+// hubFields holds the v1beta1 spec and status fields without a v1alpha1 equivalent
+type hubFields[Storage, Keys, Status any] struct {
+	Expose      *v1beta1.Expose      `json:"expose,omitempty"`
+	Storage     *Storage             `json:"storage,omitempty"`
+	Keys        *Keys                `json:"keys,omitempty"`
+	PodTemplate *v1beta1.PodTemplate `json:"podTemplate,omitempty"`
+	Replicas    *int32               `json:"replicas,omitempty"`
+	// Retention is only set for onboarding servers
+	Retention *v1beta1.VoucherRetention `json:"retention,omitempty"`
+	// VoucherExport is only set for manufacturing servers
+	VoucherExport *v1beta1.VoucherExport `json:"voucherExport,omitempty"`
+	// RegistrationObjects is only set for rendezvous servers
+	RegistrationObjects bool `json:"registrationObjects,omitempty"`
+	// DeviceObjects is only set for manufacturing and onboarding servers
+	DeviceObjects bool `json:"deviceObjects,omitempty"`
+	// Status is the v1beta1 status without the pods and conditions, which v1alpha1 has
+	Status *Status `json:"status,omitempty"`
+}
+
+func (f *hubFields[Storage, Keys, Status]) isEmpty() bool {
+	return f.Expose == nil && f.Storage == nil && f.Keys == nil && f.PodTemplate == nil && f.Replicas == nil && f.Retention == nil &&
+		f.VoucherExport == nil && !f.RegistrationObjects && !f.DeviceObjects && f.Status == nil
+}
+
+// save stores the fields in the HubFieldsAnnotation of obj, or removes the annotation if there is nothing to store
+func (f *hubFields[Storage, Keys, Status]) save(obj metav1.Object) error {
+	annotations := obj.GetAnnotations()
+	if f.isEmpty() {
+		delete(annotations, HubFieldsAnnotation)
+		obj.SetAnnotations(annotations)
+		return nil
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[HubFieldsAnnotation] = string(data)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// restore loads the fields from the HubFieldsAnnotation of obj and removes the annotation
+func (f *hubFields[Storage, Keys, Status]) restore(obj metav1.Object) error {
+	annotations := obj.GetAnnotations()
+	data, ok := annotations[HubFieldsAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(annotations, HubFieldsAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	return json.Unmarshal([]byte(data), f)
+}
+
+// hubStatus returns status if it holds any field, or nil. The fields represented in v1alpha1
+// must be cleared by the caller.
+func hubStatus[Status any](status *Status) *Status {
+	var empty Status
+	if reflect.DeepEqual(*status, empty) {
+		return nil
+	}
+	return status
+}
@@ -0,0 +1,186 @@
+package v1alpha1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+// serverStatus returns a v1beta1 server status with all the common fields set
+func serverStatus() v1beta1.ServerStatus {
+	notAfter := metav1.NewTime(time.Unix(1735689600, 0))
+	return v1beta1.ServerStatus{
+		ObservedGeneration: 3,
+		Replicas:           2,
+		ReadyReplicas:      1,
+		Endpoints:          []string{"http://server.example.com"},
+		Pods:               []string{"pod-1", "pod-2"},
+		Keys:               []v1beta1.KeyStatus{{File: "owner_cert.pem", Secret: "fdo-owner-cert", NotAfter: &notAfter, SHA256Fingerprint: "0a1b"}},
+		Conditions: []metav1.Condition{{
+			Type: "Available", Status: metav1.ConditionTrue, Reason: "Available", LastTransitionTime: metav1.NewTime(time.Unix(1700000000, 0)),
+		}},
+	}
+}
+
+var _ = Describe("FDOManufacturingServer conversion", func() {
+	It("should round-trip a v1alpha1 server through v1beta1", func() {
+		server := &FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server", Namespace: "fdo"},
+			Spec: FDOManufacturingServerSpec{
+				Image:    "quay.io/example/manufacturing:latest",
+				LogLevel: "DEBUG",
+				RendezvousServers: []RendezvousServer{
+					{DNS: "rendezvous.example.com", DevicePort: 8082, OwnerPort: 8082, Protocol: "http"},
+				},
+				Protocols: &Protocols{
+					DIUN: &DIUN{KeyType: "SECP384R1", AllowedKeyStorageTypes: []KeyStorageType{"Tpm"}},
+				},
+			},
+			Status: FDOManufacturingServerStatus{Pods: []string{"pod-1"}},
+		}
+
+		hub := &v1beta1.FDOManufacturingServer{}
+		Expect(server.DeepCopy().ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.Protocols.DIUN.KeyType).To(Equal("SECP384R1"))
+		Expect(hub.Spec.RendezvousServers[0].DNS).To(Equal("rendezvous.example.com"))
+
+		converted := &FDOManufacturingServer{}
+		Expect(converted.ConvertFrom(hub)).To(Succeed())
+		Expect(converted).To(Equal(server))
+	})
+
+	It("should preserve v1beta1 fields through v1alpha1", func() {
+		replicas := int32(2)
+		hub := &v1beta1.FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server", Annotations: map[string]string{"example": "value"}},
+			Spec: v1beta1.FDOManufacturingServerSpec{
+				RendezvousServers: []v1beta1.RendezvousServer{{IPAddress: "10.0.0.1"}},
+				Protocols:         v1beta1.Protocols{PlainDI: true},
+				Replicas:          &replicas,
+				Expose:            &v1beta1.Expose{Host: "manufacturing.example.com"},
+				Storage: &v1beta1.ManufacturingStorage{Database: &v1beta1.DatabaseStorage{
+					Driver:           v1beta1.DatabaseDriverPostgres,
+					ConnectionSecret: &v1beta1.SecretKeyReference{Name: "fdo-db"},
+				}},
+				Keys: &v1beta1.ManufacturingKeys{
+					DIUN:      &v1beta1.KeyPairReference{Cert: &v1beta1.SecretKeyReference{Name: "diun", Key: "tls.crt"}},
+					OwnerCert: &v1beta1.SecretKeyReference{Name: "owner"},
+				},
+				PodTemplate: &v1beta1.PodTemplate{
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+					},
+					NodeSelector: map[string]string{"fdo": "true"},
+				},
+				VoucherExport: &v1beta1.VoucherExport{TokenSecret: v1beta1.SecretKeyReference{Name: "mes-token"}},
+			},
+			Status: v1beta1.FDOManufacturingServerStatus{
+				ServerStatus:    serverStatus(),
+				Image:           "quay.io/fido-fdo/manufacturing-server:0.4",
+				Inventory:       &v1beta1.VoucherInventoryStatus{Vouchers: 12, Invalid: 1},
+				ExportEndpoints: []string{"https://manufacturing-export.example.com/export/vouchers"},
+			},
+		}
+
+		spoke := &FDOManufacturingServer{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Status.Pods).To(Equal(hub.Status.Pods))
+		Expect(spoke.Status.Conditions).To(Equal(hub.Status.Conditions))
+		Expect(spoke.Annotations).To(HaveKey(HubFieldsAnnotation))
+		Expect(hub.Annotations).ToNot(HaveKey(HubFieldsAnnotation))
+
+		converted := &v1beta1.FDOManufacturingServer{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted.Spec.PodTemplate.Resources.Limits.Memory().Equal(resource.MustParse("256Mi"))).To(BeTrue())
+		converted.Spec.PodTemplate.Resources = hub.Spec.PodTemplate.Resources
+		Expect(converted).To(Equal(hub))
+	})
+})
+
+var _ = Describe("FDOOnboardingServer conversion", func() {
+	It("should round-trip a v1alpha1 server through v1beta1", func() {
+		server := &FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: FDOOnboardingServerSpec{
+				OwnerOnboardingImage: "quay.io/example/owner-onboarding:latest",
+				ServiceInfo: &ServiceInfo{
+					InitialUser: &InitialUser{Username: "admin", SSHKeys: []string{"ssh-ed25519 AAAA"}},
+					Commands:    []Command{{Command: "ls", Args: []string{"-l"}, ReturnStdOut: true}},
+					DiskEncryptionClevises: []DiskEncryptionClevis{
+						{DiskLabel: "/dev/vda", Binding: &ServiceInfoDiskEncryptionClevisBinding{Pin: "tpm2", Config: "{}"}, ReEncrypt: true},
+					},
+				},
+			},
+		}
+
+		hub := &v1beta1.FDOOnboardingServer{}
+		Expect(server.DeepCopy().ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.ServiceInfo.DiskEncryptionClevises[0].Binding.Pin).To(Equal("tpm2"))
+
+		converted := &FDOOnboardingServer{}
+		Expect(converted.ConvertFrom(hub)).To(Succeed())
+		Expect(converted).To(Equal(server))
+	})
+
+	It("should preserve v1beta1 fields through v1alpha1", func() {
+		hub := &v1beta1.FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: v1beta1.FDOOnboardingServerSpec{
+				Keys: &v1beta1.OnboardingKeys{
+					Owner: &v1beta1.KeyPairReference{Key: &v1beta1.SecretKeyReference{Name: "owner-key"}},
+				},
+				Retention:     &v1beta1.VoucherRetention{Days: 30, Action: v1beta1.VoucherRetentionDelete},
+				DeviceObjects: true,
+			},
+			Status: v1beta1.FDOOnboardingServerStatus{
+				ServerStatus:         serverStatus(),
+				OwnerOnboardingImage: "quay.io/fido-fdo/owner-onboarding-server:0.4",
+				ServiceInfoImage:     "quay.io/fido-fdo/serviceinfo-api-server:0.4",
+				Inventory:            &v1beta1.VoucherInventoryStatus{Vouchers: 3},
+				Retention:            &v1beta1.VoucherRetentionStatus{Onboarded: 2, LastReclaimed: 1, Reclaimed: 5},
+			},
+		}
+
+		spoke := &FDOOnboardingServer{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		converted := &v1beta1.FDOOnboardingServer{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted).To(Equal(hub))
+	})
+})
+
+var _ = Describe("FDORendezvousServer conversion", func() {
+	It("should preserve v1beta1 fields through v1alpha1", func() {
+		hub := &v1beta1.FDORendezvousServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: v1beta1.FDORendezvousServerSpec{
+				Image:               "quay.io/example/rendezvous:latest",
+				Storage:             &v1beta1.RendezvousStorage{RegistrationsClaimName: "registrations"},
+				RegistrationObjects: true,
+			},
+			Status: v1beta1.FDORendezvousServerStatus{
+				ServerStatus: serverStatus(),
+				Image:        "quay.io/example/rendezvous:latest",
+				Inventory: &v1beta1.RegistrationInventoryStatus{
+					Registrations: 4,
+					Expired:       1,
+					Expiry:        []v1beta1.RegistrationExpiryBucket{{Within: &metav1.Duration{Duration: time.Hour}, Count: 1}},
+				},
+			},
+		}
+
+		spoke := &FDORendezvousServer{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Image).To(Equal(hub.Spec.Image))
+		converted := &v1beta1.FDORendezvousServer{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted).To(Equal(hub))
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ conversion.Convertible = &FDOManufacturingServer{}
+
+// ConvertTo converts this FDOManufacturingServer to the hub version (v1beta1)
+func (src *FDOManufacturingServer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.FDOManufacturingServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.Image = src.Spec.Image
+	dst.Spec.LogLevel = src.Spec.LogLevel
+	dst.Spec.RendezvousServers = nil
+	for _, rv := range src.Spec.RendezvousServers {
+		dst.Spec.RendezvousServers = append(dst.Spec.RendezvousServers, v1beta1.RendezvousServer(rv))
+	}
+	dst.Spec.Protocols = v1beta1.Protocols{}
+	if src.Spec.Protocols != nil {
+		dst.Spec.Protocols.PlainDI = src.Spec.Protocols.PlainDI
+		if diun := src.Spec.Protocols.DIUN; diun != nil {
+			dst.Spec.Protocols.DIUN = &v1beta1.DIUN{KeyType: diun.KeyType}
+			for _, t := range diun.AllowedKeyStorageTypes {
+				dst.Spec.Protocols.DIUN.AllowedKeyStorageTypes = append(dst.Spec.Protocols.DIUN.AllowedKeyStorageTypes, v1beta1.KeyStorageType(t))
+			}
+		}
+	}
+
+	fields := hubFields[v1beta1.ManufacturingStorage, v1beta1.ManufacturingKeys, v1beta1.FDOManufacturingServerStatus]{}
+	if err := fields.restore(dst); err != nil {
+		return err
+	}
+	dst.Spec.Expose = fields.Expose
+	dst.Spec.Storage = fields.Storage
+	dst.Spec.Keys = fields.Keys
+	dst.Spec.PodTemplate = fields.PodTemplate
+	dst.Spec.Replicas = fields.Replicas
+	dst.Spec.VoucherExport = fields.VoucherExport
+	dst.Spec.DeviceObjects = fields.DeviceObjects
+
+	if fields.Status != nil {
+		dst.Status = *fields.Status
+	}
+	status := src.Status.DeepCopy()
+	dst.Status.Pods = status.Pods
+	dst.Status.Conditions = status.Conditions
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version
+func (dst *FDOManufacturingServer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.FDOManufacturingServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.Image = src.Spec.Image
+	dst.Spec.LogLevel = src.Spec.LogLevel
+	dst.Spec.RendezvousServers = nil
+	for _, rv := range src.Spec.RendezvousServers {
+		dst.Spec.RendezvousServers = append(dst.Spec.RendezvousServers, RendezvousServer(rv))
+	}
+	dst.Spec.Protocols = &Protocols{PlainDI: src.Spec.Protocols.PlainDI}
+	if diun := src.Spec.Protocols.DIUN; diun != nil {
+		dst.Spec.Protocols.DIUN = &DIUN{KeyType: diun.KeyType}
+		for _, t := range diun.AllowedKeyStorageTypes {
+			dst.Spec.Protocols.DIUN.AllowedKeyStorageTypes = append(dst.Spec.Protocols.DIUN.AllowedKeyStorageTypes, KeyStorageType(t))
+		}
+	}
+
+	status := src.Status.DeepCopy()
+	dst.Status = FDOManufacturingServerStatus{Pods: status.Pods, Conditions: status.Conditions}
+	status.Pods, status.Conditions = nil, nil
+
+	fields := hubFields[v1beta1.ManufacturingStorage, v1beta1.ManufacturingKeys, v1beta1.FDOManufacturingServerStatus]{
+		Expose:      src.Spec.Expose.DeepCopy(),
+		Storage:     src.Spec.Storage.DeepCopy(),
+		Keys:        src.Spec.Keys.DeepCopy(),
+		PodTemplate: src.Spec.PodTemplate.DeepCopy(),
+		Replicas:    src.Spec.Replicas,
+		// the voucher export API is only available in v1beta1
+		VoucherExport: src.Spec.VoucherExport.DeepCopy(),
+		DeviceObjects: src.Spec.DeviceObjects,
+		Status:        hubStatus(status),
+	}
+	return fields.save(dst)
+}
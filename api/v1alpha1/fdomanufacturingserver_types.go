@@ -28,8 +28,7 @@ type FDOManufacturingServerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Container image
-	// +kubebuilder:default="quay.io/fido-fdo/manufacturing-server:0.4"
+	// Container image, defaults to quay.io/fido-fdo/manufacturing-server:0.4
 	Image string `json:"image,omitempty"`
 
 	// Log level: TRACE, DEBUG, INFO(default), WARN, ERROR or OFF
@@ -52,10 +51,10 @@ type RendezvousServer struct {
 	// IP address of a rendezvous server, must select either an IP address or a hostname
 	IPAddress string `json:"ipAddress,omitempty"`
 
-	// Rendezvous port for device connections
+	// Rendezvous port for device connections, defaults to the well-known port of the protocol
 	DevicePort uint16 `json:"devicePort,omitempty"`
 
-	// Rendezvous port for owner connections
+	// Rendezvous port for owner connections, defaults to the well-known port of the protocol
 	OwnerPort uint16 `json:"ownerPort,omitempty"`
 
 	// Rendezvous transport protocol - tcp, tls (default), http, coap, https or coaps
@@ -71,8 +70,9 @@ type Protocols struct {
 type DIUN struct {
 	// +kubebuilder:validation:Enum=SECP256R1;SECP384R1
 	KeyType string `json:"keyType"`
+	// Allowed device key storage types, defaults to FileSystem and Tpm
 	// +kubebuilder:validation:MinItems=1
-	AllowedKeyStorageTypes []KeyStorageType `json:"allowedKeyStorageTypes"`
+	AllowedKeyStorageTypes []KeyStorageType `json:"allowedKeyStorageTypes,omitempty"`
 }
 
 // +kubebuilder:validation:Enum=FileSystem;Tpm
@@ -95,6 +95,7 @@ type FDOManufacturingServerStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:deprecatedversion:warning="fdo.redhat.com/v1alpha1 FDOManufacturingServer is deprecated, use fdo.redhat.com/v1beta1"
 
 // FDOManufacturingServer is the Schema for the fdomanufacturingservers API
 type FDOManufacturingServer struct {
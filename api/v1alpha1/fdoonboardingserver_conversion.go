@@ -0,0 +1,111 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ conversion.Convertible = &FDOOnboardingServer{}
+
+// ConvertTo converts this FDOOnboardingServer to the hub version (v1beta1)
+func (src *FDOOnboardingServer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.FDOOnboardingServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.OwnerOnboardingImage = src.Spec.OwnerOnboardingImage
+	dst.Spec.ServiceInfoImage = src.Spec.ServiceInfoImage
+	dst.Spec.ServiceInfo = nil
+	if si := src.Spec.ServiceInfo.DeepCopy(); si != nil {
+		dst.Spec.ServiceInfo = &v1beta1.ServiceInfo{}
+		if u := si.InitialUser; u != nil {
+			dst.Spec.ServiceInfo.InitialUser = &v1beta1.InitialUser{Username: u.Username, Password: u.Password, SSHKeys: u.SSHKeys}
+		}
+		for _, cmd := range si.Commands {
+			dst.Spec.ServiceInfo.Commands = append(dst.Spec.ServiceInfo.Commands, v1beta1.Command(cmd))
+		}
+		for _, clevis := range si.DiskEncryptionClevises {
+			c := v1beta1.DiskEncryptionClevis{DiskLabel: clevis.DiskLabel, ReEncrypt: clevis.ReEncrypt}
+			if clevis.Binding != nil {
+				c.Binding = v1beta1.ServiceInfoDiskEncryptionClevisBinding(*clevis.Binding)
+			}
+			dst.Spec.ServiceInfo.DiskEncryptionClevises = append(dst.Spec.ServiceInfo.DiskEncryptionClevises, c)
+		}
+	}
+
+	fields := hubFields[v1beta1.OnboardingStorage, v1beta1.OnboardingKeys, v1beta1.FDOOnboardingServerStatus]{}
+	if err := fields.restore(dst); err != nil {
+		return err
+	}
+	dst.Spec.Expose = fields.Expose
+	dst.Spec.Storage = fields.Storage
+	dst.Spec.Keys = fields.Keys
+	dst.Spec.PodTemplate = fields.PodTemplate
+	dst.Spec.Replicas = fields.Replicas
+	dst.Spec.Retention = fields.Retention
+	dst.Spec.DeviceObjects = fields.DeviceObjects
+
+	if fields.Status != nil {
+		dst.Status = *fields.Status
+	}
+	status := src.Status.DeepCopy()
+	dst.Status.Pods = status.Pods
+	dst.Status.Conditions = status.Conditions
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version
+func (dst *FDOOnboardingServer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.FDOOnboardingServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.OwnerOnboardingImage = src.Spec.OwnerOnboardingImage
+	dst.Spec.ServiceInfoImage = src.Spec.ServiceInfoImage
+	dst.Spec.ServiceInfo = nil
+	if si := src.Spec.ServiceInfo.DeepCopy(); si != nil {
+		dst.Spec.ServiceInfo = &ServiceInfo{}
+		if u := si.InitialUser; u != nil {
+			dst.Spec.ServiceInfo.InitialUser = &InitialUser{Username: u.Username, Password: u.Password, SSHKeys: u.SSHKeys}
+		}
+		for _, cmd := range si.Commands {
+			dst.Spec.ServiceInfo.Commands = append(dst.Spec.ServiceInfo.Commands, Command(cmd))
+		}
+		for _, clevis := range si.DiskEncryptionClevises {
+			binding := ServiceInfoDiskEncryptionClevisBinding(clevis.Binding)
+			dst.Spec.ServiceInfo.DiskEncryptionClevises = append(dst.Spec.ServiceInfo.DiskEncryptionClevises,
+				DiskEncryptionClevis{DiskLabel: clevis.DiskLabel, Binding: &binding, ReEncrypt: clevis.ReEncrypt})
+		}
+	}
+
+	status := src.Status.DeepCopy()
+	dst.Status = FDOOnboardingServerStatus{Pods: status.Pods, Conditions: status.Conditions}
+	status.Pods, status.Conditions = nil, nil
+
+	fields := hubFields[v1beta1.OnboardingStorage, v1beta1.OnboardingKeys, v1beta1.FDOOnboardingServerStatus]{
+		Expose:        src.Spec.Expose.DeepCopy(),
+		Storage:       src.Spec.Storage.DeepCopy(),
+		Keys:          src.Spec.Keys.DeepCopy(),
+		PodTemplate:   src.Spec.PodTemplate.DeepCopy(),
+		Replicas:      src.Spec.Replicas,
+		Retention:     src.Spec.Retention.DeepCopy(),
+		DeviceObjects: src.Spec.DeviceObjects,
+		Status:        hubStatus(status),
+	}
+	return fields.save(dst)
+}
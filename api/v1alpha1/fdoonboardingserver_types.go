@@ -28,12 +28,10 @@ type FDOOnboardingServerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Owner-onboarding server container image
-	// +kubebuilder:default="quay.io/fido-fdo/owner-onboarding-server:0.4"
+	// Owner-onboarding server container image, defaults to quay.io/fido-fdo/owner-onboarding-server:0.4
 	OwnerOnboardingImage string `json:"ownerOnboardingImage,omitempty"`
 
-	// ServiceInfo API server container image
-	// +kubebuilder:default="quay.io/fido-fdo/serviceinfo-api-server:0.4"
+	// ServiceInfo API server container image, defaults to quay.io/fido-fdo/serviceinfo-api-server:0.4
 	ServiceInfoImage string `json:"serviceInfoImage,omitempty"`
 
 	// Service info device onboarding sequence
@@ -57,6 +55,7 @@ type FDOOnboardingServerStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:deprecatedversion:warning="fdo.redhat.com/v1alpha1 FDOOnboardingServer is deprecated, use fdo.redhat.com/v1beta1"
 
 // FDOOnboardingServer is the Schema for the fdoonboardingservers API
 type FDOOnboardingServer struct {
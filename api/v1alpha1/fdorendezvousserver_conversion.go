@@ -0,0 +1,76 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ conversion.Convertible = &FDORendezvousServer{}
+
+// ConvertTo converts this FDORendezvousServer to the hub version (v1beta1)
+func (src *FDORendezvousServer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.FDORendezvousServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.Image = src.Spec.Image
+
+	fields := hubFields[v1beta1.RendezvousStorage, v1beta1.RendezvousKeys, v1beta1.FDORendezvousServerStatus]{}
+	if err := fields.restore(dst); err != nil {
+		return err
+	}
+	dst.Spec.Expose = fields.Expose
+	dst.Spec.Storage = fields.Storage
+	dst.Spec.Keys = fields.Keys
+	dst.Spec.PodTemplate = fields.PodTemplate
+	dst.Spec.Replicas = fields.Replicas
+	dst.Spec.RegistrationObjects = fields.RegistrationObjects
+
+	if fields.Status != nil {
+		dst.Status = *fields.Status
+	}
+	status := src.Status.DeepCopy()
+	dst.Status.Pods = status.Pods
+	dst.Status.Conditions = status.Conditions
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version
+func (dst *FDORendezvousServer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.FDORendezvousServer)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec.Image = src.Spec.Image
+
+	status := src.Status.DeepCopy()
+	dst.Status = FDORendezvousServerStatus{Pods: status.Pods, Conditions: status.Conditions}
+	status.Pods, status.Conditions = nil, nil
+
+	fields := hubFields[v1beta1.RendezvousStorage, v1beta1.RendezvousKeys, v1beta1.FDORendezvousServerStatus]{
+		Expose:      src.Spec.Expose.DeepCopy(),
+		Storage:     src.Spec.Storage.DeepCopy(),
+		Keys:        src.Spec.Keys.DeepCopy(),
+		PodTemplate: src.Spec.PodTemplate.DeepCopy(),
+		Replicas:    src.Spec.Replicas,
+		// the registration inventory is only available in v1beta1
+		RegistrationObjects: src.Spec.RegistrationObjects,
+		Status:              hubStatus(status),
+	}
+	return fields.save(dst)
+}
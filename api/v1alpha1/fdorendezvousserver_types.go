@@ -28,8 +28,7 @@ type FDORendezvousServerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Rendezvous server container image
-	// +kubebuilder:default="quay.io/fido-fdo/rendezvous-server:0.4"
+	// Rendezvous server container image, defaults to quay.io/fido-fdo/rendezvous-server:0.4
 	Image string `json:"image,omitempty"`
 }
 
@@ -50,6 +49,7 @@ type FDORendezvousServerStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:deprecatedversion:warning="fdo.redhat.com/v1alpha1 FDORendezvousServer is deprecated, use fdo.redhat.com/v1beta1"
 
 // FDORendezvousServer is the Schema for the fdorendezvousservers API
 type FDORendezvousServer struct {
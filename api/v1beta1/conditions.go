@@ -0,0 +1,128 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+// Condition types of a server
+const (
+	// ConditionAvailable is true when at least one pod of the server is ready to serve requests
+	ConditionAvailable = "Available"
+	// ConditionProgressing is true while a new version of the server is being rolled out
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is true when the server cannot be reconciled or rolled out
+	ConditionDegraded = "Degraded"
+	// ConditionKeysReady is true when all keys and certificates of the server are found
+	ConditionKeysReady = "KeysReady"
+	// ConditionMissingKeyMaterial is true when a secret or a key of the server is missing, the
+	// server is not rolled out until it is created
+	ConditionMissingKeyMaterial = "MissingKeyMaterial"
+	// ConditionKeysValid is true when the keys and certificates of the server match each other and the
+	// configured key types, and the certificates are valid
+	ConditionKeysValid = "KeysValid"
+	// ConditionTrustConsistent is true when the certificates of the server match those of the other
+	// manufacturing, rendezvous and onboarding servers of the namespace
+	ConditionTrustConsistent = "TrustConsistent"
+	// ConditionDatabaseReady is true when the Postgres database managed by the operator for the
+	// server is running and migrated
+	ConditionDatabaseReady = "DatabaseReady"
+)
+
+// Condition reasons of a server
+const (
+	ReasonMinimumReplicasAvailable = "MinimumReplicasAvailable"
+	ReasonDeploymentUnavailable    = "DeploymentUnavailable"
+	ReasonRolloutInProgress        = "RolloutInProgress"
+	ReasonRolloutComplete          = "RolloutComplete"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonReconcileFailed          = "ReconcileFailed"
+	ReasonAsExpected               = "AsExpected"
+	ReasonKeysFound                = "KeysFound"
+	ReasonSecretNotFound           = "SecretNotFound"
+	ReasonKeyNotFound              = "KeyNotFound"
+	ReasonCertificateExpiring      = "CertificateExpiring"
+	ReasonTrustMismatch            = "TrustMismatch"
+	ReasonDatabaseProvisioning     = "DatabaseProvisioning"
+	ReasonMigrationRunning         = "MigrationRunning"
+	ReasonMigrationFailed          = "MigrationFailed"
+)
+
+// Condition types of a key set
+const (
+	// ConditionReady is true when the keys and certificates of all roles of a key set exist
+	ConditionReady = "Ready"
+	// ConditionRotating is true while a rotated certificate of a key set is still trusted
+	ConditionRotating = "Rotating"
+)
+
+// Condition reasons of a key set
+const (
+	ReasonKeysGenerated         = "KeysGenerated"
+	ReasonIncompleteKeyMaterial = "IncompleteKeyMaterial"
+	ReasonInvalidKeyMaterial    = "InvalidKeyMaterial"
+	ReasonRotationStarted       = "RotationStarted"
+	ReasonOverlapWindow         = "OverlapWindow"
+	ReasonCertificateRetired    = "CertificateRetired"
+)
+
+// Condition reasons of a trust bundle, whose Ready condition is true when its secret holds the
+// certificates of all its enabled sources
+const (
+	ReasonBundleGenerated = "BundleGenerated"
+	ReasonInvalidSource   = "InvalidSource"
+	ReasonNoCertificates  = "NoCertificates"
+)
+
+// Condition types of an ownership voucher
+const (
+	// ConditionImported is true when the voucher is held by its onboarding server
+	ConditionImported = "Imported"
+	// ConditionRendezvousRegistered is true when a rendezvous server of the namespace with
+	// registrationObjects holds a registration of the device of the voucher
+	ConditionRendezvousRegistered = "RendezvousRegistered"
+)
+
+// Condition reasons of an ownership voucher
+const (
+	ReasonVoucherImported     = "VoucherImported"
+	ReasonInvalidVoucher      = "InvalidVoucher"
+	ReasonServerUnavailable   = "ServerUnavailable"
+	ReasonImportFailed        = "ImportFailed"
+	ReasonRegistrationPending = "RegistrationPending"
+	ReasonDeviceRegistered    = "DeviceRegistered"
+)
+
+// Condition reasons of a voucher sync, whose Ready condition is true when all vouchers of its
+// manufacturing server have been delivered to all targets
+const (
+	ReasonVouchersSynchronized = "VouchersSynchronized"
+	ReasonDeliveryFailed       = "DeliveryFailed"
+	ReasonSourceUnavailable    = "SourceUnavailable"
+	ReasonInvalidTarget        = "InvalidTarget"
+)
+
+// Condition reasons of a voucher extension, whose Ready condition is true when the extended voucher
+// has been written to its output. It also uses ReasonInvalidVoucher, ReasonInvalidKeyMaterial and
+// ReasonSourceUnavailable.
+const (
+	ReasonVoucherExtended = "VoucherExtended"
+	ReasonInvalidOutput   = "InvalidOutput"
+)
+
+// Event reasons of the voucher retention policy of an onboarding server
+const (
+	ReasonVouchersReclaimed = "VouchersReclaimed"
+	ReasonRetentionFailed   = "RetentionFailed"
+)
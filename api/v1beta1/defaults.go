@@ -0,0 +1,191 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	DefaultManufacturingImage   = "quay.io/fido-fdo/manufacturing-server:0.4"
+	DefaultOwnerOnboardingImage = "quay.io/fido-fdo/owner-onboarding-server:0.4"
+	DefaultServiceInfoImage     = "quay.io/fido-fdo/serviceinfo-api-server:0.4"
+	DefaultRendezvousImage      = "quay.io/fido-fdo/rendezvous-server:0.4"
+
+	DefaultRendezvousProtocol = "tls"
+
+	DefaultKeyType = "SECP384R1"
+)
+
+// DefaultKeyValidity is the validity of certificates generated by a key set if none is specified
+var DefaultKeyValidity = metav1.Duration{Duration: 365 * 24 * time.Hour}
+
+// DefaultRenewBefore is the time before the expiry of a certificate when a key set rotates it
+var DefaultRenewBefore = metav1.Duration{Duration: 30 * 24 * time.Hour}
+
+// DefaultOverlapWindow is the time during which a rotated certificate is still trusted
+var DefaultOverlapWindow = metav1.Duration{Duration: 7 * 24 * time.Hour}
+
+// DefaultKeyRoles are the key roles generated by a key set if none are specified
+var DefaultKeyRoles = []KeyRole{KeyRoleDIUN, KeyRoleManufacturer, KeyRoleDeviceCA, KeyRoleOwner}
+
+// AppliedDefaultsAnnotation records the values assumed by the operator for fields left empty by the user,
+// as a JSON object that maps a field path to its default value
+const AppliedDefaultsAnnotation = "fdo.redhat.com/applied-defaults"
+
+// DefaultAllowedKeyStorageTypes are the DIUN key storage types allowed if none are specified
+var DefaultAllowedKeyStorageTypes = []KeyStorageType{"FileSystem", "Tpm"}
+
+// defaultRendezvousPorts are the well-known ports of each rendezvous transport protocol, as defined in the FDO specification
+var defaultRendezvousPorts = map[string]uint16{
+	"tcp":   8040,
+	"tls":   8041,
+	"http":  80,
+	"https": 443,
+	"coap":  5683,
+	"coaps": 5684,
+}
+
+// defaulter fills in empty fields and keeps track of the values it has assumed
+type defaulter struct {
+	previous map[string]string
+	applied  map[string]string
+}
+
+func newDefaulter(obj metav1.Object) *defaulter {
+	d := &defaulter{
+		previous: map[string]string{},
+		applied:  map[string]string{},
+	}
+	if v, ok := obj.GetAnnotations()[AppliedDefaultsAnnotation]; ok {
+		// an unparsable annotation is simply overwritten
+		_ = json.Unmarshal([]byte(v), &d.previous)
+	}
+	return d
+}
+
+// record remembers a default for a field. A field set to a default earlier is still
+// reported, unless the user has changed it to a different value since.
+func (d *defaulter) record(path *field.Path, current string, value func() string) string {
+	if current == "" {
+		current = value()
+		d.applied[path.String()] = current
+	} else if d.previous[path.String()] == current {
+		d.applied[path.String()] = current
+	}
+	return current
+}
+
+func (d *defaulter) setString(path *field.Path, v *string, def string) {
+	*v = d.record(path, *v, func() string { return def })
+}
+
+// clearString empties a field that still holds a default persisted by an earlier version of the
+// operator, so that it follows the default of the running operator again. Images are not persisted:
+// they are resolved when the server is reconciled, and an upgrade of the operator rolls them out.
+func (d *defaulter) clearString(path *field.Path, v *string) {
+	if *v != "" && d.previous[path.String()] == *v {
+		*v = ""
+	}
+}
+
+func (d *defaulter) setPort(path *field.Path, v *uint16, def uint16) {
+	if def == 0 {
+		return
+	}
+	current := ""
+	if *v != 0 {
+		current = portString(*v)
+	}
+	d.record(path, current, func() string {
+		*v = def
+		return portString(def)
+	})
+}
+
+func (d *defaulter) setKeyStorageTypes(path *field.Path, v *[]KeyStorageType, def []KeyStorageType) {
+	current := make([]string, len(*v))
+	for i, t := range *v {
+		current[i] = string(t)
+	}
+	d.record(path, strings.Join(current, ","), func() string {
+		*v = append([]KeyStorageType{}, def...)
+		values := make([]string, len(def))
+		for i, t := range def {
+			values[i] = string(t)
+		}
+		return strings.Join(values, ",")
+	})
+}
+
+// apply stores the applied defaults in the annotation of an object, or removes the annotation if there are none
+func (d *defaulter) apply(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	if len(d.applied) == 0 {
+		if _, ok := annotations[AppliedDefaultsAnnotation]; ok {
+			delete(annotations, AppliedDefaultsAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+		return
+	}
+	v, err := json.Marshal(d.applied)
+	if err != nil {
+		return
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AppliedDefaultsAnnotation] = string(v)
+	obj.SetAnnotations(annotations)
+}
+
+// ImageOrDefault returns the manufacturing server image, or the default image of the operator
+func (s *FDOManufacturingServerSpec) ImageOrDefault() string {
+	return stringOrDefault(s.Image, DefaultManufacturingImage)
+}
+
+// OwnerOnboardingImageOrDefault returns the owner-onboarding server image, or the default image of the operator
+func (s *FDOOnboardingServerSpec) OwnerOnboardingImageOrDefault() string {
+	return stringOrDefault(s.OwnerOnboardingImage, DefaultOwnerOnboardingImage)
+}
+
+// ServiceInfoImageOrDefault returns the serviceinfo API server image, or the default image of the operator
+func (s *FDOOnboardingServerSpec) ServiceInfoImageOrDefault() string {
+	return stringOrDefault(s.ServiceInfoImage, DefaultServiceInfoImage)
+}
+
+// ImageOrDefault returns the rendezvous server image, or the default image of the operator
+func (s *FDORendezvousServerSpec) ImageOrDefault() string {
+	return stringOrDefault(s.Image, DefaultRendezvousImage)
+}
+
+func stringOrDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func portString(p uint16) string {
+	return strconv.FormatUint(uint64(p), 10)
+}
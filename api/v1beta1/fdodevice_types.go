@@ -0,0 +1,115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VoucherKey describes a public key of an ownership voucher
+type VoucherKey struct {
+	// Type of the key, e.g. SECP384R1
+	Type string `json:"type"`
+
+	// Encoding of the key in the voucher, e.g. X5CHAIN
+	Encoding string `json:"encoding"`
+
+	// Hex encoded SHA-256 hash of the public key
+	// +optional
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// DeviceServerReference references the server holding the voucher of a device
+type DeviceServerReference struct {
+	// Kind of the server, FDOManufacturingServer or FDOOnboardingServer
+	Kind string `json:"kind"`
+
+	// Name of the server
+	Name string `json:"name"`
+}
+
+// FDODeviceStatus describes the ownership voucher of a device
+type FDODeviceStatus struct {
+	// GUID of the device
+	GUID string `json:"guid"`
+
+	// Device info set by the manufacturer
+	// +optional
+	DeviceInfo string `json:"deviceInfo,omitempty"`
+
+	// Serial number in the subject of the device certificate
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+
+	// Server holding the voucher
+	Server DeviceServerReference `json:"server"`
+
+	// FDO protocol version of the voucher
+	// +optional
+	ProtocolVersion int64 `json:"protocolVersion,omitempty"`
+
+	// Public key of the manufacturer
+	ManufacturerKey VoucherKey `json:"manufacturerKey"`
+
+	// Public key of the current owner, the key of the last entry of the voucher
+	OwnerKey VoucherKey `json:"ownerKey"`
+
+	// Number of entries of the voucher
+	// +optional
+	Entries int32 `json:"entries,omitempty"`
+
+	// Rendezvous directives of the voucher, e.g. "dns=rv.example.com device_port=80 protocol=http"
+	// +optional
+	RendezvousInfo []string `json:"rendezvousInfo,omitempty"`
+
+	// Time the voucher was last written to the store
+	// +optional
+	VoucherTime *metav1.Time `json:"voucherTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=fdodev
+//+kubebuilder:printcolumn:name="GUID",type=string,JSONPath=`.status.guid`
+//+kubebuilder:printcolumn:name="Device Info",type=string,JSONPath=`.status.deviceInfo`
+//+kubebuilder:printcolumn:name="Serial",type=string,JSONPath=`.status.serialNumber`,priority=1
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.status.server.name`
+//+kubebuilder:printcolumn:name="Entries",type=integer,JSONPath=`.status.entries`
+//+kubebuilder:printcolumn:name="Owner Key",type=string,JSONPath=`.status.ownerKey.fingerprint`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDODevice is the Schema for the fdodevices API. It describes the ownership voucher of a device
+// held by a manufacturing or onboarding server. Devices are written by the operator from the
+// voucher inventory of the servers, and deleted when their voucher leaves the store.
+type FDODevice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status FDODeviceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FDODeviceList contains a list of FDODevice
+type FDODeviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDODevice `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDODevice{}, &FDODeviceList{})
+}
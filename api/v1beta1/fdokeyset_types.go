@@ -0,0 +1,173 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeyRole is the role of an FDO key pair
+// +kubebuilder:validation:Enum=diun;manufacturer;device-ca;owner
+type KeyRole string
+
+const (
+	KeyRoleDIUN         KeyRole = "diun"
+	KeyRoleManufacturer KeyRole = "manufacturer"
+	KeyRoleDeviceCA     KeyRole = "device-ca"
+	KeyRoleOwner        KeyRole = "owner"
+)
+
+// FDOKeySetSpec defines the desired state of FDOKeySet
+type FDOKeySetSpec struct {
+	// Organization in the subject of the certificates
+	// +kubebuilder:validation:MinLength=1
+	Organization string `json:"organization"`
+
+	// Country code in the subject of the certificates, e.g. US
+	// +kubebuilder:validation:Pattern=`^[A-Z]{2}$`
+	Country string `json:"country"`
+
+	// Validity of the certificates, defaults to 8760h (one year)
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Type of the keys, defaults to SECP384R1
+	// +kubebuilder:validation:Enum=SECP256R1;SECP384R1
+	// +optional
+	KeyType string `json:"keyType,omitempty"`
+
+	// Key roles to generate, defaults to diun, manufacturer, device-ca and owner.
+	// The key pair of a role is written to the secrets fdo-<role>-key and fdo-<role>-cert.
+	// +listType=set
+	// +optional
+	Roles []KeyRole `json:"roles,omitempty"`
+
+	// Rotation of the keys and certificates, they are never rotated if not set.
+	// The owner key is never rotated since ownership vouchers are bound to it.
+	// +optional
+	Rotation *KeyRotation `json:"rotation,omitempty"`
+}
+
+// KeyRotation defines when the keys and certificates of a key set are rotated
+type KeyRotation struct {
+	// Time before the expiry of a certificate when a new key and certificate replace it,
+	// defaults to 720h (30 days)
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+
+	// Time after a rotation during which servers trust both the previous and the new
+	// certificate, defaults to 168h (7 days)
+	// +optional
+	OverlapWindow *metav1.Duration `json:"overlapWindow,omitempty"`
+}
+
+// KeyStage is the rotation stage of a key pair
+// +kubebuilder:validation:Enum=Active;Overlap
+type KeyStage string
+
+const (
+	// KeyStageActive is the stage of a key pair whose certificate is the only one trusted
+	KeyStageActive KeyStage = "Active"
+	// KeyStageOverlap is the stage of a rotated key pair while its previous certificate is still trusted
+	KeyStageOverlap KeyStage = "Overlap"
+)
+
+// KeySetKeyStatus defines the observed state of the key pair of a role
+type KeySetKeyStatus struct {
+	// Role of the key pair
+	Role KeyRole `json:"role"`
+
+	// Secret holding the certificate
+	CertSecret string `json:"certSecret"`
+
+	// Secret holding the private key
+	KeySecret string `json:"keySecret"`
+
+	// Expiry time of the certificate
+	// +optional
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+
+	// Rotation stage of the key pair
+	// +optional
+	Stage KeyStage `json:"stage,omitempty"`
+
+	// Time of the last rotation of the key pair
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+
+	// End of the overlap window, when the previous certificate is retired
+	// +optional
+	OverlapUntil *metav1.Time `json:"overlapUntil,omitempty"`
+}
+
+// FDOKeySetStatus defines the observed state of FDOKeySet
+type FDOKeySetStatus struct {
+	// Generation of the key set last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Key pairs of the key set
+	// +listType=map
+	// +listMapKey=role
+	// +optional
+	Keys []KeySetKeyStatus `json:"keys,omitempty"`
+
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Rotating",type=string,JSONPath=`.status.conditions[?(@.type=="Rotating")].status`
+//+kubebuilder:printcolumn:name="Key Type",type=string,JSONPath=`.spec.keyType`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOKeySet is the Schema for the fdokeysets API. It generates the keys and certificates
+// read by the FDO servers of its namespace, existing keys and certificates are never replaced.
+type FDOKeySet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOKeySetSpec   `json:"spec,omitempty"`
+	Status FDOKeySetStatus `json:"status,omitempty"`
+}
+
+func (m *FDOKeySet) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOKeySet) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// FDOKeySetList contains a list of FDOKeySet
+type FDOKeySetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOKeySet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOKeySet{}, &FDOKeySetList{})
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOManufacturingServerSpec defines the desired state of FDOManufacturingServer
+type FDOManufacturingServerSpec struct {
+	// Container image, defaults to quay.io/fido-fdo/manufacturing-server:0.4
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Log level: TRACE, DEBUG, INFO(default), WARN, ERROR or OFF
+	// +kubebuilder:validation:Enum=TRACE;DEBUG;INFO;WARN;ERROR;OFF
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// List of rendezvous servers
+	// +kubebuilder:validation:MinItems=1
+	// +listType=atomic
+	RendezvousServers []RendezvousServer `json:"rendezvousServers"`
+
+	// Device initialization protocols
+	Protocols Protocols `json:"protocols"`
+
+	// Exposure of the server outside of the cluster
+	// +optional
+	Expose *Expose `json:"expose,omitempty"`
+
+	// Number of server pods, defaults to 1. More than one pod requires a Postgres database.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Storage of the server
+	// +optional
+	Storage *ManufacturingStorage `json:"storage,omitempty"`
+
+	// Keys and certificates of the server
+	// +optional
+	Keys *ManufacturingKeys `json:"keys,omitempty"`
+
+	// Customization of the server pods
+	// +optional
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// API exporting the vouchers to manufacturing line systems, requires the vouchers to be
+	// stored in a directory
+	// +optional
+	VoucherExport *VoucherExport `json:"voucherExport,omitempty"`
+
+	// Write a read-only FDODevice describing each voucher of the server, requires the vouchers to be
+	// stored in a directory
+	// +optional
+	DeviceObjects bool `json:"deviceObjects,omitempty"`
+}
+
+// VoucherExport defines the API serving the vouchers of a manufacturing server, e.g. to fetch the
+// voucher of a serial number once the device is initialized
+type VoucherExport struct {
+	// Secret holding the bearer token of the clients of the API, the key defaults to token
+	TokenSecret SecretKeyReference `json:"tokenSecret"`
+
+	// Exposure of the API outside of the cluster through a TLS route, the API is only served
+	// inside the cluster if not set
+	// +optional
+	Expose *Expose `json:"expose,omitempty"`
+}
+
+// RendezvousServer defines an entry of rendezvous server configuration
+type RendezvousServer struct {
+	// Hostname of a rendezvous server, must select either a hostname or an IP address
+	// +optional
+	DNS string `json:"dns,omitempty"`
+
+	// IP address of a rendezvous server, must select either an IP address or a hostname
+	// +optional
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// Rendezvous port for device connections, defaults to the well-known port of the protocol
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	DevicePort uint16 `json:"devicePort,omitempty"`
+
+	// Rendezvous port for owner connections, defaults to the well-known port of the protocol
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	OwnerPort uint16 `json:"ownerPort,omitempty"`
+
+	// Rendezvous transport protocol - tcp, tls (default), http, coap, https or coaps
+	// +kubebuilder:validation:Enum=tcp;tls;http;coap;https;coaps
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Protocols defines the device initialization protocols supported by a manufacturing server
+type Protocols struct {
+	// Allow plain device initialization, without the DIUN protocol
+	// +optional
+	PlainDI bool `json:"plainDI,omitempty"`
+
+	// DIUN protocol configuration, required unless plain DI is allowed
+	// +optional
+	DIUN *DIUN `json:"diun,omitempty"`
+}
+
+// DIUN defines the device initialize over untrusted networks protocol
+type DIUN struct {
+	// Type of the DIUN key
+	// +kubebuilder:validation:Enum=SECP256R1;SECP384R1
+	KeyType string `json:"keyType"`
+
+	// Allowed device key storage types, defaults to FileSystem and Tpm
+	// +kubebuilder:validation:MinItems=1
+	// +optional
+	AllowedKeyStorageTypes []KeyStorageType `json:"allowedKeyStorageTypes,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=FileSystem;Tpm
+type KeyStorageType string
+
+// ManufacturingStorage defines the storage of a manufacturing server
+type ManufacturingStorage struct {
+	// Persistent volume claim for ownership vouchers, defaults to fdo-ownership-vouchers-pvc
+	// +optional
+	OwnershipVouchersClaimName string `json:"ownershipVouchersClaimName,omitempty"`
+
+	// Database storing the ownership vouchers instead of files
+	// +optional
+	Database *DatabaseStorage `json:"database,omitempty"`
+
+	// Persistent volume claim holding the sessions of the server, which must be ReadWriteMany to be
+	// shared by its pods. Required for more than one replica, each pod keeps its sessions otherwise.
+	// +optional
+	SessionsClaimName string `json:"sessionsClaimName,omitempty"`
+}
+
+// GetDatabase returns the database of the storage, nil if the stores are kept in files
+func (s *ManufacturingStorage) GetDatabase() *DatabaseStorage {
+	if s == nil {
+		return nil
+	}
+	return s.Database
+}
+
+// GetSessionsClaimName returns the claim holding the sessions of the server, empty if each pod keeps its sessions
+func (s *ManufacturingStorage) GetSessionsClaimName() string {
+	if s == nil {
+		return ""
+	}
+	return s.SessionsClaimName
+}
+
+// ManufacturingKeys defines the secrets holding the keys and certificates of a manufacturing server.
+// Secrets that are not set default to fdo-<role>-cert and fdo-<role>-key, e.g. fdo-diun-cert.
+type ManufacturingKeys struct {
+	// DIUN certificate and private key
+	// +optional
+	DIUN *KeyPairReference `json:"diun,omitempty"`
+
+	// Manufacturer certificate and private key
+	// +optional
+	Manufacturer *KeyPairReference `json:"manufacturer,omitempty"`
+
+	// Device CA certificate chain and private key
+	// +optional
+	DeviceCA *KeyPairReference `json:"deviceCA,omitempty"`
+
+	// Owner certificate
+	// +optional
+	OwnerCert *SecretKeyReference `json:"ownerCert,omitempty"`
+}
+
+// FDOManufacturingServerStatus defines the observed state of FDOManufacturingServer
+type FDOManufacturingServerStatus struct {
+	ServerStatus `json:",inline"`
+
+	// Image running the manufacturing server
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Vouchers in the store of the server, read when the vouchers are stored in a directory
+	// +optional
+	Inventory *VoucherInventoryStatus `json:"inventory,omitempty"`
+
+	// URLs of the voucher export API
+	// +optional
+	// +listType=atomic
+	ExportEndpoints []string `json:"exportEndpoints,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoints[0]`
+//+kubebuilder:printcolumn:name="Vouchers",type=integer,JSONPath=`.status.inventory.vouchers`,priority=1
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.image`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOManufacturingServer is the Schema for the fdomanufacturingservers API
+type FDOManufacturingServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOManufacturingServerSpec   `json:"spec,omitempty"`
+	Status FDOManufacturingServerStatus `json:"status,omitempty"`
+}
+
+func (m *FDOManufacturingServer) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOManufacturingServer) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+func (m *FDOManufacturingServer) GetServerStatus() *ServerStatus {
+	return &m.Status.ServerStatus
+}
+
+//+kubebuilder:object:root=true
+
+// FDOManufacturingServerList contains a list of FDOManufacturingServer
+type FDOManufacturingServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOManufacturingServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOManufacturingServer{}, &FDOManufacturingServerList{})
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var fdomanufacturingserverlog = logf.Log.WithName("fdomanufacturingserver-resource")
+
+func (r *FDOManufacturingServer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-fdo-redhat-com-v1beta1-fdomanufacturingserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdomanufacturingservers,verbs=create;update,versions=v1beta1,name=mfdomanufacturingserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &FDOManufacturingServer{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// The defaults applied are recorded in the AppliedDefaultsAnnotation.
+func (r *FDOManufacturingServer) Default() {
+	fdomanufacturingserverlog.Info("default", "name", r.Name)
+	d := newDefaulter(r)
+	path := field.NewPath("spec")
+	d.clearString(path.Child("image"), &r.Spec.Image)
+	for i := range r.Spec.RendezvousServers {
+		rv := &r.Spec.RendezvousServers[i]
+		rvPath := path.Child("rendezvousServers").Index(i)
+		d.setString(rvPath.Child("protocol"), &rv.Protocol, DefaultRendezvousProtocol)
+		d.setPort(rvPath.Child("devicePort"), &rv.DevicePort, defaultRendezvousPorts[rv.Protocol])
+		d.setPort(rvPath.Child("ownerPort"), &rv.OwnerPort, defaultRendezvousPorts[rv.Protocol])
+	}
+	if r.Spec.Protocols.DIUN != nil {
+		diunPath := path.Child("protocols", "diun")
+		d.setKeyStorageTypes(diunPath.Child("allowedKeyStorageTypes"), &r.Spec.Protocols.DIUN.AllowedKeyStorageTypes, DefaultAllowedKeyStorageTypes)
+	}
+	d.apply(r)
+}
+
+//+kubebuilder:webhook:path=/validate-fdo-redhat-com-v1beta1-fdomanufacturingserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdomanufacturingservers,verbs=create;update,versions=v1beta1,name=vfdomanufacturingserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &FDOManufacturingServer{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOManufacturingServer) ValidateCreate() (admission.Warnings, error) {
+	fdomanufacturingserverlog.Info("validate create", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOManufacturingServer) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	fdomanufacturingserverlog.Info("validate update", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOManufacturingServer) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *FDOManufacturingServer) validate() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("FDOManufacturingServer").GroupKind(), r.Name, allErrs)
+}
+
+func (s *FDOManufacturingServerSpec) validate(path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, ValidateImage(path.Child("image"), s.Image)...)
+	allErrs = append(allErrs, ValidateStorage(path, s.Replicas, s.Storage.GetDatabase(), s.Storage.GetSessionsClaimName())...)
+
+	rendezvousPath := path.Child("rendezvousServers")
+	if len(s.RendezvousServers) == 0 {
+		allErrs = append(allErrs, field.Required(rendezvousPath, "at least one rendezvous server is required"))
+	}
+	for i, rv := range s.RendezvousServers {
+		allErrs = append(allErrs, rv.validate(rendezvousPath.Index(i))...)
+	}
+
+	protocolsPath := path.Child("protocols")
+	if !s.Protocols.PlainDI && s.Protocols.DIUN == nil {
+		allErrs = append(allErrs, field.Required(protocolsPath.Child("diun"), "DIUN must be configured if plain DI is false"))
+	}
+
+	if s.Keys != nil {
+		keysPath := path.Child("keys")
+		allErrs = append(allErrs, ValidateKeyPair(keysPath.Child("diun"), s.Keys.DIUN)...)
+		allErrs = append(allErrs, ValidateKeyPair(keysPath.Child("manufacturer"), s.Keys.Manufacturer)...)
+		allErrs = append(allErrs, ValidateKeyPair(keysPath.Child("deviceCA"), s.Keys.DeviceCA)...)
+	}
+	if s.VoucherExport != nil && s.Storage.GetDatabase() != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("voucherExport"), "requires vouchers stored in a directory"))
+	}
+	if s.DeviceObjects && s.Storage.GetDatabase() != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("deviceObjects"), "requires vouchers stored in a directory"))
+	}
+	return allErrs
+}
+
+func (s *RendezvousServer) validate(path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch {
+	case s.DNS != "" && s.IPAddress != "":
+		allErrs = append(allErrs, field.Forbidden(path.Child("ipAddress"), "cannot use both DNS and IP address for rendezvous server"))
+	case s.DNS == "" && s.IPAddress == "":
+		allErrs = append(allErrs, field.Required(path, "either a DNS or IP address is required for rendezvous server"))
+	case s.DNS != "":
+		allErrs = append(allErrs, ValidateHostname(path.Child("dns"), s.DNS)...)
+	default:
+		allErrs = append(allErrs, ValidateIPAddress(path.Child("ipAddress"), s.IPAddress)...)
+	}
+	allErrs = append(allErrs, ValidatePort(path.Child("devicePort"), s.DevicePort)...)
+	allErrs = append(allErrs, ValidatePort(path.Child("ownerPort"), s.OwnerPort)...)
+	return allErrs
+}
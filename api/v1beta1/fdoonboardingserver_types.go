@@ -0,0 +1,319 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOOnboardingServerSpec defines the desired state of FDOOnboardingServer
+type FDOOnboardingServerSpec struct {
+	// Owner-onboarding server container image, defaults to quay.io/fido-fdo/owner-onboarding-server:0.4
+	// +optional
+	OwnerOnboardingImage string `json:"ownerOnboardingImage,omitempty"`
+
+	// ServiceInfo API server container image, defaults to quay.io/fido-fdo/serviceinfo-api-server:0.4
+	// +optional
+	ServiceInfoImage string `json:"serviceInfoImage,omitempty"`
+
+	// Service info device onboarding sequence
+	// +optional
+	ServiceInfo *ServiceInfo `json:"serviceInfo,omitempty"`
+
+	// Exposure of the server outside of the cluster
+	// +optional
+	Expose *Expose `json:"expose,omitempty"`
+
+	// Number of server pods, defaults to 1. More than one pod requires a Postgres database.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Storage of the server
+	// +optional
+	Storage *OnboardingStorage `json:"storage,omitempty"`
+
+	// Keys and certificates of the server
+	// +optional
+	Keys *OnboardingKeys `json:"keys,omitempty"`
+
+	// Customization of the server pods
+	// +optional
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// Retention of the vouchers of the devices that completed onboarding, which are kept forever if
+	// it is not set. It requires vouchers stored in a directory.
+	// +optional
+	Retention *VoucherRetention `json:"retention,omitempty"`
+
+	// Write a read-only FDODevice describing each voucher of the server, requires the vouchers to be
+	// stored in a directory
+	// +optional
+	DeviceObjects bool `json:"deviceObjects,omitempty"`
+}
+
+// ServiceInfo defines a custom device onboarding sequence run through service info API
+type ServiceInfo struct {
+	// Initial user created on a device
+	// +optional
+	InitialUser *InitialUser `json:"initialUser,omitempty"`
+
+	// Commands run on a device
+	// +optional
+	// +listType=atomic
+	Commands []Command `json:"commands,omitempty"`
+
+	// Disk encryption with Clevis
+	// +optional
+	// +listType=atomic
+	DiskEncryptionClevises []DiskEncryptionClevis `json:"diskencryptionClevis,omitempty"`
+}
+
+// InitialUser defines a user created on a device, with at least one authentication method
+type InitialUser struct {
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// +optional
+	Password string `json:"password,omitempty"`
+
+	// +optional
+	// +listType=atomic
+	SSHKeys []string `json:"sshKeys,omitempty"`
+}
+
+// Command defines a command run on a device
+type Command struct {
+	// +kubebuilder:validation:MinLength=1
+	Command string `json:"command"`
+
+	// +optional
+	// +listType=atomic
+	Args []string `json:"args,omitempty"`
+
+	// +optional
+	MayFail bool `json:"mayFail,omitempty"`
+
+	// +optional
+	ReturnStdOut bool `json:"returnStdOut,omitempty"`
+
+	// +optional
+	ReturnStdErr bool `json:"returnStdErr,omitempty"`
+}
+
+// DiskEncryptionClevis defines the encryption of a device disk with Clevis
+type DiskEncryptionClevis struct {
+	// +kubebuilder:validation:MinLength=1
+	DiskLabel string `json:"diskLabel"`
+
+	Binding ServiceInfoDiskEncryptionClevisBinding `json:"binding"`
+
+	// +optional
+	ReEncrypt bool `json:"reencrypt,omitempty"`
+}
+
+type ServiceInfoDiskEncryptionClevisBinding struct {
+	// +optional
+	Pin string `json:"pin,omitempty"`
+
+	// +optional
+	Config string `json:"config,omitempty"`
+}
+
+// OnboardingStorage defines the storage of an onboarding server
+type OnboardingStorage struct {
+	// Persistent volume claim for ownership vouchers, defaults to fdo-ownership-vouchers-pvc
+	// +optional
+	OwnershipVouchersClaimName string `json:"ownershipVouchersClaimName,omitempty"`
+
+	// Database storing the ownership vouchers instead of files
+	// +optional
+	Database *DatabaseStorage `json:"database,omitempty"`
+
+	// Persistent volume claim holding the sessions of the server, which must be ReadWriteMany to be
+	// shared by its pods. Required for more than one replica, each pod keeps its sessions otherwise.
+	// +optional
+	SessionsClaimName string `json:"sessionsClaimName,omitempty"`
+}
+
+// GetDatabase returns the database of the storage, nil if the stores are kept in files
+func (s *OnboardingStorage) GetDatabase() *DatabaseStorage {
+	if s == nil {
+		return nil
+	}
+	return s.Database
+}
+
+// GetSessionsClaimName returns the claim holding the sessions of the server, empty if each pod keeps its sessions
+func (s *OnboardingStorage) GetSessionsClaimName() string {
+	if s == nil {
+		return ""
+	}
+	return s.SessionsClaimName
+}
+
+// VoucherRetentionAction is applied to the vouchers of onboarded devices after the retention period
+// +kubebuilder:validation:Enum=Delete;Archive
+type VoucherRetentionAction string
+
+const (
+	VoucherRetentionDelete  VoucherRetentionAction = "Delete"
+	VoucherRetentionArchive VoucherRetentionAction = "Archive"
+)
+
+// VoucherRetention defines how long the vouchers of the devices that completed TO2 are kept
+type VoucherRetention struct {
+	// Days a voucher is kept after its device completed TO2
+	// +kubebuilder:validation:Minimum=0
+	Days int32 `json:"days"`
+
+	// Action applied to the vouchers after the retention period, defaults to Delete
+	// +kubebuilder:default=Delete
+	// +optional
+	Action VoucherRetentionAction `json:"action,omitempty"`
+
+	// Archive receiving the vouchers, required by the Archive action
+	// +optional
+	Archive *VoucherArchive `json:"archive,omitempty"`
+
+	// Interval between two runs of the policy, defaults to 1h
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Persistent volume claim recording when the devices were first seen onboarded, so that the
+	// retention periods outlive the pods of the server
+	StateClaimName string `json:"stateClaimName"`
+}
+
+// VoucherArchive defines where archived vouchers are kept, exactly one of persistentVolumeClaim and
+// secrets must be set
+type VoucherArchive struct {
+	// Persistent volume claim the vouchers are moved to
+	// +optional
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+
+	// Write each voucher to a secret named <server>-voucher-<guid> in the namespace of the server
+	// +optional
+	Secrets bool `json:"secrets,omitempty"`
+}
+
+// OnboardingKeys defines the secrets holding the keys and certificates of an onboarding server.
+// Secrets that are not set default to fdo-<role>-cert and fdo-<role>-key, e.g. fdo-owner-cert.
+type OnboardingKeys struct {
+	// Owner certificate and private key
+	// +optional
+	Owner *KeyPairReference `json:"owner,omitempty"`
+
+	// Device CA certificate chain
+	// +optional
+	DeviceCACert *SecretKeyReference `json:"deviceCACert,omitempty"`
+
+	// Trust bundle of the device CA certificates of several manufacturers, instead of deviceCACert
+	// +optional
+	DeviceCATrustBundle *TrustBundleReference `json:"deviceCATrustBundle,omitempty"`
+}
+
+// FDOOnboardingServerStatus defines the observed state of FDOOnboardingServer
+type FDOOnboardingServerStatus struct {
+	ServerStatus `json:",inline"`
+
+	// Image running the owner-onboarding server
+	// +optional
+	OwnerOnboardingImage string `json:"ownerOnboardingImage,omitempty"`
+
+	// Image running the serviceinfo API server
+	// +optional
+	ServiceInfoImage string `json:"serviceInfoImage,omitempty"`
+
+	// Vouchers in the store of the server, read when the vouchers are stored in a directory
+	// +optional
+	Inventory *VoucherInventoryStatus `json:"inventory,omitempty"`
+
+	// Runs of the voucher retention policy
+	// +optional
+	Retention *VoucherRetentionStatus `json:"retention,omitempty"`
+}
+
+// VoucherRetentionStatus reports the vouchers pruned by the retention policy of a server
+type VoucherRetentionStatus struct {
+	// Vouchers of the devices that completed TO2 still in the store
+	Onboarded int32 `json:"onboarded"`
+
+	// Vouchers pruned by the last run
+	LastReclaimed int32 `json:"lastReclaimed"`
+
+	// Vouchers pruned since the policy was set
+	Reclaimed int32 `json:"reclaimed"`
+
+	// Time of the last run
+	// +optional
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+
+	// Time of the next run
+	// +optional
+	NextRunTime *metav1.Time `json:"nextRunTime,omitempty"`
+
+	// Error of the last run, if it failed
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoints[0]`
+//+kubebuilder:printcolumn:name="Vouchers",type=integer,JSONPath=`.status.inventory.vouchers`,priority=1
+//+kubebuilder:printcolumn:name="Reclaimed",type=integer,JSONPath=`.status.retention.reclaimed`,priority=1
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.ownerOnboardingImage`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOOnboardingServer is the Schema for the fdoonboardingservers API
+type FDOOnboardingServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOOnboardingServerSpec   `json:"spec,omitempty"`
+	Status FDOOnboardingServerStatus `json:"status,omitempty"`
+}
+
+func (m *FDOOnboardingServer) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOOnboardingServer) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+func (m *FDOOnboardingServer) GetServerStatus() *ServerStatus {
+	return &m.Status.ServerStatus
+}
+
+//+kubebuilder:object:root=true
+
+// FDOOnboardingServerList contains a list of FDOOnboardingServer
+type FDOOnboardingServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOOnboardingServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOOnboardingServer{}, &FDOOnboardingServerList{})
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var fdoonboardingserverlog = logf.Log.WithName("fdoonboardingserver-resource")
+
+func (r *FDOOnboardingServer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-fdo-redhat-com-v1beta1-fdoonboardingserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdoonboardingservers,verbs=create;update,versions=v1beta1,name=mfdoonboardingserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &FDOOnboardingServer{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// The defaults applied are recorded in the AppliedDefaultsAnnotation.
+func (r *FDOOnboardingServer) Default() {
+	fdoonboardingserverlog.Info("default", "name", r.Name)
+	d := newDefaulter(r)
+	path := field.NewPath("spec")
+	d.clearString(path.Child("ownerOnboardingImage"), &r.Spec.OwnerOnboardingImage)
+	d.clearString(path.Child("serviceInfoImage"), &r.Spec.ServiceInfoImage)
+	d.apply(r)
+}
+
+//+kubebuilder:webhook:path=/validate-fdo-redhat-com-v1beta1-fdoonboardingserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdoonboardingservers,verbs=create;update,versions=v1beta1,name=vfdoonboardingserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &FDOOnboardingServer{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOOnboardingServer) ValidateCreate() (admission.Warnings, error) {
+	fdoonboardingserverlog.Info("validate create", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOOnboardingServer) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	fdoonboardingserverlog.Info("validate update", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *FDOOnboardingServer) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *FDOOnboardingServer) validate() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("FDOOnboardingServer").GroupKind(), r.Name, allErrs)
+}
+
+func (s *FDOOnboardingServerSpec) validate(path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, ValidateImage(path.Child("ownerOnboardingImage"), s.OwnerOnboardingImage)...)
+	allErrs = append(allErrs, ValidateImage(path.Child("serviceInfoImage"), s.ServiceInfoImage)...)
+	allErrs = append(allErrs, ValidateStorage(path, s.Replicas, s.Storage.GetDatabase(), s.Storage.GetSessionsClaimName())...)
+	if s.Retention != nil {
+		allErrs = append(allErrs, s.Retention.validate(path.Child("retention"), s.Storage.GetDatabase())...)
+	}
+	if s.DeviceObjects && s.Storage.GetDatabase() != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("deviceObjects"), "requires vouchers stored in a directory"))
+	}
+	if s.ServiceInfo != nil {
+		allErrs = append(allErrs, s.ServiceInfo.validate(path.Child("serviceInfo"))...)
+	}
+	if s.Keys != nil {
+		allErrs = append(allErrs, ValidateKeyPair(path.Child("keys", "owner"), s.Keys.Owner)...)
+		if s.Keys.DeviceCACert != nil && s.Keys.DeviceCATrustBundle != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("keys", "deviceCATrustBundle"), "cannot be set with deviceCACert"))
+		}
+	}
+	return allErrs
+}
+
+func (r *VoucherRetention) validate(path *field.Path, db *DatabaseStorage) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if db != nil {
+		allErrs = append(allErrs, field.Forbidden(path, "requires vouchers stored in a directory"))
+	}
+	if r.Interval != nil && r.Interval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("interval"), r.Interval.Duration.String(), "must be positive"))
+	}
+	if r.StateClaimName == "" {
+		allErrs = append(allErrs, field.Required(path.Child("stateClaimName"), "the record of the onboarded devices must outlive the pods"))
+	}
+	if r.Action != VoucherRetentionArchive {
+		if r.Archive != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("archive"), "requires the Archive action"))
+		}
+		return allErrs
+	}
+	if r.Archive == nil || (r.Archive.PersistentVolumeClaim == "") == !r.Archive.Secrets {
+		allErrs = append(allErrs, field.Required(path.Child("archive"), "exactly one of persistentVolumeClaim and secrets must be set"))
+	}
+	return allErrs
+}
+
+func (s *ServiceInfo) validate(path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if u := s.InitialUser; u != nil {
+		userPath := path.Child("initialUser")
+		if u.Username == "" {
+			allErrs = append(allErrs, field.Required(userPath.Child("username"), ""))
+		}
+		if u.Password == "" && len(u.SSHKeys) == 0 {
+			allErrs = append(allErrs, field.Required(userPath, "at least one authentication method (password or sshKeys) is required for initial user"))
+		}
+	}
+	for i, cmd := range s.Commands {
+		if cmd.Command == "" {
+			allErrs = append(allErrs, field.Required(path.Child("commands").Index(i).Child("command"), ""))
+		}
+	}
+	for i, clevis := range s.DiskEncryptionClevises {
+		clevisPath := path.Child("diskencryptionClevis").Index(i)
+		if clevis.DiskLabel == "" {
+			allErrs = append(allErrs, field.Required(clevisPath.Child("diskLabel"), ""))
+		}
+		if clevis.Binding.Pin == "" {
+			allErrs = append(allErrs, field.Required(clevisPath.Child("binding", "pin"), ""))
+		}
+	}
+	return allErrs
+}
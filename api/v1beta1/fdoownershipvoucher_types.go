@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOOwnershipVoucherSpec defines the desired state of FDOOwnershipVoucher
+type FDOOwnershipVoucherSpec struct {
+	// Ownership voucher in PEM format (-----BEGIN OWNERSHIP VOUCHER-----)
+	// +optional
+	Voucher string `json:"voucher,omitempty"`
+
+	// Secret holding the ownership voucher in PEM or CBOR format, the key defaults to voucher.
+	// Exactly one of voucher and voucherSecret must be set.
+	// +optional
+	VoucherSecret *SecretKeyReference `json:"voucherSecret,omitempty"`
+
+	// Onboarding server the voucher is imported into, in the namespace of the voucher
+	OnboardingServer OnboardingServerReference `json:"onboardingServer"`
+}
+
+// OnboardingServerReference references an FDOOnboardingServer in the namespace of an object
+type OnboardingServerReference struct {
+	// Name of the onboarding server
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// FDOOwnershipVoucherStatus defines the observed state of FDOOwnershipVoucher
+type FDOOwnershipVoucherStatus struct {
+	// Generation of the voucher last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// GUID of the device of the voucher
+	// +optional
+	GUID string `json:"guid,omitempty"`
+
+	// Onboarding server holding the voucher
+	// +optional
+	OnboardingServer string `json:"onboardingServer,omitempty"`
+
+	// SHA-256 hash of the imported voucher, the voucher is imported again when it changes
+	// +optional
+	VoucherHash string `json:"voucherHash,omitempty"`
+
+	// Time the voucher was imported into the onboarding server
+	// +optional
+	ImportTime *metav1.Time `json:"importTime,omitempty"`
+
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=fdoov
+//+kubebuilder:printcolumn:name="GUID",type=string,JSONPath=`.status.guid`
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.spec.onboardingServer.name`
+//+kubebuilder:printcolumn:name="Imported",type=string,JSONPath=`.status.conditions[?(@.type=="Imported")].status`
+//+kubebuilder:printcolumn:name="Registered",type=string,JSONPath=`.status.conditions[?(@.type=="RendezvousRegistered")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOOwnershipVoucher is the Schema for the fdoownershipvouchers API. It imports an ownership
+// voucher into an onboarding server, and removes it from the server when deleted.
+type FDOOwnershipVoucher struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOOwnershipVoucherSpec   `json:"spec,omitempty"`
+	Status FDOOwnershipVoucherStatus `json:"status,omitempty"`
+}
+
+func (m *FDOOwnershipVoucher) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOOwnershipVoucher) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// FDOOwnershipVoucherList contains a list of FDOOwnershipVoucher
+type FDOOwnershipVoucherList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOOwnershipVoucher `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOOwnershipVoucher{}, &FDOOwnershipVoucherList{})
+}
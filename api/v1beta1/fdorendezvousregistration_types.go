@@ -0,0 +1,78 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDORendezvousRegistrationStatus describes the TO0 registration of a device
+type FDORendezvousRegistrationStatus struct {
+	// GUID of the device
+	GUID string `json:"guid"`
+
+	// Name of the rendezvous server holding the registration
+	Server string `json:"server"`
+
+	// Public key of the owner, which registered the device
+	OwnerKey VoucherKey `json:"ownerKey"`
+
+	// Addresses of the owner onboarding server given to the device, e.g. "dns=owner.example.com port=8081 protocol=http"
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Time the registration was last written to the store
+	// +optional
+	RegistrationTime *metav1.Time `json:"registrationTime,omitempty"`
+
+	// Time the registration expires, unset if the store doesn't record it
+	// +optional
+	ExpiryTime *metav1.Time `json:"expiryTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=fdorvreg
+//+kubebuilder:printcolumn:name="GUID",type=string,JSONPath=`.status.guid`
+//+kubebuilder:printcolumn:name="Server",type=string,JSONPath=`.status.server`
+//+kubebuilder:printcolumn:name="Expires",type=string,JSONPath=`.status.expiryTime`
+//+kubebuilder:printcolumn:name="Owner Key",type=string,JSONPath=`.status.ownerKey.fingerprint`,priority=1
+//+kubebuilder:printcolumn:name="Addresses",type=string,JSONPath=`.status.addresses`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDORendezvousRegistration is the Schema for the fdorendezvousregistrations API. It describes
+// the registration of a device held by a rendezvous server. Registrations are written by the
+// operator from the registration inventory of the servers with registrationObjects, and deleted
+// when the registration leaves the store.
+type FDORendezvousRegistration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status FDORendezvousRegistrationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FDORendezvousRegistrationList contains a list of FDORendezvousRegistration
+type FDORendezvousRegistrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDORendezvousRegistration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDORendezvousRegistration{}, &FDORendezvousRegistrationList{})
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDORendezvousServerSpec defines the desired state of FDORendezvousServer
+type FDORendezvousServerSpec struct {
+	// Rendezvous server container image, defaults to quay.io/fido-fdo/rendezvous-server:0.4
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Exposure of the server outside of the cluster
+	// +optional
+	Expose *Expose `json:"expose,omitempty"`
+
+	// Number of server pods, defaults to 1. More than one pod requires a Postgres database.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Storage of the server
+	// +optional
+	Storage *RendezvousStorage `json:"storage,omitempty"`
+
+	// Keys and certificates of the server
+	// +optional
+	Keys *RendezvousKeys `json:"keys,omitempty"`
+
+	// Customization of the server pods
+	// +optional
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// Write a read-only FDORendezvousRegistration describing each registration of the server,
+	// requires the registrations to be stored in a directory
+	// +optional
+	RegistrationObjects bool `json:"registrationObjects,omitempty"`
+}
+
+// RendezvousStorage defines the storage of a rendezvous server
+type RendezvousStorage struct {
+	// Persistent volume claim for device registrations, registrations are lost
+	// when the server restarts if not set
+	// +optional
+	RegistrationsClaimName string `json:"registrationsClaimName,omitempty"`
+
+	// Database storing the device registrations instead of files
+	// +optional
+	Database *DatabaseStorage `json:"database,omitempty"`
+
+	// Persistent volume claim holding the sessions of the server, which must be ReadWriteMany to be
+	// shared by its pods. Required for more than one replica, each pod keeps its sessions otherwise.
+	// +optional
+	SessionsClaimName string `json:"sessionsClaimName,omitempty"`
+}
+
+// GetDatabase returns the database of the storage, nil if the stores are kept in files
+func (s *RendezvousStorage) GetDatabase() *DatabaseStorage {
+	if s == nil {
+		return nil
+	}
+	return s.Database
+}
+
+// GetSessionsClaimName returns the claim holding the sessions of the server, empty if each pod keeps its sessions
+func (s *RendezvousStorage) GetSessionsClaimName() string {
+	if s == nil {
+		return ""
+	}
+	return s.SessionsClaimName
+}
+
+// RendezvousKeys defines the secrets holding the certificates trusted by a rendezvous server
+type RendezvousKeys struct {
+	// Trusted manufacturer certificate, defaults to the secret fdo-manufacturer-cert
+	// +optional
+	ManufacturerCert *SecretKeyReference `json:"manufacturerCert,omitempty"`
+
+	// Trust bundle of the certificates of several manufacturers, instead of manufacturerCert
+	// +optional
+	ManufacturerTrustBundle *TrustBundleReference `json:"manufacturerTrustBundle,omitempty"`
+}
+
+// FDORendezvousServerStatus defines the observed state of FDORendezvousServer
+type FDORendezvousServerStatus struct {
+	ServerStatus `json:",inline"`
+
+	// Image running the rendezvous server
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Registrations in the store of the server, read when the registrations are stored in a directory
+	// +optional
+	Inventory *RegistrationInventoryStatus `json:"inventory,omitempty"`
+}
+
+// RegistrationInventoryStatus counts the device registrations in the store of a rendezvous server
+type RegistrationInventoryStatus struct {
+	// Number of registrations in the store
+	Registrations int32 `json:"registrations"`
+
+	// Number of registrations past their expiry time, which the server has not removed yet
+	// +optional
+	Expired int32 `json:"expired,omitempty"`
+
+	// Number of files of the store that are not registrations
+	// +optional
+	Invalid int32 `json:"invalid,omitempty"`
+
+	// Registrations that have not expired by time left until they expire
+	// +optional
+	// +listType=atomic
+	Expiry []RegistrationExpiryBucket `json:"expiry,omitempty"`
+
+	// Time the next registration expires
+	// +optional
+	NextExpiryTime *metav1.Time `json:"nextExpiryTime,omitempty"`
+
+	// Time of the last scan of the store
+	// +optional
+	LastScanTime *metav1.Time `json:"lastScanTime,omitempty"`
+}
+
+// RegistrationExpiryBucket counts the registrations expiring within a duration, and after the
+// duration of the previous bucket
+type RegistrationExpiryBucket struct {
+	// Upper bound of the time left until the registrations expire, unset for the registrations
+	// expiring after the last bound or without expiry time
+	// +optional
+	Within *metav1.Duration `json:"within,omitempty"`
+
+	// Number of registrations
+	Count int32 `json:"count"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoints[0]`
+//+kubebuilder:printcolumn:name="Registrations",type=integer,JSONPath=`.status.inventory.registrations`,priority=1
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.image`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDORendezvousServer is the Schema for the fdorendezvousservers API
+type FDORendezvousServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDORendezvousServerSpec   `json:"spec,omitempty"`
+	Status FDORendezvousServerStatus `json:"status,omitempty"`
+}
+
+func (m *FDORendezvousServer) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDORendezvousServer) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+func (m *FDORendezvousServer) GetServerStatus() *ServerStatus {
+	return &m.Status.ServerStatus
+}
+
+//+kubebuilder:object:root=true
+
+// FDORendezvousServerList contains a list of FDORendezvousServer
+type FDORendezvousServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDORendezvousServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDORendezvousServer{}, &FDORendezvousServerList{})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var fdorendezvousserverlog = logf.Log.WithName("fdorendezvousserver-resource")
+
+func (r *FDORendezvousServer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-fdo-redhat-com-v1beta1-fdorendezvousserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdorendezvousservers,verbs=create;update,versions=v1beta1,name=mfdorendezvousserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &FDORendezvousServer{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// The defaults applied are recorded in the AppliedDefaultsAnnotation.
+func (r *FDORendezvousServer) Default() {
+	fdorendezvousserverlog.Info("default", "name", r.Name)
+	d := newDefaulter(r)
+	d.clearString(field.NewPath("spec", "image"), &r.Spec.Image)
+	d.apply(r)
+}
+
+//+kubebuilder:webhook:path=/validate-fdo-redhat-com-v1beta1-fdorendezvousserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=fdo.redhat.com,resources=fdorendezvousservers,verbs=create;update,versions=v1beta1,name=vfdorendezvousserver.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &FDORendezvousServer{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDORendezvousServer) ValidateCreate() (admission.Warnings, error) {
+	fdorendezvousserverlog.Info("validate create", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *FDORendezvousServer) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	fdorendezvousserverlog.Info("validate update", "name", r.Name)
+	return nil, r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *FDORendezvousServer) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *FDORendezvousServer) validate() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("FDORendezvousServer").GroupKind(), r.Name, allErrs)
+}
+
+func (s *FDORendezvousServerSpec) validate(path *field.Path) field.ErrorList {
+	allErrs := ValidateImage(path.Child("image"), s.Image)
+	allErrs = append(allErrs, ValidateStorage(path, s.Replicas, s.Storage.GetDatabase(), s.Storage.GetSessionsClaimName())...)
+	if s.Keys != nil && s.Keys.ManufacturerCert != nil && s.Keys.ManufacturerTrustBundle != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("keys", "manufacturerTrustBundle"), "cannot be set with manufacturerCert"))
+	}
+	if s.RegistrationObjects && s.Storage.GetDatabase() != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("registrationObjects"), "requires registrations stored in a directory"))
+	}
+	return allErrs
+}
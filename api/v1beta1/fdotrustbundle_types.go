@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOTrustBundleSpec defines the desired state of FDOTrustBundle
+type FDOTrustBundleSpec struct {
+	// Sources of the PEM certificates of the bundle
+	// +optional
+	Sources []TrustBundleSource `json:"sources,omitempty"`
+}
+
+// TrustBundleSource selects secrets or config maps holding PEM certificates. Exactly one of secret
+// and configMap must be set.
+type TrustBundleSource struct {
+	// Secrets holding certificates
+	// +optional
+	Secret *TrustBundleObjects `json:"secret,omitempty"`
+
+	// Config maps holding certificates
+	// +optional
+	ConfigMap *TrustBundleObjects `json:"configMap,omitempty"`
+
+	// Leaves the certificates of the source out of the bundle, e.g. to stop trusting a manufacturer
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// TrustBundleObjects selects objects of the namespace of a trust bundle by name or by label.
+// Exactly one of name and selector must be set.
+type TrustBundleObjects struct {
+	// Name of the object
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Label selector of the objects
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Key holding the certificates, defaults to all keys of the objects
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// TrustedCertificate is a certificate of a trust bundle
+type TrustedCertificate struct {
+	// Subject of the certificate
+	Subject string `json:"subject"`
+
+	// SHA-256 fingerprint of the certificate
+	Fingerprint string `json:"fingerprint"`
+
+	// Expiry time of the certificate
+	NotAfter metav1.Time `json:"notAfter"`
+
+	// Source of the certificate, as <kind>/<name>/<key>
+	Source string `json:"source"`
+}
+
+// FDOTrustBundleStatus defines the observed state of FDOTrustBundle
+type FDOTrustBundleStatus struct {
+	// Generation of the trust bundle last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Secret holding the combined bundle
+	// +optional
+	Secret string `json:"secret,omitempty"`
+
+	// Certificates of the bundle
+	// +optional
+	Certificates []TrustedCertificate `json:"certificates,omitempty"`
+
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secret`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOTrustBundle is the Schema for the fdotrustbundles API. It combines the PEM certificates of
+// secrets and config maps into a bundle, e.g. the device CA certificates of several manufacturers
+// trusted by an onboarding server.
+type FDOTrustBundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOTrustBundleSpec   `json:"spec,omitempty"`
+	Status FDOTrustBundleStatus `json:"status,omitempty"`
+}
+
+func (m *FDOTrustBundle) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOTrustBundle) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// FDOTrustBundleList contains a list of FDOTrustBundle
+type FDOTrustBundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOTrustBundle `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOTrustBundle{}, &FDOTrustBundleList{})
+}
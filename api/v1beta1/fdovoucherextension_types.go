@@ -0,0 +1,152 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOVoucherExtensionSpec defines the desired state of FDOVoucherExtension
+type FDOVoucherExtensionSpec struct {
+	// Voucher to extend, owned by the owner key
+	Source VoucherExtensionSource `json:"source"`
+
+	// Secret holding the private key of the current owner of the voucher, in DER or PEM format, the
+	// key defaults to owner_key.der
+	OwnerKey SecretKeyReference `json:"ownerKey"`
+
+	// Secret holding the certificate of the next owner in PEM format, the key defaults to owner_cert.pem.
+	// Its key must be of the same type as the owner key.
+	NextOwnerCert SecretKeyReference `json:"nextOwnerCert"`
+
+	// Where the extended voucher is written
+	Output VoucherExtensionOutput `json:"output"`
+}
+
+// VoucherExtensionSource selects the voucher to extend, exactly one of secret and ownershipVoucher
+// must be set
+type VoucherExtensionSource struct {
+	// Secret holding the voucher in PEM or CBOR format, the key defaults to voucher
+	// +optional
+	Secret *SecretKeyReference `json:"secret,omitempty"`
+
+	// FDOOwnershipVoucher holding the voucher, in the namespace of the extension
+	// +optional
+	OwnershipVoucher *OwnershipVoucherReference `json:"ownershipVoucher,omitempty"`
+}
+
+// OwnershipVoucherReference references an FDOOwnershipVoucher in the namespace of an object
+type OwnershipVoucherReference struct {
+	// Name of the ownership voucher
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// VoucherExtensionOutput selects where an extended voucher is written, exactly one of secret and
+// ownershipVoucher must be set. The output is owned by the extension.
+type VoucherExtensionOutput struct {
+	// Secret the voucher is written to in PEM format, the key defaults to voucher
+	// +optional
+	Secret *SecretKeyReference `json:"secret,omitempty"`
+
+	// FDOOwnershipVoucher created with the voucher, which imports it into an onboarding server
+	// +optional
+	OwnershipVoucher *ExtendedOwnershipVoucher `json:"ownershipVoucher,omitempty"`
+}
+
+// ExtendedOwnershipVoucher describes the FDOOwnershipVoucher created for an extended voucher
+type ExtendedOwnershipVoucher struct {
+	// Name of the ownership voucher
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Onboarding server the voucher is imported into
+	OnboardingServer OnboardingServerReference `json:"onboardingServer"`
+}
+
+// FDOVoucherExtensionStatus defines the observed state of FDOVoucherExtension
+type FDOVoucherExtensionStatus struct {
+	// Generation of the extension last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// GUID of the device of the voucher
+	// +optional
+	GUID string `json:"guid,omitempty"`
+
+	// Number of entries of the extended voucher
+	// +optional
+	Entries int `json:"entries,omitempty"`
+
+	// SHA-256 fingerprint of the public key of the next owner
+	// +optional
+	NextOwnerFingerprint string `json:"nextOwnerFingerprint,omitempty"`
+
+	// SHA-256 hash of the source voucher and the keys, the voucher is extended again when it changes
+	// +optional
+	InputHash string `json:"inputHash,omitempty"`
+
+	// Time the voucher was extended
+	// +optional
+	ExtensionTime *metav1.Time `json:"extensionTime,omitempty"`
+
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=fdovext
+//+kubebuilder:printcolumn:name="GUID",type=string,JSONPath=`.status.guid`
+//+kubebuilder:printcolumn:name="Entries",type=integer,JSONPath=`.status.entries`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOVoucherExtension is the Schema for the fdovoucherextensions API. It extends an ownership
+// voucher to the next owner, appending an entry signed with the key of the current owner.
+type FDOVoucherExtension struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOVoucherExtensionSpec   `json:"spec,omitempty"`
+	Status FDOVoucherExtensionStatus `json:"status,omitempty"`
+}
+
+func (m *FDOVoucherExtension) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOVoucherExtension) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// FDOVoucherExtensionList contains a list of FDOVoucherExtension
+type FDOVoucherExtensionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOVoucherExtension `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOVoucherExtension{}, &FDOVoucherExtensionList{})
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FDOVoucherSyncSpec defines the desired state of FDOVoucherSync
+type FDOVoucherSyncSpec struct {
+	// Manufacturing server whose vouchers are pushed, in the namespace of the sync. The server must
+	// store its vouchers in a directory, see the voucher inventory.
+	ManufacturingServer ManufacturingServerReference `json:"manufacturingServer"`
+
+	// Onboarding servers the vouchers are pushed to
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MinItems=1
+	Targets []VoucherSyncTarget `json:"targets"`
+
+	// Interval between two scans of the vouchers of the manufacturing server, defaults to 1m
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// ManufacturingServerReference references an FDOManufacturingServer in the namespace of an object
+type ManufacturingServerReference struct {
+	// Name of the manufacturing server
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// VoucherSyncTarget is an onboarding server receiving vouchers, either an FDOOnboardingServer of
+// the cluster or the OV management API of a remote owner-onboarding server. Exactly one of
+// onboardingServer and url must be set.
+type VoucherSyncTarget struct {
+	// Name of the target in the status, a valid config map key
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	Name string `json:"name"`
+
+	// Onboarding server of the cluster
+	// +optional
+	OnboardingServer *NamespacedOnboardingServerReference `json:"onboardingServer,omitempty"`
+
+	// Base URL of a remote owner-onboarding server, e.g. https://onboarding.example.com
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Secret holding the bearer token sent to the target, the key defaults to token
+	// +optional
+	AuthSecret *SecretKeyReference `json:"authSecret,omitempty"`
+
+	// Secret holding the PEM certificates of the CAs trusted to serve the URL, the key defaults to ca.crt
+	// +optional
+	CASecret *SecretKeyReference `json:"caSecret,omitempty"`
+}
+
+// NamespacedOnboardingServerReference references an FDOOnboardingServer of any namespace
+type NamespacedOnboardingServerReference struct {
+	// Name of the onboarding server
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the onboarding server, defaults to the namespace of the referencing object. Other
+	// namespaces require the operator to run with --allow-cross-namespace-voucher-sync.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// VoucherSyncTargetStatus reports the delivery of vouchers to a target
+type VoucherSyncTargetStatus struct {
+	// Name of the target
+	Name string `json:"name"`
+
+	// Number of vouchers of the manufacturing server delivered to the target
+	Delivered int32 `json:"delivered"`
+
+	// Number of vouchers of the manufacturing server waiting to be delivered
+	Pending int32 `json:"pending"`
+
+	// Number of consecutive failed deliveries, deliveries are retried with an exponential backoff
+	// +optional
+	Failures int32 `json:"failures,omitempty"`
+
+	// Error of the last failed delivery
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Time of the last delivered voucher
+	// +optional
+	LastDeliveryTime *metav1.Time `json:"lastDeliveryTime,omitempty"`
+}
+
+// FDOVoucherSyncStatus defines the observed state of FDOVoucherSync
+type FDOVoucherSyncStatus struct {
+	// Generation of the sync last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Number of vouchers of the manufacturing server
+	// +optional
+	Vouchers int32 `json:"vouchers,omitempty"`
+
+	// Delivery status of each target
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Targets []VoucherSyncTargetStatus `json:"targets,omitempty"`
+
+	// Time of the last scan of the vouchers of the manufacturing server
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.manufacturingServer.name`
+//+kubebuilder:printcolumn:name="Vouchers",type=integer,JSONPath=`.status.vouchers`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FDOVoucherSync is the Schema for the fdovouchersyncs API. It pushes the vouchers of a
+// manufacturing server to onboarding servers of the cluster or of other clusters, each voucher
+// being delivered once to each target.
+type FDOVoucherSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FDOVoucherSyncSpec   `json:"spec,omitempty"`
+	Status FDOVoucherSyncStatus `json:"status,omitempty"`
+}
+
+func (m *FDOVoucherSync) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *FDOVoucherSync) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// FDOVoucherSyncList contains a list of FDOVoucherSync
+type FDOVoucherSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FDOVoucherSync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FDOVoucherSync{}, &FDOVoucherSyncList{})
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateHostname checks that a value is a valid DNS name
+func ValidateHostname(path *field.Path, hostname string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(hostname) {
+		allErrs = append(allErrs, field.Invalid(path, hostname, msg))
+	}
+	return allErrs
+}
+
+// ValidateIPAddress checks that a value is a valid IPv4 or IPv6 address
+func ValidateIPAddress(path *field.Path, ip string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if net.ParseIP(ip) == nil {
+		allErrs = append(allErrs, field.Invalid(path, ip, "must be a valid IP address, (e.g. 10.9.8.7 or 2001:db8::ffff)"))
+	}
+	return allErrs
+}
+
+// ValidatePort checks that a port, if set, is in the valid range
+func ValidatePort(path *field.Path, port uint16) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if port == 0 {
+		return allErrs
+	}
+	for _, msg := range validation.IsValidPortNum(int(port)) {
+		allErrs = append(allErrs, field.Invalid(path, port, msg))
+	}
+	return allErrs
+}
+
+// ValidateFilePermissions checks that file permissions, if set, are in octal notation (e.g. 644 or 0755)
+func ValidateFilePermissions(path *field.Path, permissions string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if permissions == "" {
+		return allErrs
+	}
+	if len(permissions) > 4 {
+		return append(allErrs, field.Invalid(path, permissions, "must be at most 4 octal digits"))
+	}
+	if _, err := strconv.ParseUint(permissions, 8, 32); err != nil {
+		allErrs = append(allErrs, field.Invalid(path, permissions, "must be in octal notation, e.g. 644 or 0755"))
+	}
+	return allErrs
+}
+
+// ValidateStorage checks that a database is either managed by the operator or has a driver, and a
+// connection secret for Postgres only, and that several replicas share their stores through a
+// Postgres database and their sessions through a volume, since Directory and Sqlite stores are local
+// to a pod
+func ValidateStorage(path *field.Path, replicas *int32, db *DatabaseStorage, sessionsClaimName string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	dbPath := path.Child("storage", "database")
+	switch {
+	case db == nil:
+	case db.Managed:
+		if db.Driver != "" && db.Driver != DatabaseDriverPostgres {
+			allErrs = append(allErrs, field.Invalid(dbPath.Child("driver"), db.Driver, "a managed database is a Postgres database"))
+		}
+		if db.ConnectionSecret != nil {
+			allErrs = append(allErrs, field.Forbidden(dbPath.Child("connectionSecret"), "cannot be set for a managed database"))
+		}
+	case db.Driver == "":
+		allErrs = append(allErrs, field.Required(dbPath.Child("driver"), "required unless the database is managed"))
+	case db.Driver == DatabaseDriverPostgres && db.ConnectionSecret == nil:
+		allErrs = append(allErrs, field.Required(dbPath.Child("connectionSecret"), "required for Postgres"))
+	case db.Driver == DatabaseDriverSqlite && db.ConnectionSecret != nil:
+		allErrs = append(allErrs, field.Forbidden(dbPath.Child("connectionSecret"), "cannot be set for Sqlite, its database is a file of the store volume"))
+	}
+	if replicas != nil && *replicas > 1 && !db.IsPostgres() {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *replicas, "more than one replica requires a Postgres database"))
+	}
+	if replicas != nil && *replicas > 1 && sessionsClaimName == "" {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *replicas, "more than one replica requires storage.sessionsClaimName"))
+	}
+	return allErrs
+}
+
+// ValidateKeyPair checks that a key pair is either issued by a Certificate, read from a TLS secret
+// or read from the cert and key secrets, and that a private key read from a key source doesn't come
+// with another private key
+func ValidateKeyPair(path *field.Path, p *KeyPairReference) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if p == nil {
+		return allErrs
+	}
+	if p.KeySource != nil {
+		allErrs = append(allErrs, ValidateKeySource(path.Child("keySource"), p.KeySource)...)
+		if p.Key != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("key"), "cannot be set with a key source"))
+		}
+		if p.TLSSecret != nil || p.Certificate != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("keySource"), "cannot be set with a TLS secret or a certificate"))
+		}
+		return allErrs
+	}
+	source := ""
+	switch {
+	case p.Certificate != nil:
+		source = "a certificate"
+		if p.TLSSecret != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("tlsSecret"), "cannot be set with a certificate"))
+		}
+	case p.TLSSecret != nil:
+		source = "a TLS secret"
+	default:
+		return allErrs
+	}
+	if p.Cert != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("cert"), "cannot be set with "+source))
+	}
+	if p.Key != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("key"), "cannot be set with "+source))
+	}
+	return allErrs
+}
+
+// ValidateKeySource checks that a key source selects exactly one key store, key and authentication method
+func ValidateKeySource(path *field.Path, s *KeySource) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if s.Vault == nil {
+		return append(allErrs, field.Required(path.Child("vault"), "a key store must be set"))
+	}
+	vaultPath := path.Child("vault")
+	if (s.Vault.KV == nil) == (s.Vault.Transit == nil) {
+		allErrs = append(allErrs, field.Invalid(vaultPath, "", "exactly one of kv and transit must be set"))
+	}
+	if (s.Vault.Auth.Kubernetes == nil) == (s.Vault.Auth.TokenSecret == nil) {
+		allErrs = append(allErrs, field.Invalid(vaultPath.Child("auth"), "", "exactly one of kubernetes and tokenSecret must be set"))
+	}
+	return allErrs
+}
+
+// ValidateImage checks that a container image reference is well-formed enough to be pulled
+func ValidateImage(path *field.Path, image string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if image == "" {
+		return allErrs
+	}
+	if strings.TrimSpace(image) != image || strings.ContainsAny(image, " \t\n") {
+		allErrs = append(allErrs, field.Invalid(path, image, "must not contain whitespace"))
+	}
+	return allErrs
+}
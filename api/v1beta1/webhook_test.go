@@ -0,0 +1,393 @@
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("FDOManufacturingServer webhook", func() {
+	var server *FDOManufacturingServer
+
+	BeforeEach(func() {
+		server = &FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: FDOManufacturingServerSpec{
+				RendezvousServers: []RendezvousServer{
+					{DNS: "rendezvous.example.com", DevicePort: 80, OwnerPort: 80, Protocol: "http"},
+				},
+				Protocols: Protocols{
+					PlainDI: false,
+					DIUN:    &DIUN{KeyType: "SECP256R1", AllowedKeyStorageTypes: []KeyStorageType{"FileSystem"}},
+				},
+			},
+		}
+	})
+
+	It("should accept a valid server", func() {
+		_, err := server.ValidateCreate()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject both DNS and IP address for a rendezvous server", func() {
+		server.Spec.RendezvousServers[0].IPAddress = "10.0.0.1"
+		_, err := server.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.rendezvousServers[0].ipAddress"))
+	})
+
+	It("should reject a rendezvous server without DNS and IP address", func() {
+		server.Spec.RendezvousServers[0].DNS = ""
+		_, err := server.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.rendezvousServers[0]"))
+	})
+
+	It("should reject an invalid IP address", func() {
+		server.Spec.RendezvousServers[0].DNS = ""
+		server.Spec.RendezvousServers[0].IPAddress = "10.0.0.256"
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.rendezvousServers[0].ipAddress"))
+	})
+
+	It("should reject an invalid hostname", func() {
+		server.Spec.RendezvousServers[0].DNS = "Not_A_Host"
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.rendezvousServers[0].dns"))
+	})
+
+	It("should require DIUN when plain DI is false", func() {
+		server.Spec.Protocols.DIUN = nil
+		_, err := server.ValidateUpdate(server)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.protocols.diun"))
+	})
+
+	It("should require at least one rendezvous server", func() {
+		server.Spec.RendezvousServers = nil
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.rendezvousServers"))
+	})
+
+	It("should reject secrets set with a certificate", func() {
+		server.Spec.Keys = &ManufacturingKeys{
+			DeviceCA: &KeyPairReference{
+				Certificate: &CertManagerCertificate{Name: "device-ca"},
+				Key:         &SecretKeyReference{Name: "device-ca-key"},
+			},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.deviceCA.key"))
+
+		server.Spec.Keys.DeviceCA.Key = nil
+		_, err = server.ValidateCreate()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reject secrets set with a TLS secret", func() {
+		server.Spec.Keys = &ManufacturingKeys{
+			DIUN: &KeyPairReference{
+				TLSSecret: &TLSSecretReference{Name: "diun-tls"},
+				Cert:      &SecretKeyReference{Name: "diun-cert"},
+			},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.diun.cert"))
+	})
+
+	It("should require a Postgres database and shared sessions for several replicas", func() {
+		replicas := int32(2)
+		server.Spec.Replicas = &replicas
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("requires a Postgres database"))
+
+		server.Spec.Storage = &ManufacturingStorage{Database: &DatabaseStorage{Driver: DatabaseDriverPostgres}}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.storage.database.connectionSecret"))
+
+		server.Spec.Storage.Database.ConnectionSecret = &SecretKeyReference{Name: "fdo-db"}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("storage.sessionsClaimName"))
+
+		server.Spec.Storage.SessionsClaimName = "fdo-sessions"
+		_, err = server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should accept a Sqlite database for a single replica only", func() {
+		server.Spec.Storage = &ManufacturingStorage{Database: &DatabaseStorage{Driver: DatabaseDriverSqlite}}
+		_, err := server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Spec.Storage.Database.ConnectionSecret = &SecretKeyReference{Name: "fdo-db"}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("cannot be set for Sqlite"))
+
+		replicas := int32(2)
+		server.Spec.Replicas = &replicas
+		server.Spec.Storage = &ManufacturingStorage{Database: &DatabaseStorage{Driver: DatabaseDriverSqlite}, SessionsClaimName: "fdo-sessions"}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("requires a Postgres database"))
+	})
+
+	It("should accept a managed database without connection secret", func() {
+		replicas := int32(2)
+		server.Spec.Replicas = &replicas
+		server.Spec.Storage = &ManufacturingStorage{Database: &DatabaseStorage{Managed: true}, SessionsClaimName: "fdo-sessions"}
+		_, err := server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Spec.Storage.Database.ConnectionSecret = &SecretKeyReference{Name: "fdo-db"}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.storage.database.connectionSecret"))
+
+		server.Spec.Storage.Database = &DatabaseStorage{}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.storage.database.driver"))
+	})
+
+	It("should require a directory store for the voucher export API", func() {
+		server.Spec.VoucherExport = &VoucherExport{TokenSecret: SecretKeyReference{Name: "mes-token"}}
+		_, err := server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Spec.Storage = &ManufacturingStorage{Database: &DatabaseStorage{Managed: true}}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.voucherExport"))
+	})
+
+	It("should require one store, secret engine and auth method in key sources", func() {
+		source := &KeySource{Vault: &VaultKeySource{
+			Address: "https://vault.example.com:8200",
+			Auth:    VaultAuth{Kubernetes: &VaultKubernetesAuth{Role: "fdo"}},
+			KV:      &VaultKV{Path: "fdo/manufacturer"},
+		}}
+		server.Spec.Keys = &ManufacturingKeys{
+			Manufacturer: &KeyPairReference{KeySource: source, Key: &SecretKeyReference{Name: "manufacturer-key"}},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.manufacturer.key"))
+
+		server.Spec.Keys.Manufacturer.Key = nil
+		_, err = server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		source.Vault.Transit = &VaultTransit{Key: "fdo-manufacturer"}
+		source.Vault.Auth.TokenSecret = &SecretKeyReference{Name: "vault-token"}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.manufacturer.keySource.vault: Invalid value"))
+		Expect(err.Error()).To(ContainSubstring("spec.keys.manufacturer.keySource.vault.auth"))
+	})
+})
+
+var _ = Describe("FDOOnboardingServer webhook", func() {
+	It("should require an authentication method for the initial user", func() {
+		server := &FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: FDOOnboardingServerSpec{
+				ServiceInfo: &ServiceInfo{InitialUser: &InitialUser{Username: "admin"}},
+			},
+		}
+		_, err := server.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.serviceInfo.initialUser"))
+
+		server.Spec.ServiceInfo.InitialUser.SSHKeys = []string{"ssh-ed25519 AAAA"}
+		_, err = server.ValidateCreate()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should require an archive for the Archive retention action and a directory store", func() {
+		server := &FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: FDOOnboardingServerSpec{
+				Retention: &VoucherRetention{Days: 30, Action: VoucherRetentionArchive},
+			},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.retention.archive"))
+		Expect(err.Error()).To(ContainSubstring("spec.retention.stateClaimName"))
+		server.Spec.Retention.StateClaimName = "retention-state"
+
+		server.Spec.Retention.Archive = &VoucherArchive{PersistentVolumeClaim: "archive", Secrets: true}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+
+		server.Spec.Retention.Archive.Secrets = false
+		_, err = server.ValidateCreate()
+		Expect(err).ToNot(HaveOccurred())
+
+		server.Spec.Storage = &OnboardingStorage{Database: &DatabaseStorage{Managed: true}}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("requires vouchers stored in a directory"))
+	})
+
+	It("should require a directory store for device objects", func() {
+		server := &FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec:       FDOOnboardingServerSpec{DeviceObjects: true},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Spec.Storage = &OnboardingStorage{Database: &DatabaseStorage{Managed: true}}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.deviceObjects"))
+	})
+})
+
+var _ = Describe("FDORendezvousServer webhook", func() {
+	It("should require a directory store for registration objects", func() {
+		server := &FDORendezvousServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec:       FDORendezvousServerSpec{RegistrationObjects: true},
+		}
+		_, err := server.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Spec.Storage = &RendezvousStorage{Database: &DatabaseStorage{Managed: true}}
+		_, err = server.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.registrationObjects"))
+	})
+})
+
+var _ = Describe("ValidateFilePermissions", func() {
+	It("should accept octal permissions", func() {
+		Expect(ValidateFilePermissions(nil, "644")).To(BeEmpty())
+		Expect(ValidateFilePermissions(nil, "0755")).To(BeEmpty())
+		Expect(ValidateFilePermissions(nil, "")).To(BeEmpty())
+	})
+
+	It("should reject non-octal permissions", func() {
+		Expect(ValidateFilePermissions(nil, "899")).ToNot(BeEmpty())
+		Expect(ValidateFilePermissions(nil, "rwx")).ToNot(BeEmpty())
+		Expect(ValidateFilePermissions(nil, "07777")).ToNot(BeEmpty())
+	})
+})
+
+var _ = Describe("FDOManufacturingServer defaulting", func() {
+	var server *FDOManufacturingServer
+
+	BeforeEach(func() {
+		server = &FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server"},
+			Spec: FDOManufacturingServerSpec{
+				RendezvousServers: []RendezvousServer{{DNS: "rendezvous.example.com"}},
+				Protocols:         Protocols{DIUN: &DIUN{KeyType: "SECP256R1"}},
+			},
+		}
+	})
+
+	It("should set and record defaults", func() {
+		server.Default()
+		Expect(server.Spec.Image).To(BeEmpty())
+		Expect(server.Spec.ImageOrDefault()).To(Equal(DefaultManufacturingImage))
+		Expect(server.Spec.RendezvousServers[0].Protocol).To(Equal("tls"))
+		Expect(server.Spec.RendezvousServers[0].DevicePort).To(Equal(uint16(8041)))
+		Expect(server.Spec.RendezvousServers[0].OwnerPort).To(Equal(uint16(8041)))
+		Expect(server.Spec.Protocols.DIUN.AllowedKeyStorageTypes).To(Equal(DefaultAllowedKeyStorageTypes))
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).To(MatchJSON(`{
+			"spec.rendezvousServers[0].protocol": "tls",
+			"spec.rendezvousServers[0].devicePort": "8041",
+			"spec.rendezvousServers[0].ownerPort": "8041",
+			"spec.protocols.diun.allowedKeyStorageTypes": "FileSystem,Tpm"
+		}`))
+	})
+
+	It("should use the well-known ports of the protocol", func() {
+		server.Spec.RendezvousServers[0].Protocol = "http"
+		server.Spec.RendezvousServers[0].OwnerPort = 8080
+		server.Default()
+		Expect(server.Spec.RendezvousServers[0].DevicePort).To(Equal(uint16(80)))
+		Expect(server.Spec.RendezvousServers[0].OwnerPort).To(Equal(uint16(8080)))
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).ToNot(ContainSubstring("spec.rendezvousServers[0].ownerPort"))
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).ToNot(ContainSubstring("spec.rendezvousServers[0].protocol"))
+	})
+
+	It("should keep recorded defaults until changed by the user", func() {
+		server.Default()
+		server.Default()
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).To(ContainSubstring("spec.rendezvousServers[0].protocol"))
+
+		server.Spec.RendezvousServers[0].Protocol = "tcp"
+		server.Default()
+		Expect(server.Spec.RendezvousServers[0].Protocol).To(Equal("tcp"))
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).ToNot(ContainSubstring("spec.rendezvousServers[0].protocol"))
+	})
+
+	It("should clear an image default persisted by an earlier version", func() {
+		server.Annotations = map[string]string{AppliedDefaultsAnnotation: `{"spec.image": "quay.io/fido-fdo/manufacturing-server:0.3"}`}
+		server.Spec.Image = "quay.io/fido-fdo/manufacturing-server:0.3"
+		server.Default()
+		Expect(server.Spec.Image).To(BeEmpty())
+		Expect(server.Spec.ImageOrDefault()).To(Equal(DefaultManufacturingImage))
+		Expect(server.Annotations[AppliedDefaultsAnnotation]).ToNot(ContainSubstring("spec.image"))
+	})
+
+	It("should keep an image set by the user", func() {
+		server.Spec.Image = "quay.io/example/manufacturing-server:latest"
+		server.Default()
+		Expect(server.Spec.Image).To(Equal("quay.io/example/manufacturing-server:latest"))
+	})
+})
+
+var _ = Describe("FDORendezvousServer defaulting", func() {
+	It("should resolve the default image without persisting it", func() {
+		server := &FDORendezvousServer{}
+		server.Default()
+		Expect(server.Spec.Image).To(BeEmpty())
+		Expect(server.Spec.ImageOrDefault()).To(Equal(DefaultRendezvousImage))
+		Expect(server.Annotations).ToNot(HaveKey(AppliedDefaultsAnnotation))
+	})
+
+	It("should not annotate a server without defaults", func() {
+		server := &FDORendezvousServer{Spec: FDORendezvousServerSpec{Image: "quay.io/example/rendezvous-server:latest"}}
+		server.Default()
+		Expect(server.Annotations).ToNot(HaveKey(AppliedDefaultsAnnotation))
+	})
+})
+
+var _ = Describe("Trust bundle references", func() {
+	It("should reject a trust bundle set with a certificate", func() {
+		onboarding := &FDOOnboardingServer{Spec: FDOOnboardingServerSpec{Keys: &OnboardingKeys{
+			DeviceCACert:        &SecretKeyReference{Name: "device-ca"},
+			DeviceCATrustBundle: &TrustBundleReference{Name: "device-cas"},
+		}}}
+		_, err := onboarding.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.deviceCATrustBundle"))
+
+		rendezvous := &FDORendezvousServer{Spec: FDORendezvousServerSpec{Keys: &RendezvousKeys{
+			ManufacturerCert:        &SecretKeyReference{Name: "manufacturer"},
+			ManufacturerTrustBundle: &TrustBundleReference{Name: "manufacturers"},
+		}}}
+		_, err = rendezvous.ValidateCreate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec.keys.manufacturerTrustBundle"))
+
+		rendezvous.Spec.Keys.ManufacturerCert = nil
+		_, err = rendezvous.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,2295 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerCertificate) DeepCopyInto(out *CertManagerCertificate) {
+	*out = *in
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(CertManagerIssuerReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerCertificate.
+func (in *CertManagerCertificate) DeepCopy() *CertManagerCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Command) DeepCopyInto(out *Command) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Command.
+func (in *Command) DeepCopy() *Command {
+	if in == nil {
+		return nil
+	}
+	out := new(Command)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DIUN) DeepCopyInto(out *DIUN) {
+	*out = *in
+	if in.AllowedKeyStorageTypes != nil {
+		in, out := &in.AllowedKeyStorageTypes, &out.AllowedKeyStorageTypes
+		*out = make([]KeyStorageType, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DIUN.
+func (in *DIUN) DeepCopy() *DIUN {
+	if in == nil {
+		return nil
+	}
+	out := new(DIUN)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseStorage) DeepCopyInto(out *DatabaseStorage) {
+	*out = *in
+	if in.ConnectionSecret != nil {
+		in, out := &in.ConnectionSecret, &out.ConnectionSecret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStorage.
+func (in *DatabaseStorage) DeepCopy() *DatabaseStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceServerReference) DeepCopyInto(out *DeviceServerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceServerReference.
+func (in *DeviceServerReference) DeepCopy() *DeviceServerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceServerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskEncryptionClevis) DeepCopyInto(out *DiskEncryptionClevis) {
+	*out = *in
+	out.Binding = in.Binding
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskEncryptionClevis.
+func (in *DiskEncryptionClevis) DeepCopy() *DiskEncryptionClevis {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskEncryptionClevis)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Expose) DeepCopyInto(out *Expose) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Expose.
+func (in *Expose) DeepCopy() *Expose {
+	if in == nil {
+		return nil
+	}
+	out := new(Expose)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtendedOwnershipVoucher) DeepCopyInto(out *ExtendedOwnershipVoucher) {
+	*out = *in
+	out.OnboardingServer = in.OnboardingServer
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtendedOwnershipVoucher.
+func (in *ExtendedOwnershipVoucher) DeepCopy() *ExtendedOwnershipVoucher {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtendedOwnershipVoucher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDODevice) DeepCopyInto(out *FDODevice) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDODevice.
+func (in *FDODevice) DeepCopy() *FDODevice {
+	if in == nil {
+		return nil
+	}
+	out := new(FDODevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDODevice) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDODeviceList) DeepCopyInto(out *FDODeviceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDODevice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDODeviceList.
+func (in *FDODeviceList) DeepCopy() *FDODeviceList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDODeviceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDODeviceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDODeviceStatus) DeepCopyInto(out *FDODeviceStatus) {
+	*out = *in
+	out.Server = in.Server
+	out.ManufacturerKey = in.ManufacturerKey
+	out.OwnerKey = in.OwnerKey
+	if in.RendezvousInfo != nil {
+		in, out := &in.RendezvousInfo, &out.RendezvousInfo
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VoucherTime != nil {
+		in, out := &in.VoucherTime, &out.VoucherTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDODeviceStatus.
+func (in *FDODeviceStatus) DeepCopy() *FDODeviceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDODeviceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOKeySet) DeepCopyInto(out *FDOKeySet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOKeySet.
+func (in *FDOKeySet) DeepCopy() *FDOKeySet {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOKeySet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOKeySet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOKeySetList) DeepCopyInto(out *FDOKeySetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOKeySet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOKeySetList.
+func (in *FDOKeySetList) DeepCopy() *FDOKeySetList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOKeySetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOKeySetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOKeySetSpec) DeepCopyInto(out *FDOKeySetSpec) {
+	*out = *in
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]KeyRole, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(KeyRotation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOKeySetSpec.
+func (in *FDOKeySetSpec) DeepCopy() *FDOKeySetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOKeySetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOKeySetStatus) DeepCopyInto(out *FDOKeySetStatus) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]KeySetKeyStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOKeySetStatus.
+func (in *FDOKeySetStatus) DeepCopy() *FDOKeySetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOKeySetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOManufacturingServer) DeepCopyInto(out *FDOManufacturingServer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOManufacturingServer.
+func (in *FDOManufacturingServer) DeepCopy() *FDOManufacturingServer {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOManufacturingServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOManufacturingServer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOManufacturingServerList) DeepCopyInto(out *FDOManufacturingServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOManufacturingServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOManufacturingServerList.
+func (in *FDOManufacturingServerList) DeepCopy() *FDOManufacturingServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOManufacturingServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOManufacturingServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOManufacturingServerSpec) DeepCopyInto(out *FDOManufacturingServerSpec) {
+	*out = *in
+	if in.RendezvousServers != nil {
+		in, out := &in.RendezvousServers, &out.RendezvousServers
+		*out = make([]RendezvousServer, len(*in))
+		copy(*out, *in)
+	}
+	in.Protocols.DeepCopyInto(&out.Protocols)
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(Expose)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(ManufacturingStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = new(ManufacturingKeys)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VoucherExport != nil {
+		in, out := &in.VoucherExport, &out.VoucherExport
+		*out = new(VoucherExport)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOManufacturingServerSpec.
+func (in *FDOManufacturingServerSpec) DeepCopy() *FDOManufacturingServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOManufacturingServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOManufacturingServerStatus) DeepCopyInto(out *FDOManufacturingServerStatus) {
+	*out = *in
+	in.ServerStatus.DeepCopyInto(&out.ServerStatus)
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(VoucherInventoryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExportEndpoints != nil {
+		in, out := &in.ExportEndpoints, &out.ExportEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOManufacturingServerStatus.
+func (in *FDOManufacturingServerStatus) DeepCopy() *FDOManufacturingServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOManufacturingServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOnboardingServer) DeepCopyInto(out *FDOOnboardingServer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOnboardingServer.
+func (in *FDOOnboardingServer) DeepCopy() *FDOOnboardingServer {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOnboardingServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOOnboardingServer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOnboardingServerList) DeepCopyInto(out *FDOOnboardingServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOOnboardingServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOnboardingServerList.
+func (in *FDOOnboardingServerList) DeepCopy() *FDOOnboardingServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOnboardingServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOOnboardingServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOnboardingServerSpec) DeepCopyInto(out *FDOOnboardingServerSpec) {
+	*out = *in
+	if in.ServiceInfo != nil {
+		in, out := &in.ServiceInfo, &out.ServiceInfo
+		*out = new(ServiceInfo)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(Expose)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(OnboardingStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = new(OnboardingKeys)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(VoucherRetention)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOnboardingServerSpec.
+func (in *FDOOnboardingServerSpec) DeepCopy() *FDOOnboardingServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOnboardingServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOnboardingServerStatus) DeepCopyInto(out *FDOOnboardingServerStatus) {
+	*out = *in
+	in.ServerStatus.DeepCopyInto(&out.ServerStatus)
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(VoucherInventoryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(VoucherRetentionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOnboardingServerStatus.
+func (in *FDOOnboardingServerStatus) DeepCopy() *FDOOnboardingServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOnboardingServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOwnershipVoucher) DeepCopyInto(out *FDOOwnershipVoucher) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOwnershipVoucher.
+func (in *FDOOwnershipVoucher) DeepCopy() *FDOOwnershipVoucher {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOwnershipVoucher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOOwnershipVoucher) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOwnershipVoucherList) DeepCopyInto(out *FDOOwnershipVoucherList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOOwnershipVoucher, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOwnershipVoucherList.
+func (in *FDOOwnershipVoucherList) DeepCopy() *FDOOwnershipVoucherList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOwnershipVoucherList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOOwnershipVoucherList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOwnershipVoucherSpec) DeepCopyInto(out *FDOOwnershipVoucherSpec) {
+	*out = *in
+	if in.VoucherSecret != nil {
+		in, out := &in.VoucherSecret, &out.VoucherSecret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	out.OnboardingServer = in.OnboardingServer
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOwnershipVoucherSpec.
+func (in *FDOOwnershipVoucherSpec) DeepCopy() *FDOOwnershipVoucherSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOwnershipVoucherSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOOwnershipVoucherStatus) DeepCopyInto(out *FDOOwnershipVoucherStatus) {
+	*out = *in
+	if in.ImportTime != nil {
+		in, out := &in.ImportTime, &out.ImportTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOOwnershipVoucherStatus.
+func (in *FDOOwnershipVoucherStatus) DeepCopy() *FDOOwnershipVoucherStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOOwnershipVoucherStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousRegistration) DeepCopyInto(out *FDORendezvousRegistration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousRegistration.
+func (in *FDORendezvousRegistration) DeepCopy() *FDORendezvousRegistration {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousRegistration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDORendezvousRegistration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousRegistrationList) DeepCopyInto(out *FDORendezvousRegistrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDORendezvousRegistration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousRegistrationList.
+func (in *FDORendezvousRegistrationList) DeepCopy() *FDORendezvousRegistrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousRegistrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDORendezvousRegistrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousRegistrationStatus) DeepCopyInto(out *FDORendezvousRegistrationStatus) {
+	*out = *in
+	out.OwnerKey = in.OwnerKey
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistrationTime != nil {
+		in, out := &in.RegistrationTime, &out.RegistrationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiryTime != nil {
+		in, out := &in.ExpiryTime, &out.ExpiryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousRegistrationStatus.
+func (in *FDORendezvousRegistrationStatus) DeepCopy() *FDORendezvousRegistrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousRegistrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousServer) DeepCopyInto(out *FDORendezvousServer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousServer.
+func (in *FDORendezvousServer) DeepCopy() *FDORendezvousServer {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDORendezvousServer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousServerList) DeepCopyInto(out *FDORendezvousServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDORendezvousServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousServerList.
+func (in *FDORendezvousServerList) DeepCopy() *FDORendezvousServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDORendezvousServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousServerSpec) DeepCopyInto(out *FDORendezvousServerSpec) {
+	*out = *in
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(Expose)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(RendezvousStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = new(RendezvousKeys)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousServerSpec.
+func (in *FDORendezvousServerSpec) DeepCopy() *FDORendezvousServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDORendezvousServerStatus) DeepCopyInto(out *FDORendezvousServerStatus) {
+	*out = *in
+	in.ServerStatus.DeepCopyInto(&out.ServerStatus)
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(RegistrationInventoryStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDORendezvousServerStatus.
+func (in *FDORendezvousServerStatus) DeepCopy() *FDORendezvousServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDORendezvousServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOTrustBundle) DeepCopyInto(out *FDOTrustBundle) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOTrustBundle.
+func (in *FDOTrustBundle) DeepCopy() *FDOTrustBundle {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOTrustBundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOTrustBundle) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOTrustBundleList) DeepCopyInto(out *FDOTrustBundleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOTrustBundle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOTrustBundleList.
+func (in *FDOTrustBundleList) DeepCopy() *FDOTrustBundleList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOTrustBundleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOTrustBundleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOTrustBundleSpec) DeepCopyInto(out *FDOTrustBundleSpec) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]TrustBundleSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOTrustBundleSpec.
+func (in *FDOTrustBundleSpec) DeepCopy() *FDOTrustBundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOTrustBundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOTrustBundleStatus) DeepCopyInto(out *FDOTrustBundleStatus) {
+	*out = *in
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]TrustedCertificate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOTrustBundleStatus.
+func (in *FDOTrustBundleStatus) DeepCopy() *FDOTrustBundleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOTrustBundleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherExtension) DeepCopyInto(out *FDOVoucherExtension) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherExtension.
+func (in *FDOVoucherExtension) DeepCopy() *FDOVoucherExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOVoucherExtension) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherExtensionList) DeepCopyInto(out *FDOVoucherExtensionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOVoucherExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherExtensionList.
+func (in *FDOVoucherExtensionList) DeepCopy() *FDOVoucherExtensionList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherExtensionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOVoucherExtensionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherExtensionSpec) DeepCopyInto(out *FDOVoucherExtensionSpec) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+	out.OwnerKey = in.OwnerKey
+	out.NextOwnerCert = in.NextOwnerCert
+	in.Output.DeepCopyInto(&out.Output)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherExtensionSpec.
+func (in *FDOVoucherExtensionSpec) DeepCopy() *FDOVoucherExtensionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherExtensionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherExtensionStatus) DeepCopyInto(out *FDOVoucherExtensionStatus) {
+	*out = *in
+	if in.ExtensionTime != nil {
+		in, out := &in.ExtensionTime, &out.ExtensionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherExtensionStatus.
+func (in *FDOVoucherExtensionStatus) DeepCopy() *FDOVoucherExtensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherExtensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherSync) DeepCopyInto(out *FDOVoucherSync) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherSync.
+func (in *FDOVoucherSync) DeepCopy() *FDOVoucherSync {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOVoucherSync) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherSyncList) DeepCopyInto(out *FDOVoucherSyncList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FDOVoucherSync, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherSyncList.
+func (in *FDOVoucherSyncList) DeepCopy() *FDOVoucherSyncList {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherSyncList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FDOVoucherSyncList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherSyncSpec) DeepCopyInto(out *FDOVoucherSyncSpec) {
+	*out = *in
+	out.ManufacturingServer = in.ManufacturingServer
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]VoucherSyncTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherSyncSpec.
+func (in *FDOVoucherSyncSpec) DeepCopy() *FDOVoucherSyncSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherSyncSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FDOVoucherSyncStatus) DeepCopyInto(out *FDOVoucherSyncStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]VoucherSyncTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FDOVoucherSyncStatus.
+func (in *FDOVoucherSyncStatus) DeepCopy() *FDOVoucherSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FDOVoucherSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitialUser) DeepCopyInto(out *InitialUser) {
+	*out = *in
+	if in.SSHKeys != nil {
+		in, out := &in.SSHKeys, &out.SSHKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitialUser.
+func (in *InitialUser) DeepCopy() *InitialUser {
+	if in == nil {
+		return nil
+	}
+	out := new(InitialUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyPairReference) DeepCopyInto(out *KeyPairReference) {
+	*out = *in
+	if in.Cert != nil {
+		in, out := &in.Cert, &out.Cert
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.Key != nil {
+		in, out := &in.Key, &out.Key
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.TLSSecret != nil {
+		in, out := &in.TLSSecret, &out.TLSSecret
+		*out = new(TLSSecretReference)
+		**out = **in
+	}
+	if in.Certificate != nil {
+		in, out := &in.Certificate, &out.Certificate
+		*out = new(CertManagerCertificate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeySource != nil {
+		in, out := &in.KeySource, &out.KeySource
+		*out = new(KeySource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairReference.
+func (in *KeyPairReference) DeepCopy() *KeyPairReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyPairReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotation) DeepCopyInto(out *KeyRotation) {
+	*out = *in
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.OverlapWindow != nil {
+		in, out := &in.OverlapWindow, &out.OverlapWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotation.
+func (in *KeyRotation) DeepCopy() *KeyRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeySetKeyStatus) DeepCopyInto(out *KeySetKeyStatus) {
+	*out = *in
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OverlapUntil != nil {
+		in, out := &in.OverlapUntil, &out.OverlapUntil
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeySetKeyStatus.
+func (in *KeySetKeyStatus) DeepCopy() *KeySetKeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeySetKeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeySource) DeepCopyInto(out *KeySource) {
+	*out = *in
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = new(VaultKeySource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeySource.
+func (in *KeySource) DeepCopy() *KeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(KeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyStatus) DeepCopyInto(out *KeyStatus) {
+	*out = *in
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyStatus.
+func (in *KeyStatus) DeepCopy() *KeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManufacturingKeys) DeepCopyInto(out *ManufacturingKeys) {
+	*out = *in
+	if in.DIUN != nil {
+		in, out := &in.DIUN, &out.DIUN
+		*out = new(KeyPairReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Manufacturer != nil {
+		in, out := &in.Manufacturer, &out.Manufacturer
+		*out = new(KeyPairReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeviceCA != nil {
+		in, out := &in.DeviceCA, &out.DeviceCA
+		*out = new(KeyPairReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OwnerCert != nil {
+		in, out := &in.OwnerCert, &out.OwnerCert
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManufacturingKeys.
+func (in *ManufacturingKeys) DeepCopy() *ManufacturingKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(ManufacturingKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManufacturingServerReference) DeepCopyInto(out *ManufacturingServerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManufacturingServerReference.
+func (in *ManufacturingServerReference) DeepCopy() *ManufacturingServerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ManufacturingServerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManufacturingStorage) DeepCopyInto(out *ManufacturingStorage) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(DatabaseStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManufacturingStorage.
+func (in *ManufacturingStorage) DeepCopy() *ManufacturingStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ManufacturingStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedOnboardingServerReference) DeepCopyInto(out *NamespacedOnboardingServerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedOnboardingServerReference.
+func (in *NamespacedOnboardingServerReference) DeepCopy() *NamespacedOnboardingServerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedOnboardingServerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardingKeys) DeepCopyInto(out *OnboardingKeys) {
+	*out = *in
+	if in.Owner != nil {
+		in, out := &in.Owner, &out.Owner
+		*out = new(KeyPairReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeviceCACert != nil {
+		in, out := &in.DeviceCACert, &out.DeviceCACert
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.DeviceCATrustBundle != nil {
+		in, out := &in.DeviceCATrustBundle, &out.DeviceCATrustBundle
+		*out = new(TrustBundleReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardingKeys.
+func (in *OnboardingKeys) DeepCopy() *OnboardingKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardingKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardingServerReference) DeepCopyInto(out *OnboardingServerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardingServerReference.
+func (in *OnboardingServerReference) DeepCopy() *OnboardingServerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardingServerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardingStorage) DeepCopyInto(out *OnboardingStorage) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(DatabaseStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardingStorage.
+func (in *OnboardingStorage) DeepCopy() *OnboardingStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardingStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnershipVoucherReference) DeepCopyInto(out *OwnershipVoucherReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnershipVoucherReference.
+func (in *OwnershipVoucherReference) DeepCopy() *OwnershipVoucherReference {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnershipVoucherReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodTemplate) DeepCopyInto(out *PodTemplate) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodTemplate.
+func (in *PodTemplate) DeepCopy() *PodTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PodTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Protocols) DeepCopyInto(out *Protocols) {
+	*out = *in
+	if in.DIUN != nil {
+		in, out := &in.DIUN, &out.DIUN
+		*out = new(DIUN)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Protocols.
+func (in *Protocols) DeepCopy() *Protocols {
+	if in == nil {
+		return nil
+	}
+	out := new(Protocols)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistrationExpiryBucket) DeepCopyInto(out *RegistrationExpiryBucket) {
+	*out = *in
+	if in.Within != nil {
+		in, out := &in.Within, &out.Within
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistrationExpiryBucket.
+func (in *RegistrationExpiryBucket) DeepCopy() *RegistrationExpiryBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistrationExpiryBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistrationInventoryStatus) DeepCopyInto(out *RegistrationInventoryStatus) {
+	*out = *in
+	if in.Expiry != nil {
+		in, out := &in.Expiry, &out.Expiry
+		*out = make([]RegistrationExpiryBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextExpiryTime != nil {
+		in, out := &in.NextExpiryTime, &out.NextExpiryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastScanTime != nil {
+		in, out := &in.LastScanTime, &out.LastScanTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistrationInventoryStatus.
+func (in *RegistrationInventoryStatus) DeepCopy() *RegistrationInventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistrationInventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RendezvousKeys) DeepCopyInto(out *RendezvousKeys) {
+	*out = *in
+	if in.ManufacturerCert != nil {
+		in, out := &in.ManufacturerCert, &out.ManufacturerCert
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.ManufacturerTrustBundle != nil {
+		in, out := &in.ManufacturerTrustBundle, &out.ManufacturerTrustBundle
+		*out = new(TrustBundleReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RendezvousKeys.
+func (in *RendezvousKeys) DeepCopy() *RendezvousKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(RendezvousKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RendezvousServer) DeepCopyInto(out *RendezvousServer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RendezvousServer.
+func (in *RendezvousServer) DeepCopy() *RendezvousServer {
+	if in == nil {
+		return nil
+	}
+	out := new(RendezvousServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RendezvousStorage) DeepCopyInto(out *RendezvousStorage) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(DatabaseStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RendezvousStorage.
+func (in *RendezvousStorage) DeepCopy() *RendezvousStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(RendezvousStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerStatus) DeepCopyInto(out *ServerStatus) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]KeyStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerStatus.
+func (in *ServerStatus) DeepCopy() *ServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceInfo) DeepCopyInto(out *ServiceInfo) {
+	*out = *in
+	if in.InitialUser != nil {
+		in, out := &in.InitialUser, &out.InitialUser
+		*out = new(InitialUser)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]Command, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DiskEncryptionClevises != nil {
+		in, out := &in.DiskEncryptionClevises, &out.DiskEncryptionClevises
+		*out = make([]DiskEncryptionClevis, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceInfo.
+func (in *ServiceInfo) DeepCopy() *ServiceInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceInfoDiskEncryptionClevisBinding) DeepCopyInto(out *ServiceInfoDiskEncryptionClevisBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceInfoDiskEncryptionClevisBinding.
+func (in *ServiceInfoDiskEncryptionClevisBinding) DeepCopy() *ServiceInfoDiskEncryptionClevisBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceInfoDiskEncryptionClevisBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecretReference) DeepCopyInto(out *TLSSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecretReference.
+func (in *TLSSecretReference) DeepCopy() *TLSSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustBundleObjects) DeepCopyInto(out *TrustBundleObjects) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustBundleObjects.
+func (in *TrustBundleObjects) DeepCopy() *TrustBundleObjects {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustBundleObjects)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustBundleReference) DeepCopyInto(out *TrustBundleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustBundleReference.
+func (in *TrustBundleReference) DeepCopy() *TrustBundleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustBundleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustBundleSource) DeepCopyInto(out *TrustBundleSource) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(TrustBundleObjects)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(TrustBundleObjects)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustBundleSource.
+func (in *TrustBundleSource) DeepCopy() *TrustBundleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustBundleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedCertificate) DeepCopyInto(out *TrustedCertificate) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedCertificate.
+func (in *TrustedCertificate) DeepCopy() *TrustedCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAuth) DeepCopyInto(out *VaultAuth) {
+	*out = *in
+	if in.Kubernetes != nil {
+		in, out := &in.Kubernetes, &out.Kubernetes
+		*out = new(VaultKubernetesAuth)
+		**out = **in
+	}
+	if in.TokenSecret != nil {
+		in, out := &in.TokenSecret, &out.TokenSecret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuth.
+func (in *VaultAuth) DeepCopy() *VaultAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKV) DeepCopyInto(out *VaultKV) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKV.
+func (in *VaultKV) DeepCopy() *VaultKV {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKV)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKeySource) DeepCopyInto(out *VaultKeySource) {
+	*out = *in
+	if in.CACert != nil {
+		in, out := &in.CACert, &out.CACert
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.KV != nil {
+		in, out := &in.KV, &out.KV
+		*out = new(VaultKV)
+		**out = **in
+	}
+	if in.Transit != nil {
+		in, out := &in.Transit, &out.Transit
+		*out = new(VaultTransit)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKeySource.
+func (in *VaultKeySource) DeepCopy() *VaultKeySource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKeySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKubernetesAuth) DeepCopyInto(out *VaultKubernetesAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesAuth.
+func (in *VaultKubernetesAuth) DeepCopy() *VaultKubernetesAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKubernetesAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultTransit) DeepCopyInto(out *VaultTransit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultTransit.
+func (in *VaultTransit) DeepCopy() *VaultTransit {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultTransit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherArchive) DeepCopyInto(out *VoucherArchive) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherArchive.
+func (in *VoucherArchive) DeepCopy() *VoucherArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherExport) DeepCopyInto(out *VoucherExport) {
+	*out = *in
+	out.TokenSecret = in.TokenSecret
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(Expose)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherExport.
+func (in *VoucherExport) DeepCopy() *VoucherExport {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherExtensionOutput) DeepCopyInto(out *VoucherExtensionOutput) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.OwnershipVoucher != nil {
+		in, out := &in.OwnershipVoucher, &out.OwnershipVoucher
+		*out = new(ExtendedOwnershipVoucher)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherExtensionOutput.
+func (in *VoucherExtensionOutput) DeepCopy() *VoucherExtensionOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherExtensionOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherExtensionSource) DeepCopyInto(out *VoucherExtensionSource) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.OwnershipVoucher != nil {
+		in, out := &in.OwnershipVoucher, &out.OwnershipVoucher
+		*out = new(OwnershipVoucherReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherExtensionSource.
+func (in *VoucherExtensionSource) DeepCopy() *VoucherExtensionSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherExtensionSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherInventoryStatus) DeepCopyInto(out *VoucherInventoryStatus) {
+	*out = *in
+	if in.LastScanTime != nil {
+		in, out := &in.LastScanTime, &out.LastScanTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherInventoryStatus.
+func (in *VoucherInventoryStatus) DeepCopy() *VoucherInventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherInventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherKey) DeepCopyInto(out *VoucherKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherKey.
+func (in *VoucherKey) DeepCopy() *VoucherKey {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherRetention) DeepCopyInto(out *VoucherRetention) {
+	*out = *in
+	if in.Archive != nil {
+		in, out := &in.Archive, &out.Archive
+		*out = new(VoucherArchive)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherRetention.
+func (in *VoucherRetention) DeepCopy() *VoucherRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherRetentionStatus) DeepCopyInto(out *VoucherRetentionStatus) {
+	*out = *in
+	if in.LastRunTime != nil {
+		in, out := &in.LastRunTime, &out.LastRunTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRunTime != nil {
+		in, out := &in.NextRunTime, &out.NextRunTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherRetentionStatus.
+func (in *VoucherRetentionStatus) DeepCopy() *VoucherRetentionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherRetentionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherSyncTarget) DeepCopyInto(out *VoucherSyncTarget) {
+	*out = *in
+	if in.OnboardingServer != nil {
+		in, out := &in.OnboardingServer, &out.OnboardingServer
+		*out = new(NamespacedOnboardingServerReference)
+		**out = **in
+	}
+	if in.AuthSecret != nil {
+		in, out := &in.AuthSecret, &out.AuthSecret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	if in.CASecret != nil {
+		in, out := &in.CASecret, &out.CASecret
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherSyncTarget.
+func (in *VoucherSyncTarget) DeepCopy() *VoucherSyncTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherSyncTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VoucherSyncTargetStatus) DeepCopyInto(out *VoucherSyncTargetStatus) {
+	*out = *in
+	if in.LastDeliveryTime != nil {
+		in, out := &in.LastDeliveryTime, &out.LastDeliveryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VoucherSyncTargetStatus.
+func (in *VoucherSyncTargetStatus) DeepCopy() *VoucherSyncTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VoucherSyncTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// certificateGVK is the kind of cert-manager certificates, handled as unstructured objects
+// so that the operator doesn't depend on cert-manager
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// certificateNameAnnotation is set by cert-manager on the secret of a certificate
+const certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+// keyCertificatesIndex is the field index of servers by the name of the certificates issuing their keys
+const keyCertificatesIndex = ".spec.keys.certificates"
+
+// Keys of the secret holding a key pair converted from the secret of a certificate
+const (
+	certificateCertKey = "cert.pem"
+	certificateKeyKey  = "key.der"
+)
+
+// certificateKeysSecret returns the name of the secret holding the converted key pair of a certificate
+func certificateKeysSecret(certificate string) string {
+	return certificate + "-fdo-keys"
+}
+
+// certificateSecret returns the name of the secret of a certificate created by the operator
+func certificateSecret(certificate string) string {
+	return certificate + "-tls"
+}
+
+// certificateKeyPair is a key pair of a server issued by a cert-manager certificate
+type certificateKeyPair struct {
+	Certificate *fdov1beta1.CertManagerCertificate
+	// CommonName, KeyType, CA and RotationPolicy configure the certificate if it is created by the operator
+	CommonName     string
+	KeyType        string
+	CA             bool
+	RotationPolicy fdov1beta1.CertificateRotationPolicy
+}
+
+// rotationPolicy returns the private key rotation policy of the certificate, the default of the
+// key role if it isn't set
+func (p certificateKeyPair) rotationPolicy() fdov1beta1.CertificateRotationPolicy {
+	if p.Certificate.RotationPolicy != "" {
+		return p.Certificate.RotationPolicy
+	}
+	return p.RotationPolicy
+}
+
+// manufacturingCertificates lists the key pairs of a manufacturing server issued by certificates
+func manufacturingCertificates(server *fdov1beta1.FDOManufacturingServer) []certificateKeyPair {
+	pairs := []certificateKeyPair{}
+	k := server.Spec.Keys
+	if k == nil {
+		return pairs
+	}
+	diunKeyType := fdov1beta1.DefaultKeyType
+	if server.Spec.Protocols.DIUN != nil {
+		diunKeyType = server.Spec.Protocols.DIUN.KeyType
+	}
+	if k.DIUN != nil && k.DIUN.Certificate != nil {
+		pairs = append(pairs, certificateKeyPair{Certificate: k.DIUN.Certificate, CommonName: keySetCommonNames[fdov1beta1.KeyRoleDIUN], KeyType: diunKeyType, RotationPolicy: fdov1beta1.CertificateRotationAlways})
+	}
+	if k.Manufacturer != nil && k.Manufacturer.Certificate != nil {
+		pairs = append(pairs, certificateKeyPair{Certificate: k.Manufacturer.Certificate, CommonName: keySetCommonNames[fdov1beta1.KeyRoleManufacturer], KeyType: fdov1beta1.DefaultKeyType, RotationPolicy: fdov1beta1.CertificateRotationNever})
+	}
+	if k.DeviceCA != nil && k.DeviceCA.Certificate != nil {
+		pairs = append(pairs, certificateKeyPair{Certificate: k.DeviceCA.Certificate, CommonName: keySetCommonNames[fdov1beta1.KeyRoleDeviceCA], KeyType: fdov1beta1.DefaultKeyType, CA: true, RotationPolicy: fdov1beta1.CertificateRotationNever})
+	}
+	return pairs
+}
+
+// onboardingCertificates lists the key pairs of an onboarding server issued by certificates
+func onboardingCertificates(server *fdov1beta1.FDOOnboardingServer) []certificateKeyPair {
+	pairs := []certificateKeyPair{}
+	k := server.Spec.Keys
+	if k != nil && k.Owner != nil && k.Owner.Certificate != nil {
+		pairs = append(pairs, certificateKeyPair{Certificate: k.Owner.Certificate, CommonName: keySetCommonNames[fdov1beta1.KeyRoleOwner], KeyType: fdov1beta1.DefaultKeyType, RotationPolicy: fdov1beta1.CertificateRotationNever})
+	}
+	return pairs
+}
+
+// certificateNames returns the names of the certificates of the given key pairs, for the keyCertificatesIndex
+func certificateNames(pairs []certificateKeyPair) []string {
+	names := []string{}
+	for _, p := range pairs {
+		if !containsString(names, p.Certificate.Name) {
+			names = append(names, p.Certificate.Name)
+		}
+	}
+	return names
+}
+
+// requestsForCertificateSecret maps the secret of a certificate to reconcile requests of the servers
+// of the list type whose keys it issues
+func requestsForCertificateSecret(ctx context.Context, c client.Reader, list client.ObjectList, secret client.Object) []reconcile.Request {
+	name, ok := secret.GetAnnotations()[certificateNameAnnotation]
+	if !ok {
+		return nil
+	}
+	return requestsForIndex(ctx, c, list, secret.GetNamespace(), keyCertificatesIndex, name)
+}
+
+// reconcileCertificates creates the certificates that have an issuer, and converts the key pairs
+// issued by the certificates to the secrets read by the server. Certificates that are not issued
+// yet are skipped, the server reports their secrets as missing.
+func reconcileCertificates(ctx context.Context, r *util.ReconcilerBase, server client.Object, pairs []certificateKeyPair) error {
+	for _, p := range pairs {
+		secretName, err := reconcileCertificate(ctx, r, server, p)
+		if err != nil {
+			return err
+		}
+		if secretName == "" {
+			continue
+		}
+		if err := convertCertificateSecret(ctx, r, server, p.Certificate.Name, secretName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileCertificate returns the name of the secret of a certificate, empty if the certificate doesn't exist
+func reconcileCertificate(ctx context.Context, r *util.ReconcilerBase, server client.Object, p certificateKeyPair) (string, error) {
+	log := logf.FromContext(ctx)
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetName(p.Certificate.Name)
+	cert.SetNamespace(server.GetNamespace())
+
+	if p.Certificate.IssuerRef == nil {
+		if err := r.GetClient().Get(ctx, client.ObjectKeyFromObject(cert), cert); err != nil {
+			if errors.IsNotFound(err) {
+				log.Info("Certificate not found", "certificate", p.Certificate.Name)
+				return "", nil
+			}
+			return "", err
+		}
+		secretName, _, err := unstructured.NestedString(cert.Object, "spec", "secretName")
+		return secretName, err
+	}
+
+	size, ok := map[string]int64{keys.SECP256R1: 256, keys.SECP384R1: 384}[p.KeyType]
+	if !ok {
+		return "", fmt.Errorf("unsupported key type %q", p.KeyType)
+	}
+	issuer := p.Certificate.IssuerRef
+	issuerKind := issuer.Kind
+	if issuerKind == "" {
+		issuerKind = "Issuer"
+	}
+	issuerGroup := issuer.Group
+	if issuerGroup == "" {
+		issuerGroup = certificateGVK.Group
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), cert, func() error {
+		spec := map[string]interface{}{
+			"secretName": certificateSecret(p.Certificate.Name),
+			"commonName": p.CommonName,
+			"isCA":       p.CA,
+			"issuerRef": map[string]interface{}{
+				"name":  issuer.Name,
+				"kind":  issuerKind,
+				"group": issuerGroup,
+			},
+			"privateKey": map[string]interface{}{
+				"algorithm":      "ECDSA",
+				"size":           size,
+				"rotationPolicy": string(p.rotationPolicy()),
+			},
+		}
+		if err := unstructured.SetNestedMap(cert.Object, spec, "spec"); err != nil {
+			return err
+		}
+		// servers may share a certificate, none of them controls it
+		return controllerutil.SetOwnerReference(server, cert, r.GetScheme())
+	})
+	if err != nil {
+		log.Error(err, "Certificate reconcile failed", "certificate", p.Certificate.Name)
+		return "", err
+	}
+	log.Info("Certificate successfully reconciled", "certificate", p.Certificate.Name, "operation", op)
+	return certificateSecret(p.Certificate.Name), nil
+}
+
+// convertCertificateSecret converts the PEM private key of the secret of a certificate to DER, and
+// writes it with the certificate chain to the secret read by the server
+func convertCertificateSecret(ctx context.Context, r *util.ReconcilerBase, server client.Object, certificate, secretName string) error {
+	log := logf.FromContext(ctx)
+	tlsSecret := &corev1.Secret{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: server.GetNamespace(), Name: secretName}, tlsSecret); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Certificate not issued yet", "certificate", certificate, "secret", secretName)
+			return nil
+		}
+		return err
+	}
+	crt, tlsKey := tlsSecret.Data[corev1.TLSCertKey], tlsSecret.Data[corev1.TLSPrivateKeyKey]
+	if len(crt) == 0 || len(tlsKey) == 0 {
+		log.Info("Certificate not issued yet", "certificate", certificate, "secret", secretName)
+		return nil
+	}
+	key, err := keys.ParsePEMKey(tlsKey)
+	if err != nil {
+		return fmt.Errorf("secret %s: %w", secretName, err)
+	}
+	der, err := keys.MarshalKey(key)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: certificateKeysSecret(certificate), Namespace: server.GetNamespace()}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), secret, func() error {
+		secret.Data = map[string][]byte{
+			certificateCertKey: crt,
+			certificateKeyKey:  der,
+		}
+		return controllerutil.SetOwnerReference(server, secret, r.GetScheme())
+	})
+	if err != nil {
+		log.Error(err, "Secret reconcile failed", "secret", secret.Name)
+		return err
+	}
+	log.Info("Secret successfully reconciled", "secret", secret.Name, "operation", op)
+	return nil
+}
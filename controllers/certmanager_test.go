@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("cert-manager certificates", func() {
+	var (
+		c      k8sclient.Client
+		ctx    context.Context
+		r      util.ReconcilerBase
+		server *fdov1beta1.FDOManufacturingServer
+	)
+
+	// certificate returns the cert-manager Certificate of the DIUN key pair, or nil if it doesn't exist
+	certificate := func() *unstructured.Unstructured {
+		cert := &unstructured.Unstructured{}
+		cert.SetGroupVersionKind(certificateGVK)
+		err := c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "diun"}, cert)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return cert
+	}
+
+	rotationPolicy := func(cert *unstructured.Unstructured) string {
+		policy, _, err := unstructured.NestedString(cert.Object, "spec", "privateKey", "rotationPolicy")
+		Expect(err).NotTo(HaveOccurred())
+		return policy
+	}
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder().Build()
+		ctx = context.TODO()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)
+		server = &fdov1beta1.FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "manufacturing", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOManufacturingServerSpec{
+				Protocols: fdov1beta1.Protocols{DIUN: &fdov1beta1.DIUN{KeyType: keys.SECP256R1}},
+				Keys: &fdov1beta1.ManufacturingKeys{
+					DIUN: &fdov1beta1.KeyPairReference{
+						Certificate: &fdov1beta1.CertManagerCertificate{
+							Name:      "diun",
+							IssuerRef: &fdov1beta1.CertManagerIssuerReference{Name: "ca-issuer"},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	It("should reference the converted key pair", func() {
+		files := manufacturingKeyFiles(server.Spec.Keys)
+		Expect(files[0]).To(Equal(keyFile{Volume: "diun-cert", File: "diun_cert.pem", Secret: "diun-fdo-keys", Key: "cert.pem"}))
+		Expect(files[1]).To(Equal(keyFile{Volume: "diun-key", File: "diun_key.der", Secret: "diun-fdo-keys", Key: "key.der"}))
+		Expect(certificateNames(manufacturingCertificates(server))).To(Equal([]string{"diun"}))
+	})
+
+	It("should create a certificate with an issuer", func() {
+		Expect(reconcileCertificates(ctx, &r, server, manufacturingCertificates(server))).To(Succeed())
+
+		cert := certificate()
+		Expect(cert.GetKind()).To(Equal("Certificate"))
+		Expect(cert.GetOwnerReferences()).To(HaveLen(1))
+		Expect(cert.Object).To(HaveKeyWithValue("spec", And(
+			HaveKeyWithValue("secretName", "diun-tls"),
+			HaveKeyWithValue("issuerRef", HaveKeyWithValue("kind", "Issuer")),
+			HaveKeyWithValue("privateKey", HaveKeyWithValue("size", int64(256))),
+		)))
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "diun-fdo-keys"}, &corev1.Secret{})).To(WithTransform(apierrors.IsNotFound, BeTrue()))
+	})
+
+	It("should only rotate the private key of the DIUN certificate by default", func() {
+		issuer := &fdov1beta1.CertManagerIssuerReference{Name: "ca-issuer"}
+		server.Spec.Keys.Manufacturer = &fdov1beta1.KeyPairReference{Certificate: &fdov1beta1.CertManagerCertificate{Name: "manufacturer", IssuerRef: issuer}}
+		server.Spec.Keys.DeviceCA = &fdov1beta1.KeyPairReference{Certificate: &fdov1beta1.CertManagerCertificate{Name: "device-ca", IssuerRef: issuer}}
+		onboarding := &fdov1beta1.FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOOnboardingServerSpec{Keys: &fdov1beta1.OnboardingKeys{
+				Owner: &fdov1beta1.KeyPairReference{Certificate: &fdov1beta1.CertManagerCertificate{Name: "owner", IssuerRef: issuer}},
+			}},
+		}
+		Expect(reconcileCertificates(ctx, &r, server, manufacturingCertificates(server))).To(Succeed())
+		Expect(reconcileCertificates(ctx, &r, onboarding, onboardingCertificates(onboarding))).To(Succeed())
+
+		for name, policy := range map[string]string{"diun": "Always", "manufacturer": "Never", "device-ca": "Never", "owner": "Never"} {
+			cert := &unstructured.Unstructured{}
+			cert.SetGroupVersionKind(certificateGVK)
+			Expect(c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: name}, cert)).To(Succeed())
+			Expect(rotationPolicy(cert)).To(Equal(policy), name)
+		}
+	})
+
+	It("should use the rotation policy of the certificate", func() {
+		server.Spec.Keys.DIUN.Certificate.RotationPolicy = fdov1beta1.CertificateRotationNever
+		Expect(reconcileCertificates(ctx, &r, server, manufacturingCertificates(server))).To(Succeed())
+		Expect(rotationPolicy(certificate())).To(Equal("Never"))
+	})
+
+	It("should convert the key pair issued by a certificate", func() {
+		key, err := keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		crt, err := keys.SelfSignedCert(key, keys.Subject{CommonName: "DIUN"}, false, time.Now(), time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "diun-tls", Namespace: "fdo"},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       crt,
+				corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			},
+		})).To(Succeed())
+
+		Expect(reconcileCertificates(ctx, &r, server, manufacturingCertificates(server))).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "diun-fdo-keys"}, secret)).To(Succeed())
+		Expect(secret.Data["cert.pem"]).To(Equal(crt))
+		parsed, err := keys.ParseKey(secret.Data["key.der"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.Equal(key)).To(BeTrue())
+	})
+
+	It("should wait for an existing certificate", func() {
+		server.Spec.Keys.DIUN.Certificate.IssuerRef = nil
+		Expect(reconcileCertificates(ctx, &r, server, manufacturingCertificates(server))).To(Succeed())
+		Expect(certificate()).To(BeNil())
+	})
+})
@@ -19,7 +19,7 @@ package controllers
 import (
 	"fmt"
 
-	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	routev1 "github.com/openshift/api/route/v1"
 )
 
@@ -27,6 +27,14 @@ const ServiceInfoAuthToken = "ExampleAuthToken"
 
 type Driver struct {
 	Directory *Directory `yaml:"Directory,omitempty"`
+	Postgres  *Database  `yaml:"Postgres,omitempty"`
+	Sqlite    *Database  `yaml:"Sqlite,omitempty"`
+}
+
+// Database selects the tables of a store, the connection URL is read by the server from the
+// environment, e.g. POSTGRES_MANUFACTURER_DATABASE_URL
+type Database struct {
+	Server string `yaml:"server"`
 }
 
 type Directory struct {
@@ -81,9 +89,9 @@ func NewServiceInfoAPIAuthentication(token string) *ServiceInfoAPIAuthentication
 	}
 }
 
-func (c *OwnerOnboardingServerConfig) setValues(server *fdov1alpha1.FDOOnboardingServer, route *routev1.Route) error {
+func (c *OwnerOnboardingServerConfig) setValues(server *fdov1beta1.FDOOnboardingServer, route *routev1.Route) error {
 	c.SessionStoreDriver = NewDriver("/etc/fdo/sessions/")
-	c.OwnerShipVoucherStoreDriver = NewDriver("/etc/fdo/ownership_vouchers/")
+	c.OwnerShipVoucherStoreDriver = ownerVoucherStore.driver(server.Spec.Storage.GetDatabase())
 	c.Bind = "0.0.0.0:8081"
 	c.TrustedDeviceKeysPath = "/etc/fdo/keys/device_ca_cert.pem"
 	c.OwnerPrivateKeyPath = "/etc/fdo/keys/owner_key.der"
@@ -155,7 +163,7 @@ type ServiceInfoDiskEncryptionClevisBinding struct {
 	Config string `yaml:"config,omitempty"`
 }
 
-func (c *ServiceInfoAPIServerConfig) setValues(server *fdov1alpha1.FDOOnboardingServer, files []ServiceInfoFile) error {
+func (c *ServiceInfoAPIServerConfig) setValues(server *fdov1beta1.FDOOnboardingServer, files []ServiceInfoFile) error {
 	c.Bind = "0.0.0.0:8083"
 	c.DeviceSpecificStoreDriver = NewDriver("/etc/fdo/device_specific_serviceinfo")
 	c.ServiceInfoAuthToken = ServiceInfoAuthToken
@@ -195,16 +203,14 @@ func (c *ServiceInfoAPIServerConfig) setValues(server *fdov1alpha1.FDOOnboarding
 	return nil
 }
 
-func NewServiceInfoDiskEncryptionClevis(cl fdov1alpha1.DiskEncryptionClevis) ServiceInfoDiskEncryptionClevis {
+func NewServiceInfoDiskEncryptionClevis(cl fdov1beta1.DiskEncryptionClevis) ServiceInfoDiskEncryptionClevis {
 	c := ServiceInfoDiskEncryptionClevis{
 		DiskLabel: cl.DiskLabel,
 		ReEncrypt: cl.ReEncrypt,
 	}
-	if cl.Binding != nil {
-		c.Binding = &ServiceInfoDiskEncryptionClevisBinding{
-			Pin:    cl.Binding.Pin,
-			Config: cl.Binding.Config,
-		}
+	c.Binding = &ServiceInfoDiskEncryptionClevisBinding{
+		Pin:    cl.Binding.Pin,
+		Config: cl.Binding.Config,
 	}
 	return c
 }
@@ -250,16 +256,16 @@ type RendezvousInfo struct {
 	Protocol   string `yaml:"protocol,omitempty"`
 }
 
-func (c *ManufacturingServerConfig) setValues(server *fdov1alpha1.FDOManufacturingServer) error {
+func (c *ManufacturingServerConfig) setValues(server *fdov1beta1.FDOManufacturingServer) error {
 	c.SessionStoreDriver = NewDriver("/etc/fdo/sessions/")
-	c.OwnerShipVoucherStoreDriver = NewDriver("/etc/fdo/ownership_vouchers/")
+	c.OwnerShipVoucherStoreDriver = manufacturerVoucherStore.driver(server.Spec.Storage.GetDatabase())
 	c.PublicKeyStoreDriver = NewDriver("/etc/fdo/keys/")
 	c.Bind = "0.0.0.0:8080"
 	if err := c.setRendezvousValues(server.Spec.RendezvousServers); err != nil {
 		return err
 	}
 
-	if err := c.setProtocolsValues(server.Spec.Protocols); err != nil {
+	if err := c.setProtocolsValues(&server.Spec.Protocols); err != nil {
 		return err
 	}
 
@@ -273,7 +279,7 @@ func (c *ManufacturingServerConfig) setValues(server *fdov1alpha1.FDOManufacturi
 	return nil
 }
 
-func (c *ManufacturingServerConfig) setRendezvousValues(r []fdov1alpha1.RendezvousServer) error {
+func (c *ManufacturingServerConfig) setRendezvousValues(r []fdov1beta1.RendezvousServer) error {
 	rendezvousInfo := make([]RendezvousInfo, len(r))
 	if len(r) == 0 {
 		return fmt.Errorf("rendezvous servers must contain at least one value")
@@ -292,7 +298,7 @@ func (c *ManufacturingServerConfig) setRendezvousValues(r []fdov1alpha1.Rendezvo
 	return nil
 }
 
-func (c *ManufacturingServerConfig) setProtocolsValues(p *fdov1alpha1.Protocols) error {
+func (c *ManufacturingServerConfig) setProtocolsValues(p *fdov1beta1.Protocols) error {
 	if !p.PlainDI && p.DIUN == nil {
 		return fmt.Errorf("DIUN must be configured if plain DI is false")
 	}
@@ -329,8 +335,8 @@ type RendezvousServerConfig struct {
 	TrustedManufacturerKeysPath string  `yaml:"trusted_manufacturer_keys_path"`
 }
 
-func (c *RendezvousServerConfig) setValues(s *fdov1alpha1.FDORendezvousServer) error {
-	c.StorageDriver = NewDriver("/etc/fdo/registered/")
+func (c *RendezvousServerConfig) setValues(s *fdov1beta1.FDORendezvousServer) error {
+	c.StorageDriver = rendezvousStore.driver(s.Spec.Storage.GetDatabase())
 	c.SessionStoreDriver = NewDriver("/etc/fdo/sessions/")
 	c.TrustedManufacturerKeysPath = "/etc/fdo/keys/manufacturer_cert.pem"
 	c.Bind = "0.0.0.0:8082"
@@ -0,0 +1,394 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	// DatabaseServiceType labels the Postgres database managed by the operator
+	DatabaseServiceType FDOServiceType = "database"
+
+	// managedDatabaseName names the StatefulSet, Service, PVC and credentials Secret of the managed
+	// database of a namespace
+	managedDatabaseName       = "fdo-postgres"
+	managedDatabaseMigrations = "fdo-postgres-migrations"
+	managedDatabaseMigrateJob = "fdo-postgres-migrate"
+	// DefaultDatabaseImage runs the managed database and its migrations unless the operator is given
+	// another image
+	DefaultDatabaseImage = "quay.io/sclorg/postgresql-15-c9s:20240401"
+	managedDatabaseUser  = "fdo"
+	managedDatabasePort  = 5432
+	managedDatabaseSize  = "1Gi"
+	// managedDatabaseURLKey is the key of the connection URL in the credentials Secret
+	managedDatabaseURLKey = "url"
+	// migrationsHashAnnotation records the migrations run by the migration Job, which is replaced
+	// when they change
+	migrationsHashAnnotation = "fdo.redhat.com/migrations-hash"
+)
+
+// serverMigrations are the Postgres and Sqlite migrations of the FDO servers, copied unchanged from the
+// release of fido-device-onboard-rs set by FDO_VERSION in the Makefile (make migrations)
+//
+//go:embed migrations
+var serverMigrations embed.FS
+
+// migrationFiles returns the up.sql files of the migrations of a driver, postgres or sqlite, keyed by
+// server and migration, e.g. owner_onboarding_server.2023-10-03-152801_create_db.sql, so that the
+// migrations of each server sort in the order they are run
+func migrationFiles(driver string) (map[string]string, error) {
+	files := map[string]string{}
+	err := fs.WalkDir(serverMigrations, "migrations", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Base(name) != "up.sql" {
+			return err
+		}
+		migration := path.Dir(name)
+		server, ok := strings.CutSuffix(strings.TrimPrefix(path.Base(path.Dir(migration)), "migrations_"), "_"+driver)
+		if !ok {
+			return nil
+		}
+		data, err := serverMigrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		files[server+"."+path.Base(migration)+".sql"] = string(data)
+		return nil
+	})
+	return files, err
+}
+
+// migrateScript runs the migrations that haven't run yet, each in a transaction recording it in the
+// fdo_operator_migrations table, since the migrations of FDO servers don't check whether their tables exist
+const migrateScript = `until pg_isready; do sleep 2; done
+psql -v ON_ERROR_STOP=1 -c 'CREATE TABLE IF NOT EXISTS fdo_operator_migrations (name text PRIMARY KEY)' || exit 1
+for f in /migrations/*.sql; do
+  name=$(basename "$f" .sql)
+  [ -n "$(psql -tA -c "SELECT 1 FROM fdo_operator_migrations WHERE name = '$name'")" ] && continue
+  psql -v ON_ERROR_STOP=1 -1 -f "$f" -c "INSERT INTO fdo_operator_migrations VALUES ('$name')" || exit 1
+done`
+
+// resolveDatabase returns the database of the stores of a server, with the connection secret of the
+// managed database if it is managed by the operator
+func resolveDatabase(db *fdov1beta1.DatabaseStorage) *fdov1beta1.DatabaseStorage {
+	if db == nil || !db.Managed {
+		return db
+	}
+	return &fdov1beta1.DatabaseStorage{
+		Driver:           fdov1beta1.DatabaseDriverPostgres,
+		ConnectionSecret: &fdov1beta1.SecretKeyReference{Name: managedDatabaseName, Key: managedDatabaseURLKey},
+	}
+}
+
+// reconcileManagedDatabase deploys and migrates the Postgres database of the namespace of a server if
+// the server uses it, and sets the DatabaseReady condition of the server. All servers using the
+// database own its objects, which are deleted with the last of them, except its persistent volume
+// claim which keeps the vouchers and registrations. The database runs image, or DefaultDatabaseImage.
+func reconcileManagedDatabase(ctx context.Context, r *util.ReconcilerBase, server serverObject, db *fdov1beta1.DatabaseStorage, image string) error {
+	if db == nil || !db.Managed {
+		meta.RemoveStatusCondition(&server.GetServerStatus().Conditions, fdov1beta1.ConditionDatabaseReady)
+		return nil
+	}
+	if image == "" {
+		image = DefaultDatabaseImage
+	}
+	files, err := migrationFiles("postgres")
+	if err != nil {
+		return err
+	}
+	labels := getLabels(DatabaseServiceType)
+	objectMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: server.GetNamespace(), Labels: labels}
+	}
+	createOrUpdate := func(obj client.Object, mutate func() error) error {
+		_, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), obj, func() error {
+			if err := mutate(); err != nil {
+				return err
+			}
+			return controllerutil.SetOwnerReference(server, obj, r.GetScheme())
+		})
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: objectMeta(managedDatabaseName)}
+	if err := createOrUpdate(secret, func() error {
+		return setDatabaseCredentials(secret)
+	}); err != nil {
+		return err
+	}
+
+	migrations := &corev1.ConfigMap{ObjectMeta: objectMeta(managedDatabaseMigrations)}
+	if err := createOrUpdate(migrations, func() error {
+		migrations.Data = files
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// the claim isn't owned by the servers, so that deleting them doesn't delete the vouchers
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: objectMeta(managedDatabaseName)}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), pvc, func() error {
+		// the spec of a claim is immutable once bound
+		if pvc.CreationTimestamp.IsZero() {
+			pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+			pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(managedDatabaseSize)}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	service := &corev1.Service{ObjectMeta: objectMeta(managedDatabaseName)}
+	if err := createOrUpdate(service, func() error {
+		service.Spec.Selector = labels
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       "postgres",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       managedDatabasePort,
+			TargetPort: intstr.FromInt(managedDatabasePort),
+		}}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: objectMeta(managedDatabaseName)}
+	if err := createOrUpdate(statefulSet, func() error {
+		if statefulSet.CreationTimestamp.IsZero() {
+			statefulSet.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+			statefulSet.Spec.ServiceName = managedDatabaseName
+		}
+		replicas := int32(1)
+		statefulSet.Spec.Replicas = &replicas
+		statefulSet.Spec.Template = databasePodTemplate(labels, image)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	ready, reason, message, err := reconcileMigrationJob(ctx, r, migrations, image, objectMeta(managedDatabaseMigrateJob), createOrUpdate)
+	if err != nil {
+		return err
+	}
+	if statefulSet.Status.ReadyReplicas < 1 {
+		ready, reason, message = false, fdov1beta1.ReasonDatabaseProvisioning, fmt.Sprintf("waiting for the pod of the StatefulSet %s", managedDatabaseName)
+	}
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	setCondition(server, fdov1beta1.ConditionDatabaseReady, status, reason, message)
+	return nil
+}
+
+// setDatabaseCredentials generates the password of the managed database once, and sets the
+// connection URL read by the FDO servers
+func setDatabaseCredentials(secret *corev1.Secret) error {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	if len(secret.Data["password"]) == 0 {
+		password := make([]byte, 24)
+		if _, err := rand.Read(password); err != nil {
+			return err
+		}
+		secret.Data["password"] = []byte(hex.EncodeToString(password))
+	}
+	secret.Data["username"] = []byte(managedDatabaseUser)
+	secret.Data["database"] = []byte(managedDatabaseUser)
+	dsn := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(managedDatabaseUser, string(secret.Data["password"])),
+		Host:   fmt.Sprintf("%s.%s.svc:%d", managedDatabaseName, secret.Namespace, managedDatabasePort),
+		Path:   managedDatabaseUser,
+	}
+	secret.Data[managedDatabaseURLKey] = []byte(dsn.String())
+	return nil
+}
+
+// databaseEnv returns the credentials of the managed database as environment variables, prefixed by
+// POSTGRESQL_ for the database server or PG for psql
+func databaseEnv(prefix string) []corev1.EnvVar {
+	names := map[string]string{"USER": "username", "PASSWORD": "password", "DATABASE": "database"}
+	env := []corev1.EnvVar{}
+	for _, name := range sortedKeys(names) {
+		env = append(env, secretEnvVar(prefix+name, managedDatabaseName, names[name], ""))
+	}
+	return env
+}
+
+func databasePodTemplate(labels map[string]string, image string) corev1.PodTemplateSpec {
+	privilegeEscalation := false
+	nonRoot := true
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "postgres",
+				Image: image,
+				Env:   databaseEnv("POSTGRESQL_"),
+				Ports: []corev1.ContainerPort{{ContainerPort: managedDatabasePort}},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "data",
+					MountPath: "/var/lib/pgsql/data",
+				}},
+				ReadinessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						Exec: &corev1.ExecAction{Command: []string{"/usr/libexec/check-container"}},
+					},
+					PeriodSeconds: 10,
+				},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &privilegeEscalation,
+					Capabilities: &corev1.Capabilities{
+						Drop: []corev1.Capability{
+							"ALL",
+						},
+					},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: managedDatabaseName},
+				},
+			}},
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: &nonRoot,
+				SeccompProfile: &corev1.SeccompProfile{
+					Type: "RuntimeDefault",
+				},
+			},
+		},
+	}
+}
+
+// reconcileMigrationJob runs the migrations of the managed database in a Job, which is replaced when
+// the migrations change, and returns whether they succeeded with the reason and message of the
+// DatabaseReady condition
+func reconcileMigrationJob(ctx context.Context, r *util.ReconcilerBase, migrations *corev1.ConfigMap, image string, objectMeta metav1.ObjectMeta,
+	createOrUpdate func(client.Object, func() error) error) (bool, string, string, error) {
+	hash := configMapsHash(migrations)
+	job := &batchv1.Job{ObjectMeta: objectMeta}
+	err := r.GetClient().Get(ctx, client.ObjectKeyFromObject(job), job)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, "", "", err
+	}
+	if err == nil && job.Annotations[migrationsHashAnnotation] != hash {
+		// the pod template of a Job is immutable
+		if err := r.GetClient().Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return false, "", "", err
+		}
+		return false, fdov1beta1.ReasonMigrationRunning, "replacing the migration Job", nil
+	}
+
+	if err := createOrUpdate(job, func() error {
+		if job.CreationTimestamp.IsZero() {
+			job.Annotations = map[string]string{migrationsHashAnnotation: hash}
+			job.Spec = migrationJobSpec(image)
+		}
+		return nil
+	}); err != nil {
+		return false, "", "", err
+	}
+
+	if job.Status.Succeeded > 0 {
+		return true, fdov1beta1.ReasonAsExpected, "the database is ready and migrated", nil
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return false, fdov1beta1.ReasonMigrationFailed, fmt.Sprintf("the migration Job %s failed: %s", job.Name, c.Message), nil
+		}
+	}
+	return false, fdov1beta1.ReasonMigrationRunning, fmt.Sprintf("waiting for the migration Job %s", job.Name), nil
+}
+
+func migrationJobSpec(image string) batchv1.JobSpec {
+	privilegeEscalation := false
+	nonRoot := true
+	backoffLimit := int32(10)
+	return batchv1.JobSpec{
+		BackoffLimit: &backoffLimit,
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{{
+					Name:    "migrate",
+					Image:   image,
+					Command: []string{"/bin/sh", "-c", migrateScript},
+					Env: append([]corev1.EnvVar{
+						{Name: "PGHOST", Value: managedDatabaseName},
+						{Name: "PGPORT", Value: fmt.Sprint(managedDatabasePort)},
+					}, databaseEnv("PG")...),
+					VolumeMounts: []corev1.VolumeMount{{
+						Name:      "migrations",
+						MountPath: "/migrations",
+						ReadOnly:  true,
+					}},
+					SecurityContext: &corev1.SecurityContext{
+						AllowPrivilegeEscalation: &privilegeEscalation,
+						Capabilities: &corev1.Capabilities{
+							Drop: []corev1.Capability{
+								"ALL",
+							},
+						},
+					},
+				}},
+				Volumes: []corev1.Volume{{
+					Name: "migrations",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: managedDatabaseMigrations},
+						},
+					},
+				}},
+				SecurityContext: &corev1.PodSecurityContext{
+					RunAsNonRoot: &nonRoot,
+					SeccompProfile: &corev1.SeccompProfile{
+						Type: "RuntimeDefault",
+					},
+				},
+			},
+		},
+	}
+}
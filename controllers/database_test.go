@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ = Describe("Managed database", func() {
+	var (
+		c       k8sclient.Client
+		ctx     context.Context
+		r       util.ReconcilerBase
+		server  *fdov1beta1.FDORendezvousServer
+		managed *fdov1beta1.DatabaseStorage
+	)
+
+	get := func(name string, obj k8sclient.Object) {
+		ExpectWithOffset(1, c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: name}, obj)).To(Succeed())
+	}
+
+	// setReady marks the database as running, and its migration job as succeeded or failed
+	setReady := func(migrated batchv1.JobCondition) {
+		statefulSet := &appsv1.StatefulSet{}
+		get("fdo-postgres", statefulSet)
+		statefulSet.Status.ReadyReplicas = 1
+		ExpectWithOffset(1, c.Status().Update(ctx, statefulSet)).To(Succeed())
+		job := &batchv1.Job{}
+		get("fdo-postgres-migrate", job)
+		ExpectWithOffset(1, job.Annotations).To(HaveKey(migrationsHashAnnotation))
+		if migrated.Type == batchv1.JobComplete {
+			job.Status.Succeeded = 1
+		} else {
+			job.Status.Conditions = []batchv1.JobCondition{migrated}
+		}
+		ExpectWithOffset(1, c.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder().Build()
+		ctx = context.TODO()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)
+		server = &fdov1beta1.FDORendezvousServer{ObjectMeta: metav1.ObjectMeta{Name: "rendezvous", Namespace: "fdo", UID: "uid"}}
+		managed = &fdov1beta1.DatabaseStorage{Managed: true}
+	})
+
+	It("should connect servers to the managed database", func() {
+		db := resolveDatabase(managed)
+		Expect(db.Driver).To(Equal(fdov1beta1.DatabaseDriverPostgres))
+		Expect(db.ConnectionSecret.Name).To(Equal("fdo-postgres"))
+		Expect(rendezvousStore.env(managed)[0].Name).To(Equal("POSTGRES_RV_DATABASE_URL"))
+	})
+
+	It("should deploy the database and report when it is migrated", func() {
+		Expect(reconcileManagedDatabase(ctx, &r, server, managed, "")).To(Succeed())
+		condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionDatabaseReady)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(fdov1beta1.ReasonDatabaseProvisioning))
+
+		secret := &corev1.Secret{}
+		get("fdo-postgres", secret)
+		password := string(secret.Data["password"])
+		Expect(password).To(HaveLen(48))
+		Expect(string(secret.Data["url"])).To(Equal("postgresql://fdo:" + password + "@fdo-postgres.fdo.svc:5432/fdo"))
+		Expect(secret.OwnerReferences).To(HaveLen(1))
+		pvc := &corev1.PersistentVolumeClaim{}
+		get("fdo-postgres", pvc)
+		Expect(pvc.OwnerReferences).To(BeEmpty())
+		get("fdo-postgres", &corev1.Service{})
+		migrations := &corev1.ConfigMap{}
+		get("fdo-postgres-migrations", migrations)
+		Expect(sortedKeys(migrations.Data)).To(Equal([]string{
+			"manufacturing_server.2023-10-03-152801_create_db.sql",
+			"owner_onboarding_server.2023-10-03-152801_create_db.sql",
+			"rendezvous_server.2023-10-03-152801_create_db.sql",
+		}))
+		Expect(migrations.Data["owner_onboarding_server.2023-10-03-152801_create_db.sql"]).To(ContainSubstring("CREATE TABLE owner_vouchers"))
+		statefulSet := &appsv1.StatefulSet{}
+		get("fdo-postgres", statefulSet)
+		Expect(statefulSet.Spec.Template.Spec.Containers[0].Image).To(Equal(DefaultDatabaseImage))
+
+		setReady(batchv1.JobCondition{Type: batchv1.JobComplete})
+		Expect(reconcileManagedDatabase(ctx, &r, server, managed, "")).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionDatabaseReady)).To(BeTrue())
+		get("fdo-postgres", secret)
+		Expect(secret.Data["password"]).To(BeEquivalentTo(password))
+	})
+
+	It("should run the database image of the operator", func() {
+		Expect(reconcileManagedDatabase(ctx, &r, server, managed, "registry.example.com/postgresql-15:1")).To(Succeed())
+		statefulSet := &appsv1.StatefulSet{}
+		get("fdo-postgres", statefulSet)
+		Expect(statefulSet.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/postgresql-15:1"))
+		job := &batchv1.Job{}
+		get("fdo-postgres-migrate", job)
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/postgresql-15:1"))
+	})
+
+	It("should report failed migrations", func() {
+		Expect(reconcileManagedDatabase(ctx, &r, server, managed, "")).To(Succeed())
+		setReady(batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"})
+		Expect(reconcileManagedDatabase(ctx, &r, server, managed, "")).To(Succeed())
+		condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionDatabaseReady)
+		Expect(condition.Reason).To(Equal(fdov1beta1.ReasonMigrationFailed))
+		Expect(condition.Message).To(ContainSubstring("BackoffLimitExceeded"))
+	})
+
+	It("should remove the condition of servers not using it", func() {
+		setCondition(server, fdov1beta1.ConditionDatabaseReady, metav1.ConditionTrue, fdov1beta1.ReasonAsExpected, "")
+		Expect(reconcileManagedDatabase(ctx, &r, server, nil, "")).To(Succeed())
+		Expect(server.Status.Conditions).To(BeEmpty())
+	})
+})
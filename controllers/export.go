@@ -0,0 +1,165 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+const (
+	// exportTokenKey is the default key of the token of the voucher export API
+	exportTokenKey = "token"
+	// exportTokenVolume is the volume of the token secret, mounted at exportTokenPath
+	exportTokenVolume = "voucher-export-token"
+	exportTokenPath   = "/etc/fdo/export"
+)
+
+// exportRouteName returns the name of the route exposing the voucher export API of a server
+func exportRouteName(server string) string {
+	return server + "-export"
+}
+
+// withVoucherExport has the inventory sidecar, if any, serve the voucher export API of a server.
+// The token secret is mounted rather than passed in the environment, so that a rotated token is
+// picked up without restarting the server.
+func withVoucherExport(sidecar *corev1.Container, server *fdov1beta1.FDOManufacturingServer) *corev1.Container {
+	if sidecar == nil || server.Spec.VoucherExport == nil {
+		return sidecar
+	}
+	sidecar.Args = append(sidecar.Args, "--export-token-file", path.Join(exportTokenPath, exportTokenKey))
+	sidecar.Ports = append(sidecar.Ports, corev1.ContainerPort{Name: "export", ContainerPort: inventory.ExportPort})
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{Name: exportTokenVolume, MountPath: exportTokenPath, ReadOnly: true})
+	return sidecar
+}
+
+// voucherExportVolumes returns the token volume mounted by the inventory sidecar, if any. The
+// secret is optional so that a missing token only disables the API, not the server.
+func voucherExportVolumes(sidecar *corev1.Container, server *fdov1beta1.FDOManufacturingServer) []corev1.Volume {
+	export := server.Spec.VoucherExport
+	if sidecar == nil || export == nil {
+		return nil
+	}
+	key := export.TokenSecret.Key
+	if key == "" {
+		key = exportTokenKey
+	}
+	optional := true
+	return []corev1.Volume{{
+		Name: exportTokenVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: export.TokenSecret.Name,
+				Items:      []corev1.KeyToPath{{Key: key, Path: exportTokenKey}},
+				Optional:   &optional,
+			},
+		},
+	}}
+}
+
+// voucherExportToken returns the token of the voucher export API of a server, which the inventory
+// sidecar also requires to serve the vouchers, or an empty token if the API is not served or its
+// secret is missing
+func voucherExportToken(ctx context.Context, c client.Client, server *fdov1beta1.FDOManufacturingServer) (string, error) {
+	export := server.Spec.VoucherExport
+	if export == nil {
+		return "", nil
+	}
+	key := export.TokenSecret.Key
+	if key == "" {
+		key = exportTokenKey
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: server.Namespace, Name: export.TokenSecret.Name}, secret); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return strings.TrimSpace(string(secret.Data[key])), nil
+}
+
+// withVoucherExportPort appends the port of the voucher export API, if served, to the ports of a service
+func withVoucherExportPort(ports []corev1.ServicePort, sidecar *corev1.Container, server *fdov1beta1.FDOManufacturingServer) []corev1.ServicePort {
+	if sidecar == nil || server.Spec.VoucherExport == nil {
+		return ports
+	}
+	return append(ports, corev1.ServicePort{
+		Name:       "export",
+		Protocol:   "TCP",
+		Port:       inventory.ExportPort,
+		TargetPort: intstr.FromString("export"),
+	})
+}
+
+// reconcileVoucherExportRoute exposes the voucher export API of a server through a route
+// terminating TLS, which doesn't accept plain HTTP so that tokens are never sent in clear text.
+// The route is deleted when the API isn't exposed.
+func reconcileVoucherExportRoute(ctx context.Context, r *util.ReconcilerBase, server *fdov1beta1.FDOManufacturingServer, sidecar *corev1.Container) (*routev1.Route, error) {
+	route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: exportRouteName(server.Name), Namespace: server.Namespace}}
+	export := server.Spec.VoucherExport
+	if sidecar == nil || export == nil || export.Expose == nil {
+		return nil, r.DeleteResourceIfExists(ctx, route)
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), route, func() error {
+		route.Labels = getLabels(ManufacturingServiceType)
+		route.Spec = routev1.RouteSpec{
+			Host: routeHost(export.Expose),
+			To: routev1.RouteTargetReference{
+				Kind: "Service",
+				Name: server.Name,
+			},
+			Port: &routev1.RoutePort{
+				TargetPort: intstr.FromString("export"),
+			},
+			TLS: &routev1.TLSConfig{
+				Termination:                   routev1.TLSTerminationEdge,
+				InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyNone,
+			},
+			WildcardPolicy: routev1.WildcardPolicyNone,
+		}
+		return ctrl.SetControllerReference(server, route, r.GetScheme())
+	})
+	return route, err
+}
+
+// voucherExportEndpoints returns the URLs of the voucher export API of a server, in the cluster
+// and through its route
+func voucherExportEndpoints(server *fdov1beta1.FDOManufacturingServer, sidecar *corev1.Container, route *routev1.Route) []string {
+	if sidecar == nil || server.Spec.VoucherExport == nil {
+		return nil
+	}
+	endpoints := []string{fmt.Sprintf("http://%s.%s.svc:%d", server.Name, server.Namespace, inventory.ExportPort)}
+	if route != nil {
+		for _, host := range routeHosts(route) {
+			endpoints = append(endpoints, "https://"+host)
+		}
+	}
+	return endpoints
+}
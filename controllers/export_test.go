@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	routev1 "github.com/openshift/api/route/v1"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ = Describe("Voucher export", func() {
+	var (
+		c      k8sclient.Client
+		ctx    context.Context
+		r      util.ReconcilerBase
+		server *fdov1beta1.FDOManufacturingServer
+	)
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder().Build()
+		ctx = context.TODO()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)
+		server = &fdov1beta1.FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "manufacturing", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOManufacturingServerSpec{
+				VoucherExport: &fdov1beta1.VoucherExport{
+					TokenSecret: fdov1beta1.SecretKeyReference{Name: "mes", Key: "api-token"},
+					Expose:      &fdov1beta1.Expose{Host: "vouchers.example.com"},
+				},
+			},
+		}
+	})
+
+	It("should serve the API from the inventory sidecar with the token of the secret", func() {
+		sidecar := withVoucherExport(inventorySidecar("operator", nil), server)
+		Expect(sidecar.Args).To(Equal([]string{"voucher-inventory", "--dir", "/etc/fdo/ownership_vouchers", "--export-token-file", "/etc/fdo/export/token"}))
+		Expect(sidecar.Ports[1].Name).To(Equal("export"))
+		volumes := voucherExportVolumes(sidecar, server)
+		Expect(volumes).To(HaveLen(1))
+		Expect(volumes[0].Secret.SecretName).To(Equal("mes"))
+		Expect(volumes[0].Secret.Items[0].Key).To(Equal("api-token"))
+		Expect(*volumes[0].Secret.Optional).To(BeTrue())
+		Expect(withVoucherExportPort(nil, sidecar, server)[0].Port).To(Equal(int32(8091)))
+
+		server.Spec.VoucherExport = nil
+		sidecar = withVoucherExport(inventorySidecar("operator", nil), server)
+		Expect(sidecar.Args).To(HaveLen(3))
+		Expect(voucherExportVolumes(sidecar, server)).To(BeEmpty())
+		Expect(voucherExportEndpoints(server, sidecar, nil)).To(BeEmpty())
+	})
+
+	It("should read the token the inventory of the server requires", func() {
+		token, err := voucherExportToken(ctx, c, server)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(BeEmpty())
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mes", Namespace: "fdo"},
+			Data:       map[string][]byte{"api-token": []byte("s3cr3t\n")},
+		})).To(Succeed())
+		Expect(voucherExportToken(ctx, c, server)).To(Equal("s3cr3t"))
+
+		server.Spec.VoucherExport = nil
+		Expect(voucherExportToken(ctx, c, server)).To(BeEmpty())
+	})
+
+	It("should expose the API through a TLS route", func() {
+		sidecar := withVoucherExport(inventorySidecar("operator", nil), server)
+		route, err := reconcileVoucherExportRoute(ctx, &r, server, sidecar)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "manufacturing-export"}, &routev1.Route{})).To(Succeed())
+		Expect(route.Spec.TLS.Termination).To(Equal(routev1.TLSTerminationEdge))
+		Expect(route.Spec.TLS.InsecureEdgeTerminationPolicy).To(Equal(routev1.InsecureEdgeTerminationPolicyNone))
+		Expect(route.OwnerReferences).To(HaveLen(1))
+		Expect(voucherExportEndpoints(server, sidecar, route)).To(Equal([]string{
+			"http://manufacturing.fdo.svc:8091",
+			"https://vouchers.example.com",
+		}))
+
+		server.Spec.VoucherExport.Expose = nil
+		route, err = reconcileVoucherExportRoute(ctx, &r, server, sidecar)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(route).To(BeNil())
+		err = c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "manufacturing-export"}, &routev1.Route{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
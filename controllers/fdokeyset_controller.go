@@ -0,0 +1,454 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// KeySetLabel is set on the secrets created by a key set, to the name of the key set
+const KeySetLabel = "fdo.redhat.com/keyset"
+
+// Annotations of the certificate secrets rotated by a key set
+const (
+	RotatedAtAnnotation    = "fdo.redhat.com/rotated-at"
+	OverlapUntilAnnotation = "fdo.redhat.com/overlap-until"
+)
+
+// keySetCommonNames are the common names of the certificates generated for each role
+var keySetCommonNames = map[fdov1beta1.KeyRole]string{
+	fdov1beta1.KeyRoleDIUN:         "FDO DIUN",
+	fdov1beta1.KeyRoleManufacturer: "FDO Manufacturer",
+	fdov1beta1.KeyRoleDeviceCA:     "FDO Device CA",
+	fdov1beta1.KeyRoleOwner:        "FDO Owner",
+}
+
+// FDOKeySetReconciler reconciles a FDOKeySet object
+type FDOKeySetReconciler struct {
+	util.ReconcilerBase
+	Log logr.Logger
+}
+
+// keyPairFiles are the secrets and keys holding the certificate and the private key of a role,
+// named as expected by default by the servers
+type keyPairFiles struct {
+	Cert keyFile
+	Key  keyFile
+}
+
+func newKeyPairFiles(role fdov1beta1.KeyRole) keyPairFiles {
+	file := strings.ReplaceAll(string(role), "-", "_")
+	return keyPairFiles{
+		Cert: newTrustedKeyFile(string(role)+"-cert", file+"_cert.pem", fmt.Sprintf("fdo-%s-cert", role), nil),
+		Key:  newKeyFile(string(role)+"-key", file+"_key.der", fmt.Sprintf("fdo-%s-key", role), nil),
+	}
+}
+
+// keyPairResult is the outcome of reconciling the key pair of a role
+type keyPairResult struct {
+	Status    fdov1beta1.KeySetKeyStatus
+	Generated bool
+	Rotated   bool
+	Retired   bool
+	// Problem is set if existing key material prevents completing the key pair
+	Problem string
+	// Next is when the key pair has to be reconciled again, zero if it doesn't
+	Next time.Time
+}
+
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdokeysets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdokeysets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdokeysets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile creates the keys and certificates of a key set that don't exist yet, and rotates those
+// close to expiry if rotation is enabled. Secrets that were not created by the key set are never
+// modified, a certificate is only issued for an existing private key.
+func (r *FDOKeySetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.Log.WithName("fdokeyset_controller").WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	log.Info("Reconciling FDO key set")
+
+	keySet := &fdov1beta1.FDOKeySet{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, keySet); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("FDOKeySet resource not found. Ignoring since object must have been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get FDOKeySet resource")
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	keySet.Status.ObservedGeneration = keySet.Generation
+	keySet.Status.Keys = nil
+	generated := []string{}
+	overlapping := []string{}
+	problems := []string{}
+	var next time.Time
+	for _, role := range keySetRoles(keySet) {
+		result, err := r.reconcileKeyPair(ctx, keySet, role, now)
+		if err != nil {
+			return r.ManageError(ctx, keySet, err)
+		}
+		keySet.Status.Keys = append(keySet.Status.Keys, result.Status)
+		if result.Problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", role, result.Problem))
+		}
+		if result.Generated {
+			generated = append(generated, string(role))
+		}
+		if result.Rotated {
+			message := fmt.Sprintf("rotated the %s key, the previous certificate is trusted until %s", role, result.Status.OverlapUntil.UTC().Format(time.RFC3339))
+			log.Info(message)
+			r.GetRecorder().Event(keySet, corev1.EventTypeNormal, fdov1beta1.ReasonRotationStarted, message)
+		}
+		if result.Retired {
+			message := fmt.Sprintf("retired the previous %s certificate", role)
+			log.Info(message)
+			r.GetRecorder().Event(keySet, corev1.EventTypeNormal, fdov1beta1.ReasonCertificateRetired, message)
+		}
+		if result.Status.Stage == fdov1beta1.KeyStageOverlap {
+			overlapping = append(overlapping, string(role))
+		}
+		if !result.Next.IsZero() && (next.IsZero() || result.Next.Before(next)) {
+			next = result.Next
+		}
+	}
+
+	if len(generated) > 0 {
+		message := fmt.Sprintf("generated keys and certificates: %s", strings.Join(generated, ", "))
+		log.Info(message)
+		r.GetRecorder().Event(keySet, corev1.EventTypeNormal, fdov1beta1.ReasonKeysGenerated, message)
+	}
+	if len(problems) > 0 {
+		message := strings.Join(problems, "; ")
+		r.GetRecorder().Event(keySet, corev1.EventTypeWarning, fdov1beta1.ReasonIncompleteKeyMaterial, message)
+		setKeySetCondition(keySet, fdov1beta1.ConditionReady, metav1.ConditionFalse, fdov1beta1.ReasonIncompleteKeyMaterial, message)
+	} else if len(generated) > 0 {
+		setKeySetCondition(keySet, fdov1beta1.ConditionReady, metav1.ConditionTrue, fdov1beta1.ReasonKeysGenerated, "all keys and certificates exist")
+	} else {
+		setKeySetCondition(keySet, fdov1beta1.ConditionReady, metav1.ConditionTrue, fdov1beta1.ReasonKeysFound, "all keys and certificates exist")
+	}
+	if len(overlapping) > 0 {
+		setKeySetCondition(keySet, fdov1beta1.ConditionRotating, metav1.ConditionTrue, fdov1beta1.ReasonOverlapWindow,
+			fmt.Sprintf("previous certificates still trusted: %s", strings.Join(overlapping, ", ")))
+	} else {
+		setKeySetCondition(keySet, fdov1beta1.ConditionRotating, metav1.ConditionFalse, fdov1beta1.ReasonAsExpected, "no previous certificate is trusted")
+	}
+
+	if next.IsZero() {
+		return r.ManageSuccess(ctx, keySet)
+	}
+	return r.ManageSuccessWithRequeue(ctx, keySet, next.Sub(now))
+}
+
+// reconcileKeyPair creates the private key and the certificate of a role if they don't exist, and
+// rotates them if they were created by the key set
+func (r *FDOKeySetReconciler) reconcileKeyPair(ctx context.Context, keySet *fdov1beta1.FDOKeySet, role fdov1beta1.KeyRole, now time.Time) (keyPairResult, error) {
+	files := newKeyPairFiles(role)
+	result := keyPairResult{
+		Status: fdov1beta1.KeySetKeyStatus{Role: role, CertSecret: files.Cert.Secret, KeySecret: files.Key.Secret, Stage: fdov1beta1.KeyStageActive},
+	}
+
+	certSecret, err := r.getSecret(ctx, keySet.Namespace, files.Cert.Secret)
+	if err != nil {
+		return result, err
+	}
+	keySecret, err := r.getSecret(ctx, keySet.Namespace, files.Key.Secret)
+	if err != nil {
+		return result, err
+	}
+
+	var privateKey []byte
+	switch {
+	case keySecret != nil:
+		if privateKey = keySecret.Data[files.Key.Key]; privateKey == nil {
+			result.Problem = fmt.Sprintf("secret %s has no key %s", files.Key.Secret, files.Key.Key)
+			return result, nil
+		}
+	case certSecret != nil:
+		result.Problem = fmt.Sprintf("secret %s exists without the private key secret %s", files.Cert.Secret, files.Key.Secret)
+		return result, nil
+	default:
+		key, err := keys.GenerateKey(keySetKeyType(keySet))
+		if err != nil {
+			return result, err
+		}
+		if privateKey, err = keys.MarshalKey(key); err != nil {
+			return result, err
+		}
+		if keySecret, err = r.createSecret(ctx, keySet, files.Key.Secret, map[string][]byte{files.Key.Key: privateKey}); err != nil {
+			return result, err
+		}
+		result.Generated = true
+	}
+
+	key, err := keys.ParseKey(privateKey)
+	if err != nil {
+		result.Problem = fmt.Sprintf("secret %s: %v", files.Key.Secret, err)
+		return result, nil
+	}
+
+	if certSecret == nil {
+		cert, err := r.issueCert(keySet, role, key, now)
+		if err != nil {
+			return result, err
+		}
+		if certSecret, err = r.createSecret(ctx, keySet, files.Cert.Secret, map[string][]byte{files.Cert.Key: cert, files.Cert.Bundle: cert}); err != nil {
+			return result, err
+		}
+		result.Generated = true
+	}
+
+	cert := certSecret.Data[files.Cert.Key]
+	if cert == nil {
+		result.Problem = fmt.Sprintf("secret %s has no key %s", files.Cert.Secret, files.Cert.Key)
+		return result, nil
+	}
+	parsed, err := keys.ParseCert(cert)
+	if err != nil {
+		result.Problem = fmt.Sprintf("secret %s: %v", files.Cert.Secret, err)
+		return result, nil
+	}
+
+	if isKeySetSecret(keySet, keySecret) && isKeySetSecret(keySet, certSecret) {
+		if err := r.rotateKeyPair(ctx, keySet, role, files, keySecret, certSecret, now, &result); err != nil {
+			return result, err
+		}
+		if parsed, err = keys.ParseCert(certSecret.Data[files.Cert.Key]); err != nil {
+			return result, err
+		}
+	}
+
+	notAfter := metav1.NewTime(parsed.NotAfter)
+	result.Status.NotAfter = &notAfter
+	return result, nil
+}
+
+// rotateKeyPair moves the key pair of a role created by the key set through its rotation stages:
+// a new key and certificate replace the current ones ahead of expiry, the trust bundle holds both the
+// previous and the new certificate during the overlap window, then the previous certificate is retired
+func (r *FDOKeySetReconciler) rotateKeyPair(ctx context.Context, keySet *fdov1beta1.FDOKeySet, role fdov1beta1.KeyRole, files keyPairFiles, keySecret, certSecret *corev1.Secret, now time.Time, result *keyPairResult) error {
+	if t, err := time.Parse(time.RFC3339, certSecret.Annotations[RotatedAtAnnotation]); err == nil {
+		rotatedAt := metav1.NewTime(t)
+		result.Status.LastRotationTime = &rotatedAt
+	}
+
+	key, err := keys.ParseKey(keySecret.Data[files.Key.Key])
+	if err != nil {
+		return err
+	}
+	cert, err := keys.ParseCert(certSecret.Data[files.Cert.Key])
+	if err != nil {
+		return err
+	}
+
+	// a private key that doesn't match the certificate was replaced by a rotation that didn't complete
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return r.rotateCert(ctx, keySet, role, files, key, certSecret, now, result)
+	}
+
+	if v, ok := certSecret.Annotations[OverlapUntilAnnotation]; ok {
+		overlapUntil, err := time.Parse(time.RFC3339, v)
+		if err == nil && now.Before(overlapUntil) {
+			until := metav1.NewTime(overlapUntil)
+			result.Status.Stage = fdov1beta1.KeyStageOverlap
+			result.Status.OverlapUntil = &until
+			result.Next = overlapUntil
+			return nil
+		}
+		delete(certSecret.Annotations, OverlapUntilAnnotation)
+		certSecret.Data[files.Cert.Bundle] = certSecret.Data[files.Cert.Key]
+		if err := r.GetClient().Update(ctx, certSecret); err != nil {
+			return err
+		}
+		result.Retired = true
+	}
+
+	if keySet.Spec.Rotation == nil || role == fdov1beta1.KeyRoleOwner {
+		return nil
+	}
+	renewAt := cert.NotAfter.Add(-keySetRenewBefore(keySet))
+	if now.Before(renewAt) {
+		result.Next = renewAt
+		return nil
+	}
+
+	if key, err = keys.GenerateKey(keySetKeyType(keySet)); err != nil {
+		return err
+	}
+	der, err := keys.MarshalKey(key)
+	if err != nil {
+		return err
+	}
+	keySecret.Data[files.Key.Key] = der
+	if err := r.GetClient().Update(ctx, keySecret); err != nil {
+		return err
+	}
+	return r.rotateCert(ctx, keySet, role, files, key, certSecret, now, result)
+}
+
+// rotateCert issues the certificate of a new private key, and trusts both the previous and the new
+// certificate until the end of the overlap window
+func (r *FDOKeySetReconciler) rotateCert(ctx context.Context, keySet *fdov1beta1.FDOKeySet, role fdov1beta1.KeyRole, files keyPairFiles, key *ecdsa.PrivateKey, certSecret *corev1.Secret, now time.Time, result *keyPairResult) error {
+	cert, err := r.issueCert(keySet, role, key, now)
+	if err != nil {
+		return err
+	}
+	overlapUntil := now.Add(keySetOverlapWindow(keySet)).Truncate(time.Second)
+	previous := certSecret.Data[files.Cert.Key]
+	certSecret.Data[files.Cert.Key] = cert
+	certSecret.Data[files.Cert.Bundle] = append(append([]byte{}, cert...), previous...)
+	if certSecret.Annotations == nil {
+		certSecret.Annotations = map[string]string{}
+	}
+	certSecret.Annotations[RotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	certSecret.Annotations[OverlapUntilAnnotation] = overlapUntil.UTC().Format(time.RFC3339)
+	if err := r.GetClient().Update(ctx, certSecret); err != nil {
+		return err
+	}
+
+	rotatedAt, until := metav1.NewTime(now), metav1.NewTime(overlapUntil)
+	result.Rotated = true
+	result.Status.Stage = fdov1beta1.KeyStageOverlap
+	result.Status.LastRotationTime = &rotatedAt
+	result.Status.OverlapUntil = &until
+	result.Next = overlapUntil
+	return nil
+}
+
+func (r *FDOKeySetReconciler) issueCert(keySet *fdov1beta1.FDOKeySet, role fdov1beta1.KeyRole, key *ecdsa.PrivateKey, now time.Time) ([]byte, error) {
+	subject := keys.Subject{
+		CommonName:   keySetCommonNames[role],
+		Organization: keySet.Spec.Organization,
+		Country:      keySet.Spec.Country,
+	}
+	return keys.SelfSignedCert(key, subject, role == fdov1beta1.KeyRoleDeviceCA, now, keySetValidity(keySet))
+}
+
+// getSecret returns nil if the secret doesn't exist
+func (r *FDOKeySetReconciler) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// createSecret creates a secret holding a key or a certificate. The secret is not owned by the key
+// set, so that deleting the key set doesn't delete the keys in use by the servers.
+func (r *FDOKeySetReconciler) createSecret(ctx context.Context, keySet *fdov1beta1.FDOKeySet, name string, data map[string][]byte) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: keySet.Namespace,
+			Labels:    map[string]string{KeySetLabel: keySet.Name},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	// creation fails if the secret already exists, e.g. if it was just created by someone else
+	return secret, r.GetClient().Create(ctx, secret)
+}
+
+// isKeySetSecret tells whether a secret was created by the key set
+func isKeySetSecret(keySet *fdov1beta1.FDOKeySet, secret *corev1.Secret) bool {
+	return secret.Labels[KeySetLabel] == keySet.Name
+}
+
+func setKeySetCondition(keySet *fdov1beta1.FDOKeySet, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&keySet.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: keySet.Generation,
+	})
+}
+
+func keySetKeyType(keySet *fdov1beta1.FDOKeySet) string {
+	if keySet.Spec.KeyType == "" {
+		return fdov1beta1.DefaultKeyType
+	}
+	return keySet.Spec.KeyType
+}
+
+func keySetValidity(keySet *fdov1beta1.FDOKeySet) time.Duration {
+	if keySet.Spec.Validity == nil {
+		return fdov1beta1.DefaultKeyValidity.Duration
+	}
+	return keySet.Spec.Validity.Duration
+}
+
+func keySetRenewBefore(keySet *fdov1beta1.FDOKeySet) time.Duration {
+	if keySet.Spec.Rotation == nil || keySet.Spec.Rotation.RenewBefore == nil {
+		return fdov1beta1.DefaultRenewBefore.Duration
+	}
+	return keySet.Spec.Rotation.RenewBefore.Duration
+}
+
+func keySetOverlapWindow(keySet *fdov1beta1.FDOKeySet) time.Duration {
+	if keySet.Spec.Rotation == nil || keySet.Spec.Rotation.OverlapWindow == nil {
+		return fdov1beta1.DefaultOverlapWindow.Duration
+	}
+	return keySet.Spec.Rotation.OverlapWindow.Duration
+}
+
+func keySetRoles(keySet *fdov1beta1.FDOKeySet) []fdov1beta1.KeyRole {
+	if len(keySet.Spec.Roles) == 0 {
+		return fdov1beta1.DefaultKeyRoles
+	}
+	return keySet.Spec.Roles
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FDOKeySetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&fdov1beta1.FDOKeySet{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, secret client.Object) []reconcile.Request {
+			name, ok := secret.GetLabels()[KeySetLabel]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: secret.GetNamespace(), Name: name}}}
+		})).
+		Complete(r)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("FDOKeySetReconciler", func() {
+	var (
+		c      k8sclient.Client
+		ctx    context.Context
+		r      *FDOKeySetReconciler
+		keySet *fdov1beta1.FDOKeySet
+	)
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder().Build()
+		ctx = context.TODO()
+		r = &FDOKeySetReconciler{ReconcilerBase: util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)}
+		keySet = &fdov1beta1.FDOKeySet{
+			ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "fdo"},
+			Spec:       fdov1beta1.FDOKeySetSpec{Organization: "Example", Country: "US", KeyType: keys.SECP256R1},
+		}
+	})
+
+	// secret returns a secret generated by the key set
+	secret := func(name string) *corev1.Secret {
+		secret := &corev1.Secret{}
+		ExpectWithOffset(1, c.Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: name}, secret)).To(Succeed())
+		ExpectWithOffset(1, secret.Labels).To(HaveKeyWithValue(KeySetLabel, "keys"))
+		ExpectWithOffset(1, secret.OwnerReferences).To(BeEmpty())
+		return secret
+	}
+
+	addSecret := func(name string, data map[string][]byte) {
+		ExpectWithOffset(1, c.Create(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fdo"}, Data: data})).To(Succeed())
+	}
+
+	It("should generate a missing key pair", func() {
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleDeviceCA, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Problem).To(BeEmpty())
+		Expect(result.Generated).To(BeTrue())
+		status := result.Status
+		Expect(status.Stage).To(Equal(fdov1beta1.KeyStageActive))
+		Expect(status.CertSecret).To(Equal("fdo-device-ca-cert"))
+		Expect(status.KeySecret).To(Equal("fdo-device-ca-key"))
+		Expect(status.NotAfter).NotTo(BeNil())
+
+		key, err := keys.ParseKey(secret("fdo-device-ca-key").Data["device_ca_key.der"])
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := keys.ParseCert(secret("fdo-device-ca-cert").Data["device_ca_cert.pem"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.PublicKey.Equal(cert.PublicKey)).To(BeTrue())
+		Expect(cert.IsCA).To(BeTrue())
+		Expect(cert.Subject.Organization).To(Equal([]string{"Example"}))
+		Expect(secret("fdo-device-ca-cert").Data["device_ca_trusted_certs.pem"]).To(Equal(secret("fdo-device-ca-cert").Data["device_ca_cert.pem"]))
+	})
+
+	It("should never regenerate an existing key pair", func() {
+		_, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleOwner, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleOwner, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Problem).To(BeEmpty())
+		Expect(result.Generated).To(BeFalse())
+		Expect(result.Status.NotAfter).NotTo(BeNil())
+	})
+
+	It("should issue a certificate for an existing private key", func() {
+		key, err := keys.GenerateKey(keys.SECP384R1)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := keys.MarshalKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		addSecret("fdo-owner-key", map[string][]byte{"owner_key.der": der})
+
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleOwner, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Problem).To(BeEmpty())
+		Expect(result.Generated).To(BeTrue())
+		cert, err := keys.ParseCert(secret("fdo-owner-cert").Data["owner_cert.pem"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.PublicKey.Equal(cert.PublicKey)).To(BeTrue())
+	})
+
+	It("should report a certificate without private key", func() {
+		addSecret("fdo-diun-cert", map[string][]byte{"diun_cert.pem": []byte("cert")})
+
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleDIUN, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Generated).To(BeFalse())
+		Expect(result.Problem).To(ContainSubstring("fdo-diun-key"))
+	})
+
+	It("should rotate a key pair with an overlap window", func() {
+		day := 24 * time.Hour
+		keySet.Spec.Validity = &metav1.Duration{Duration: 10 * day}
+		keySet.Spec.Rotation = &fdov1beta1.KeyRotation{
+			RenewBefore:   &metav1.Duration{Duration: 2 * day},
+			OverlapWindow: &metav1.Duration{Duration: day},
+		}
+		start := time.Now()
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleManufacturer, start)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Next).To(BeTemporally("~", start.Add(8*day), time.Second))
+		previous := secret("fdo-manufacturer-cert").Data["manufacturer_cert.pem"]
+
+		By("rotating ahead of expiry")
+		result, err = r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleManufacturer, start.Add(9*day))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Rotated).To(BeTrue())
+		Expect(result.Status.Stage).To(Equal(fdov1beta1.KeyStageOverlap))
+		Expect(result.Status.OverlapUntil.Time).To(BeTemporally("~", start.Add(10*day), time.Second))
+		certSecret := secret("fdo-manufacturer-cert")
+		current := certSecret.Data["manufacturer_cert.pem"]
+		Expect(current).NotTo(Equal(previous))
+		Expect(certSecret.Data["manufacturer_trusted_certs.pem"]).To(Equal(append(append([]byte{}, current...), previous...)))
+		key, err := keys.ParseKey(secret("fdo-manufacturer-key").Data["manufacturer_key.der"])
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := keys.ParseCert(current)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.PublicKey.Equal(cert.PublicKey)).To(BeTrue())
+
+		By("trusting both certificates during the overlap window")
+		result, err = r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleManufacturer, start.Add(9*day+12*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Rotated).To(BeFalse())
+		Expect(result.Status.Stage).To(Equal(fdov1beta1.KeyStageOverlap))
+
+		By("retiring the previous certificate")
+		result, err = r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleManufacturer, start.Add(10*day+time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Retired).To(BeTrue())
+		Expect(result.Status.Stage).To(Equal(fdov1beta1.KeyStageActive))
+		Expect(result.Status.LastRotationTime).NotTo(BeNil())
+		certSecret = secret("fdo-manufacturer-cert")
+		Expect(certSecret.Data["manufacturer_trusted_certs.pem"]).To(Equal(current))
+		Expect(certSecret.Annotations).NotTo(HaveKey(OverlapUntilAnnotation))
+	})
+
+	It("should never rotate secrets it didn't create", func() {
+		keySet.Spec.Validity = &metav1.Duration{Duration: time.Hour}
+		_, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleDIUN, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		diunKey := secret("fdo-diun-key")
+		delete(diunKey.Labels, KeySetLabel)
+		Expect(c.Update(ctx, diunKey)).To(Succeed())
+		keySet.Spec.Rotation = &fdov1beta1.KeyRotation{}
+
+		result, err := r.reconcileKeyPair(ctx, keySet, fdov1beta1.KeyRoleDIUN, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Rotated).To(BeFalse())
+	})
+})
@@ -19,31 +19,46 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
-	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	"github.com/go-logr/logr"
 	routev1 "github.com/openshift/api/route/v1"
 	util "github.com/redhat-cop/operator-utils/pkg/util"
 	"gopkg.in/yaml.v2"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // FDOManufacturingServerReconciler reconciles a FDOManufacturingServer object
 type FDOManufacturingServerReconciler struct {
 	util.ReconcilerBase
 	Log logr.Logger
+	// OperatorImage runs the init containers fetching keys from external key stores and the
+	// sidecar serving the voucher inventory
+	OperatorImage string
+	// DatabaseImage runs the Postgres database managed by the operator, DefaultDatabaseImage if empty
+	DatabaseImage string
+	// SqliteImage migrates the Sqlite database of the server, DefaultSqliteImage if empty
+	SqliteImage string
+	// HTTPClient reads the voucher inventory
+	HTTPClient *http.Client
 }
 
 const (
 	manufacturingConfigMapTemplate = "%s-config"
-	manufacturingDefaultImage      = "quay.io/fido-fdo/manufacturing-server:0.4"
 )
 
 //+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdomanufacturingservers,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +67,10 @@ const (
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods;secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=create;update;patch;delete
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdodevices,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -73,29 +92,92 @@ func (r *FDOManufacturingServerReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, err
 	}
 
-	r.setDefaultValues(server)
+	// Defaults are normally persisted by the mutating webhook, this covers
+	// servers created while webhooks were disabled
+	server.Default()
 
-	if _, err = r.createOrUpdateRoute(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	var route *routev1.Route
+	if route, err = r.createOrUpdateRoute(log, server); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateConfigMap(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	var configMap *corev1.ConfigMap
+	if configMap, err = r.createOrUpdateConfigMap(log, server); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateDeployment(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	if err = reconcileCertificates(ctx, &r.ReconcilerBase, server, manufacturingCertificates(server)); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateService(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	keyFiles, err := deriveDERKeys(ctx, &r.ReconcilerBase, server, manufacturingKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	missing, err := verifyKeyFiles(ctx, r.GetClient(), server, keyFiles)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if len(missing) > 0 {
+		return manageMissingKeys(ctx, &r.ReconcilerBase, server, missing)
+	}
+	next, err := validateKeyFiles(ctx, &r.ReconcilerBase, server, keyFiles, manufacturingKeyTypes(server), time.Now())
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = createOrUpdateFingerprintsConfigMap(ctx, &r.ReconcilerBase, server, ManufacturingServiceType); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = checkTrust(ctx, r.GetClient(), server, "FDOManufacturingServer"); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = reconcileManagedDatabase(ctx, &r.ReconcilerBase, server, server.Spec.Storage.GetDatabase(), r.DatabaseImage); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	// sidecar serving the voucher inventory and export API
+	sidecar := withVoucherExport(inventorySidecar(r.OperatorImage, server.Spec.Storage.GetDatabase()), server)
+
+	var deploy *appsv1.Deployment
+	if deploy, err = r.createOrUpdateDeployment(log, server, keyFiles, configMap, sidecar); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	if _, err = r.createOrUpdateService(log, server, sidecar); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	exportRoute, err := reconcileVoucherExportRoute(ctx, &r.ReconcilerBase, server, sidecar)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	images, err := updateServerStatus(ctx, r.GetClient(), server, ManufacturingServiceType, deploy, route)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	server.Status.Image = images["manufacturing"]
+	server.Status.ExportEndpoints = voucherExportEndpoints(server, sidecar, exportRoute)
+
+	if sidecar != nil {
+		token, err := voucherExportToken(ctx, r.GetClient(), server)
+		if err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+		if server.Status.Inventory, err = reconcileVoucherInventory(ctx, &r.ReconcilerBase, r.HTTPClient, server, "FDOManufacturingServer", ManufacturingServiceType, inventoryURL(server), token, server.Spec.DeviceObjects, server.Status.Inventory); err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+		if inventoryScan := time.Now().Add(inventoryInterval); next.IsZero() || inventoryScan.Before(next) {
+			next = inventoryScan
+		}
+	} else {
+		server.Status.Inventory = nil
 	}
 
-	return r.ManageSuccess(ctx, server)
+	return manageSuccess(ctx, &r.ReconcilerBase, server, next)
 }
 
-func (r *FDOManufacturingServerReconciler) getManufacturingServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1alpha1.FDOManufacturingServer, bool, error) {
-	server := &fdov1alpha1.FDOManufacturingServer{}
+func (r *FDOManufacturingServerReconciler) getManufacturingServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1beta1.FDOManufacturingServer, bool, error) {
+	server := &fdov1beta1.FDOManufacturingServer{}
 	err := r.ReconcilerBase.GetClient().Get(ctx, req.NamespacedName, server)
 	if err == nil {
 		return server, true, nil
@@ -108,13 +190,20 @@ func (r *FDOManufacturingServerReconciler) getManufacturingServer(log logr.Logge
 	return nil, false, err
 }
 
-func (r *FDOManufacturingServerReconciler) setDefaultValues(server *fdov1alpha1.FDOManufacturingServer) {
-	if server.Spec.Image == "" {
-		server.Spec.Image = manufacturingDefaultImage
+func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1beta1.FDOManufacturingServer, keyFiles []keyFile, configMap *corev1.ConfigMap, sidecar *corev1.Container) (*appsv1.Deployment, error) {
+	annotations, err := podTemplateAnnotations(context.TODO(), r.GetClient(), server.Namespace, keyFiles, configMap)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1alpha1.FDOManufacturingServer) (*appsv1.Deployment, error) {
+	initContainers, err := keySourceInitContainers(r.OperatorImage, keyFiles)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := manufacturerVoucherStore.migrationContainers(server.Spec.Storage.GetDatabase(), r.SqliteImage, "ownership-vouchers")
+	if err != nil {
+		return nil, err
+	}
+	initContainers = append(initContainers, migrations...)
 
 	labels := getLabels(ManufacturingServiceType)
 	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
@@ -124,26 +213,30 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 				MatchLabels: labels,
 			}
 		}
-		optional := false
 		privilegeEscalation := false
 		nonRoot := true
-		labels := getLabels(ManufacturingServiceType)
-		replicas := int32(1)
-		deploy.Spec.Replicas = &replicas
+		labels := getPodLabels(ManufacturingServiceType, server.Name)
+		deploy.Spec.Replicas = serverReplicas(server.Spec.Replicas)
+		claimName := ownershipVouchersPVC
+		if server.Spec.Storage != nil && server.Spec.Storage.OwnershipVouchersClaimName != "" {
+			claimName = server.Spec.Storage.OwnershipVouchersClaimName
+		}
 		deploy.Spec.Template = corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
+				Annotations: annotations,
+				Labels:      labels,
 			},
 			Spec: corev1.PodSpec{
 				Containers: []corev1.Container{
 					{
-						Image: server.Spec.Image,
+						Image: server.Spec.ImageOrDefault(),
 						Name:  "manufacturing",
+						Env:   manufacturerVoucherStore.env(server.Spec.Storage.GetDatabase()),
 						Ports: []corev1.ContainerPort{
 							{
 								ContainerPort: 8080,
 							}},
-						VolumeMounts: []corev1.VolumeMount{
+						VolumeMounts: append([]corev1.VolumeMount{
 							{
 								Name:      "manufacturing-config",
 								MountPath: "/etc/fdo/manufacturing-server.conf.d",
@@ -153,54 +246,12 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 								Name:      "ownership-vouchers",
 								MountPath: "/etc/fdo/ownership_vouchers",
 							},
-							{
-								Name:      "diun-cert",
-								MountPath: "/etc/fdo/keys/diun_cert.pem",
-								SubPath:   "diun_cert.pem",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "diun-key",
-								MountPath: "/etc/fdo/keys/diun_key.der",
-								SubPath:   "diun_key.der",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "manufacturer-cert",
-								MountPath: "/etc/fdo/keys/manufacturer_cert.pem",
-								SubPath:   "manufacturer_cert.pem",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "manufacturer-key",
-								MountPath: "/etc/fdo/keys/manufacturer_key.der",
-								SubPath:   "manufacturer_key.der",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "owner-cert",
-								MountPath: "/etc/fdo/keys/owner_cert.pem",
-								SubPath:   "owner_cert.pem",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "device-ca-key",
-								MountPath: "/etc/fdo/keys/device_ca_key.der",
-								SubPath:   "device_ca_key.der",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "device-ca-chain",
-								MountPath: "/etc/fdo/keys/device_ca_cert.pem",
-								SubPath:   "device_ca_cert.pem",
-								ReadOnly:  true,
-							},
 							{
 								Name:      "sessions",
 								MountPath: "/etc/fdo/sessions",
 								ReadOnly:  false,
 							},
-						},
+						}, keyFileVolumeMounts(keyFiles)...),
 						SecurityContext: &corev1.SecurityContext{
 							AllowPrivilegeEscalation: &privilegeEscalation,
 							Capabilities: &corev1.Capabilities{
@@ -210,7 +261,7 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 							},
 						},
 					}},
-				Volumes: []corev1.Volume{
+				Volumes: append([]corev1.Volume{
 					{
 						Name: "manufacturing-config",
 						VolumeSource: corev1.VolumeSource{
@@ -223,124 +274,17 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 					},
 					{
 						Name: "ownership-vouchers",
-						VolumeSource: corev1.VolumeSource{
+						VolumeSource: storeVolume(server.Spec.Storage.GetDatabase(), corev1.VolumeSource{
 							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-								ClaimName: ownershipVouchersPVC,
-							},
-						},
-					},
-					{
-						Name: "diun-cert",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-diun-cert",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "diun_cert.pem",
-										Path: "diun_cert.pem",
-									},
-								},
-								Optional: &optional,
-							},
-						},
-					},
-					{
-						Name: "diun-key",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-diun-key",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "diun_key.der",
-										Path: "diun_key.der",
-									},
-								},
-								Optional: &optional,
-							},
-						},
-					},
-					{
-						Name: "manufacturer-cert",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-manufacturer-cert",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "manufacturer_cert.pem",
-										Path: "manufacturer_cert.pem",
-									},
-								},
-								Optional: &optional,
+								ClaimName: claimName,
 							},
-						},
+						}),
 					},
 					{
-						Name: "manufacturer-key",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-manufacturer-key",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "manufacturer_key.der",
-										Path: "manufacturer_key.der",
-									},
-								},
-								Optional: &optional,
-							},
-						},
+						Name:         "sessions",
+						VolumeSource: sessionsVolume(server.Spec.Storage.GetSessionsClaimName()),
 					},
-					{
-						Name: "owner-cert",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-owner-cert",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "owner_cert.pem",
-										Path: "owner_cert.pem",
-									},
-								},
-								Optional: &optional,
-							},
-						},
-					},
-					{
-						Name: "device-ca-chain",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-device-ca-cert",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "device_ca_cert.pem",
-										Path: "device_ca_cert.pem",
-									},
-								},
-								Optional: &optional,
-							},
-						},
-					},
-					{
-						Name: "device-ca-key",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-device-ca-key",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "device_ca_key.der",
-										Path: "device_ca_key.der",
-									},
-								},
-								Optional: &optional,
-							},
-						},
-					},
-					{
-						Name: "sessions",
-						VolumeSource: corev1.VolumeSource{
-							EmptyDir: &corev1.EmptyDirVolumeSource{},
-						},
-					},
-				},
+				}, append(keyFileVolumes(keyFiles), voucherExportVolumes(sidecar, server)...)...),
 				SecurityContext: &corev1.PodSecurityContext{
 					RunAsNonRoot: &nonRoot,
 					SeccompProfile: &corev1.SeccompProfile{
@@ -349,6 +293,9 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 				},
 			},
 		}
+		deploy.Spec.Template.Spec.InitContainers = initContainers
+		deploy.Spec.Template.Spec.Containers = withInventorySidecar(deploy.Spec.Template.Spec.Containers, sidecar)
+		applyPodTemplate(&deploy.Spec.Template.Spec, server.Spec.PodTemplate)
 		return ctrl.SetControllerReference(server, deploy, r.GetScheme())
 	})
 
@@ -361,19 +308,20 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateDeployment(log logr.Log
 	}
 }
 
-func (r *FDOManufacturingServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1alpha1.FDOManufacturingServer) (*corev1.Service, error) {
+func (r *FDOManufacturingServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1beta1.FDOManufacturingServer, sidecar *corev1.Container) (*corev1.Service, error) {
 	labels := getLabels(ManufacturingServiceType)
 	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), service, func() error {
 		service.Spec = corev1.ServiceSpec{
 			Selector: labels,
-			Ports: []corev1.ServicePort{
+			Ports: withVoucherExportPort(withInventoryPort([]corev1.ServicePort{
 				{
+					Name:       "http",
 					Protocol:   "TCP",
 					Port:       int32(8080),
 					TargetPort: intstr.FromInt(8080),
 				},
-			},
+			}, sidecar), sidecar, server),
 		}
 		return ctrl.SetControllerReference(server, service, r.GetScheme())
 	})
@@ -386,11 +334,12 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateService(log logr.Logger
 	}
 }
 
-func (r *FDOManufacturingServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1alpha1.FDOManufacturingServer) (*routev1.Route, error) {
+func (r *FDOManufacturingServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1beta1.FDOManufacturingServer) (*routev1.Route, error) {
 	labels := getLabels(ManufacturingServiceType)
 	route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), route, func() error {
 		route.Spec = routev1.RouteSpec{
+			Host: routeHost(server.Spec.Expose),
 			To: routev1.RouteTargetReference{
 				Kind: "Service",
 				Name: server.Name,
@@ -411,7 +360,7 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateRoute(log logr.Logger,
 	}
 }
 
-func (r *FDOManufacturingServerReconciler) createOrUpdateConfigMap(log logr.Logger, server *fdov1alpha1.FDOManufacturingServer) (*corev1.ConfigMap, error) {
+func (r *FDOManufacturingServerReconciler) createOrUpdateConfigMap(log logr.Logger, server *fdov1beta1.FDOManufacturingServer) (*corev1.ConfigMap, error) {
 	labels := getLabels(ManufacturingServiceType)
 	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(manufacturingConfigMapTemplate, server.Name), Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), configMap, func() error {
@@ -433,16 +382,41 @@ func (r *FDOManufacturingServerReconciler) createOrUpdateConfigMap(log logr.Logg
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *FDOManufacturingServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOManufacturingServer{}, keySecretsIndex, func(obj client.Object) []string {
+		return keySecretNames(manufacturingKeyFiles(obj.(*fdov1beta1.FDOManufacturingServer).Spec.Keys))
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOManufacturingServer{}, keyCertificatesIndex, func(obj client.Object) []string {
+		return certificateNames(manufacturingCertificates(obj.(*fdov1beta1.FDOManufacturingServer)))
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&fdov1alpha1.FDOManufacturingServer{}).
+		For(&fdov1beta1.FDOManufacturingServer{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
 		Owns(&routev1.Route{}).
+		Owns(&appsv1.StatefulSet{}, builder.MatchEveryOwner).
+		Owns(&batchv1.Job{}, builder.MatchEveryOwner).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, secret client.Object) []reconcile.Request {
+			requests := append(requestsForSecret(ctx, r.GetClient(), &fdov1beta1.FDOManufacturingServerList{}, secret),
+				requestsForCertificateSecret(ctx, r.GetClient(), &fdov1beta1.FDOManufacturingServerList{}, secret)...)
+			return append(requests, requestsForPeerSecret(ctx, r.GetClient(), &fdov1beta1.FDOManufacturingServerList{}, secret, &fdov1beta1.FDOOnboardingServerList{}, &fdov1beta1.FDORendezvousServerList{})...)
+		})).
+		Watches(&fdov1beta1.FDOOnboardingServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDOManufacturingServerList{}, peer)
+		}), peerChanged).
+		Watches(&fdov1beta1.FDORendezvousServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDOManufacturingServerList{}, peer)
+		}), peerChanged).
 		Complete(r)
 }
 
-func (r *FDOManufacturingServerReconciler) generateConfig(fdoServer *fdov1alpha1.FDOManufacturingServer) (string, error) {
+func (r *FDOManufacturingServerReconciler) generateConfig(fdoServer *fdov1beta1.FDOManufacturingServer) (string, error) {
 	config := ManufacturingServerConfig{}
 	if err := config.setValues(fdoServer); err != nil {
 		return "", err
@@ -19,31 +19,47 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"path"
 	"sort"
 	"time"
 
-	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	"github.com/go-logr/logr"
 	routev1 "github.com/openshift/api/route/v1"
 	util "github.com/redhat-cop/operator-utils/pkg/util"
 	"gopkg.in/yaml.v2"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // FDOOnboardingServerReconciler reconciles a FDOOnboardingServer object
 type FDOOnboardingServerReconciler struct {
 	util.ReconcilerBase
 	Log logr.Logger
+	// OperatorImage runs the init containers fetching keys from external key stores and the
+	// sidecar serving the voucher inventory
+	OperatorImage string
+	// DatabaseImage runs the Postgres database managed by the operator, DefaultDatabaseImage if empty
+	DatabaseImage string
+	// SqliteImage migrates the Sqlite database of the server, DefaultSqliteImage if empty
+	SqliteImage string
+	// HTTPClient reads the voucher inventory
+	HTTPClient *http.Client
 }
 
 type FDOServiceType string
@@ -59,8 +75,6 @@ const (
 	serviceInfoAPIConfigMapTemplate  = "%s-serviceinfo-api-config"
 	ownershipVouchersPVC             = "fdo-ownership-vouchers-pvc"
 	serviceInfoFilesPVC              = "fdo-serviceinfo-files-pvc"
-	ownerOnboardingDefaultImage      = "quay.io/fido-fdo/owner-onboarding-server:0.4"
-	serviceInfoAPIDefaultImage       = "quay.io/fido-fdo/serviceinfo-api-server:0.4"
 )
 
 const (
@@ -77,6 +91,10 @@ const (
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods;secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=create;update;patch;delete
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdodevices,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -111,51 +129,145 @@ func (r *FDOOnboardingServerReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, err
 	}
 
-	r.setDefaultValues(server)
+	// Defaults are normally persisted by the mutating webhook, this covers
+	// servers created while webhooks were disabled
+	server.Default()
 
 	var route *routev1.Route
 	if route, err = r.createOrUpdateRoute(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateOwnerOnboardingConfigMap(log, server, route); err != nil {
-		return r.ManageError(ctx, server, err)
+	var ownerOnboardingConfigMap, serviceInfoAPIConfigMap *corev1.ConfigMap
+	if ownerOnboardingConfigMap, err = r.createOrUpdateOwnerOnboardingConfigMap(log, server, route); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
 	files, err := r.listConfigMaps(log, ctx, req, server.Name)
 	if err != nil {
-		return r.ManageErrorWithRequeue(ctx, server, err, 30*time.Second) // allow time for the user to fix the configuration
+		return manageError(ctx, &r.ReconcilerBase, server, err, 30*time.Second) // allow time for the user to fix the configuration
 	}
 
-	if _, err = r.createOrUpdateServiceInfoAPIConfigMap(log, server, files); err != nil {
-		return r.ManageError(ctx, server, err)
+	if serviceInfoAPIConfigMap, err = r.createOrUpdateServiceInfoAPIConfigMap(log, server, files); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateDeployment(log, server, files); err != nil {
-		return r.ManageError(ctx, server, err)
+	if err = reconcileCertificates(ctx, &r.ReconcilerBase, server, onboardingCertificates(server)); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	keyFiles, err := resolveTrustBundles(ctx, r.GetClient(), server.Namespace, onboardingKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if keyFiles, err = deriveDERKeys(ctx, &r.ReconcilerBase, server, keyFiles); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	missing, err := verifyKeyFiles(ctx, r.GetClient(), server, keyFiles)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if len(missing) > 0 {
+		return manageMissingKeys(ctx, &r.ReconcilerBase, server, missing)
+	}
+	next, err := validateKeyFiles(ctx, &r.ReconcilerBase, server, keyFiles, nil, time.Now())
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = createOrUpdateFingerprintsConfigMap(ctx, &r.ReconcilerBase, server, OwnerOnboardingServiceType); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = checkTrust(ctx, r.GetClient(), server, "FDOOnboardingServer"); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = reconcileManagedDatabase(ctx, &r.ReconcilerBase, server, server.Spec.Storage.GetDatabase(), r.DatabaseImage); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	if err = reconcileInventoryToken(ctx, &r.ReconcilerBase, server, inventorySidecar(r.OperatorImage, server.Spec.Storage.GetDatabase())); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	var deploy *appsv1.Deployment
+	if deploy, err = r.createOrUpdateDeployment(log, server, keyFiles, files, ownerOnboardingConfigMap, serviceInfoAPIConfigMap); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
 	if _, err = r.createOrUpdateService(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	images, err := updateServerStatus(ctx, r.GetClient(), server, OwnerOnboardingServiceType, deploy, route)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	server.Status.OwnerOnboardingImage = images["owner-onboarding"]
+	server.Status.ServiceInfoImage = images["serviceinfo-api"]
+
+	if inventorySidecar(r.OperatorImage, server.Spec.Storage.GetDatabase()) != nil {
+		// pruned vouchers leave the inventory read next
+		if server.Status.Retention, err = reconcileRetention(ctx, &r.ReconcilerBase, r.HTTPClient, server, inventoryURL(server), time.Now()); err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+		if server.Status.Retention != nil && (next.IsZero() || server.Status.Retention.NextRunTime.Time.Before(next)) {
+			next = server.Status.Retention.NextRunTime.Time
+		}
+		if server.Status.Inventory, err = reconcileVoucherInventory(ctx, &r.ReconcilerBase, r.HTTPClient, server, "FDOOnboardingServer", OwnerOnboardingServiceType, inventoryURL(server), "", server.Spec.DeviceObjects, server.Status.Inventory); err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+		if inventoryScan := time.Now().Add(inventoryInterval); next.IsZero() || inventoryScan.Before(next) {
+			next = inventoryScan
+		}
+	} else {
+		server.Status.Inventory = nil
+		server.Status.Retention = nil
 	}
 
 	// Allow the controller to pick up new serviceinfo files
-	return r.ManageSuccessWithRequeue(ctx, server, 5*time.Minute)
+	if serviceInfoCheck := time.Now().Add(5 * time.Minute); next.IsZero() || serviceInfoCheck.Before(next) {
+		next = serviceInfoCheck
+	}
+	return manageSuccess(ctx, &r.ReconcilerBase, server, next)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *FDOOnboardingServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOOnboardingServer{}, keySecretsIndex, func(obj client.Object) []string {
+		return keySecretNames(onboardingKeyFiles(obj.(*fdov1beta1.FDOOnboardingServer).Spec.Keys))
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOOnboardingServer{}, keyCertificatesIndex, func(obj client.Object) []string {
+		return certificateNames(onboardingCertificates(obj.(*fdov1beta1.FDOOnboardingServer)))
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&fdov1alpha1.FDOOnboardingServer{}).
+		For(&fdov1beta1.FDOOnboardingServer{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
 		Owns(&routev1.Route{}).
+		Owns(&appsv1.StatefulSet{}, builder.MatchEveryOwner).
+		Owns(&batchv1.Job{}, builder.MatchEveryOwner).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, secret client.Object) []reconcile.Request {
+			requests := append(requestsForSecret(ctx, r.GetClient(), &fdov1beta1.FDOOnboardingServerList{}, secret),
+				requestsForCertificateSecret(ctx, r.GetClient(), &fdov1beta1.FDOOnboardingServerList{}, secret)...)
+			return append(requests, requestsForPeerSecret(ctx, r.GetClient(), &fdov1beta1.FDOOnboardingServerList{}, secret, &fdov1beta1.FDOManufacturingServerList{}, &fdov1beta1.FDORendezvousServerList{})...)
+		})).
+		Watches(&fdov1beta1.FDOManufacturingServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDOOnboardingServerList{}, peer)
+		}), peerChanged).
+		Watches(&fdov1beta1.FDORendezvousServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDOOnboardingServerList{}, peer)
+		}), peerChanged).
 		Complete(r)
 }
 
-func (r *FDOOnboardingServerReconciler) getOnboardingServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1alpha1.FDOOnboardingServer, bool, error) {
-	server := &fdov1alpha1.FDOOnboardingServer{}
+func (r *FDOOnboardingServerReconciler) getOnboardingServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1beta1.FDOOnboardingServer, bool, error) {
+	server := &fdov1beta1.FDOOnboardingServer{}
 	err := r.ReconcilerBase.GetClient().Get(ctx, req.NamespacedName, server)
 	if err == nil {
 		return server, true, nil
@@ -168,16 +280,20 @@ func (r *FDOOnboardingServerReconciler) getOnboardingServer(log logr.Logger, ctx
 	return nil, false, err
 }
 
-func (r *FDOOnboardingServerReconciler) setDefaultValues(server *fdov1alpha1.FDOOnboardingServer) {
-	if server.Spec.OwnerOnboardingImage == "" {
-		server.Spec.OwnerOnboardingImage = ownerOnboardingDefaultImage
+func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1beta1.FDOOnboardingServer, keyFiles []keyFile, files []ServiceInfoFile, configMaps ...*corev1.ConfigMap) (*appsv1.Deployment, error) {
+	annotations, err := podTemplateAnnotations(context.TODO(), r.GetClient(), server.Namespace, keyFiles, configMaps...)
+	if err != nil {
+		return nil, err
 	}
-	if server.Spec.ServiceInfoImage == "" {
-		server.Spec.ServiceInfoImage = serviceInfoAPIDefaultImage
+	initContainers, err := keySourceInitContainers(r.OperatorImage, keyFiles)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1alpha1.FDOOnboardingServer, files []ServiceInfoFile) (*appsv1.Deployment, error) {
+	migrations, err := ownerVoucherStore.migrationContainers(server.Spec.Storage.GetDatabase(), r.SqliteImage, "ownership-vouchers")
+	if err != nil {
+		return nil, err
+	}
+	initContainers = append(initContainers, migrations...)
 
 	labels := getLabels(OwnerOnboardingServiceType)
 	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
@@ -187,12 +303,14 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 				MatchLabels: labels,
 			}
 		}
-		optional := false
 		privilegeEscalation := false
 		nonRoot := true
-		labels := getLabels(OwnerOnboardingServiceType)
-		replicas := int32(1)
-		deploy.Spec.Replicas = &replicas
+		labels := getPodLabels(OwnerOnboardingServiceType, server.Name)
+		deploy.Spec.Replicas = serverReplicas(server.Spec.Replicas)
+		claimName := ownershipVouchersPVC
+		if server.Spec.Storage != nil && server.Spec.Storage.OwnershipVouchersClaimName != "" {
+			claimName = server.Spec.Storage.OwnershipVouchersClaimName
+		}
 
 		serviceInfoVolumeMounts := []corev1.VolumeMount{
 			{
@@ -212,51 +330,6 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 					},
 				},
 			},
-			{
-				Name: "owner-cert",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: "fdo-owner-cert",
-						Items: []corev1.KeyToPath{
-							{
-								Key:  "owner_cert.pem",
-								Path: "owner_cert.pem",
-							},
-						},
-						Optional: &optional,
-					},
-				},
-			},
-			{
-				Name: "owner-key",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: "fdo-owner-key",
-						Items: []corev1.KeyToPath{
-							{
-								Key:  "owner_key.der",
-								Path: "owner_key.der",
-							},
-						},
-						Optional: &optional,
-					},
-				},
-			},
-			{
-				Name: "device-ca-chain",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: "fdo-device-ca-cert",
-						Items: []corev1.KeyToPath{
-							{
-								Key:  "device_ca_cert.pem",
-								Path: "device_ca_cert.pem",
-							},
-						},
-						Optional: &optional,
-					},
-				},
-			},
 			{
 				Name: "serviceinfo-api-config",
 				VolumeSource: corev1.VolumeSource{
@@ -269,17 +342,15 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 			},
 			{
 				Name: "ownership-vouchers",
-				VolumeSource: corev1.VolumeSource{
+				VolumeSource: storeVolume(server.Spec.Storage.GetDatabase(), corev1.VolumeSource{
 					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: ownershipVouchersPVC,
+						ClaimName: claimName,
 					},
-				},
+				}),
 			},
 			{
-				Name: "sessions",
-				VolumeSource: corev1.VolumeSource{
-					EmptyDir: &corev1.EmptyDirVolumeSource{},
-				},
+				Name:         "sessions",
+				VolumeSource: sessionsVolume(server.Spec.Storage.GetSessionsClaimName()),
 			},
 			{
 				Name: "device-specific-serviceinfo",
@@ -289,6 +360,10 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 			},
 		}
 
+		volumes = append(volumes, keyFileVolumes(keyFiles)...)
+		sidecar := withRetention(withInventoryToken(inventorySidecar(r.OperatorImage, server.Spec.Storage.GetDatabase()), server), server)
+		volumes = append(volumes, retentionVolumes(sidecar, server)...)
+
 		for _, f := range files {
 			serviceInfoVolumeMounts = append(serviceInfoVolumeMounts, corev1.VolumeMount{
 				Name:      f.ConfigMap,
@@ -309,18 +384,20 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 
 		deploy.Spec.Template = corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
+				Annotations: annotations,
+				Labels:      labels,
 			},
 			Spec: corev1.PodSpec{
 				Containers: []corev1.Container{
 					{
-						Image: server.Spec.OwnerOnboardingImage,
+						Image: server.Spec.OwnerOnboardingImageOrDefault(),
 						Name:  "owner-onboarding",
+						Env:   ownerVoucherStore.env(server.Spec.Storage.GetDatabase()),
 						Ports: []corev1.ContainerPort{
 							{
 								ContainerPort: 8081,
 							}},
-						VolumeMounts: []corev1.VolumeMount{
+						VolumeMounts: append([]corev1.VolumeMount{
 							{
 								Name:      "owner-onboarding-config",
 								MountPath: "/etc/fdo/owner-onboarding-server.conf.d",
@@ -330,24 +407,6 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 								Name:      "ownership-vouchers",
 								MountPath: "/etc/fdo/ownership_vouchers",
 							},
-							{
-								Name:      "owner-cert",
-								MountPath: "/etc/fdo/keys/owner_cert.pem",
-								SubPath:   "owner_cert.pem",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "owner-key",
-								MountPath: "/etc/fdo/keys/owner_key.der",
-								SubPath:   "owner_key.der",
-								ReadOnly:  true,
-							},
-							{
-								Name:      "device-ca-chain",
-								MountPath: "/etc/fdo/keys/device_ca_cert.pem",
-								SubPath:   "device_ca_cert.pem",
-								ReadOnly:  true,
-							},
 							{
 								Name:      "sessions",
 								MountPath: "/etc/fdo/sessions",
@@ -358,7 +417,7 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 								MountPath: "/etc/fdo/keys/device_specific_serviceinfo",
 								ReadOnly:  false,
 							},
-						},
+						}, keyFileVolumeMounts(keyFiles)...),
 						SecurityContext: &corev1.SecurityContext{
 							AllowPrivilegeEscalation: &privilegeEscalation,
 							Capabilities: &corev1.Capabilities{
@@ -368,7 +427,7 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 							},
 						},
 					}, {
-						Image: server.Spec.ServiceInfoImage,
+						Image: server.Spec.ServiceInfoImageOrDefault(),
 						Name:  "serviceinfo-api",
 						Ports: []corev1.ContainerPort{
 							{
@@ -393,6 +452,9 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 				},
 			},
 		}
+		deploy.Spec.Template.Spec.InitContainers = initContainers
+		deploy.Spec.Template.Spec.Containers = withInventorySidecar(deploy.Spec.Template.Spec.Containers, sidecar)
+		applyPodTemplate(&deploy.Spec.Template.Spec, server.Spec.PodTemplate)
 		return ctrl.SetControllerReference(server, deploy, r.GetScheme())
 	})
 
@@ -405,19 +467,20 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateDeployment(log logr.Logger
 	}
 }
 
-func (r *FDOOnboardingServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1alpha1.FDOOnboardingServer) (*corev1.Service, error) {
+func (r *FDOOnboardingServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1beta1.FDOOnboardingServer) (*corev1.Service, error) {
 	labels := getLabels(OwnerOnboardingServiceType)
 	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), service, func() error {
 		service.Spec = corev1.ServiceSpec{
 			Selector: labels,
-			Ports: []corev1.ServicePort{
+			Ports: withInventoryPort([]corev1.ServicePort{
 				{
+					Name:       "http",
 					Protocol:   "TCP",
 					Port:       int32(8081),
 					TargetPort: intstr.FromInt(8081),
 				},
-			},
+			}, inventorySidecar(r.OperatorImage, server.Spec.Storage.GetDatabase())),
 		}
 		return ctrl.SetControllerReference(server, service, r.GetScheme())
 	})
@@ -430,11 +493,12 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateService(log logr.Logger, s
 	}
 }
 
-func (r *FDOOnboardingServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1alpha1.FDOOnboardingServer) (*routev1.Route, error) {
+func (r *FDOOnboardingServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1beta1.FDOOnboardingServer) (*routev1.Route, error) {
 	labels := getLabels(OwnerOnboardingServiceType)
 	route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), route, func() error {
 		route.Spec = routev1.RouteSpec{
+			Host: routeHost(server.Spec.Expose),
 			To: routev1.RouteTargetReference{
 				Kind: "Service",
 				Name: server.Name,
@@ -455,7 +519,7 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateRoute(log logr.Logger, ser
 	}
 }
 
-func (r *FDOOnboardingServerReconciler) createOrUpdateOwnerOnboardingConfigMap(log logr.Logger, server *fdov1alpha1.FDOOnboardingServer, route *routev1.Route) (*corev1.ConfigMap, error) {
+func (r *FDOOnboardingServerReconciler) createOrUpdateOwnerOnboardingConfigMap(log logr.Logger, server *fdov1beta1.FDOOnboardingServer, route *routev1.Route) (*corev1.ConfigMap, error) {
 	labels := getLabels(OwnerOnboardingServiceType)
 	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(ownerOnboardingConfigMapTemplate, server.Name), Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), configMap, func() error {
@@ -475,7 +539,7 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateOwnerOnboardingConfigMap(l
 	}
 }
 
-func (r *FDOOnboardingServerReconciler) createOrUpdateServiceInfoAPIConfigMap(log logr.Logger, server *fdov1alpha1.FDOOnboardingServer, files []ServiceInfoFile) (*corev1.ConfigMap, error) {
+func (r *FDOOnboardingServerReconciler) createOrUpdateServiceInfoAPIConfigMap(log logr.Logger, server *fdov1beta1.FDOOnboardingServer, files []ServiceInfoFile) (*corev1.ConfigMap, error) {
 	labels := getLabels(OwnerOnboardingServiceType)
 	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(serviceInfoAPIConfigMapTemplate, server.Name), Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), configMap, func() error {
@@ -495,7 +559,7 @@ func (r *FDOOnboardingServerReconciler) createOrUpdateServiceInfoAPIConfigMap(lo
 	}
 }
 
-func (r *FDOOnboardingServerReconciler) generateOwnerOnboardingConfig(fdoServer *fdov1alpha1.FDOOnboardingServer, route *routev1.Route) (string, error) {
+func (r *FDOOnboardingServerReconciler) generateOwnerOnboardingConfig(fdoServer *fdov1beta1.FDOOnboardingServer, route *routev1.Route) (string, error) {
 	config := OwnerOnboardingServerConfig{}
 	if err := config.setValues(fdoServer, route); err != nil {
 		return "", err
@@ -508,7 +572,7 @@ func (r *FDOOnboardingServerReconciler) generateOwnerOnboardingConfig(fdoServer
 	return string(v), nil
 }
 
-func (r *FDOOnboardingServerReconciler) generateServiceInfoAPIConfig(fdoServer *fdov1alpha1.FDOOnboardingServer, files []ServiceInfoFile) (string, error) {
+func (r *FDOOnboardingServerReconciler) generateServiceInfoAPIConfig(fdoServer *fdov1beta1.FDOOnboardingServer, files []ServiceInfoFile) (string, error) {
 	config := ServiceInfoAPIServerConfig{}
 	if err := config.setValues(fdoServer, files); err != nil {
 		return "", err
@@ -557,23 +621,57 @@ func (r *FDOOnboardingServerReconciler) listConfigMaps(log logr.Logger, ctx cont
 }
 
 func readServiceInfoFileFromConfigMap(cm corev1.ConfigMap, c *ServiceInfoFile) error {
-	var fileName string
-	for k, v := range cm.Annotations {
-		if k == FileKey {
-			fileName = v
-		} else if k == PermissionsKey {
-			c.Permissions = v
-		} else if k == PathKey {
-			c.Path = v
-		}
+	keys := make([]string, 0, len(cm.BinaryData)+len(cm.Data))
+	for k := range cm.BinaryData {
+		keys = append(keys, k)
 	}
-	if fileName == "" || c.Path == "" {
-		return fmt.Errorf("serviceinfo file name and destination path are required: %s", cm.Name)
+	for k := range cm.Data {
+		keys = append(keys, k)
 	}
-	if _, ok := cm.BinaryData[fileName]; !ok {
-		return fmt.Errorf("configmap '%s' does not contain file '%s'", cm.Name, fileName)
+	file, errs := ParseServiceInfoFile(&cm, keys)
+	if len(errs) > 0 {
+		return fmt.Errorf("configmap '%s' is not a valid serviceinfo file: %s", cm.Name, errs.ToAggregate())
 	}
-	c.SourcePath = fmt.Sprintf(FilePathTemplate, cm.Name, fileName)
-	c.ConfigMap = cm.Name
+	*c = *file
 	return nil
 }
+
+// ParseServiceInfoFile reads the serviceinfo file annotations of a ConfigMap or a Secret
+// holding the given keys, and checks that they describe a file that can be copied to a device
+func ParseServiceInfoFile(obj metav1.Object, keys []string) (*ServiceInfoFile, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	annotationsPath := field.NewPath("metadata", "annotations")
+	annotations := obj.GetAnnotations()
+	c := &ServiceInfoFile{
+		Path:        annotations[PathKey],
+		Permissions: annotations[PermissionsKey],
+		ConfigMap:   obj.GetName(),
+	}
+
+	fileName := annotations[FileKey]
+	if fileName == "" {
+		allErrs = append(allErrs, field.Required(annotationsPath.Key(FileKey), "serviceinfo file name is required"))
+	} else if !containsString(keys, fileName) {
+		allErrs = append(allErrs, field.NotFound(annotationsPath.Key(FileKey), fileName))
+	}
+
+	if c.Path == "" {
+		allErrs = append(allErrs, field.Required(annotationsPath.Key(PathKey), "serviceinfo file destination path is required"))
+	} else if !path.IsAbs(c.Path) {
+		allErrs = append(allErrs, field.Invalid(annotationsPath.Key(PathKey), c.Path, "must be an absolute path"))
+	}
+
+	allErrs = append(allErrs, fdov1beta1.ValidateFilePermissions(annotationsPath.Key(PermissionsKey), c.Permissions)...)
+
+	c.SourcePath = fmt.Sprintf(FilePathTemplate, obj.GetName(), fileName)
+	return c, allErrs
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -14,7 +14,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	"github.com/fdo-rs/fdo-operator/internal/client"
 )
 
@@ -44,7 +44,7 @@ var _ = Describe("FDOOnboardingServerReconciler", func() {
 		When("a client error other than not-found occurs", func() {
 			BeforeEach(func() {
 				s := scheme.Scheme
-				Expect(fdov1alpha1.AddToScheme(scheme.Scheme)).ToNot(HaveOccurred())
+				Expect(fdov1beta1.AddToScheme(scheme.Scheme)).ToNot(HaveOccurred())
 
 				r = &FDOOnboardingServerReconciler{
 					ReconcilerBase: util.NewReconcilerBase(c, s, nil, nil, nil),
@@ -0,0 +1,331 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+const (
+	// ownershipVoucherFinalizer removes a voucher from its onboarding server when it is deleted
+	ownershipVoucherFinalizer = "fdo.redhat.com/ownership-voucher"
+	// ownershipVoucherSecretKey is the default key of a voucher in a secret
+	ownershipVoucherSecretKey = "voucher"
+	// unavailableServerRequeue is the delay before importing a voucher into an unavailable server again
+	unavailableServerRequeue = 30 * time.Second
+	// voucherSecretIndex indexes vouchers by the name of their secret
+	voucherSecretIndex = ".spec.voucherSecret.name"
+	// voucherServerIndex indexes vouchers by the name of their onboarding server
+	voucherServerIndex = ".spec.onboardingServer.name"
+	// deviceGUIDIndex indexes vouchers and rendezvous registrations by the GUID of their device
+	deviceGUIDIndex = ".status.guid"
+)
+
+// FDOOwnershipVoucherReconciler reconciles a FDOOwnershipVoucher object
+type FDOOwnershipVoucherReconciler struct {
+	util.ReconcilerBase
+	Log        logr.Logger
+	HTTPClient *http.Client
+	// ManagementURL returns the URL of the OV management API of an onboarding server, defaults to its service
+	ManagementURL func(server *fdov1beta1.FDOOnboardingServer) string
+}
+
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoownershipvouchers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoownershipvouchers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoownershipvouchers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoonboardingservers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdorendezvousregistrations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile imports an ownership voucher into its onboarding server through the OV management API,
+// which writes it into the voucher store of the server, and removes it from the server when the
+// voucher is deleted. The voucher is imported again when it changes or targets another server.
+func (r *FDOOwnershipVoucherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.Log.WithName("fdoownershipvoucher_controller").WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	log.Info("Reconciling FDO ownership voucher")
+
+	voucher := &fdov1beta1.FDOOwnershipVoucher{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, voucher); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("FDOOwnershipVoucher resource not found. Ignoring since object must have been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get FDOOwnershipVoucher resource")
+		return ctrl.Result{}, err
+	}
+
+	if !voucher.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, voucher)
+	}
+	if controllerutil.AddFinalizer(voucher, ownershipVoucherFinalizer) {
+		if err := r.GetClient().Update(ctx, voucher); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	voucher.Status.ObservedGeneration = voucher.Generation
+	if meta.FindStatusCondition(voucher.Status.Conditions, fdov1beta1.ConditionRendezvousRegistered) == nil {
+		setVoucherCondition(voucher, fdov1beta1.ConditionRendezvousRegistered, metav1.ConditionUnknown, fdov1beta1.ReasonRegistrationPending,
+			"the voucher isn't imported yet")
+	}
+
+	data, problem, err := ownershipVoucherData(ctx, r.GetClient(), voucher)
+	if err != nil {
+		return r.ManageError(ctx, voucher, err)
+	}
+	if problem != "" {
+		if setVoucherCondition(voucher, fdov1beta1.ConditionImported, metav1.ConditionFalse, fdov1beta1.ReasonInvalidVoucher, problem) {
+			r.GetRecorder().Event(voucher, corev1.EventTypeWarning, fdov1beta1.ReasonInvalidVoucher, problem)
+		}
+		return r.ManageSuccess(ctx, voucher)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	server := &fdov1beta1.FDOOnboardingServer{}
+	err = r.GetClient().Get(ctx, types.NamespacedName{Namespace: voucher.Namespace, Name: voucher.Spec.OnboardingServer.Name}, server)
+	if err != nil && !errors.IsNotFound(err) {
+		return r.ManageError(ctx, voucher, err)
+	}
+	if err != nil || !meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionAvailable) {
+		setVoucherCondition(voucher, fdov1beta1.ConditionImported, metav1.ConditionFalse, fdov1beta1.ReasonServerUnavailable,
+			fmt.Sprintf("onboarding server %s is not available", voucher.Spec.OnboardingServer.Name))
+		return r.ManageSuccessWithRequeue(ctx, voucher, unavailableServerRequeue)
+	}
+
+	if voucher.Status.VoucherHash == hash && voucher.Status.OnboardingServer == server.Name &&
+		meta.IsStatusConditionTrue(voucher.Status.Conditions, fdov1beta1.ConditionImported) {
+		if err := setRegistrationCondition(ctx, r.GetClient(), voucher); err != nil {
+			return r.ManageError(ctx, voucher, err)
+		}
+		return r.ManageSuccess(ctx, voucher)
+	}
+	// the voucher changed or moved to another server, remove the previous one first
+	if err := r.removeVoucher(ctx, voucher); err != nil {
+		log.Error(err, "Failed to remove previous voucher", "guid", voucher.Status.GUID, "server", voucher.Status.OnboardingServer)
+		return r.ManageError(ctx, voucher, err)
+	}
+	guid, err := r.managementClient(server).importVoucher(ctx, data)
+	if err != nil {
+		log.Error(err, "Failed to import voucher", "server", server.Name)
+		r.GetRecorder().Event(voucher, corev1.EventTypeWarning, fdov1beta1.ReasonImportFailed, err.Error())
+		setVoucherCondition(voucher, fdov1beta1.ConditionImported, metav1.ConditionFalse, fdov1beta1.ReasonImportFailed, err.Error())
+		return r.ManageError(ctx, voucher, err)
+	}
+	now := metav1.Now()
+	voucher.Status.GUID = guid
+	voucher.Status.OnboardingServer = server.Name
+	voucher.Status.VoucherHash = hash
+	voucher.Status.ImportTime = &now
+	message := fmt.Sprintf("imported the voucher of device %s into onboarding server %s", guid, server.Name)
+	r.GetRecorder().Event(voucher, corev1.EventTypeNormal, fdov1beta1.ReasonVoucherImported, message)
+	setVoucherCondition(voucher, fdov1beta1.ConditionImported, metav1.ConditionTrue, fdov1beta1.ReasonVoucherImported, message)
+	if err := setRegistrationCondition(ctx, r.GetClient(), voucher); err != nil {
+		return r.ManageError(ctx, voucher, err)
+	}
+	return r.ManageSuccess(ctx, voucher)
+}
+
+// setRegistrationCondition sets the RendezvousRegistered condition of an imported voucher from the
+// registration objects of the rendezvous servers of its namespace. Registrations of servers without
+// registrationObjects or in other namespaces aren't known, so the condition stays pending.
+func setRegistrationCondition(ctx context.Context, c client.Client, voucher *fdov1beta1.FDOOwnershipVoucher) error {
+	registrations := &fdov1beta1.FDORendezvousRegistrationList{}
+	if err := c.List(ctx, registrations, client.InNamespace(voucher.Namespace), client.MatchingFields{deviceGUIDIndex: voucher.Status.GUID}); err != nil {
+		return err
+	}
+	servers := []string{}
+	for _, registration := range registrations.Items {
+		servers = append(servers, registration.Status.Server)
+	}
+	if len(servers) == 0 {
+		setVoucherCondition(voucher, fdov1beta1.ConditionRendezvousRegistered, metav1.ConditionUnknown, fdov1beta1.ReasonRegistrationPending,
+			fmt.Sprintf("no rendezvous server with registrationObjects holds a registration of device %s", voucher.Status.GUID))
+		return nil
+	}
+	sort.Strings(servers)
+	setVoucherCondition(voucher, fdov1beta1.ConditionRendezvousRegistered, metav1.ConditionTrue, fdov1beta1.ReasonDeviceRegistered,
+		fmt.Sprintf("device %s is registered with rendezvous server %s", voucher.Status.GUID, strings.Join(servers, ", ")))
+	return nil
+}
+
+// ownershipVoucherData returns the inline voucher or reads it from its secret. Problems with the
+// voucher are returned instead of an error.
+func ownershipVoucherData(ctx context.Context, c client.Client, voucher *fdov1beta1.FDOOwnershipVoucher) ([]byte, string, error) {
+	spec := voucher.Spec
+	if (spec.Voucher == "") == (spec.VoucherSecret == nil) {
+		return nil, "exactly one of voucher and voucherSecret must be set", nil
+	}
+	if spec.Voucher != "" {
+		return []byte(spec.Voucher), "", nil
+	}
+	key := spec.VoucherSecret.Key
+	if key == "" {
+		key = ownershipVoucherSecretKey
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: voucher.Namespace, Name: spec.VoucherSecret.Name}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Sprintf("secret %s not found", spec.VoucherSecret.Name), nil
+		}
+		return nil, "", err
+	}
+	data := secret.Data[key]
+	if len(data) == 0 {
+		return nil, fmt.Sprintf("secret %s has no key %s", spec.VoucherSecret.Name, key), nil
+	}
+	return data, "", nil
+}
+
+// finalize removes a deleted voucher from its onboarding server, unless the server is gone
+func (r *FDOOwnershipVoucherReconciler) finalize(ctx context.Context, voucher *fdov1beta1.FDOOwnershipVoucher) error {
+	if !controllerutil.ContainsFinalizer(voucher, ownershipVoucherFinalizer) {
+		return nil
+	}
+	if err := r.removeVoucher(ctx, voucher); err != nil {
+		r.GetRecorder().Event(voucher, corev1.EventTypeWarning, fdov1beta1.ReasonImportFailed, err.Error())
+		return err
+	}
+	controllerutil.RemoveFinalizer(voucher, ownershipVoucherFinalizer)
+	return r.GetClient().Update(ctx, voucher)
+}
+
+// removeVoucher removes the imported voucher from the onboarding server holding it, if the server still exists
+func (r *FDOOwnershipVoucherReconciler) removeVoucher(ctx context.Context, voucher *fdov1beta1.FDOOwnershipVoucher) error {
+	if voucher.Status.GUID == "" || voucher.Status.OnboardingServer == "" {
+		return nil
+	}
+	server := &fdov1beta1.FDOOnboardingServer{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: voucher.Namespace, Name: voucher.Status.OnboardingServer}, server); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := r.managementClient(server).deleteVouchers(ctx, voucher.Status.GUID); err != nil {
+		return err
+	}
+	voucher.Status.GUID = ""
+	voucher.Status.OnboardingServer = ""
+	voucher.Status.VoucherHash = ""
+	voucher.Status.ImportTime = nil
+	return nil
+}
+
+func (r *FDOOwnershipVoucherReconciler) managementClient(server *fdov1beta1.FDOOnboardingServer) *voucherManagementClient {
+	if r.ManagementURL != nil {
+		return &voucherManagementClient{HTTPClient: r.HTTPClient, URL: r.ManagementURL(server)}
+	}
+	return &voucherManagementClient{HTTPClient: r.HTTPClient, URL: managementURL(server.Name, server.Namespace)}
+}
+
+// setVoucherCondition sets a condition of a voucher and returns whether its status, reason or message changed
+func setVoucherCondition(voucher *fdov1beta1.FDOOwnershipVoucher, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	previous := meta.FindStatusCondition(voucher.Status.Conditions, conditionType)
+	changed := previous == nil || previous.Status != status || previous.Reason != reason || previous.Message != message
+	meta.SetStatusCondition(&voucher.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: voucher.Generation,
+	})
+	return changed
+}
+
+// voucherSecretNames returns the name of the secret of a voucher, the value of voucherSecretIndex
+func voucherSecretNames(obj client.Object) []string {
+	if secret := obj.(*fdov1beta1.FDOOwnershipVoucher).Spec.VoucherSecret; secret != nil {
+		return []string{secret.Name}
+	}
+	return nil
+}
+
+// voucherServerNames returns the name of the onboarding server of a voucher, the value of voucherServerIndex
+func voucherServerNames(obj client.Object) []string {
+	return []string{obj.(*fdov1beta1.FDOOwnershipVoucher).Spec.OnboardingServer.Name}
+}
+
+// voucherGUIDs returns the GUID of the imported device of a voucher, the value of deviceGUIDIndex
+func voucherGUIDs(obj client.Object) []string {
+	if guid := obj.(*fdov1beta1.FDOOwnershipVoucher).Status.GUID; guid != "" {
+		return []string{guid}
+	}
+	return nil
+}
+
+// registrationGUIDs returns the GUID of the device of a registration, the value of deviceGUIDIndex
+func registrationGUIDs(obj client.Object) []string {
+	return []string{obj.(*fdov1beta1.FDORendezvousRegistration).Status.GUID}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FDOOwnershipVoucherReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexes := []struct {
+		obj     client.Object
+		index   string
+		extract client.IndexerFunc
+	}{
+		{&fdov1beta1.FDOOwnershipVoucher{}, voucherSecretIndex, voucherSecretNames},
+		{&fdov1beta1.FDOOwnershipVoucher{}, voucherServerIndex, voucherServerNames},
+		{&fdov1beta1.FDOOwnershipVoucher{}, deviceGUIDIndex, voucherGUIDs},
+		{&fdov1beta1.FDORendezvousRegistration{}, deviceGUIDIndex, registrationGUIDs},
+	}
+	for _, i := range indexes {
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), i.obj, i.index, i.extract); err != nil {
+			return err
+		}
+	}
+	// vouchers are imported once their server is available, their secret may change, and their
+	// device is registered by the onboarding server
+	vouchersFor := func(index string, value func(client.Object) string) handler.EventHandler {
+		return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return requestsForIndex(ctx, r.GetClient(), &fdov1beta1.FDOOwnershipVoucherList{}, obj.GetNamespace(), index, value(obj))
+		})
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&fdov1beta1.FDOOwnershipVoucher{}).
+		Watches(&fdov1beta1.FDOOnboardingServer{}, vouchersFor(voucherServerIndex, client.Object.GetName)).
+		Watches(&corev1.Secret{}, vouchersFor(voucherSecretIndex, client.Object.GetName)).
+		Watches(&fdov1beta1.FDORendezvousRegistration{}, vouchersFor(deviceGUIDIndex, func(obj client.Object) string {
+			return obj.(*fdov1beta1.FDORendezvousRegistration).Status.GUID
+		})).
+		Complete(r)
+}
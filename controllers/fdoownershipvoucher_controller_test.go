@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ = Describe("FDOOwnershipVoucherReconciler", func() {
+	var (
+		c        k8sclient.Client
+		ctx      context.Context
+		r        *FDOOwnershipVoucherReconciler
+		voucher  *fdov1beta1.FDOOwnershipVoucher
+		server   *httptest.Server
+		requests []*http.Request
+		bodies   []string
+	)
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "voucher", Namespace: "fdo"},
+				Data:       map[string][]byte{"voucher": {0x86, 0x01}},
+			},
+			&fdov1beta1.FDOOnboardingServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "onboarding-server", Namespace: "fdo"},
+			},
+			&fdov1beta1.FDOOwnershipVoucher{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "fdo"},
+				Spec: fdov1beta1.FDOOwnershipVoucherSpec{
+					VoucherSecret:    &fdov1beta1.SecretKeyReference{Name: "other"},
+					OnboardingServer: fdov1beta1.OnboardingServerReference{Name: "other"},
+				},
+				Status: fdov1beta1.FDOOwnershipVoucherStatus{GUID: "d4e5f6"},
+			},
+		).
+			WithIndex(&fdov1beta1.FDOOwnershipVoucher{}, voucherSecretIndex, voucherSecretNames).
+			WithIndex(&fdov1beta1.FDOOwnershipVoucher{}, voucherServerIndex, voucherServerNames).
+			WithIndex(&fdov1beta1.FDOOwnershipVoucher{}, deviceGUIDIndex, voucherGUIDs).
+			WithIndex(&fdov1beta1.FDORendezvousRegistration{}, deviceGUIDIndex, registrationGUIDs).
+			Build()
+		ctx = context.TODO()
+		requests = nil
+		bodies = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			requests = append(requests, req)
+			bodies = append(bodies, string(body))
+			if req.URL.Path == ownershipVoucherPath {
+				_ = json.NewEncoder(w).Encode([]map[string]string{{"guid": "a1b2c3"}})
+			}
+		}))
+		DeferCleanup(server.Close)
+		r = &FDOOwnershipVoucherReconciler{
+			ReconcilerBase: util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil),
+			HTTPClient:     server.Client(),
+			ManagementURL:  func(*fdov1beta1.FDOOnboardingServer) string { return server.URL },
+		}
+		voucher = &fdov1beta1.FDOOwnershipVoucher{
+			ObjectMeta: metav1.ObjectMeta{Name: "device", Namespace: "fdo"},
+			Spec: fdov1beta1.FDOOwnershipVoucherSpec{
+				VoucherSecret:    &fdov1beta1.SecretKeyReference{Name: "voucher"},
+				OnboardingServer: fdov1beta1.OnboardingServerReference{Name: "onboarding-server"},
+			},
+		}
+	})
+
+	It("should read the voucher from the default key of its secret", func() {
+		data, problem, err := ownershipVoucherData(ctx, r.GetClient(), voucher)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(BeEmpty())
+		Expect(data).To(Equal([]byte{0x86, 0x01}))
+	})
+
+	It("should report an invalid voucher", func() {
+		voucher.Spec.Voucher = "-----BEGIN OWNERSHIP VOUCHER-----"
+		_, problem, err := ownershipVoucherData(ctx, r.GetClient(), voucher)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(ContainSubstring("exactly one"))
+
+		voucher.Spec.Voucher = ""
+		voucher.Spec.VoucherSecret.Key = "ov.pem"
+		_, problem, err = ownershipVoucherData(ctx, r.GetClient(), voucher)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(Equal("secret voucher has no key ov.pem"))
+	})
+
+	It("should warn once about an invalid voucher", func() {
+		recorder := record.NewFakeRecorder(10)
+		r.ReconcilerBase = util.NewReconcilerBase(c, scheme.Scheme, nil, recorder, nil)
+		voucher.Spec.VoucherSecret.Key = "ov.pem"
+		Expect(c.Create(ctx, voucher)).To(Succeed())
+		request := ctrl.Request{NamespacedName: k8sclient.ObjectKeyFromObject(voucher)}
+		_, err := r.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring(fdov1beta1.ReasonInvalidVoucher)))
+
+		_, err = r.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+		Expect(c.Get(ctx, request.NamespacedName, voucher)).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(voucher.Status.Conditions, fdov1beta1.ConditionImported)).To(BeTrue())
+	})
+
+	It("should import a voucher in its format and return the GUID of the device", func() {
+		guid, err := r.managementClient(nil).importVoucher(ctx, []byte("-----BEGIN OWNERSHIP VOUCHER-----\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(guid).To(Equal("a1b2c3"))
+		Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-pem-file"))
+		Expect(requests[0].Header.Get("X-Number-Of-Vouchers")).To(Equal("1"))
+
+		_, err = r.managementClient(nil).importVoucher(ctx, []byte{0x86, 0x01})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests[1].Header.Get("Content-Type")).To(Equal("application/cbor"))
+	})
+
+	It("should remove the imported voucher from its server", func() {
+		voucher.Status.GUID = "a1b2c3"
+		voucher.Status.OnboardingServer = "onboarding-server"
+		voucher.Status.VoucherHash = "hash"
+		Expect(r.removeVoucher(ctx, voucher)).To(Succeed())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Path).To(Equal(ownershipVoucherPath + "/delete"))
+		Expect(bodies[0]).To(Equal(`["a1b2c3"]`))
+		Expect(voucher.Status.GUID).To(BeEmpty())
+		Expect(voucher.Status.VoucherHash).To(BeEmpty())
+	})
+
+	It("should forget the voucher of a deleted server", func() {
+		voucher.Status.GUID = "a1b2c3"
+		voucher.Status.OnboardingServer = "deleted"
+		Expect(r.removeVoucher(ctx, voucher)).To(Succeed())
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("should only reconcile the vouchers of a secret or a server", func() {
+		Expect(c.Create(ctx, voucher)).To(Succeed())
+		requests := requestsForIndex(ctx, c, &fdov1beta1.FDOOwnershipVoucherList{}, "fdo", voucherSecretIndex, "voucher")
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("device"))
+		requests = requestsForIndex(ctx, c, &fdov1beta1.FDOOwnershipVoucherList{}, "fdo", voucherServerIndex, "other")
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("other"))
+		Expect(requestsForIndex(ctx, c, &fdov1beta1.FDOOwnershipVoucherList{}, "fdo", deviceGUIDIndex, "d4e5f6")).To(HaveLen(1))
+	})
+
+	It("should report the rendezvous registrations of the device", func() {
+		voucher.Status.GUID = "a1b2c3"
+		Expect(setRegistrationCondition(ctx, c, voucher)).To(Succeed())
+		condition := meta.FindStatusCondition(voucher.Status.Conditions, fdov1beta1.ConditionRendezvousRegistered)
+		Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+		Expect(condition.Reason).To(Equal(fdov1beta1.ReasonRegistrationPending))
+
+		for _, server := range []string{"rendezvous-b", "rendezvous-a"} {
+			Expect(c.Create(ctx, &fdov1beta1.FDORendezvousRegistration{
+				ObjectMeta: metav1.ObjectMeta{Name: deviceName(server, "a1b2c3"), Namespace: "fdo"},
+				Status:     fdov1beta1.FDORendezvousRegistrationStatus{GUID: "a1b2c3", Server: server},
+			})).To(Succeed())
+		}
+		Expect(setRegistrationCondition(ctx, c, voucher)).To(Succeed())
+		condition = meta.FindStatusCondition(voucher.Status.Conditions, fdov1beta1.ConditionRendezvousRegistered)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(fdov1beta1.ReasonDeviceRegistered))
+		Expect(condition.Message).To(Equal("device a1b2c3 is registered with rendezvous server rendezvous-a, rendezvous-b"))
+	})
+})
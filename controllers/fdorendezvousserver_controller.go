@@ -19,6 +19,8 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/go-logr/logr"
 	routev1 "github.com/openshift/api/route/v1"
@@ -27,24 +29,36 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 
-	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	util "github.com/redhat-cop/operator-utils/pkg/util"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	rendezvousConfigMapTemplate = "%s-config"
-	rendezvousImage             = "quay.io/fido-fdo/rendezvous-server:0.4"
 )
 
 // FDORendezvousServerReconciler reconciles a FDORendezvousServer object
 type FDORendezvousServerReconciler struct {
 	util.ReconcilerBase
 	Log logr.Logger
+	// OperatorImage runs the sidecar serving the registration inventory
+	OperatorImage string
+	// DatabaseImage runs the Postgres database managed by the operator, DefaultDatabaseImage if empty
+	DatabaseImage string
+	// SqliteImage migrates the Sqlite database of the server, DefaultSqliteImage if empty
+	SqliteImage string
+	// HTTPClient reads the registration inventory
+	HTTPClient *http.Client
 }
 
 //+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdorendezvousservers,verbs=get;list;watch;create;update;patch;delete
@@ -53,6 +67,8 @@ type FDORendezvousServerReconciler struct {
 //+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods;secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdorendezvousregistrations,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -71,41 +87,112 @@ func (r *FDORendezvousServerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 	server, ok, err := r.getRendezvousServer(log, ctx, req)
 	if !ok {
+		if err == nil {
+			deleteRegistrationMetrics(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, err
 	}
 
-	if _, err = r.createOrUpdateConfigMap(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	// Defaults are normally persisted by the mutating webhook, this covers
+	// servers created while webhooks were disabled
+	server.Default()
+
+	var configMap *corev1.ConfigMap
+	if configMap, err = r.createOrUpdateConfigMap(log, server); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+
+	keyFiles, err := resolveTrustBundles(ctx, r.GetClient(), server.Namespace, rendezvousKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	missing, err := verifyKeyFiles(ctx, r.GetClient(), server, keyFiles)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if len(missing) > 0 {
+		return manageMissingKeys(ctx, &r.ReconcilerBase, server, missing)
+	}
+	next, err := validateKeyFiles(ctx, &r.ReconcilerBase, server, keyFiles, nil, time.Now())
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = checkTrust(ctx, r.GetClient(), server, "FDORendezvousServer"); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	if err = reconcileManagedDatabase(ctx, &r.ReconcilerBase, server, server.Spec.Storage.GetDatabase(), r.DatabaseImage); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateDeployment(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	var deploy *appsv1.Deployment
+	if deploy, err = r.createOrUpdateDeployment(log, server, keyFiles, configMap); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
 	if _, err = r.createOrUpdateService(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	if _, err = r.createOrUpdateRoute(log, server); err != nil {
-		return r.ManageError(ctx, server, err)
+	var route *routev1.Route
+	if route, err = r.createOrUpdateRoute(log, server); err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
 	}
 
-	return r.ManageSuccess(ctx, server)
+	images, err := updateServerStatus(ctx, r.GetClient(), server, RendezvousServiceType, deploy, route)
+	if err != nil {
+		return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+	}
+	server.Status.Image = images["rendezvous"]
+
+	if registrationSidecar(r.OperatorImage, server.Spec.Storage.GetDatabase()) != nil {
+		if server.Status.Inventory, err = reconcileRegistrationInventory(ctx, &r.ReconcilerBase, r.HTTPClient, server, inventoryURL(server), time.Now()); err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+		if inventoryScan := time.Now().Add(inventoryInterval); next.IsZero() || inventoryScan.Before(next) {
+			next = inventoryScan
+		}
+	} else {
+		server.Status.Inventory = nil
+		deleteRegistrationMetrics(server.Namespace, server.Name)
+		if err = reconcileRegistrationObjects(ctx, &r.ReconcilerBase, server, nil); err != nil {
+			return manageError(ctx, &r.ReconcilerBase, server, err, 0)
+		}
+	}
+
+	return manageSuccess(ctx, &r.ReconcilerBase, server, next)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *FDORendezvousServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDORendezvousServer{}, keySecretsIndex, func(obj client.Object) []string {
+		return keySecretNames(rendezvousKeyFiles(obj.(*fdov1beta1.FDORendezvousServer).Spec.Keys))
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&fdov1alpha1.FDORendezvousServer{}).
+		For(&fdov1beta1.FDORendezvousServer{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
 		Owns(&routev1.Route{}).
+		Owns(&appsv1.StatefulSet{}, builder.MatchEveryOwner).
+		Owns(&batchv1.Job{}, builder.MatchEveryOwner).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, secret client.Object) []reconcile.Request {
+			return append(requestsForSecret(ctx, r.GetClient(), &fdov1beta1.FDORendezvousServerList{}, secret),
+				requestsForPeerSecret(ctx, r.GetClient(), &fdov1beta1.FDORendezvousServerList{}, secret, &fdov1beta1.FDOManufacturingServerList{}, &fdov1beta1.FDOOnboardingServerList{})...)
+		})).
+		Watches(&fdov1beta1.FDOManufacturingServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDORendezvousServerList{}, peer)
+		}), peerChanged).
+		Watches(&fdov1beta1.FDOOnboardingServer{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, peer client.Object) []reconcile.Request {
+			return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDORendezvousServerList{}, peer)
+		}), peerChanged).
 		Complete(r)
 }
 
-func (r *FDORendezvousServerReconciler) getRendezvousServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1alpha1.FDORendezvousServer, bool, error) {
-	server := &fdov1alpha1.FDORendezvousServer{}
+func (r *FDORendezvousServerReconciler) getRendezvousServer(log logr.Logger, ctx context.Context, req ctrl.Request) (*fdov1beta1.FDORendezvousServer, bool, error) {
+	server := &fdov1beta1.FDORendezvousServer{}
 	err := r.ReconcilerBase.GetClient().Get(ctx, req.NamespacedName, server)
 	if err == nil {
 		return server, true, nil
@@ -118,7 +205,15 @@ func (r *FDORendezvousServerReconciler) getRendezvousServer(log logr.Logger, ctx
 	return nil, false, err
 }
 
-func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1alpha1.FDORendezvousServer) (*appsv1.Deployment, error) {
+func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger, server *fdov1beta1.FDORendezvousServer, keyFiles []keyFile, configMap *corev1.ConfigMap) (*appsv1.Deployment, error) {
+	annotations, err := podTemplateAnnotations(context.TODO(), r.GetClient(), server.Namespace, keyFiles, configMap)
+	if err != nil {
+		return nil, err
+	}
+	initContainers, err := rendezvousStore.migrationContainers(server.Spec.Storage.GetDatabase(), r.SqliteImage, "registered")
+	if err != nil {
+		return nil, err
+	}
 	labels := getLabels(RendezvousServiceType)
 	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), deploy, func() error {
@@ -127,36 +222,38 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 				MatchLabels: getLabels(RendezvousServiceType),
 			}
 		}
-		optional := false
 		privilegeEscalation := false
 		nonRoot := true
-		replicas := int32(1)
-		deploy.Spec.Replicas = &replicas
+		deploy.Spec.Replicas = serverReplicas(server.Spec.Replicas)
+		registered := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+		if server.Spec.Storage != nil && server.Spec.Storage.RegistrationsClaimName != "" {
+			registered = storeVolume(server.Spec.Storage.Database, corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: server.Spec.Storage.RegistrationsClaimName,
+				},
+			})
+		}
 		deploy.Spec.Template = corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
+				Annotations: annotations,
+				Labels:      getPodLabels(RendezvousServiceType, server.Name),
 			},
 			Spec: corev1.PodSpec{
 				Containers: []corev1.Container{
 					{
-						Image: server.Spec.Image,
+						Image: server.Spec.ImageOrDefault(),
 						Name:  "rendezvous",
+						Env:   rendezvousStore.env(server.Spec.Storage.GetDatabase()),
 						Ports: []corev1.ContainerPort{
 							{
 								ContainerPort: 8082,
 							}},
-						VolumeMounts: []corev1.VolumeMount{
+						VolumeMounts: append([]corev1.VolumeMount{
 							{
 								Name:      "config",
 								MountPath: "/etc/fdo/rendezvous-server.conf.d",
 								ReadOnly:  true,
 							},
-							{
-								Name:      "manufacturer-cert",
-								MountPath: "/etc/fdo/keys/manufacturer_cert.pem",
-								SubPath:   "manufacturer_cert.pem",
-								ReadOnly:  true,
-							},
 							{
 								Name:      "registered",
 								MountPath: "/etc/fdo/registered",
@@ -167,7 +264,7 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 								MountPath: "/etc/fdo/sessions",
 								ReadOnly:  false,
 							},
-						},
+						}, keyFileVolumeMounts(keyFiles)...),
 						SecurityContext: &corev1.SecurityContext{
 							AllowPrivilegeEscalation: &privilegeEscalation,
 							Capabilities: &corev1.Capabilities{
@@ -178,7 +275,7 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 						},
 					},
 				},
-				Volumes: []corev1.Volume{
+				Volumes: append([]corev1.Volume{
 					{
 						Name: "config",
 						VolumeSource: corev1.VolumeSource{
@@ -190,33 +287,14 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 						},
 					},
 					{
-						Name: "manufacturer-cert",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: "fdo-manufacturer-cert",
-								Items: []corev1.KeyToPath{
-									{
-										Key:  "manufacturer_cert.pem",
-										Path: "manufacturer_cert.pem",
-									},
-								},
-								Optional: &optional,
-							},
-						},
+						Name:         "registered",
+						VolumeSource: registered,
 					},
 					{
-						Name: "registered",
-						VolumeSource: corev1.VolumeSource{
-							EmptyDir: &corev1.EmptyDirVolumeSource{},
-						},
+						Name:         "sessions",
+						VolumeSource: sessionsVolume(server.Spec.Storage.GetSessionsClaimName()),
 					},
-					{
-						Name: "sessions",
-						VolumeSource: corev1.VolumeSource{
-							EmptyDir: &corev1.EmptyDirVolumeSource{},
-						},
-					},
-				},
+				}, keyFileVolumes(keyFiles)...),
 				SecurityContext: &corev1.PodSecurityContext{
 					RunAsNonRoot: &nonRoot,
 					SeccompProfile: &corev1.SeccompProfile{
@@ -225,6 +303,9 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 				},
 			},
 		}
+		deploy.Spec.Template.Spec.InitContainers = initContainers
+		deploy.Spec.Template.Spec.Containers = withInventorySidecar(deploy.Spec.Template.Spec.Containers, registrationSidecar(r.OperatorImage, server.Spec.Storage.GetDatabase()))
+		applyPodTemplate(&deploy.Spec.Template.Spec, server.Spec.PodTemplate)
 		return ctrl.SetControllerReference(server, deploy, r.GetScheme())
 	})
 
@@ -237,19 +318,20 @@ func (r *FDORendezvousServerReconciler) createOrUpdateDeployment(log logr.Logger
 	}
 }
 
-func (r *FDORendezvousServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1alpha1.FDORendezvousServer) (*corev1.Service, error) {
+func (r *FDORendezvousServerReconciler) createOrUpdateService(log logr.Logger, server *fdov1beta1.FDORendezvousServer) (*corev1.Service, error) {
 	labels := getLabels(RendezvousServiceType)
 	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), service, func() error {
 		service.Spec = corev1.ServiceSpec{
 			Selector: labels,
-			Ports: []corev1.ServicePort{
+			Ports: withInventoryPort([]corev1.ServicePort{
 				{
+					Name:       "http",
 					Protocol:   "TCP",
 					Port:       int32(8082),
 					TargetPort: intstr.FromInt(8082),
 				},
-			},
+			}, registrationSidecar(r.OperatorImage, server.Spec.Storage.GetDatabase())),
 		}
 		return ctrl.SetControllerReference(server, service, r.GetScheme())
 	})
@@ -262,11 +344,12 @@ func (r *FDORendezvousServerReconciler) createOrUpdateService(log logr.Logger, s
 	}
 }
 
-func (r *FDORendezvousServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1alpha1.FDORendezvousServer) (*routev1.Route, error) {
+func (r *FDORendezvousServerReconciler) createOrUpdateRoute(log logr.Logger, server *fdov1beta1.FDORendezvousServer) (*routev1.Route, error) {
 	labels := getLabels(RendezvousServiceType)
 	route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: server.Name, Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), route, func() error {
 		route.Spec = routev1.RouteSpec{
+			Host: routeHost(server.Spec.Expose),
 			To: routev1.RouteTargetReference{
 				Kind: "Service",
 				Name: server.Name,
@@ -287,7 +370,7 @@ func (r *FDORendezvousServerReconciler) createOrUpdateRoute(log logr.Logger, ser
 	}
 }
 
-func (r *FDORendezvousServerReconciler) createOrUpdateConfigMap(log logr.Logger, server *fdov1alpha1.FDORendezvousServer) (*corev1.ConfigMap, error) {
+func (r *FDORendezvousServerReconciler) createOrUpdateConfigMap(log logr.Logger, server *fdov1beta1.FDORendezvousServer) (*corev1.ConfigMap, error) {
 	labels := getLabels(RendezvousServiceType)
 	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(rendezvousConfigMapTemplate, server.Name), Namespace: server.Namespace, Labels: labels}}
 	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.GetClient(), configMap, func() error {
@@ -307,7 +390,7 @@ func (r *FDORendezvousServerReconciler) createOrUpdateConfigMap(log logr.Logger,
 	}
 }
 
-func (r *FDORendezvousServerReconciler) generateConfig(fdoServer *fdov1alpha1.FDORendezvousServer) (string, error) {
+func (r *FDORendezvousServerReconciler) generateConfig(fdoServer *fdov1beta1.FDORendezvousServer) (string, error) {
 	config := &RendezvousServerConfig{}
 	if err := config.setValues(fdoServer); err != nil {
 		return "", err
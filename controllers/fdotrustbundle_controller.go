@@ -0,0 +1,361 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// trustBundleSourcesIndex is the field index of trust bundles by their sources
+const trustBundleSourcesIndex = ".spec.sources"
+
+// anySource stands for the objects selected by label in the trustBundleSourcesIndex
+const anySource = "*"
+
+// trustBundleSecretKey is the key of the combined certificates in the secret of a trust bundle
+const trustBundleSecretKey = "ca-bundle.pem"
+
+// trustBundleSecret returns the name of the secret holding the combined certificates of a trust bundle
+func trustBundleSecret(name string) string {
+	return name + "-trust-bundle"
+}
+
+// FDOTrustBundleReconciler reconciles a FDOTrustBundle object
+type FDOTrustBundleReconciler struct {
+	util.ReconcilerBase
+	Log logr.Logger
+}
+
+// trustBundleObject is a secret or a config map read by a trust bundle
+type trustBundleObject struct {
+	Kind string
+	Name string
+	Data map[string][]byte
+}
+
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdotrustbundles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdotrustbundles/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdotrustbundles/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile combines the certificates of the enabled sources of a trust bundle into its secret. The
+// secret is left unchanged while a source is invalid, so that servers keep trusting the certificates
+// of the other sources.
+func (r *FDOTrustBundleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.Log.WithName("fdotrustbundle_controller").WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	log.Info("Reconciling FDO trust bundle")
+
+	bundle := &fdov1beta1.FDOTrustBundle{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, bundle); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("FDOTrustBundle resource not found. Ignoring since object must have been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get FDOTrustBundle resource")
+		return ctrl.Result{}, err
+	}
+	bundle.Status.ObservedGeneration = bundle.Generation
+
+	certs, data, problems, err := r.collectCertificates(ctx, bundle)
+	if err != nil {
+		return r.ManageError(ctx, bundle, err)
+	}
+	if len(problems) > 0 {
+		message := strings.Join(problems, "; ")
+		r.GetRecorder().Event(bundle, corev1.EventTypeWarning, fdov1beta1.ReasonInvalidSource, message)
+		setTrustBundleCondition(bundle, metav1.ConditionFalse, fdov1beta1.ReasonInvalidSource, message)
+		return r.ManageSuccess(ctx, bundle)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: trustBundleSecret(bundle.Name), Namespace: bundle.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), secret, func() error {
+		secret.Data = map[string][]byte{trustBundleSecretKey: data}
+		return ctrl.SetControllerReference(bundle, secret, r.GetScheme())
+	})
+	if err != nil {
+		log.Error(err, "Secret reconcile failed", "secret", secret.Name)
+		return r.ManageError(ctx, bundle, err)
+	}
+	log.Info("Secret successfully reconciled", "secret", secret.Name, "operation", op)
+	if op != controllerutil.OperationResultNone {
+		r.GetRecorder().Event(bundle, corev1.EventTypeNormal, fdov1beta1.ReasonBundleGenerated,
+			fmt.Sprintf("wrote %d certificates to secret %s", len(certs), secret.Name))
+	}
+
+	bundle.Status.Secret = secret.Name
+	bundle.Status.Certificates = certs
+	if len(certs) == 0 {
+		setTrustBundleCondition(bundle, metav1.ConditionFalse, fdov1beta1.ReasonNoCertificates, "no enabled source holds certificates")
+	} else {
+		setTrustBundleCondition(bundle, metav1.ConditionTrue, fdov1beta1.ReasonBundleGenerated, fmt.Sprintf("the bundle holds %d certificates", len(certs)))
+	}
+	return r.ManageSuccess(ctx, bundle)
+}
+
+// collectCertificates reads the certificates of the enabled sources of a trust bundle in order, and
+// returns them without duplicates along with their PEM encoding. Problems with the sources are
+// returned instead of an error.
+func (r *FDOTrustBundleReconciler) collectCertificates(ctx context.Context, bundle *fdov1beta1.FDOTrustBundle) ([]fdov1beta1.TrustedCertificate, []byte, []string, error) {
+	certs := []fdov1beta1.TrustedCertificate{}
+	data := []byte{}
+	problems := []string{}
+	for i, source := range bundle.Spec.Sources {
+		if source.Disabled {
+			continue
+		}
+		objects, problem, err := r.sourceObjects(ctx, bundle.Namespace, source)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("sources[%d]: %s", i, problem))
+			continue
+		}
+		key := sourceKey(source)
+		for _, obj := range objects {
+			dataKeys := []string{key}
+			if key == "" {
+				dataKeys = sortedKeys(obj.Data)
+			}
+			found := 0
+			for _, k := range dataKeys {
+				value, ok := obj.Data[k]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("sources[%d]: %s %s has no key %s", i, obj.Kind, obj.Name, k))
+					continue
+				}
+				parsed, err := keys.ParseCerts(value)
+				if err != nil && key != "" {
+					problems = append(problems, fmt.Sprintf("sources[%d]: %s %s, key %s: %v", i, obj.Kind, obj.Name, k, err))
+				}
+				for _, cert := range parsed {
+					found++
+					fingerprint := keys.Fingerprint(cert)
+					if containsFingerprint(certs, fingerprint) {
+						continue
+					}
+					certs = append(certs, fdov1beta1.TrustedCertificate{
+						Subject:     cert.Subject.String(),
+						Fingerprint: fingerprint,
+						NotAfter:    metav1.NewTime(cert.NotAfter),
+						Source:      fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Name, k),
+					})
+					data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+				}
+			}
+			if found == 0 && key == "" {
+				problems = append(problems, fmt.Sprintf("sources[%d]: %s %s holds no certificate", i, obj.Kind, obj.Name))
+			}
+		}
+	}
+	return certs, data, problems, nil
+}
+
+// sourceObjects returns the secrets or config maps selected by a source, sorted by name
+func (r *FDOTrustBundleReconciler) sourceObjects(ctx context.Context, namespace string, source fdov1beta1.TrustBundleSource) ([]trustBundleObject, string, error) {
+	var selected *fdov1beta1.TrustBundleObjects
+	kind := ""
+	switch {
+	case source.Secret != nil && source.ConfigMap != nil:
+		return nil, "only one of secret and configMap can be set", nil
+	case source.Secret != nil:
+		selected, kind = source.Secret, "Secret"
+	case source.ConfigMap != nil:
+		selected, kind = source.ConfigMap, "ConfigMap"
+	default:
+		return nil, "one of secret and configMap must be set", nil
+	}
+	if (selected.Name == "") == (selected.Selector == nil) {
+		return nil, "exactly one of name and selector must be set", nil
+	}
+
+	if selected.Name != "" {
+		var obj client.Object = &corev1.Secret{}
+		if kind == "ConfigMap" {
+			obj = &corev1.ConfigMap{}
+		}
+		if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: selected.Name}, obj); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Sprintf("%s %s not found", kind, selected.Name), nil
+			}
+			return nil, "", err
+		}
+		return []trustBundleObject{newTrustBundleObject(obj)}, "", nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(selected.Selector)
+	if err != nil {
+		return nil, fmt.Sprintf("invalid selector: %v", err), nil
+	}
+	var list client.ObjectList = &corev1.SecretList{}
+	if kind == "ConfigMap" {
+		list = &corev1.ConfigMapList{}
+	}
+	if err := r.GetClient().List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, "", err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, "", err
+	}
+	objects := []trustBundleObject{}
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok {
+			objects = append(objects, newTrustBundleObject(obj))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, "", nil
+}
+
+func newTrustBundleObject(obj client.Object) trustBundleObject {
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		return trustBundleObject{Kind: "Secret", Name: o.Name, Data: o.Data}
+	case *corev1.ConfigMap:
+		data := map[string][]byte{}
+		for k, v := range o.BinaryData {
+			data[k] = v
+		}
+		for k, v := range o.Data {
+			data[k] = []byte(v)
+		}
+		return trustBundleObject{Kind: "ConfigMap", Name: o.Name, Data: data}
+	}
+	return trustBundleObject{Name: obj.GetName()}
+}
+
+func sourceKey(source fdov1beta1.TrustBundleSource) string {
+	if source.Secret != nil {
+		return source.Secret.Key
+	}
+	return source.ConfigMap.Key
+}
+
+func containsFingerprint(certs []fdov1beta1.TrustedCertificate, fingerprint string) bool {
+	for _, cert := range certs {
+		if cert.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+func setTrustBundleCondition(bundle *fdov1beta1.FDOTrustBundle, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&bundle.Status.Conditions, metav1.Condition{
+		Type:               fdov1beta1.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: bundle.Generation,
+	})
+}
+
+// trustBundleSources returns the sources of a trust bundle for the trustBundleSourcesIndex: the kind
+// and name of the objects selected by name, and the kind followed by a wildcard for label selectors
+func trustBundleSources(bundle *fdov1beta1.FDOTrustBundle) []string {
+	sources := []string{}
+	for _, source := range bundle.Spec.Sources {
+		for kind, selected := range map[string]*fdov1beta1.TrustBundleObjects{"Secret": source.Secret, "ConfigMap": source.ConfigMap} {
+			if source.Disabled || selected == nil {
+				continue
+			}
+			value := kind + "/" + anySource
+			if selected.Name != "" {
+				value = kind + "/" + selected.Name
+			}
+			if !containsString(sources, value) {
+				sources = append(sources, value)
+			}
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// requestsForSource maps a secret or a config map to reconcile requests of the trust bundles of its
+// namespace that select it by name or by label
+func requestsForSource(ctx context.Context, c client.Reader, kind string, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	requests := requestsForIndex(ctx, c, &fdov1beta1.FDOTrustBundleList{}, obj.GetNamespace(), trustBundleSourcesIndex, kind+"/"+obj.GetName())
+	bundles := &fdov1beta1.FDOTrustBundleList{}
+	if err := c.List(ctx, bundles, client.InNamespace(obj.GetNamespace()), client.MatchingFields{trustBundleSourcesIndex: kind + "/" + anySource}); err != nil {
+		log.Error(err, "Failed to list trust bundles")
+		return requests
+	}
+	for _, bundle := range bundles.Items {
+		for _, source := range bundle.Spec.Sources {
+			selected := source.Secret
+			if kind == "ConfigMap" {
+				selected = source.ConfigMap
+			}
+			if source.Disabled || selected == nil || selected.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(selected.Selector)
+			if err == nil && selector.Matches(labels.Set(obj.GetLabels())) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: bundle.Namespace, Name: bundle.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FDOTrustBundleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOTrustBundle{}, trustBundleSourcesIndex, func(obj client.Object) []string {
+		return trustBundleSources(obj.(*fdov1beta1.FDOTrustBundle))
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&fdov1beta1.FDOTrustBundle{}).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, secret client.Object) []reconcile.Request {
+			return requestsForSource(ctx, r.GetClient(), "Secret", secret)
+		})).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, configMap client.Object) []reconcile.Request {
+			return requestsForSource(ctx, r.GetClient(), "ConfigMap", configMap)
+		})).
+		Complete(r)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("FDOTrustBundleReconciler", func() {
+	var (
+		ctx    context.Context
+		r      *FDOTrustBundleReconciler
+		bundle *fdov1beta1.FDOTrustBundle
+		first  []byte
+		second []byte
+	)
+
+	newCert := func(commonName string) []byte {
+		key, err := keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := keys.SelfSignedCert(key, keys.Subject{CommonName: commonName}, true, time.Now(), time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		return cert
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		first = newCert("First OEM")
+		second = newCert("Second OEM")
+		oem := map[string]string{"oem": "true"}
+		c := newFakeClientBuilder(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "first-oem", Namespace: "fdo"}, Data: map[string][]byte{"device_ca_cert.pem": first}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "second-oem", Namespace: "fdo", Labels: oem}, Data: map[string]string{"ca.crt": string(second), "README": "not a certificate"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "duplicate", Namespace: "fdo", Labels: oem}, Data: map[string]string{"ca.crt": string(first)}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "fdo"}, Data: map[string]string{"ca.crt": string(second)}},
+		).Build()
+		r = &FDOTrustBundleReconciler{ReconcilerBase: util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)}
+		bundle = &fdov1beta1.FDOTrustBundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-cas", Namespace: "fdo"},
+			Spec: fdov1beta1.FDOTrustBundleSpec{Sources: []fdov1beta1.TrustBundleSource{
+				{Secret: &fdov1beta1.TrustBundleObjects{Name: "first-oem", Key: "device_ca_cert.pem"}},
+				{ConfigMap: &fdov1beta1.TrustBundleObjects{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"oem": "true"}}}},
+			}},
+		}
+	})
+
+	It("should combine the certificates of the sources without duplicates", func() {
+		certs, data, problems, err := r.collectCertificates(ctx, bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+		Expect(certs).To(HaveLen(2))
+		Expect(certs[0].Source).To(Equal("Secret/first-oem/device_ca_cert.pem"))
+		Expect(certs[0].Subject).To(HavePrefix("CN=First OEM"))
+		Expect(certs[1].Source).To(Equal("ConfigMap/second-oem/ca.crt"))
+
+		parsed, err := keys.ParseCerts(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(HaveLen(2))
+		Expect(keys.Fingerprint(parsed[1])).To(Equal(certs[1].Fingerprint))
+	})
+
+	It("should leave out disabled sources", func() {
+		bundle.Spec.Sources[0].Disabled = true
+		certs, _, problems, err := r.collectCertificates(ctx, bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+		Expect(certs).To(HaveLen(2))
+		Expect(certs[0].Source).To(Equal("ConfigMap/duplicate/ca.crt"))
+	})
+
+	It("should report invalid sources", func() {
+		bundle.Spec.Sources = append(bundle.Spec.Sources,
+			fdov1beta1.TrustBundleSource{Secret: &fdov1beta1.TrustBundleObjects{Name: "missing"}},
+			fdov1beta1.TrustBundleSource{Secret: &fdov1beta1.TrustBundleObjects{Name: "first-oem", Key: "other.pem"}},
+			fdov1beta1.TrustBundleSource{},
+		)
+		_, _, problems, err := r.collectCertificates(ctx, bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problems).To(Equal([]string{
+			"sources[2]: Secret missing not found",
+			"sources[3]: Secret first-oem has no key other.pem",
+			"sources[4]: one of secret and configMap must be set",
+		}))
+	})
+	It("should only map the sources of a bundle to it", func() {
+		c := newFakeClientBuilder(bundle).WithIndex(&fdov1beta1.FDOTrustBundle{}, trustBundleSourcesIndex, func(obj k8sclient.Object) []string {
+			return trustBundleSources(obj.(*fdov1beta1.FDOTrustBundle))
+		}).Build()
+		Expect(trustBundleSources(bundle)).To(Equal([]string{"ConfigMap/*", "Secret/first-oem"}))
+
+		object := metav1.ObjectMeta{Name: "first-oem", Namespace: "fdo"}
+		Expect(requestsForSource(ctx, c, "Secret", &corev1.Secret{ObjectMeta: object})).To(ConsistOf(HaveField("Name", "device-cas")))
+		Expect(requestsForSource(ctx, c, "ConfigMap", &corev1.ConfigMap{ObjectMeta: object})).To(BeEmpty())
+
+		object = metav1.ObjectMeta{Name: "third-oem", Namespace: "fdo", Labels: map[string]string{"oem": "true"}}
+		Expect(requestsForSource(ctx, c, "ConfigMap", &corev1.ConfigMap{ObjectMeta: object})).To(ConsistOf(HaveField("Name", "device-cas")))
+		Expect(requestsForSource(ctx, c, "Secret", &corev1.Secret{ObjectMeta: object})).To(BeEmpty())
+		object.Labels = nil
+		Expect(requestsForSource(ctx, c, "ConfigMap", &corev1.ConfigMap{ObjectMeta: object})).To(BeEmpty())
+	})
+})
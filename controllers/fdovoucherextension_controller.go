@@ -0,0 +1,428 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+const (
+	// voucherExtensionOwnerKey and voucherExtensionNextOwnerKey are the default keys of the owner
+	// key and the next owner certificate in their secrets
+	voucherExtensionOwnerKey     = "owner_key.der"
+	voucherExtensionNextOwnerKey = "owner_cert.pem"
+	// voucherExtensionSecretsIndex indexes extensions by the names of the secrets they read or write
+	voucherExtensionSecretsIndex = ".spec.secrets"
+	// voucherExtensionVouchersIndex indexes extensions by the names of the ownership vouchers they
+	// read or write
+	voucherExtensionVouchersIndex = ".spec.ownershipVouchers"
+)
+
+// FDOVoucherExtensionReconciler reconciles a FDOVoucherExtension object
+type FDOVoucherExtensionReconciler struct {
+	util.ReconcilerBase
+	Log logr.Logger
+}
+
+// extensionInputs are the voucher and the keys of an extension
+type extensionInputs struct {
+	voucher   []byte
+	ownerKey  *ecdsa.PrivateKey
+	nextOwner *ecdsa.PublicKey
+}
+
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovoucherextensions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovoucherextensions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovoucherextensions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoownershipvouchers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile extends a voucher to the next owner, appending an entry signed with the key of the
+// current owner, and writes the extended voucher to a secret or an FDOOwnershipVoucher. The voucher
+// is only signed again when the source voucher or the keys change.
+func (r *FDOVoucherExtensionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.Log.WithName("fdovoucherextension_controller").WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	log.Info("Reconciling FDO voucher extension")
+
+	extension := &fdov1beta1.FDOVoucherExtension{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, extension); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("FDOVoucherExtension resource not found. Ignoring since object must have been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get FDOVoucherExtension resource")
+		return ctrl.Result{}, err
+	}
+	extension.Status.ObservedGeneration = extension.Generation
+
+	if problem := validateExtension(extension); problem != "" {
+		return r.manageProblem(ctx, extension, fdov1beta1.ReasonInvalidOutput, problem)
+	}
+	inputs, reason, problem, err := r.readInputs(ctx, extension)
+	if err != nil {
+		return r.ManageError(ctx, extension, err)
+	}
+	if problem != "" {
+		return r.manageProblem(ctx, extension, reason, problem)
+	}
+	hash, err := inputs.hash()
+	if err != nil {
+		return r.ManageError(ctx, extension, err)
+	}
+
+	data, err := r.outputData(ctx, extension)
+	if err != nil {
+		return r.ManageError(ctx, extension, err)
+	}
+	// signatures are randomized, keep the voucher extended from the same inputs
+	if data == nil || extension.Status.InputHash != hash {
+		if data, reason, problem, err = extendVoucher(inputs); err != nil {
+			return r.ManageError(ctx, extension, err)
+		}
+		if problem != "" {
+			return r.manageProblem(ctx, extension, reason, problem)
+		}
+		now := metav1.Now()
+		extension.Status.ExtensionTime = &now
+	}
+	if problem, err := r.writeOutput(ctx, extension, data); err != nil {
+		log.Error(err, "Failed to write extended voucher")
+		return r.ManageError(ctx, extension, err)
+	} else if problem != "" {
+		return r.manageProblem(ctx, extension, fdov1beta1.ReasonInvalidOutput, problem)
+	}
+
+	extended, err := voucher.Parse(data)
+	if err != nil {
+		return r.ManageError(ctx, extension, err)
+	}
+	fingerprint, err := keys.PublicKeyFingerprint(inputs.nextOwner)
+	if err != nil {
+		return r.ManageError(ctx, extension, err)
+	}
+	extension.Status.GUID = extended.GUID.String()
+	extension.Status.Entries = len(extended.Entries)
+	extension.Status.NextOwnerFingerprint = fingerprint
+	message := fmt.Sprintf("extended the voucher of device %s to the owner key %s", extension.Status.GUID, fingerprint)
+	if extension.Status.InputHash != hash {
+		extension.Status.InputHash = hash
+		r.GetRecorder().Event(extension, corev1.EventTypeNormal, fdov1beta1.ReasonVoucherExtended, message)
+	}
+	setExtensionCondition(extension, metav1.ConditionTrue, fdov1beta1.ReasonVoucherExtended, message)
+	return r.ManageSuccess(ctx, extension)
+}
+
+// manageProblem reports a problem with the spec or the inputs of an extension, which is reconciled
+// again when they change
+func (r *FDOVoucherExtensionReconciler) manageProblem(ctx context.Context, extension *fdov1beta1.FDOVoucherExtension, reason, problem string) (ctrl.Result, error) {
+	if setExtensionCondition(extension, metav1.ConditionFalse, reason, problem) {
+		r.GetRecorder().Event(extension, corev1.EventTypeWarning, reason, problem)
+	}
+	return r.ManageSuccess(ctx, extension)
+}
+
+// validateExtension checks that the spec selects a single source and output, distinct from each other
+func validateExtension(extension *fdov1beta1.FDOVoucherExtension) string {
+	spec := extension.Spec
+	if (spec.Source.Secret == nil) == (spec.Source.OwnershipVoucher == nil) {
+		return "exactly one of source.secret and source.ownershipVoucher must be set"
+	}
+	if (spec.Output.Secret == nil) == (spec.Output.OwnershipVoucher == nil) {
+		return "exactly one of output.secret and output.ownershipVoucher must be set"
+	}
+	if spec.Source.OwnershipVoucher != nil && spec.Output.OwnershipVoucher != nil &&
+		spec.Source.OwnershipVoucher.Name == spec.Output.OwnershipVoucher.Name {
+		return "the output ownership voucher must differ from the source"
+	}
+	if spec.Source.Secret != nil && spec.Output.Secret != nil && spec.Source.Secret.Name == spec.Output.Secret.Name {
+		return "the output secret must differ from the source"
+	}
+	return ""
+}
+
+// readInputs reads the source voucher and the keys of an extension. Problems with the inputs are
+// returned with their reason instead of an error.
+func (r *FDOVoucherExtensionReconciler) readInputs(ctx context.Context, extension *fdov1beta1.FDOVoucherExtension) (*extensionInputs, string, string, error) {
+	inputs := &extensionInputs{}
+	source := extension.Spec.Source
+	if source.Secret != nil {
+		data, problem, err := r.secretData(ctx, extension.Namespace, source.Secret, ownershipVoucherSecretKey)
+		if err != nil || problem != "" {
+			return nil, fdov1beta1.ReasonSourceUnavailable, problem, err
+		}
+		inputs.voucher = data
+	} else {
+		ov := &fdov1beta1.FDOOwnershipVoucher{}
+		if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: extension.Namespace, Name: source.OwnershipVoucher.Name}, ov); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fdov1beta1.ReasonSourceUnavailable, fmt.Sprintf("ownership voucher %s not found", source.OwnershipVoucher.Name), nil
+			}
+			return nil, "", "", err
+		}
+		data, problem, err := ownershipVoucherData(ctx, r.GetClient(), ov)
+		if err != nil || problem != "" {
+			return nil, fdov1beta1.ReasonSourceUnavailable, problem, err
+		}
+		inputs.voucher = data
+	}
+
+	keyData, problem, err := r.secretData(ctx, extension.Namespace, &extension.Spec.OwnerKey, voucherExtensionOwnerKey)
+	if err != nil || problem != "" {
+		return nil, fdov1beta1.ReasonSecretNotFound, problem, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(keyData), []byte("-----BEGIN")) {
+		inputs.ownerKey, err = keys.ParsePEMKey(keyData)
+	} else {
+		inputs.ownerKey, err = keys.ParseKey(keyData)
+	}
+	if err != nil {
+		return nil, fdov1beta1.ReasonInvalidKeyMaterial, fmt.Sprintf("invalid owner key: %v", err), nil
+	}
+
+	certData, problem, err := r.secretData(ctx, extension.Namespace, &extension.Spec.NextOwnerCert, voucherExtensionNextOwnerKey)
+	if err != nil || problem != "" {
+		return nil, fdov1beta1.ReasonSecretNotFound, problem, err
+	}
+	cert, err := keys.ParseCert(certData)
+	if err != nil {
+		return nil, fdov1beta1.ReasonInvalidKeyMaterial, fmt.Sprintf("invalid next owner certificate: %v", err), nil
+	}
+	var ok bool
+	if inputs.nextOwner, ok = cert.PublicKey.(*ecdsa.PublicKey); !ok {
+		return nil, fdov1beta1.ReasonInvalidKeyMaterial, "the next owner certificate has no EC key", nil
+	}
+	return inputs, "", "", nil
+}
+
+// secretData reads a key of a secret. A missing secret or key is returned as a problem.
+func (r *FDOVoucherExtensionReconciler) secretData(ctx context.Context, namespace string, ref *fdov1beta1.SecretKeyReference, defaultKey string) ([]byte, string, error) {
+	key := secretKeyOrDefault(ref, defaultKey)
+	secret := &corev1.Secret{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Sprintf("secret %s not found", ref.Name), nil
+		}
+		return nil, "", err
+	}
+	data := secret.Data[key]
+	if len(data) == 0 {
+		return nil, fmt.Sprintf("secret %s has no key %s", ref.Name, key), nil
+	}
+	return data, "", nil
+}
+
+// hash returns the SHA-256 hash of the voucher and the keys of an extension
+func (i *extensionInputs) hash() (string, error) {
+	ownerKey, err := keys.MarshalKey(i.ownerKey)
+	if err != nil {
+		return "", err
+	}
+	nextOwner, err := x509.MarshalPKIXPublicKey(i.nextOwner)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, data := range [][]byte{i.voucher, ownerKey, nextOwner} {
+		sum := sha256.Sum256(data)
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extendVoucher appends an entry for the next owner to the voucher and encodes it in PEM format. The
+// entries of the voucher are verified first, so that a broken chain is never signed. Problems with
+// the inputs are returned with their reason instead of an error.
+func extendVoucher(inputs *extensionInputs) ([]byte, string, string, error) {
+	v, err := voucher.Parse(inputs.voucher)
+	if err != nil {
+		return nil, fdov1beta1.ReasonInvalidVoucher, err.Error(), nil
+	}
+	if err := v.VerifyEntries(); err != nil {
+		return nil, fdov1beta1.ReasonInvalidVoucher, fmt.Sprintf("invalid voucher entries: %v", err), nil
+	}
+	next, err := voucher.NewX509PublicKey(inputs.nextOwner)
+	if err != nil {
+		return nil, fdov1beta1.ReasonInvalidKeyMaterial, fmt.Sprintf("invalid next owner key: %v", err), nil
+	}
+	if err := v.Extend(inputs.ownerKey, next); err != nil {
+		return nil, fdov1beta1.ReasonInvalidKeyMaterial, err.Error(), nil
+	}
+	data, err := v.MarshalPEM()
+	return data, "", "", err
+}
+
+// outputData returns the voucher held by the output of an extension, nil if there is none or the
+// output isn't owned by the extension
+func (r *FDOVoucherExtensionReconciler) outputData(ctx context.Context, extension *fdov1beta1.FDOVoucherExtension) ([]byte, error) {
+	output := extension.Spec.Output
+	if output.Secret != nil {
+		secret := &corev1.Secret{}
+		if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: extension.Namespace, Name: output.Secret.Name}, secret); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		if !metav1.IsControlledBy(secret, extension) {
+			return nil, nil
+		}
+		return secret.Data[secretKeyOrDefault(output.Secret, ownershipVoucherSecretKey)], nil
+	}
+	ov := &fdov1beta1.FDOOwnershipVoucher{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: extension.Namespace, Name: output.OwnershipVoucher.Name}, ov); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if ov.Spec.Voucher == "" || !metav1.IsControlledBy(ov, extension) {
+		return nil, nil
+	}
+	return []byte(ov.Spec.Voucher), nil
+}
+
+// writeOutput writes the extended voucher to the secret or the ownership voucher of an extension. An
+// existing output that isn't owned by the extension is left alone and returned as a problem.
+func (r *FDOVoucherExtensionReconciler) writeOutput(ctx context.Context, extension *fdov1beta1.FDOVoucherExtension, data []byte) (string, error) {
+	output := extension.Spec.Output
+	var obj client.Object
+	var mutate func()
+	if output.Secret != nil {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: output.Secret.Name, Namespace: extension.Namespace}}
+		obj, mutate = secret, func() {
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			secret.Data[secretKeyOrDefault(output.Secret, ownershipVoucherSecretKey)] = data
+		}
+	} else {
+		ov := &fdov1beta1.FDOOwnershipVoucher{ObjectMeta: metav1.ObjectMeta{Name: output.OwnershipVoucher.Name, Namespace: extension.Namespace}}
+		obj, mutate = ov, func() {
+			ov.Spec.Voucher = string(data)
+			ov.Spec.VoucherSecret = nil
+			ov.Spec.OnboardingServer = output.OwnershipVoucher.OnboardingServer
+		}
+	}
+	notOwned := false
+	_, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), obj, func() error {
+		if obj.GetResourceVersion() != "" && !metav1.IsControlledBy(obj, extension) {
+			// left unchanged, so it isn't updated
+			notOwned = true
+			return nil
+		}
+		mutate()
+		return ctrl.SetControllerReference(extension, obj, r.GetScheme())
+	})
+	if err != nil || !notOwned {
+		return "", err
+	}
+	kind := "secret"
+	if output.OwnershipVoucher != nil {
+		kind = "ownership voucher"
+	}
+	return fmt.Sprintf("%s %s exists and is not owned by this extension", kind, obj.GetName()), nil
+}
+
+func secretKeyOrDefault(ref *fdov1beta1.SecretKeyReference, defaultKey string) string {
+	if ref.Key == "" {
+		return defaultKey
+	}
+	return ref.Key
+}
+
+// setExtensionCondition sets the Ready condition of an extension and returns whether its status,
+// reason or message changed
+func setExtensionCondition(extension *fdov1beta1.FDOVoucherExtension, status metav1.ConditionStatus, reason, message string) bool {
+	previous := meta.FindStatusCondition(extension.Status.Conditions, fdov1beta1.ConditionReady)
+	changed := previous == nil || previous.Status != status || previous.Reason != reason || previous.Message != message
+	meta.SetStatusCondition(&extension.Status.Conditions, metav1.Condition{
+		Type:               fdov1beta1.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: extension.Generation,
+	})
+	return changed
+}
+
+// extensionSecretNames returns the names of the secrets of an extension, the values of
+// voucherExtensionSecretsIndex
+func extensionSecretNames(obj client.Object) []string {
+	spec := obj.(*fdov1beta1.FDOVoucherExtension).Spec
+	names := []string{spec.OwnerKey.Name, spec.NextOwnerCert.Name}
+	if spec.Source.Secret != nil {
+		names = append(names, spec.Source.Secret.Name)
+	}
+	if spec.Output.Secret != nil {
+		names = append(names, spec.Output.Secret.Name)
+	}
+	return names
+}
+
+// extensionVoucherNames returns the names of the ownership vouchers of an extension, the values of
+// voucherExtensionVouchersIndex
+func extensionVoucherNames(obj client.Object) []string {
+	spec := obj.(*fdov1beta1.FDOVoucherExtension).Spec
+	var names []string
+	if spec.Source.OwnershipVoucher != nil {
+		names = append(names, spec.Source.OwnershipVoucher.Name)
+	}
+	if spec.Output.OwnershipVoucher != nil {
+		names = append(names, spec.Output.OwnershipVoucher.Name)
+	}
+	return names
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FDOVoucherExtensionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOVoucherExtension{}, voucherExtensionSecretsIndex, extensionSecretNames); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &fdov1beta1.FDOVoucherExtension{}, voucherExtensionVouchersIndex, extensionVoucherNames); err != nil {
+		return err
+	}
+	// extensions read vouchers and keys from secrets and ownership vouchers, and write to them
+	extensionsFor := func(index string) handler.EventHandler {
+		return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return requestsForIndex(ctx, r.GetClient(), &fdov1beta1.FDOVoucherExtensionList{}, obj.GetNamespace(), index, obj.GetName())
+		})
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&fdov1beta1.FDOVoucherExtension{}).
+		Watches(&fdov1beta1.FDOOwnershipVoucher{}, extensionsFor(voucherExtensionVouchersIndex)).
+		Watches(&corev1.Secret{}, extensionsFor(voucherExtensionSecretsIndex)).
+		Complete(r)
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/pem"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+var _ = Describe("FDOVoucherExtensionReconciler", func() {
+	var (
+		c         k8sclient.Client
+		ctx       context.Context
+		r         *FDOVoucherExtensionReconciler
+		extension *fdov1beta1.FDOVoucherExtension
+		next      *ecdsa.PrivateKey
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+
+		ov, err := os.ReadFile("testdata/voucher.pem")
+		Expect(err).NotTo(HaveOccurred())
+		ownerKey, err := os.ReadFile("testdata/manufacturer_key.pem")
+		Expect(err).NotTo(HaveOccurred())
+		next, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		nextCert, err := keys.SelfSignedCert(next, keys.Subject{CommonName: "Customer"}, false, time.Now(), time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		c = newFakeClientBuilder(
+			&fdov1beta1.FDOOwnershipVoucher{
+				ObjectMeta: metav1.ObjectMeta{Name: "device-1234", Namespace: "fdo"},
+				Spec:       fdov1beta1.FDOOwnershipVoucherSpec{Voucher: string(ov)},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "fdo-owner-key", Namespace: "fdo"},
+				Data:       map[string][]byte{"owner_key.pem": ownerKey},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "customer-owner-cert", Namespace: "fdo"},
+				Data:       map[string][]byte{"owner_cert.pem": nextCert},
+			},
+		).
+			WithIndex(&fdov1beta1.FDOVoucherExtension{}, voucherExtensionSecretsIndex, extensionSecretNames).
+			WithIndex(&fdov1beta1.FDOVoucherExtension{}, voucherExtensionVouchersIndex, extensionVoucherNames).
+			Build()
+		r = &FDOVoucherExtensionReconciler{
+			ReconcilerBase: util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil),
+		}
+		extension = &fdov1beta1.FDOVoucherExtension{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-1234-customer", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOVoucherExtensionSpec{
+				Source:        fdov1beta1.VoucherExtensionSource{OwnershipVoucher: &fdov1beta1.OwnershipVoucherReference{Name: "device-1234"}},
+				OwnerKey:      fdov1beta1.SecretKeyReference{Name: "fdo-owner-key", Key: "owner_key.pem"},
+				NextOwnerCert: fdov1beta1.SecretKeyReference{Name: "customer-owner-cert"},
+				Output:        fdov1beta1.VoucherExtensionOutput{Secret: &fdov1beta1.SecretKeyReference{Name: "device-1234-customer"}},
+			},
+		}
+	})
+
+	It("should extend the voucher to the next owner", func() {
+		inputs, _, problem, err := r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(BeEmpty())
+		data, _, problem, err := extendVoucher(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(BeEmpty())
+
+		v, err := voucher.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.GUID.String()).To(Equal("01234567-89ab-cdef-0123-456789abcdef"))
+		Expect(v.Entries).To(HaveLen(1))
+		Expect(v.VerifyEntries()).To(Succeed())
+		fingerprint, err := v.OwnerKey().Fingerprint()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys.PublicKeyFingerprint(&next.PublicKey)).To(Equal(fingerprint))
+	})
+
+	It("should not sign a voucher whose entries don't verify", func() {
+		inputs, _, _, err := r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		data, _, _, err := extendVoucher(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		block, _ := pem.Decode(data)
+		// the voucher ends with the signature of its last entry
+		block.Bytes[len(block.Bytes)-1] ^= 0xff
+		inputs.voucher = block.Bytes
+		inputs.ownerKey = next
+
+		_, reason, problem, err := extendVoucher(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal(fdov1beta1.ReasonInvalidVoucher))
+		Expect(problem).To(ContainSubstring("invalid signature"))
+	})
+
+	It("should report an owner key that doesn't own the voucher", func() {
+		other, err := keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := keys.MarshalKey(other)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Update(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "fdo-owner-key", Namespace: "fdo"},
+			Data:       map[string][]byte{"owner_key.der": der},
+		})).To(Succeed())
+		extension.Spec.OwnerKey.Key = ""
+
+		inputs, _, problem, err := r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(problem).To(BeEmpty())
+		_, reason, problem, err := extendVoucher(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal(fdov1beta1.ReasonInvalidKeyMaterial))
+		Expect(problem).To(ContainSubstring("doesn't match the owner key"))
+	})
+
+	It("should warn once about a problem", func() {
+		recorder := record.NewFakeRecorder(10)
+		r.ReconcilerBase = util.NewReconcilerBase(c, scheme.Scheme, nil, recorder, nil)
+		extension.Spec.NextOwnerCert.Name = "missing"
+		Expect(c.Create(ctx, extension)).To(Succeed())
+		request := ctrl.Request{NamespacedName: k8sclient.ObjectKeyFromObject(extension)}
+		_, err := r.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring(fdov1beta1.ReasonSecretNotFound)))
+
+		_, err = r.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should only reconcile the extensions of a secret or an ownership voucher", func() {
+		Expect(c.Create(ctx, extension)).To(Succeed())
+		for _, name := range []string{"fdo-owner-key", "customer-owner-cert", "device-1234-customer"} {
+			Expect(requestsForIndex(ctx, c, &fdov1beta1.FDOVoucherExtensionList{}, "fdo", voucherExtensionSecretsIndex, name)).To(HaveLen(1))
+		}
+		Expect(requestsForIndex(ctx, c, &fdov1beta1.FDOVoucherExtensionList{}, "fdo", voucherExtensionVouchersIndex, "device-1234")).To(HaveLen(1))
+		Expect(requestsForIndex(ctx, c, &fdov1beta1.FDOVoucherExtensionList{}, "fdo", voucherExtensionSecretsIndex, "other")).To(BeEmpty())
+	})
+
+	It("should report missing inputs", func() {
+		extension.Spec.NextOwnerCert.Name = "missing"
+		_, reason, problem, err := r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal(fdov1beta1.ReasonSecretNotFound))
+		Expect(problem).To(Equal("secret missing not found"))
+
+		extension.Spec.Source.OwnershipVoucher.Name = "missing"
+		_, reason, problem, err = r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal(fdov1beta1.ReasonSourceUnavailable))
+		Expect(problem).To(Equal("ownership voucher missing not found"))
+	})
+
+	It("should hash the inputs", func() {
+		inputs, _, _, err := r.readInputs(ctx, extension)
+		Expect(err).NotTo(HaveOccurred())
+		hash, err := inputs.hash()
+		Expect(err).NotTo(HaveOccurred())
+		inputs.voucher = append(inputs.voucher, '\n')
+		Expect(inputs.hash()).NotTo(Equal(hash))
+	})
+
+	It("should validate the source and the output", func() {
+		Expect(validateExtension(extension)).To(BeEmpty())
+		extension.Spec.Source.Secret = &fdov1beta1.SecretKeyReference{Name: "voucher"}
+		Expect(validateExtension(extension)).To(ContainSubstring("exactly one of source.secret"))
+		extension.Spec.Source.OwnershipVoucher = nil
+		extension.Spec.Output.OwnershipVoucher = &fdov1beta1.ExtendedOwnershipVoucher{Name: "device-1234"}
+		Expect(validateExtension(extension)).To(ContainSubstring("exactly one of output.secret"))
+		extension.Spec.Output.Secret = nil
+		extension.Spec.Source = fdov1beta1.VoucherExtensionSource{OwnershipVoucher: &fdov1beta1.OwnershipVoucherReference{Name: "device-1234"}}
+		Expect(validateExtension(extension)).To(ContainSubstring("must differ from the source"))
+	})
+
+	It("should write the extended voucher to its output", func() {
+		Expect(r.writeOutput(ctx, extension, []byte("extended"))).To(BeEmpty())
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "device-1234-customer", Namespace: "fdo"}, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("voucher", []byte("extended")))
+		Expect(secret.OwnerReferences).To(HaveLen(1))
+		Expect(r.outputData(ctx, extension)).To(Equal([]byte("extended")))
+
+		extension.Spec.Output = fdov1beta1.VoucherExtensionOutput{OwnershipVoucher: &fdov1beta1.ExtendedOwnershipVoucher{
+			Name:             "device-1234-customer-ov",
+			OnboardingServer: fdov1beta1.OnboardingServerReference{Name: "onboarding-server"},
+		}}
+		Expect(r.outputData(ctx, extension)).To(BeNil())
+		Expect(r.writeOutput(ctx, extension, []byte("extended"))).To(BeEmpty())
+		ov := &fdov1beta1.FDOOwnershipVoucher{}
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "device-1234-customer-ov", Namespace: "fdo"}, ov)).To(Succeed())
+		Expect(ov.Spec.Voucher).To(Equal("extended"))
+		Expect(ov.Spec.OnboardingServer.Name).To(Equal("onboarding-server"))
+	})
+
+	It("should not take over an output it doesn't own", func() {
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-1234-customer", Namespace: "fdo"},
+			Data:       map[string][]byte{"voucher": []byte("other")},
+		})).To(Succeed())
+		Expect(r.outputData(ctx, extension)).To(BeNil())
+		Expect(r.writeOutput(ctx, extension, []byte("extended"))).To(Equal("secret device-1234-customer exists and is not owned by this extension"))
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "device-1234-customer", Namespace: "fdo"}, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("voucher", []byte("other")))
+		Expect(secret.OwnerReferences).To(BeEmpty())
+	})
+})
@@ -0,0 +1,450 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+const (
+	// voucherSyncLedgerTemplate is the name of a shard of the ledger of a sync, a config map listing
+	// the GUIDs delivered to each target whose hash starts with the shard. A config map holds at most
+	// 1 MiB, about 27000 GUIDs, so the ledger has up to 256 shards.
+	voucherSyncLedgerTemplate = "%s-delivered-%s"
+	// voucherSyncLedgerLabel labels the shards of the ledger of a sync with its UID
+	voucherSyncLedgerLabel = "fdo.redhat.com/voucher-sync-uid"
+	// defaultVoucherSyncInterval is the default delay between two scans of the vouchers of a manufacturing server
+	defaultVoucherSyncInterval = time.Minute
+	// voucherSyncTokenKey and voucherSyncCAKey are the default keys of the secrets of a target
+	voucherSyncTokenKey = "token"
+	voucherSyncCAKey    = "ca.crt"
+	// firstRetryDelay and maxRetryDelay bound the exponential backoff of failed deliveries
+	firstRetryDelay = 10 * time.Second
+	maxRetryDelay   = 10 * time.Minute
+)
+
+// FDOVoucherSyncReconciler reconciles a FDOVoucherSync object
+type FDOVoucherSyncReconciler struct {
+	util.ReconcilerBase
+	Log        logr.Logger
+	HTTPClient *http.Client
+	// AllowCrossNamespaceTargets allows syncs to push vouchers to the onboarding servers of other
+	// namespaces, which lets the users of a namespace import vouchers into the servers of others
+	AllowCrossNamespaceTargets bool
+	// InventoryURL returns the URL of the voucher inventory of a manufacturing server, defaults to its service
+	InventoryURL func(server client.Object) string
+	// ManagementURL returns the URL of the OV management API of an onboarding server, defaults to its service
+	ManagementURL func(name, namespace string) string
+}
+
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovouchersyncs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovouchersyncs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdovouchersyncs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdomanufacturingservers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile pushes the vouchers of a manufacturing server that have not been delivered to a target
+// yet, read from the voucher inventory of the server. The GUIDs delivered to each target are kept
+// in the config maps of the ledger of the sync, so that each voucher is delivered once. A target
+// stops at its first failed delivery, which is retried with an exponential backoff.
+func (r *FDOVoucherSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.Log.WithName("fdovouchersync_controller").WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	log.Info("Reconciling FDO voucher sync")
+
+	sync := &fdov1beta1.FDOVoucherSync{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, sync); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("FDOVoucherSync resource not found. Ignoring since object must have been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get FDOVoucherSync resource")
+		return ctrl.Result{}, err
+	}
+	sync.Status.ObservedGeneration = sync.Generation
+	interval := defaultVoucherSyncInterval
+	if sync.Spec.Interval != nil && sync.Spec.Interval.Duration > 0 {
+		interval = sync.Spec.Interval.Duration
+	}
+
+	if problem := r.validateTargets(sync); problem != "" {
+		r.GetRecorder().Event(sync, corev1.EventTypeWarning, fdov1beta1.ReasonInvalidTarget, problem)
+		setVoucherSyncCondition(sync, metav1.ConditionFalse, fdov1beta1.ReasonInvalidTarget, problem)
+		return r.ManageSuccess(ctx, sync)
+	}
+	devices, token, problem, err := r.sourceDevices(ctx, sync)
+	if err != nil {
+		return r.ManageError(ctx, sync, err)
+	}
+	if problem != "" {
+		setVoucherSyncCondition(sync, metav1.ConditionFalse, fdov1beta1.ReasonSourceUnavailable, problem)
+		return r.ManageSuccessWithRequeue(ctx, sync, interval)
+	}
+
+	ledger, err := readLedger(ctx, r.GetClient(), sync)
+	if err != nil {
+		return r.ManageError(ctx, sync, err)
+	}
+	delivered := map[string][]string{}
+	statuses := []fdov1beta1.VoucherSyncTargetStatus{}
+	failures := int32(0)
+	problems := []string{}
+	for _, target := range sync.Spec.Targets {
+		status := r.syncTarget(ctx, sync, target, devices, token, ledger[target.Name])
+		delivered[target.Name] = status.guids
+		statuses = append(statuses, status.VoucherSyncTargetStatus)
+		if status.Failures > failures {
+			failures = status.Failures
+		}
+		if status.LastError != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", target.Name, status.LastError))
+		}
+	}
+
+	if err := r.writeLedger(ctx, sync, delivered); err != nil {
+		log.Error(err, "Ledger reconcile failed")
+		return r.ManageError(ctx, sync, err)
+	}
+
+	now := metav1.Now()
+	sync.Status.Vouchers = int32(len(devices))
+	sync.Status.Targets = statuses
+	sync.Status.LastSyncTime = &now
+	if len(problems) > 0 {
+		message := strings.Join(problems, "; ")
+		r.GetRecorder().Event(sync, corev1.EventTypeWarning, fdov1beta1.ReasonDeliveryFailed, message)
+		setVoucherSyncCondition(sync, metav1.ConditionFalse, fdov1beta1.ReasonDeliveryFailed, message)
+		return r.ManageSuccessWithRequeue(ctx, sync, retryDelay(failures, interval))
+	}
+	setVoucherSyncCondition(sync, metav1.ConditionTrue, fdov1beta1.ReasonVouchersSynchronized,
+		fmt.Sprintf("the %d vouchers of %s are delivered to all targets", len(devices), sync.Spec.ManufacturingServer.Name))
+	return r.ManageSuccessWithRequeue(ctx, sync, interval)
+}
+
+// sourceDevices returns the GUIDs of the vouchers of the manufacturing server of a sync, sorted and
+// without duplicates, and the token its inventory requires if it serves the voucher export API.
+// Problems with the server are returned instead of an error.
+func (r *FDOVoucherSyncReconciler) sourceDevices(ctx context.Context, sync *fdov1beta1.FDOVoucherSync) ([]string, string, string, error) {
+	server := &fdov1beta1.FDOManufacturingServer{}
+	name := sync.Spec.ManufacturingServer.Name
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: sync.Namespace, Name: name}, server); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, "", fmt.Sprintf("manufacturing server %s not found", name), nil
+		}
+		return nil, "", "", err
+	}
+	if server.Status.Inventory == nil {
+		return nil, "", fmt.Sprintf("manufacturing server %s has no voucher inventory, its vouchers must be stored in a directory", name), nil
+	}
+	token, err := voucherExportToken(ctx, r.GetClient(), server)
+	if err != nil {
+		return nil, "", "", err
+	}
+	vouchers, err := inventory.Fetch(ctx, r.HTTPClient, r.sourceURL(sync), token)
+	if err != nil {
+		return nil, "", fmt.Sprintf("voucher inventory of manufacturing server %s not available: %s", name, err), nil
+	}
+	seen := map[string]bool{}
+	guids := []string{}
+	for _, device := range vouchers.Devices {
+		if !seen[device.GUID] {
+			seen[device.GUID] = true
+			guids = append(guids, device.GUID)
+		}
+	}
+	sort.Strings(guids)
+	return guids, token, "", nil
+}
+
+// targetSync is the result of the delivery of vouchers to a target
+type targetSync struct {
+	fdov1beta1.VoucherSyncTargetStatus
+	// guids are the GUIDs delivered to the target that the manufacturing server still holds
+	guids []string
+}
+
+// syncTarget delivers the vouchers of devices that are not in the delivered GUIDs of a target,
+// reading them from the inventory of the manufacturing server with its token
+func (r *FDOVoucherSyncReconciler) syncTarget(ctx context.Context, sync *fdov1beta1.FDOVoucherSync, target fdov1beta1.VoucherSyncTarget, devices []string, token string, delivered map[string]bool) targetSync {
+	status := targetSync{VoucherSyncTargetStatus: fdov1beta1.VoucherSyncTargetStatus{Name: target.Name}}
+	if previous := findTargetStatus(sync.Status.Targets, target.Name); previous != nil {
+		status.VoucherSyncTargetStatus = *previous
+	}
+	pending := []string{}
+	for _, guid := range devices {
+		if delivered[guid] {
+			status.guids = append(status.guids, guid)
+		} else {
+			pending = append(pending, guid)
+		}
+	}
+
+	fail := func(err error) targetSync {
+		status.Failures++
+		status.LastError = err.Error()
+		status.Delivered = int32(len(status.guids))
+		status.Pending = int32(len(devices) - len(status.guids))
+		return status
+	}
+	managementClient, err := r.targetClient(ctx, sync, target)
+	if err != nil {
+		return fail(err)
+	}
+	for _, guid := range pending {
+		data, err := inventory.FetchVoucher(ctx, r.HTTPClient, r.sourceURL(sync), token, guid)
+		if err != nil {
+			return fail(err)
+		}
+		// a voucher delivered before the ledger was written, or by another sync, is already imported
+		if _, err := managementClient.importVoucher(ctx, data); err != nil && !goerrors.Is(err, errVoucherExists) {
+			return fail(fmt.Errorf("voucher %s: %w", guid, err))
+		}
+		now := metav1.Now()
+		status.LastDeliveryTime = &now
+		status.guids = append(status.guids, guid)
+	}
+	sort.Strings(status.guids)
+	status.Failures = 0
+	status.LastError = ""
+	status.Delivered = int32(len(status.guids))
+	status.Pending = 0
+	if len(pending) > 0 {
+		r.GetRecorder().Event(sync, corev1.EventTypeNormal, fdov1beta1.ReasonVouchersSynchronized,
+			fmt.Sprintf("delivered %d vouchers to %s", len(pending), target.Name))
+	}
+	return status
+}
+
+// sourceURL returns the URL of the voucher inventory of the manufacturing server of a sync
+func (r *FDOVoucherSyncReconciler) sourceURL(sync *fdov1beta1.FDOVoucherSync) string {
+	server := &fdov1beta1.FDOManufacturingServer{ObjectMeta: metav1.ObjectMeta{Name: sync.Spec.ManufacturingServer.Name, Namespace: sync.Namespace}}
+	if r.InventoryURL != nil {
+		return r.InventoryURL(server)
+	}
+	return inventoryURL(server)
+}
+
+// validateTargets returns the problem of the first invalid target of a sync: a target must be an
+// onboarding server or a URL, and the onboarding servers of other namespaces must be allowed
+func (r *FDOVoucherSyncReconciler) validateTargets(sync *fdov1beta1.FDOVoucherSync) string {
+	for _, target := range sync.Spec.Targets {
+		switch {
+		case (target.OnboardingServer == nil) == (target.URL == ""):
+			return fmt.Sprintf("target %s: exactly one of onboardingServer and url must be set", target.Name)
+		case target.OnboardingServer != nil && target.OnboardingServer.Namespace != "" &&
+			target.OnboardingServer.Namespace != sync.Namespace && !r.AllowCrossNamespaceTargets:
+			return fmt.Sprintf("target %s: onboarding servers of other namespaces are not allowed, the operator must run with --allow-cross-namespace-voucher-sync", target.Name)
+		}
+	}
+	return ""
+}
+
+// targetClient returns the client of the OV management API of a validated target, authenticated
+// with the token of its secret and trusting the CAs of its CA secret
+func (r *FDOVoucherSyncReconciler) targetClient(ctx context.Context, sync *fdov1beta1.FDOVoucherSync, target fdov1beta1.VoucherSyncTarget) (*voucherManagementClient, error) {
+	c := &voucherManagementClient{HTTPClient: r.HTTPClient, URL: target.URL}
+	if target.OnboardingServer != nil {
+		namespace := target.OnboardingServer.Namespace
+		if namespace == "" {
+			namespace = sync.Namespace
+		}
+		c.URL = managementURL(target.OnboardingServer.Name, namespace)
+		if r.ManagementURL != nil {
+			c.URL = r.ManagementURL(target.OnboardingServer.Name, namespace)
+		}
+	}
+	if target.AuthSecret != nil {
+		token, err := r.secretValue(ctx, sync.Namespace, target.AuthSecret, voucherSyncTokenKey)
+		if err != nil {
+			return nil, err
+		}
+		c.Token = strings.TrimSpace(string(token))
+	}
+	if target.CASecret != nil {
+		ca, err := r.secretValue(ctx, sync.Namespace, target.CASecret, voucherSyncCAKey)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("secret %s holds no PEM certificate", target.CASecret.Name)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		c.HTTPClient = &http.Client{Transport: transport, Timeout: inventory.DefaultHTTPClient.Timeout}
+	}
+	return c, nil
+}
+
+func (r *FDOVoucherSyncReconciler) secretValue(ctx context.Context, namespace string, ref *fdov1beta1.SecretKeyReference, defaultKey string) ([]byte, error) {
+	key := ref.Key
+	if key == "" {
+		key = defaultKey
+	}
+	secret := &corev1.Secret{}
+	if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", ref.Name, key)
+	}
+	return value, nil
+}
+
+// ledgerShard returns the shard of the ledger listing a GUID
+func ledgerShard(guid string) string {
+	sum := sha256.Sum256([]byte(guid))
+	return hex.EncodeToString(sum[:1])
+}
+
+// readLedger returns the GUIDs delivered to each target of a sync, read from the shards of its ledger
+func readLedger(ctx context.Context, c client.Client, sync *fdov1beta1.FDOVoucherSync) (map[string]map[string]bool, error) {
+	shards := &corev1.ConfigMapList{}
+	if err := c.List(ctx, shards, client.InNamespace(sync.Namespace), client.MatchingLabels{voucherSyncLedgerLabel: string(sync.UID)}); err != nil {
+		return nil, err
+	}
+	ledger := map[string]map[string]bool{}
+	for _, shard := range shards.Items {
+		for target, guids := range shard.Data {
+			if ledger[target] == nil {
+				ledger[target] = map[string]bool{}
+			}
+			for _, guid := range strings.Fields(guids) {
+				ledger[target][guid] = true
+			}
+		}
+	}
+	return ledger, nil
+}
+
+// writeLedger writes the GUIDs delivered to each target of a sync into the shards of its ledger and
+// deletes the empty shards
+func (r *FDOVoucherSyncReconciler) writeLedger(ctx context.Context, sync *fdov1beta1.FDOVoucherSync, delivered map[string][]string) error {
+	shards := map[string]map[string][]string{}
+	for target, guids := range delivered {
+		for _, guid := range guids {
+			shard := ledgerShard(guid)
+			if shards[shard] == nil {
+				shards[shard] = map[string][]string{}
+			}
+			shards[shard][target] = append(shards[shard][target], guid)
+		}
+	}
+	for shard, targets := range shards {
+		ledger := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(voucherSyncLedgerTemplate, sync.Name, shard), Namespace: sync.Namespace}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), ledger, func() error {
+			ledger.Labels = map[string]string{voucherSyncLedgerLabel: string(sync.UID)}
+			ledger.Data = map[string]string{}
+			for target, guids := range targets {
+				ledger.Data[target] = strings.Join(guids, "\n")
+			}
+			return ctrl.SetControllerReference(sync, ledger, r.GetScheme())
+		}); err != nil {
+			return err
+		}
+	}
+
+	existing := &corev1.ConfigMapList{}
+	if err := r.GetClient().List(ctx, existing, client.InNamespace(sync.Namespace), client.MatchingLabels{voucherSyncLedgerLabel: string(sync.UID)}); err != nil {
+		return err
+	}
+	for i := range existing.Items {
+		if shard := strings.TrimPrefix(existing.Items[i].Name, fmt.Sprintf(voucherSyncLedgerTemplate, sync.Name, "")); shards[shard] == nil {
+			if err := client.IgnoreNotFound(r.GetClient().Delete(ctx, &existing.Items[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func findTargetStatus(statuses []fdov1beta1.VoucherSyncTargetStatus, name string) *fdov1beta1.VoucherSyncTargetStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// retryDelay doubles the delay before retrying a failed delivery after each failure, up to
+// maxRetryDelay, and retries at the latest at the next scan
+func retryDelay(failures int32, interval time.Duration) time.Duration {
+	delay := firstRetryDelay
+	for i := int32(1); i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	if delay > interval {
+		return interval
+	}
+	return delay
+}
+
+func setVoucherSyncCondition(sync *fdov1beta1.FDOVoucherSync, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&sync.Status.Conditions, metav1.Condition{
+		Type:               fdov1beta1.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: sync.Generation,
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FDOVoucherSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// the inventory of a manufacturing server is updated along with its status
+	requestsForSyncs := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return requestsForNamespace(ctx, r.GetClient(), &fdov1beta1.FDOVoucherSyncList{}, obj)
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&fdov1beta1.FDOVoucherSync{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&fdov1beta1.FDOManufacturingServer{}, requestsForSyncs).
+		Complete(r)
+}
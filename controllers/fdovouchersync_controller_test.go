@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+var _ = Describe("FDOVoucherSyncReconciler", func() {
+	var (
+		ctx      context.Context
+		r        *FDOVoucherSyncReconciler
+		sync     *fdov1beta1.FDOVoucherSync
+		target   fdov1beta1.VoucherSyncTarget
+		imported []string
+		tokens   []string
+		failAt   int
+		existing string
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		c := newFakeClientBuilder(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "datacenter-token", Namespace: "fdo"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t\n")},
+		}).Build()
+		imported = nil
+		tokens = nil
+		failAt = -1
+		existing = ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case strings.HasPrefix(req.URL.Path, inventory.Path+"/"):
+				_, _ = w.Write([]byte(strings.TrimPrefix(req.URL.Path, inventory.Path+"/")))
+			case req.URL.Path == ownershipVoucherPath:
+				if len(imported) == failAt {
+					http.Error(w, "store unavailable", http.StatusInternalServerError)
+					return
+				}
+				body, _ := io.ReadAll(req.Body)
+				if string(body) == existing {
+					http.Error(w, "voucher already exists", http.StatusConflict)
+					return
+				}
+				imported = append(imported, string(body))
+				tokens = append(tokens, req.Header.Get("Authorization"))
+				_ = json.NewEncoder(w).Encode([]map[string]string{{"guid": string(body)}})
+			default:
+				http.NotFound(w, req)
+			}
+		}))
+		DeferCleanup(server.Close)
+		r = &FDOVoucherSyncReconciler{
+			ReconcilerBase: util.NewReconcilerBase(c, scheme.Scheme, nil, record.NewFakeRecorder(10), nil),
+			HTTPClient:     server.Client(),
+			InventoryURL:   func(k8sclient.Object) string { return server.URL },
+			ManagementURL:  func(string, string) string { return server.URL },
+		}
+		target = fdov1beta1.VoucherSyncTarget{
+			Name:             "datacenter",
+			OnboardingServer: &fdov1beta1.NamespacedOnboardingServerReference{Name: "onboarding", Namespace: "datacenter"},
+			AuthSecret:       &fdov1beta1.SecretKeyReference{Name: "datacenter-token"},
+		}
+		sync = &fdov1beta1.FDOVoucherSync{
+			ObjectMeta: metav1.ObjectMeta{Name: "factory", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOVoucherSyncSpec{
+				ManufacturingServer: fdov1beta1.ManufacturingServerReference{Name: "manufacturing"},
+				Targets:             []fdov1beta1.VoucherSyncTarget{target},
+			},
+		}
+	})
+
+	It("should deliver the vouchers that were not delivered yet with the token of the target", func() {
+		status := r.syncTarget(ctx, sync, target, []string{"guid-1", "guid-2", "guid-3"}, "", map[string]bool{"guid-2": true, "guid-0": true})
+		Expect(imported).To(Equal([]string{"guid-1", "guid-3"}))
+		Expect(tokens).To(HaveEach("Bearer s3cr3t"))
+		Expect(status.guids).To(Equal([]string{"guid-1", "guid-2", "guid-3"}))
+		Expect(status.Delivered).To(Equal(int32(3)))
+		Expect(status.Pending).To(BeZero())
+		Expect(status.LastDeliveryTime).NotTo(BeNil())
+	})
+
+	It("should stop at the first failed delivery and count the failures", func() {
+		failAt = 1
+		sync.Status.Targets = []fdov1beta1.VoucherSyncTargetStatus{{Name: "datacenter", Failures: 2}}
+		status := r.syncTarget(ctx, sync, target, []string{"guid-1", "guid-2", "guid-3"}, "", map[string]bool{})
+		Expect(imported).To(Equal([]string{"guid-1"}))
+		Expect(status.guids).To(Equal([]string{"guid-1"}))
+		Expect(status.Delivered).To(Equal(int32(1)))
+		Expect(status.Pending).To(Equal(int32(2)))
+		Expect(status.Failures).To(Equal(int32(3)))
+		Expect(status.LastError).To(ContainSubstring("voucher guid-2"))
+		Expect(status.LastError).To(ContainSubstring("store unavailable"))
+	})
+
+	It("should count the vouchers the target already holds as delivered", func() {
+		existing = "guid-2"
+		status := r.syncTarget(ctx, sync, target, []string{"guid-1", "guid-2", "guid-3"}, "", map[string]bool{})
+		Expect(imported).To(Equal([]string{"guid-1", "guid-3"}))
+		Expect(status.guids).To(Equal([]string{"guid-1", "guid-2", "guid-3"}))
+		Expect(status.LastError).To(BeEmpty())
+	})
+
+	It("should reject invalid targets and onboarding servers of other namespaces", func() {
+		Expect(r.validateTargets(sync)).To(ContainSubstring("onboarding servers of other namespaces are not allowed"))
+		r.AllowCrossNamespaceTargets = true
+		Expect(r.validateTargets(sync)).To(BeEmpty())
+
+		sync.Spec.Targets[0].URL = "https://onboarding.example.com"
+		Expect(r.validateTargets(sync)).To(ContainSubstring("exactly one of onboardingServer and url"))
+		sync.Spec.Targets[0].OnboardingServer = nil
+		Expect(r.validateTargets(sync)).To(BeEmpty())
+		sync.Spec.Targets[0].URL = ""
+		sync.Spec.Targets[0].OnboardingServer = &fdov1beta1.NamespacedOnboardingServerReference{Name: "onboarding"}
+		r.AllowCrossNamespaceTargets = false
+		Expect(r.validateTargets(sync)).To(BeEmpty())
+	})
+
+	It("should shard the ledger", func() {
+		c := r.GetClient()
+		guids := []string{}
+		for i := 0; i < 1000; i++ {
+			guids = append(guids, fmt.Sprintf("guid-%d", i))
+		}
+		used := map[string]bool{}
+		for _, guid := range guids {
+			used[ledgerShard(guid)] = true
+		}
+		Expect(r.writeLedger(ctx, sync, map[string][]string{"datacenter": guids})).To(Succeed())
+		shards := &corev1.ConfigMapList{}
+		Expect(c.List(ctx, shards, k8sclient.InNamespace("fdo"))).To(Succeed())
+		Expect(shards.Items).To(HaveLen(len(used)))
+		for _, shard := range shards.Items {
+			Expect(used).To(HaveKey(strings.TrimPrefix(shard.Name, "factory-delivered-")))
+			Expect(shard.OwnerReferences).To(HaveLen(1))
+		}
+		ledger, err := readLedger(ctx, c, sync)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ledger["datacenter"]).To(HaveLen(1000))
+
+		Expect(r.writeLedger(ctx, sync, map[string][]string{"datacenter": {"guid-1"}})).To(Succeed())
+		Expect(c.List(ctx, shards, k8sclient.InNamespace("fdo"))).To(Succeed())
+		Expect(shards.Items).To(HaveLen(1))
+		Expect(shards.Items[0].Name).To(Equal("factory-delivered-" + ledgerShard("guid-1")))
+		Expect(shards.Items[0].Data).To(Equal(map[string]string{"datacenter": "guid-1"}))
+	})
+
+	It("should back off exponentially up to the interval", func() {
+		Expect(retryDelay(1, time.Hour)).To(Equal(10 * time.Second))
+		Expect(retryDelay(3, time.Hour)).To(Equal(40 * time.Second))
+		Expect(retryDelay(20, time.Hour)).To(Equal(10 * time.Minute))
+		Expect(retryDelay(20, time.Minute)).To(Equal(time.Minute))
+	})
+})
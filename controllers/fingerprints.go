@@ -0,0 +1,67 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+const (
+	fingerprintsConfigMapTemplate = "%s-fingerprints"
+	// diunPublicKeyHashKey is the ConfigMap key of the DIUN certificate hash, named after the
+	// environment variable of the manufacturing client
+	diunPublicKeyHashKey = "DIUN_PUB_KEY_HASH"
+)
+
+// fingerprintsData returns the fingerprints of the certificates of a server, e.g. owner_cert.sha256,
+// and the hash of the DIUN certificate
+func fingerprintsData(keys []fdov1beta1.KeyStatus) map[string]string {
+	data := map[string]string{}
+	for _, k := range keys {
+		if k.SHA256Fingerprint == "" {
+			continue
+		}
+		name := strings.TrimSuffix(k.File, ".pem")
+		data[name+".sha256"] = k.SHA256Fingerprint
+		data[name+".sha384"] = k.SHA384Fingerprint
+		if k.PublicKeyHash != "" {
+			data[diunPublicKeyHashKey] = k.PublicKeyHash
+		}
+	}
+	return data
+}
+
+// createOrUpdateFingerprintsConfigMap publishes the fingerprints of the certificates in status.keys
+// in a ConfigMap, for image build pipelines and factory tooling
+func createOrUpdateFingerprintsConfigMap(ctx context.Context, r *util.ReconcilerBase, server serverObject, svc FDOServiceType) error {
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(fingerprintsConfigMapTemplate, server.GetName()), Namespace: server.GetNamespace(), Labels: getLabels(svc)}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), configMap, func() error {
+		configMap.Data = fingerprintsData(server.GetServerStatus().Keys)
+		return ctrl.SetControllerReference(server, configMap, r.GetScheme())
+	})
+	return err
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations of the pod template of a server, any change of the configuration
+// or of the keys and certificates rolls out new pods
+const (
+	ConfigHashAnnotation  = "fdo.redhat.com/config-hash"
+	SecretsHashAnnotation = "fdo.redhat.com/secrets-hash"
+)
+
+// configMapsHash returns a hash of the content of the given ConfigMaps
+func configMapsHash(configMaps ...*corev1.ConfigMap) string {
+	h := sha256.New()
+	for _, cm := range configMaps {
+		writeField(h, cm.Name)
+		for _, k := range sortedKeys(cm.Data) {
+			writeField(h, k)
+			writeField(h, cm.Data[k])
+		}
+		for _, k := range sortedKeys(cm.BinaryData) {
+			writeField(h, k)
+			writeField(h, string(cm.BinaryData[k]))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// keyFilesHash returns a hash of the content of the given key files, missing secrets or keys are
+// hashed as empty so that the pods are rolled out again once they are created
+func keyFilesHash(ctx context.Context, c client.Reader, namespace string, files []keyFile) (string, error) {
+	h := sha256.New()
+	for _, f := range files {
+		if f.external() {
+			// changes to the key source are rolled out as changes to the init container
+			continue
+		}
+		writeField(h, f.Secret)
+		writeField(h, f.Key)
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: f.Secret}, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		writeField(h, string(secret.Data[f.Key]))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeField writes a length-prefixed value, so that different sequences of values never hash the same
+func writeField(h hash.Hash, value string) {
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(value)))
+	h.Write(length[:])
+	h.Write([]byte(value))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// podTemplateAnnotations returns the hash annotations of the pod template of a server
+func podTemplateAnnotations(ctx context.Context, c client.Reader, namespace string, files []keyFile, configMaps ...*corev1.ConfigMap) (map[string]string, error) {
+	secretsHash, err := keyFilesHash(ctx, c, namespace, files)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		ConfigHashAnnotation:  configMapsHash(configMaps...),
+		SecretsHashAnnotation: secretsHash,
+	}, nil
+}
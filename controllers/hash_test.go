@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gomock "go.uber.org/mock/gomock"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fdo-rs/fdo-operator/internal/client"
+)
+
+var _ = Describe("Pod template hashes", func() {
+	It("should change the config hash only when the content changes", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "server-config"},
+			Data:       map[string]string{"a.yml": "a", "b.yml": "b"},
+		}
+		hash := configMapsHash(cm)
+		Expect(configMapsHash(cm.DeepCopy())).To(Equal(hash))
+
+		cm.Data["b.yml"] = "c"
+		Expect(configMapsHash(cm)).ToNot(Equal(hash))
+
+		// moving content between keys must not go unnoticed
+		moved := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "server-config"},
+			Data:       map[string]string{"a.yml": "ab", "b.yml": ""},
+		}
+		Expect(configMapsHash(moved)).ToNot(Equal(hash))
+	})
+
+	It("should change the secrets hash when a key is created or rotated", func() {
+		gCtrl := gomock.NewController(GinkgoT())
+		c := client.NewMockClient(gCtrl)
+		ctx := context.TODO()
+		files := rendezvousKeyFiles(nil)
+
+		c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).
+			Return(apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "fdo-manufacturer-cert"))
+		missing, err := keyFilesHash(ctx, c, "fdo", files)
+		Expect(err).ToNot(HaveOccurred())
+
+		cert := "cert"
+		c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Times(2).
+			DoAndReturn(func(_ context.Context, _ k8sclient.ObjectKey, obj k8sclient.Object, _ ...k8sclient.GetOption) error {
+				obj.(*corev1.Secret).Data = map[string][]byte{"manufacturer_cert.pem": []byte(cert)}
+				return nil
+			})
+		created, err := keyFilesHash(ctx, c, "fdo", files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created).ToNot(Equal(missing))
+
+		cert = "rotated"
+		rotated, err := keyFilesHash(ctx, c, "fdo", files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated).ToNot(Equal(created))
+	})
+})
@@ -0,0 +1,220 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+// inventoryInterval is the delay between two scans of the voucher store of a server
+const inventoryInterval = 5 * time.Minute
+
+// inventoryContainer is the name of the inventory sidecar of a server
+const inventoryContainer = "inventory"
+
+// inventoryTokenKey is the key of the token authenticating the operator to the inventory sidecar
+const inventoryTokenKey = "token"
+
+// inventorySidecar returns the container serving the inventory of the vouchers of a directory
+// store, or nil if the vouchers are stored in a database or the operator image is unknown
+func inventorySidecar(image string, db *fdov1beta1.DatabaseStorage) *corev1.Container {
+	if image == "" || resolveDatabase(db) != nil {
+		return nil
+	}
+	privilegeEscalation := false
+	return &corev1.Container{
+		Name:  inventoryContainer,
+		Image: image,
+		Args:  []string{inventory.Command, "--dir", "/etc/fdo/ownership_vouchers"},
+		Ports: []corev1.ContainerPort{{Name: "inventory", ContainerPort: inventory.Port}},
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      "ownership-vouchers",
+			MountPath: "/etc/fdo/ownership_vouchers",
+			ReadOnly:  true,
+		}},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &privilegeEscalation,
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}
+}
+
+// withInventorySidecar appends the inventory sidecar, if any, to the containers of a server
+func withInventorySidecar(containers []corev1.Container, sidecar *corev1.Container) []corev1.Container {
+	if sidecar == nil {
+		return containers
+	}
+	return append(containers, *sidecar)
+}
+
+// withInventoryPort appends the port of the inventory sidecar, if any, to the ports of a service
+func withInventoryPort(ports []corev1.ServicePort, sidecar *corev1.Container) []corev1.ServicePort {
+	if sidecar == nil {
+		return ports
+	}
+	return append(ports, corev1.ServicePort{
+		Name:       "inventory",
+		Protocol:   "TCP",
+		Port:       inventory.Port,
+		TargetPort: intstr.FromString("inventory"),
+	})
+}
+
+// inventoryTokenSecret returns the name of the secret holding the token authenticating the operator
+// to the inventory sidecar of a server
+func inventoryTokenSecret(server string) string {
+	return server + "-inventory-token"
+}
+
+// withInventoryToken sets the token authenticating the operator in the environment of the inventory
+// sidecar, if any, which requires it to serve the vouchers of the store
+func withInventoryToken(sidecar *corev1.Container, server client.Object) *corev1.Container {
+	if sidecar == nil {
+		return nil
+	}
+	sidecar.Env = []corev1.EnvVar{{
+		Name: inventory.TokenEnv,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: inventoryTokenSecret(server.GetName())},
+			Key:                  inventoryTokenKey,
+		}},
+	}}
+	return sidecar
+}
+
+// reconcileInventoryToken generates the token of the inventory sidecar of a server, once, and deletes
+// it when the server has no sidecar
+func reconcileInventoryToken(ctx context.Context, r *util.ReconcilerBase, server client.Object, sidecar *corev1.Container) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: inventoryTokenSecret(server.GetName()), Namespace: server.GetNamespace()}}
+	if sidecar == nil {
+		return r.DeleteResourceIfExists(ctx, secret)
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), secret, func() error {
+		if len(secret.Data[inventoryTokenKey]) == 0 {
+			token := make([]byte, 32)
+			if _, err := rand.Read(token); err != nil {
+				return err
+			}
+			secret.Data = map[string][]byte{inventoryTokenKey: []byte(hex.EncodeToString(token))}
+		}
+		return ctrl.SetControllerReference(server, secret, r.GetScheme())
+	})
+	return err
+}
+
+// inventoryToken returns the token authenticating the operator to the inventory sidecar of a server
+func inventoryToken(ctx context.Context, c client.Client, server client.Object) (string, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: server.GetNamespace(), Name: inventoryTokenSecret(server.GetName())}, secret); err != nil {
+		return "", err
+	}
+	return string(secret.Data[inventoryTokenKey]), nil
+}
+
+// inventoryURL returns the URL of the inventory served by the service of a server
+func inventoryURL(server client.Object) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", server.GetName(), server.GetNamespace(), inventory.Port)
+}
+
+// deviceName returns the name of the FDODevice of a voucher held by a server
+func deviceName(server, guid string) string {
+	return server + "-" + strings.ToLower(guid)
+}
+
+// reconcileVoucherInventory reads the inventory of the voucher store of a server, writes an
+// FDODevice for each voucher if the server has deviceObjects and deletes the other devices of the
+// server. The previous status is kept if the inventory can't be read, e.g. while the server is starting.
+// The token of the voucher export API of the server, if any, authenticates the requests.
+func reconcileVoucherInventory(ctx context.Context, r *util.ReconcilerBase, httpClient *http.Client, server client.Object, kind string, svc FDOServiceType, url, token string, deviceObjects bool, previous *fdov1beta1.VoucherInventoryStatus) (*fdov1beta1.VoucherInventoryStatus, error) {
+	log := logf.FromContext(ctx).WithValues("server", server.GetName())
+	vouchers, err := inventory.Fetch(ctx, httpClient, url, token)
+	if err != nil {
+		log.Info("Voucher inventory not available", "error", err.Error())
+		return previous, nil
+	}
+
+	labels := getPodLabels(svc, server.GetName())
+	devices := &fdov1beta1.FDODeviceList{}
+	if err := r.GetClient().List(ctx, devices, client.InNamespace(server.GetNamespace()), client.MatchingLabels(labels)); err != nil {
+		return previous, err
+	}
+	stale := map[string]*fdov1beta1.FDODevice{}
+	for i := range devices.Items {
+		stale[devices.Items[i].Name] = &devices.Items[i]
+	}
+	if deviceObjects {
+		for _, d := range vouchers.Devices {
+			device := &fdov1beta1.FDODevice{ObjectMeta: metav1.ObjectMeta{Name: deviceName(server.GetName(), d.GUID), Namespace: server.GetNamespace()}}
+			delete(stale, device.Name)
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), device, func() error {
+				device.Labels = labels
+				device.Status = deviceStatus(kind, server.GetName(), d)
+				return ctrl.SetControllerReference(server, device, r.GetScheme())
+			}); err != nil {
+				return previous, err
+			}
+		}
+	}
+	for _, device := range stale {
+		if err := client.IgnoreNotFound(r.GetClient().Delete(ctx, device)); err != nil {
+			return previous, err
+		}
+	}
+	now := metav1.Now()
+	return &fdov1beta1.VoucherInventoryStatus{
+		Vouchers:     int32(len(vouchers.Devices)),
+		Invalid:      int32(len(vouchers.Invalid)),
+		LastScanTime: &now,
+	}, nil
+}
+
+func deviceStatus(kind, server string, d inventory.Device) fdov1beta1.FDODeviceStatus {
+	voucherTime := metav1.NewTime(d.ModTime)
+	return fdov1beta1.FDODeviceStatus{
+		GUID:            d.GUID,
+		DeviceInfo:      d.DeviceInfo,
+		SerialNumber:    d.SerialNumber,
+		Server:          fdov1beta1.DeviceServerReference{Kind: kind, Name: server},
+		ProtocolVersion: d.ProtocolVersion,
+		ManufacturerKey: fdov1beta1.VoucherKey(d.ManufacturerKey),
+		OwnerKey:        fdov1beta1.VoucherKey(d.OwnerKey),
+		Entries:         int32(d.Entries),
+		RendezvousInfo:  d.RendezvousInfo,
+		VoucherTime:     &voucherTime,
+	}
+}
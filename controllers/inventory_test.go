@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+var _ = Describe("Voucher inventory", func() {
+	var (
+		c      k8sclient.Client
+		ctx    context.Context
+		r      util.ReconcilerBase
+		server *fdov1beta1.FDOOnboardingServer
+		served *inventory.Inventory
+		url    string
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		c = newFakeClientBuilder(&fdov1beta1.FDODevice{ObjectMeta: metav1.ObjectMeta{
+			Name:      "onboarding-gone",
+			Namespace: "fdo",
+			Labels:    getPodLabels(OwnerOnboardingServiceType, "onboarding"),
+		}}).Build()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)
+		server = &fdov1beta1.FDOOnboardingServer{ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo", UID: "uid"}}
+		served = &inventory.Inventory{
+			Devices: []inventory.Device{{
+				GUID:            "01234567-89ab-cdef-0123-456789abcdef",
+				DeviceInfo:      "test-device",
+				ManufacturerKey: inventory.Key{Type: "SECP384R1", Encoding: "X5CHAIN", Fingerprint: "aa"},
+				OwnerKey:        inventory.Key{Type: "SECP384R1", Encoding: "X509", Fingerprint: "bb"},
+				Entries:         1,
+				ModTime:         time.Now(),
+			}},
+			Invalid: []string{"README"},
+		}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != inventory.Path {
+				http.NotFound(w, req)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(served)
+		}))
+		DeferCleanup(httpServer.Close)
+		url = httpServer.URL
+	})
+
+	It("should only serve the inventory of directory stores", func() {
+		Expect(inventorySidecar("operator", nil).Args).To(Equal([]string{"voucher-inventory", "--dir", "/etc/fdo/ownership_vouchers"}))
+		Expect(inventorySidecar("", nil)).To(BeNil())
+		Expect(inventorySidecar("operator", &fdov1beta1.DatabaseStorage{Managed: true})).To(BeNil())
+	})
+
+	It("should write a device per voucher and count the vouchers", func() {
+		status, err := reconcileVoucherInventory(ctx, &r, nil, server, "FDOOnboardingServer", OwnerOnboardingServiceType, url, "", true, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Vouchers).To(Equal(int32(1)))
+		Expect(status.Invalid).To(Equal(int32(1)))
+		Expect(status.LastScanTime).NotTo(BeNil())
+
+		devices := &fdov1beta1.FDODeviceList{}
+		Expect(c.List(ctx, devices)).To(Succeed())
+		Expect(devices.Items).To(HaveLen(1))
+		device := devices.Items[0]
+		Expect(device.Name).To(Equal("onboarding-01234567-89ab-cdef-0123-456789abcdef"))
+		Expect(device.Labels).To(HaveKeyWithValue("fdo-service", "owner-onboarding"))
+		Expect(device.OwnerReferences).To(HaveLen(1))
+		Expect(device.Status.Server).To(Equal(fdov1beta1.DeviceServerReference{Kind: "FDOOnboardingServer", Name: "onboarding"}))
+		Expect(device.Status.OwnerKey.Fingerprint).To(Equal("bb"))
+		Expect(device.Status.Entries).To(Equal(int32(1)))
+	})
+
+	It("should only write devices for servers with deviceObjects", func() {
+		status, err := reconcileVoucherInventory(ctx, &r, nil, server, "FDOOnboardingServer", OwnerOnboardingServiceType, url, "", false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Vouchers).To(Equal(int32(1)))
+
+		devices := &fdov1beta1.FDODeviceList{}
+		Expect(c.List(ctx, devices)).To(Succeed())
+		Expect(devices.Items).To(BeEmpty())
+	})
+
+	It("should keep the previous status if the inventory is not available", func() {
+		previous := &fdov1beta1.VoucherInventoryStatus{Vouchers: 3}
+		status, err := reconcileVoucherInventory(ctx, &r, nil, server, "FDOOnboardingServer", OwnerOnboardingServiceType, url+"/missing", "", true, previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(previous))
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "onboarding-gone", Namespace: "fdo"}, &fdov1beta1.FDODevice{})).To(Succeed())
+	})
+})
+
+var _ = Describe("Pod template", func() {
+	It("should not apply the server resources to the inventory sidecar", func() {
+		spec := &corev1.PodSpec{Containers: withInventorySidecar([]corev1.Container{{Name: "manufacturing"}}, inventorySidecar("operator:latest", nil))}
+		applyPodTemplate(spec, &fdov1beta1.PodTemplate{
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			NodeSelector: map[string]string{"fdo": "true"},
+		})
+		Expect(spec.Containers).To(HaveLen(2))
+		Expect(spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("2Gi"))
+		Expect(spec.Containers[1].Name).To(Equal(inventoryContainer))
+		Expect(spec.Containers[1].Resources.Limits).To(BeEmpty())
+		Expect(spec.NodeSelector).To(HaveKeyWithValue("fdo", "true"))
+	})
+})
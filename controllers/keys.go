@@ -0,0 +1,353 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+const keysDir = "/etc/fdo/keys"
+
+// keySecretsIndex is the field index of servers by the name of the secrets holding their keys and certificates
+const keySecretsIndex = ".spec.keys.secrets"
+
+// keyFile is a key or certificate read by an FDO server from a Secret, or a private key fetched from
+// an external key store
+type keyFile struct {
+	// Volume is the name of the pod volume holding the file
+	Volume string
+	// File is the name of the file in the keys directory
+	File string
+	// Secret and Key select the content of the file
+	Secret string
+	Key    string
+	// Bundle is the secret key of an optional bundle of trusted certificates, read instead of Key
+	// if the secret holds it, e.g. while an FDOKeySet rotates a certificate
+	Bundle string
+	// Source is the external key store of a private key, which is not read from a secret
+	Source *fdov1beta1.KeySource
+}
+
+// newKeyFile resolves a secret reference, the default secret is used if ref is nil and the file name
+// is used as the secret key if ref doesn't specify one
+func newKeyFile(volume, file, defaultSecret string, ref *fdov1beta1.SecretKeyReference) keyFile {
+	k := keyFile{Volume: volume, File: file, Secret: defaultSecret, Key: file}
+	if ref != nil {
+		k.Secret = ref.Name
+		if ref.Key != "" {
+			k.Key = ref.Key
+		}
+	}
+	return k
+}
+
+// newTrustedKeyFile resolves a secret reference like newKeyFile. The trust bundle of the certificate
+// is preferred unless ref selects a key.
+func newTrustedKeyFile(volume, file, defaultSecret string, ref *fdov1beta1.SecretKeyReference) keyFile {
+	k := newKeyFile(volume, file, defaultSecret, ref)
+	if ref == nil || ref.Key == "" {
+		k.Bundle = trustBundleKey(file)
+	}
+	return k
+}
+
+// trustBundleKey returns the secret key of the trust bundle of a certificate file,
+// e.g. device_ca_trusted_certs.pem for device_ca_cert.pem
+func trustBundleKey(file string) string {
+	return strings.TrimSuffix(file, "_cert.pem") + "_trusted_certs.pem"
+}
+
+// Path returns the path of the file in the server container
+func (k keyFile) Path() string {
+	return path.Join(keysDir, k.File)
+}
+
+// external returns whether the file is fetched from an external key store
+func (k keyFile) external() bool {
+	return k.Source != nil
+}
+
+func (k keyFile) volume() corev1.Volume {
+	if k.external() {
+		return corev1.Volume{
+			Name:         k.Volume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+		}
+	}
+	optional := false
+	return corev1.Volume{
+		Name: k.Volume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: k.Secret,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  k.Key,
+						Path: k.File,
+					},
+				},
+				Optional: &optional,
+			},
+		},
+	}
+}
+
+func (k keyFile) volumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      k.Volume,
+		MountPath: k.Path(),
+		SubPath:   k.File,
+		ReadOnly:  true,
+	}
+}
+
+func keyFileVolumes(files []keyFile) []corev1.Volume {
+	volumes := make([]corev1.Volume, len(files))
+	for i, f := range files {
+		volumes[i] = f.volume()
+	}
+	return volumes
+}
+
+func keyFileVolumeMounts(files []keyFile) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, len(files))
+	for i, f := range files {
+		mounts[i] = f.volumeMount()
+	}
+	return mounts
+}
+
+func certRef(p *fdov1beta1.KeyPairReference) *fdov1beta1.SecretKeyReference {
+	if p == nil {
+		return nil
+	}
+	if p.Certificate != nil {
+		return &fdov1beta1.SecretKeyReference{Name: certificateKeysSecret(p.Certificate.Name), Key: certificateCertKey}
+	}
+	if p.TLSSecret != nil {
+		return &fdov1beta1.SecretKeyReference{Name: p.TLSSecret.Name, Key: corev1.TLSCertKey}
+	}
+	return p.Cert
+}
+
+func keyRef(p *fdov1beta1.KeyPairReference) *fdov1beta1.SecretKeyReference {
+	if p == nil {
+		return nil
+	}
+	if p.Certificate != nil {
+		return &fdov1beta1.SecretKeyReference{Name: certificateKeysSecret(p.Certificate.Name), Key: certificateKeyKey}
+	}
+	if p.TLSSecret != nil {
+		return &fdov1beta1.SecretKeyReference{Name: p.TLSSecret.Name, Key: corev1.TLSPrivateKeyKey}
+	}
+	return p.Key
+}
+
+// withKeySource fetches the private key of a key pair from its external key store, if any
+func withKeySource(k keyFile, p *fdov1beta1.KeyPairReference) keyFile {
+	if p != nil && p.KeySource != nil {
+		k.Secret, k.Key, k.Source = "", "", p.KeySource
+	}
+	return k
+}
+
+// trustBundleRef references the secret of an FDOTrustBundle, or returns ref if no trust bundle is set
+func trustBundleRef(bundle *fdov1beta1.TrustBundleReference, ref *fdov1beta1.SecretKeyReference) *fdov1beta1.SecretKeyReference {
+	if bundle == nil {
+		return ref
+	}
+	return &fdov1beta1.SecretKeyReference{Name: trustBundleSecret(bundle.Name), Key: trustBundleSecretKey}
+}
+
+// manufacturingKeyFiles lists the keys and certificates read by a manufacturing server
+func manufacturingKeyFiles(keys *fdov1beta1.ManufacturingKeys) []keyFile {
+	if keys == nil {
+		keys = &fdov1beta1.ManufacturingKeys{}
+	}
+	return []keyFile{
+		newKeyFile("diun-cert", "diun_cert.pem", "fdo-diun-cert", certRef(keys.DIUN)),
+		withKeySource(newKeyFile("diun-key", "diun_key.der", "fdo-diun-key", keyRef(keys.DIUN)), keys.DIUN),
+		newKeyFile("manufacturer-cert", "manufacturer_cert.pem", "fdo-manufacturer-cert", certRef(keys.Manufacturer)),
+		withKeySource(newKeyFile("manufacturer-key", "manufacturer_key.der", "fdo-manufacturer-key", keyRef(keys.Manufacturer)), keys.Manufacturer),
+		newKeyFile("owner-cert", "owner_cert.pem", "fdo-owner-cert", keys.OwnerCert),
+		newKeyFile("device-ca-chain", "device_ca_cert.pem", "fdo-device-ca-cert", certRef(keys.DeviceCA)),
+		withKeySource(newKeyFile("device-ca-key", "device_ca_key.der", "fdo-device-ca-key", keyRef(keys.DeviceCA)), keys.DeviceCA),
+	}
+}
+
+// onboardingKeyFiles lists the keys and certificates read by an owner-onboarding server
+func onboardingKeyFiles(keys *fdov1beta1.OnboardingKeys) []keyFile {
+	if keys == nil {
+		keys = &fdov1beta1.OnboardingKeys{}
+	}
+	return []keyFile{
+		newKeyFile("owner-cert", "owner_cert.pem", "fdo-owner-cert", certRef(keys.Owner)),
+		withKeySource(newKeyFile("owner-key", "owner_key.der", "fdo-owner-key", keyRef(keys.Owner)), keys.Owner),
+		newTrustedKeyFile("device-ca-chain", "device_ca_cert.pem", "fdo-device-ca-cert", trustBundleRef(keys.DeviceCATrustBundle, keys.DeviceCACert)),
+	}
+}
+
+// rendezvousKeyFiles lists the certificates read by a rendezvous server
+func rendezvousKeyFiles(keys *fdov1beta1.RendezvousKeys) []keyFile {
+	if keys == nil {
+		keys = &fdov1beta1.RendezvousKeys{}
+	}
+	return []keyFile{
+		newTrustedKeyFile("manufacturer-cert", "manufacturer_cert.pem", "fdo-manufacturer-cert", trustBundleRef(keys.ManufacturerTrustBundle, keys.ManufacturerCert)),
+	}
+}
+
+// resolveTrustBundles reads the trust bundle of the files that have one in their secret
+func resolveTrustBundles(ctx context.Context, c client.Reader, namespace string, files []keyFile) ([]keyFile, error) {
+	resolved := make([]keyFile, len(files))
+	for i, f := range files {
+		resolved[i] = f
+		if f.Bundle == "" {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: f.Secret}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if _, ok := secret.Data[f.Bundle]; ok {
+			resolved[i].Key = f.Bundle
+		}
+	}
+	return resolved, nil
+}
+
+// derivedKeysSecret returns the name of the secret holding the private keys of a server converted to DER
+func derivedKeysSecret(server string) string {
+	return server + "-derived-keys"
+}
+
+// deriveDERKeys converts the private keys in PEM format of the given key files to DER. The converted keys
+// are written to a secret owned by the server, which replaces the source secrets of the key files.
+func deriveDERKeys(ctx context.Context, r *util.ReconcilerBase, server client.Object, files []keyFile) ([]keyFile, error) {
+	log := logf.FromContext(ctx)
+	resolved := make([]keyFile, len(files))
+	data := map[string][]byte{}
+	for i, f := range files {
+		resolved[i] = f
+		if path.Ext(f.File) != ".der" || f.external() {
+			continue
+		}
+		source := &corev1.Secret{}
+		if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: server.GetNamespace(), Name: f.Secret}, source); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		value, ok := source.Data[f.Key]
+		if !ok || !bytes.HasPrefix(bytes.TrimSpace(value), []byte("-----BEGIN")) {
+			continue
+		}
+		key, err := keys.ParsePEMKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s, key %s: %w", f.Secret, f.Key, err)
+		}
+		if data[f.File], err = keys.MarshalKey(key); err != nil {
+			return nil, err
+		}
+		resolved[i].Secret = derivedKeysSecret(server.GetName())
+		resolved[i].Key = f.File
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: derivedKeysSecret(server.GetName()), Namespace: server.GetNamespace()}}
+	if len(data) == 0 {
+		// a key converted earlier may have been replaced by a key in DER format
+		if err := r.GetClient().Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+			return resolved, client.IgnoreNotFound(err)
+		}
+		return resolved, r.DeleteResourceIfExists(ctx, secret)
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), secret, func() error {
+		secret.Data = data
+		return ctrl.SetControllerReference(server, secret, r.GetScheme())
+	})
+	if err != nil {
+		log.Error(err, "Secret reconcile failed", "secret", secret.Name)
+		return nil, err
+	}
+	log.Info("Secret successfully reconciled", "secret", secret.Name, "operation", op)
+	return resolved, nil
+}
+
+// keySecretNames returns the names of the secrets holding the given key files, for the keySecretsIndex
+func keySecretNames(files []keyFile) []string {
+	names := []string{}
+	for _, f := range files {
+		if !f.external() && !containsString(names, f.Secret) {
+			names = append(names, f.Secret)
+		}
+	}
+	return names
+}
+
+// requestsForSecret maps a secret to reconcile requests of the servers of the list type that reference it
+func requestsForSecret(ctx context.Context, c client.Reader, list client.ObjectList, secret client.Object) []reconcile.Request {
+	return requestsForIndex(ctx, c, list, secret.GetNamespace(), keySecretsIndex, secret.GetName())
+}
+
+// requestsForIndex maps a value of a field index to reconcile requests of the servers of the list type
+func requestsForIndex(ctx context.Context, c client.Reader, list client.ObjectList, namespace, index, value string) []reconcile.Request {
+	return requestsForList(ctx, c, list, client.InNamespace(namespace), client.MatchingFields{index: value})
+}
+
+// requestsForList maps the servers of the list type matching the options to reconcile requests
+func requestsForList(ctx context.Context, c client.Reader, list client.ObjectList, opts ...client.ListOption) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	if err := c.List(ctx, list, opts...); err != nil {
+		log.Error(err, "Failed to list servers")
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		log.Error(err, "Failed to list servers")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}})
+		}
+	}
+	return requests
+}
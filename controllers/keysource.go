@@ -0,0 +1,102 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fdo-rs/fdo-operator/internal/keysource"
+)
+
+// keySourcesDir is where the init container mounts the volumes of the keys fetched from key stores
+const keySourcesDir = "/var/run/fdo-keys"
+
+// keySourceInitContainers returns the init container fetching the keys of external key stores into
+// their memory-backed volumes, none if all keys are read from secrets. The container runs the
+// operator image.
+func keySourceInitContainers(image string, files []keyFile) ([]corev1.Container, error) {
+	config := []keysource.Key{}
+	env := []corev1.EnvVar{}
+	mounts := []corev1.VolumeMount{}
+	for _, f := range files {
+		if !f.external() {
+			continue
+		}
+		dir := path.Join(keySourcesDir, f.Volume)
+		key := keysource.Key{Path: path.Join(dir, f.File), Source: *f.Source}
+		if vault := f.Source.Vault; vault != nil {
+			if vault.Auth.TokenSecret != nil {
+				key.TokenEnv = fmt.Sprintf("VAULT_TOKEN_%d", len(config))
+				env = append(env, secretEnvVar(key.TokenEnv, vault.Auth.TokenSecret.Name, vault.Auth.TokenSecret.Key, "token"))
+			}
+			if vault.CACert != nil {
+				key.CACertEnv = fmt.Sprintf("VAULT_CACERT_%d", len(config))
+				env = append(env, secretEnvVar(key.CACertEnv, vault.CACert.Name, vault.CACert.Key, "ca.crt"))
+			}
+		}
+		config = append(config, key)
+		mounts = append(mounts, corev1.VolumeMount{Name: f.Volume, MountPath: dir})
+	}
+	if len(config) == 0 {
+		return nil, nil
+	}
+	if image == "" {
+		return nil, errors.New("the operator image is not set, keys can't be fetched from key stores")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	privilegeEscalation := false
+	return []corev1.Container{{
+		Name:         "fetch-keys",
+		Image:        image,
+		Command:      []string{"/manager", keysource.Command},
+		Env:          append([]corev1.EnvVar{{Name: keysource.ConfigEnv, Value: string(data)}}, env...),
+		VolumeMounts: mounts,
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &privilegeEscalation,
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{
+					"ALL",
+				},
+			},
+		},
+	}}, nil
+}
+
+// secretEnvVar reads an environment variable from a key of a secret, defaultKey if key is empty
+func secretEnvVar(name, secret, key, defaultKey string) corev1.EnvVar {
+	if key == "" {
+		key = defaultKey
+	}
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+				Key:                  key,
+			},
+		},
+	}
+}
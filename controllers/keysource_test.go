@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keysource"
+)
+
+var _ = Describe("Key sources", func() {
+	var files []keyFile
+
+	BeforeEach(func() {
+		files = onboardingKeyFiles(&fdov1beta1.OnboardingKeys{
+			Owner: &fdov1beta1.KeyPairReference{KeySource: &fdov1beta1.KeySource{Vault: &fdov1beta1.VaultKeySource{
+				Address: "https://vault.example.com:8200",
+				CACert:  &fdov1beta1.SecretKeyReference{Name: "vault-ca"},
+				Auth:    fdov1beta1.VaultAuth{TokenSecret: &fdov1beta1.SecretKeyReference{Name: "vault-token", Key: "fdo"}},
+				KV:      &fdov1beta1.VaultKV{Path: "fdo/owner"},
+			}}},
+		})
+	})
+
+	It("should fetch the private key into a memory-backed volume", func() {
+		Expect(files[1].external()).To(BeTrue())
+		Expect(files[1].Secret).To(BeEmpty())
+		volume := files[1].volume()
+		Expect(volume.Secret).To(BeNil())
+		Expect(volume.EmptyDir.Medium).To(Equal(corev1.StorageMediumMemory))
+		Expect(keySecretNames(files)).NotTo(ContainElement(""))
+	})
+
+	It("should run the operator image in an init container", func() {
+		containers, err := keySourceInitContainers("quay.io/fdo/operator:latest", files)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Command).To(Equal([]string{"/manager", keysource.Command}))
+		Expect(containers[0].VolumeMounts).To(Equal([]corev1.VolumeMount{{Name: "owner-key", MountPath: "/var/run/fdo-keys/owner-key"}}))
+
+		env := containers[0].Env
+		Expect(env).To(HaveLen(3))
+		config := []keysource.Key{}
+		Expect(json.Unmarshal([]byte(env[0].Value), &config)).To(Succeed())
+		Expect(config).To(HaveLen(1))
+		Expect(config[0].Path).To(Equal("/var/run/fdo-keys/owner-key/owner_key.der"))
+		Expect(config[0].TokenEnv).To(Equal(env[1].Name))
+		Expect(env[1].ValueFrom.SecretKeyRef.Key).To(Equal("fdo"))
+		Expect(config[0].CACertEnv).To(Equal(env[2].Name))
+		Expect(env[2].ValueFrom.SecretKeyRef.Key).To(Equal("ca.crt"))
+	})
+
+	It("should require the operator image only for key sources", func() {
+		_, err := keySourceInitContainers("", files)
+		Expect(err).To(HaveOccurred())
+
+		containers, err := keySourceInitContainers("", onboardingKeyFiles(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containers).To(BeEmpty())
+	})
+})
@@ -0,0 +1,128 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+// ownershipVoucherPath is the path of the OV management API of the owner-onboarding server
+const ownershipVoucherPath = "/management/v1/ownership_voucher"
+
+// errVoucherExists is returned when the server already holds the imported voucher
+var errVoucherExists = errors.New("the onboarding server already holds the voucher")
+
+// voucherManagementClient imports ownership vouchers into an owner-onboarding server and removes them
+type voucherManagementClient struct {
+	HTTPClient *http.Client
+	URL        string
+	// Token is sent as a bearer token, if set
+	Token string
+}
+
+// managementURL returns the URL of the OV management API served by the service of an onboarding server
+func managementURL(name, namespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc:8081", name, namespace)
+}
+
+// importVoucher posts a voucher in PEM or CBOR format to the server and returns the GUID of its device
+func (c *voucherManagementClient) importVoucher(ctx context.Context, voucher []byte) (string, error) {
+	contentType := "application/cbor"
+	if bytes.HasPrefix(bytes.TrimSpace(voucher), []byte("-----BEGIN")) {
+		contentType = "application/x-pem-file"
+	}
+	body, err := c.post(ctx, ownershipVoucherPath, contentType, voucher, map[string]string{"X-Number-Of-Vouchers": "1"})
+	var statusErr *managementError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusConflict || strings.Contains(strings.ToLower(statusErr.Body), "already exist")) {
+		return "", fmt.Errorf("%w: %s", errVoucherExists, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	imported := []struct {
+		GUID string `json:"guid"`
+	}{}
+	if err := json.Unmarshal(body, &imported); err != nil {
+		return "", fmt.Errorf("unexpected response of the onboarding server: %w", err)
+	}
+	if len(imported) != 1 || imported[0].GUID == "" {
+		return "", fmt.Errorf("the onboarding server imported %d vouchers instead of 1", len(imported))
+	}
+	return imported[0].GUID, nil
+}
+
+// deleteVouchers removes the vouchers of devices from the server
+func (c *voucherManagementClient) deleteVouchers(ctx context.Context, guids ...string) error {
+	data, err := json.Marshal(guids)
+	if err != nil {
+		return err
+	}
+	_, err = c.post(ctx, ownershipVoucherPath+"/delete", "application/json", data, nil)
+	return err
+}
+
+func (c *voucherManagementClient) post(ctx context.Context, path, contentType string, data []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.URL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = inventory.DefaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &managementError{Method: req.Method, Path: path, StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+// managementError is the error status returned by the OV management API
+type managementError struct {
+	Method, Path string
+	StatusCode   int
+	Status       string
+	Body         string
+}
+
+func (e *managementError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.Path, e.Status, e.Body)
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+// registrationExpiryBounds are the bounds of the expiry histogram of the registrations
+var registrationExpiryBounds = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+var (
+	registrationsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fdo_rendezvous_registrations",
+		Help: "Number of device registrations held by a rendezvous server",
+	}, []string{"namespace", "server"})
+	registrationsExpiredGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fdo_rendezvous_registrations_expired",
+		Help: "Number of registrations of a rendezvous server past their expiry time",
+	}, []string{"namespace", "server"})
+	registrationsExpiringGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fdo_rendezvous_registrations_expiring_within",
+		Help: "Number of registrations of a rendezvous server expiring within a duration, e.g. 24h",
+	}, []string{"namespace", "server", "within"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(registrationsGauge, registrationsExpiredGauge, registrationsExpiringGauge)
+}
+
+// registrationSidecar returns the container serving the inventory of the registrations of a
+// directory store, or nil if the registrations are stored in a database or the operator image is
+// unknown
+func registrationSidecar(image string, db *fdov1beta1.DatabaseStorage) *corev1.Container {
+	sidecar := inventorySidecar(image, db)
+	if sidecar == nil {
+		return nil
+	}
+	sidecar.Args = []string{inventory.Command, "--registrations", "--dir", "/etc/fdo/registered"}
+	sidecar.VolumeMounts = []corev1.VolumeMount{{
+		Name:      "registered",
+		MountPath: "/etc/fdo/registered",
+		ReadOnly:  true,
+	}}
+	return sidecar
+}
+
+// reconcileRegistrationInventory reads the inventory of the registration store of a rendezvous
+// server, publishes its metrics and writes the registration objects of the server. The previous
+// status is kept if the inventory can't be read, e.g. while the server is starting.
+func reconcileRegistrationInventory(ctx context.Context, r *util.ReconcilerBase, httpClient *http.Client, server *fdov1beta1.FDORendezvousServer, url string, now time.Time) (*fdov1beta1.RegistrationInventoryStatus, error) {
+	log := logf.FromContext(ctx).WithValues("server", server.GetName())
+	previous := server.Status.Inventory
+	registrations, err := inventory.FetchRegistrations(ctx, httpClient, url)
+	if err != nil {
+		log.Info("Registration inventory not available", "error", err.Error())
+		return previous, nil
+	}
+	if err := reconcileRegistrationObjects(ctx, r, server, registrations); err != nil {
+		return previous, err
+	}
+	status := registrationInventoryStatus(registrations, now)
+	setRegistrationMetrics(server.Namespace, server.Name, status)
+	return status, nil
+}
+
+// registrationInventoryStatus counts the registrations of an inventory by time left until they expire
+func registrationInventoryStatus(registrations *inventory.RegistrationInventory, now time.Time) *fdov1beta1.RegistrationInventoryStatus {
+	scanTime := metav1.NewTime(now)
+	status := &fdov1beta1.RegistrationInventoryStatus{
+		Registrations: int32(len(registrations.Registrations)),
+		Invalid:       int32(len(registrations.Invalid)),
+		LastScanTime:  &scanTime,
+	}
+	for _, bound := range registrationExpiryBounds {
+		status.Expiry = append(status.Expiry, fdov1beta1.RegistrationExpiryBucket{Within: &metav1.Duration{Duration: bound}})
+	}
+	status.Expiry = append(status.Expiry, fdov1beta1.RegistrationExpiryBucket{})
+	for _, registration := range registrations.Registrations {
+		if registration.ExpiryTime == nil {
+			status.Expiry[len(registrationExpiryBounds)].Count++
+			continue
+		}
+		left := registration.ExpiryTime.Sub(now)
+		if left <= 0 {
+			status.Expired++
+			continue
+		}
+		if status.NextExpiryTime == nil || registration.ExpiryTime.Before(status.NextExpiryTime.Time) {
+			next := metav1.NewTime(*registration.ExpiryTime)
+			status.NextExpiryTime = &next
+		}
+		i := 0
+		for i < len(registrationExpiryBounds) && left > registrationExpiryBounds[i] {
+			i++
+		}
+		status.Expiry[i].Count++
+	}
+	return status
+}
+
+// setRegistrationMetrics publishes the registration inventory of a server. The registrations
+// expiring within each bound of the expiry buckets include those of the shorter bounds, the
+// registrations expiring later being the others.
+func setRegistrationMetrics(namespace, name string, status *fdov1beta1.RegistrationInventoryStatus) {
+	registrationsGauge.WithLabelValues(namespace, name).Set(float64(status.Registrations))
+	registrationsExpiredGauge.WithLabelValues(namespace, name).Set(float64(status.Expired))
+	count := int32(0)
+	for _, bucket := range status.Expiry {
+		if bucket.Within == nil {
+			continue
+		}
+		count += bucket.Count
+		within := strconv.FormatFloat(bucket.Within.Hours(), 'f', -1, 64) + "h"
+		registrationsExpiringGauge.WithLabelValues(namespace, name, within).Set(float64(count))
+	}
+}
+
+// deleteRegistrationMetrics removes the metrics of a server without registration inventory
+func deleteRegistrationMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "server": name}
+	registrationsGauge.Delete(labels)
+	registrationsExpiredGauge.Delete(labels)
+	registrationsExpiringGauge.DeletePartialMatch(labels)
+}
+
+// reconcileRegistrationObjects writes an FDORendezvousRegistration for each registration of the
+// inventory of a server with registrationObjects, and deletes the other registration objects of
+// the server, all of them if there is no inventory
+func reconcileRegistrationObjects(ctx context.Context, r *util.ReconcilerBase, server *fdov1beta1.FDORendezvousServer, registrations *inventory.RegistrationInventory) error {
+	labels := getPodLabels(RendezvousServiceType, server.Name)
+	objects := &fdov1beta1.FDORendezvousRegistrationList{}
+	if err := r.GetClient().List(ctx, objects, client.InNamespace(server.Namespace), client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+	stale := map[string]*fdov1beta1.FDORendezvousRegistration{}
+	for i := range objects.Items {
+		stale[objects.Items[i].Name] = &objects.Items[i]
+	}
+	if server.Spec.RegistrationObjects && registrations != nil {
+		for _, registration := range registrations.Registrations {
+			object := &fdov1beta1.FDORendezvousRegistration{ObjectMeta: metav1.ObjectMeta{Name: deviceName(server.Name, registration.GUID), Namespace: server.Namespace}}
+			delete(stale, object.Name)
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), object, func() error {
+				object.Labels = labels
+				object.Status = registrationStatus(server.Name, registration)
+				return ctrl.SetControllerReference(server, object, r.GetScheme())
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, object := range stale {
+		if err := client.IgnoreNotFound(r.GetClient().Delete(ctx, object)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registrationStatus(server string, registration inventory.Registration) fdov1beta1.FDORendezvousRegistrationStatus {
+	registrationTime := metav1.NewTime(registration.ModTime)
+	status := fdov1beta1.FDORendezvousRegistrationStatus{
+		GUID:             registration.GUID,
+		Server:           server,
+		OwnerKey:         fdov1beta1.VoucherKey(registration.OwnerKey),
+		Addresses:        registration.Addresses,
+		RegistrationTime: &registrationTime,
+	}
+	if registration.ExpiryTime != nil {
+		expiryTime := metav1.NewTime(*registration.ExpiryTime)
+		status.ExpiryTime = &expiryTime
+	}
+	return status
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	dto "github.com/prometheus/client_model/go"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+var _ = Describe("Registration inventory", func() {
+	var (
+		c      k8sclient.Client
+		ctx    context.Context
+		r      util.ReconcilerBase
+		server *fdov1beta1.FDORendezvousServer
+		served *inventory.RegistrationInventory
+		url    string
+		now    time.Time
+	)
+
+	expiring := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	gauge := func(within string) float64 {
+		metric := &dto.Metric{}
+		Expect(registrationsExpiringGauge.WithLabelValues("fdo", "rendezvous", within).Write(metric)).To(Succeed())
+		return metric.GetGauge().GetValue()
+	}
+
+	registrations := func() map[string]fdov1beta1.FDORendezvousRegistration {
+		list := &fdov1beta1.FDORendezvousRegistrationList{}
+		Expect(c.List(ctx, list)).To(Succeed())
+		registrations := map[string]fdov1beta1.FDORendezvousRegistration{}
+		for _, registration := range list.Items {
+			registrations[registration.Name] = registration
+		}
+		return registrations
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		c = newFakeClientBuilder(&fdov1beta1.FDORendezvousRegistration{ObjectMeta: metav1.ObjectMeta{
+			Name:      "rendezvous-gone",
+			Namespace: "fdo",
+			Labels:    getPodLabels(RendezvousServiceType, "rendezvous"),
+		}}).Build()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, nil, nil)
+		now = time.Now()
+		server = &fdov1beta1.FDORendezvousServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "rendezvous", Namespace: "fdo", UID: "uid"},
+			Spec:       fdov1beta1.FDORendezvousServerSpec{RegistrationObjects: true},
+		}
+		served = &inventory.RegistrationInventory{
+			Registrations: []inventory.Registration{
+				{GUID: "guid-1", OwnerKey: inventory.Key{Type: "SECP256R1", Encoding: "X509", Fingerprint: "aa"}, Addresses: []string{"dns=owner.example.com port=8081 protocol=http"}, ExpiryTime: expiring(30 * time.Minute)},
+				{GUID: "guid-2", ExpiryTime: expiring(48 * time.Hour)},
+				{GUID: "guid-3", ExpiryTime: expiring(-time.Minute)},
+				{GUID: "guid-4"},
+			},
+			Invalid: []string{"README"},
+		}
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != inventory.RegistrationsPath {
+				http.NotFound(w, req)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(served)
+		}))
+		DeferCleanup(httpServer.Close)
+		url = httpServer.URL
+	})
+
+	It("should serve the registrations of directory stores", func() {
+		sidecar := registrationSidecar("operator", nil)
+		Expect(sidecar.Args).To(Equal([]string{"voucher-inventory", "--registrations", "--dir", "/etc/fdo/registered"}))
+		Expect(sidecar.VolumeMounts[0].Name).To(Equal("registered"))
+		Expect(registrationSidecar("operator", &fdov1beta1.DatabaseStorage{Managed: true})).To(BeNil())
+	})
+
+	It("should count the registrations by time left until they expire", func() {
+		status, err := reconcileRegistrationInventory(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Registrations).To(Equal(int32(4)))
+		Expect(status.Expired).To(Equal(int32(1)))
+		Expect(status.Invalid).To(Equal(int32(1)))
+		counts := []int32{}
+		for _, bucket := range status.Expiry {
+			counts = append(counts, bucket.Count)
+		}
+		Expect(counts).To(Equal([]int32{1, 0, 1, 0, 1}))
+		Expect(status.Expiry[4].Within).To(BeNil())
+		Expect(status.NextExpiryTime.Time).To(BeTemporally("~", now.Add(30*time.Minute), time.Second))
+
+		Expect(gauge("1h")).To(Equal(float64(1)))
+		Expect(gauge("168h")).To(Equal(float64(2)))
+		Expect(gauge("720h")).To(Equal(float64(2)))
+		deleteRegistrationMetrics("fdo", "rendezvous")
+		Expect(gauge("1h")).To(BeZero())
+	})
+
+	It("should write a registration object per registration", func() {
+		_, err := reconcileRegistrationInventory(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registrations()).To(HaveLen(4))
+		registration := registrations()["rendezvous-guid-1"]
+		Expect(registration.Labels).To(HaveKeyWithValue("fdo-service", "rendezvous"))
+		Expect(registration.OwnerReferences).To(HaveLen(1))
+		Expect(registration.Status.Server).To(Equal("rendezvous"))
+		Expect(registration.Status.OwnerKey.Fingerprint).To(Equal("aa"))
+		Expect(registration.Status.Addresses).To(Equal([]string{"dns=owner.example.com port=8081 protocol=http"}))
+		Expect(registration.Status.ExpiryTime).NotTo(BeNil())
+		Expect(registrations()["rendezvous-guid-4"].Status.ExpiryTime).To(BeNil())
+
+		server.Spec.RegistrationObjects = false
+		_, err = reconcileRegistrationInventory(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registrations()).To(BeEmpty())
+	})
+
+	It("should keep the previous status if the inventory is not available", func() {
+		server.Status.Inventory = &fdov1beta1.RegistrationInventoryStatus{Registrations: 3}
+		status, err := reconcileRegistrationInventory(ctx, &r, nil, server, url+"/missing", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(server.Status.Inventory))
+		Expect(registrations()).To(HaveKey("rendezvous-gone"))
+	})
+})
@@ -0,0 +1,194 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+const (
+	// retentionInterval is the default interval between two runs of a retention policy
+	retentionInterval = time.Hour
+	// retentionArchiveVolume is the volume of the archive claim, mounted at retentionArchivePath
+	retentionArchiveVolume = "voucher-archive"
+	retentionArchivePath   = "/etc/fdo/archive"
+	// retentionStateVolume is the volume recording when the devices were first seen onboarded,
+	// mounted at retentionStatePath
+	retentionStateVolume = "retention-state"
+	retentionStatePath   = "/var/lib/fdo/retention"
+	// archivedFromLabel labels the secrets of archived vouchers with the name of their server
+	archivedFromLabel = "fdo.redhat.com/archived-from"
+)
+
+// archivedVoucherSecret returns the name of the secret archiving the voucher of a device
+func archivedVoucherSecret(server, guid string) string {
+	return server + "-voucher-" + guid
+}
+
+// retentionArchiveClaim returns the claim receiving the vouchers pruned from a server, if any
+func retentionArchiveClaim(retention *fdov1beta1.VoucherRetention) string {
+	if retention == nil || retention.Action != fdov1beta1.VoucherRetentionArchive || retention.Archive == nil {
+		return ""
+	}
+	return retention.Archive.PersistentVolumeClaim
+}
+
+// withRetention allows the inventory sidecar, if any, to prune the vouchers of a server with a
+// retention policy: the store is mounted read-write, along with the state and archive claims. The
+// sidecar authenticates the operator with the inventory token of the server.
+func withRetention(sidecar *corev1.Container, server *fdov1beta1.FDOOnboardingServer) *corev1.Container {
+	if sidecar == nil || server.Spec.Retention == nil {
+		return sidecar
+	}
+	sidecar.Args = append(sidecar.Args, "--retention", "--state-dir", retentionStatePath)
+	for i := range sidecar.VolumeMounts {
+		sidecar.VolumeMounts[i].ReadOnly = false
+	}
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{Name: retentionStateVolume, MountPath: retentionStatePath})
+	if retentionArchiveClaim(server.Spec.Retention) != "" {
+		sidecar.Args = append(sidecar.Args, "--archive-dir", retentionArchivePath)
+		sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{Name: retentionArchiveVolume, MountPath: retentionArchivePath})
+	}
+	return sidecar
+}
+
+// retentionVolumes returns the state volume and the archive volume, if any, mounted by the inventory
+// sidecar of a server with a retention policy
+func retentionVolumes(sidecar *corev1.Container, server *fdov1beta1.FDOOnboardingServer) []corev1.Volume {
+	if sidecar == nil || server.Spec.Retention == nil {
+		return nil
+	}
+	volumes := []corev1.Volume{{
+		Name: retentionStateVolume,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: server.Spec.Retention.StateClaimName},
+		},
+	}}
+	if claim := retentionArchiveClaim(server.Spec.Retention); claim != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: retentionArchiveVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		})
+	}
+	return volumes
+}
+
+// reconcileRetention runs the retention policy of a server once it is due: the inventory sidecar
+// lists the vouchers of the devices onboarded for longer than the retention period, which are
+// archived to secrets if requested and pruned by the sidecar. A failed run is retried with the
+// next inventory scan.
+func reconcileRetention(ctx context.Context, r *util.ReconcilerBase, httpClient *http.Client, server *fdov1beta1.FDOOnboardingServer, url string, now time.Time) (*fdov1beta1.VoucherRetentionStatus, error) {
+	retention := server.Spec.Retention
+	previous := server.Status.Retention
+	if retention == nil {
+		return nil, nil
+	}
+	status := &fdov1beta1.VoucherRetentionStatus{}
+	if previous != nil {
+		if previous.NextRunTime != nil && now.Before(previous.NextRunTime.Time) {
+			return previous, nil
+		}
+		status.Onboarded = previous.Onboarded
+		status.Reclaimed = previous.Reclaimed
+	}
+	log := logf.FromContext(ctx).WithValues("server", server.Name)
+	lastRun := metav1.NewTime(now)
+	status.LastRunTime = &lastRun
+	interval := retentionInterval
+	if retention.Interval != nil {
+		interval = retention.Interval.Duration
+	}
+
+	reclaimed, onboarded, err := pruneVouchers(ctx, r, httpClient, server, url)
+	status.LastReclaimed = int32(reclaimed)
+	status.Reclaimed += int32(reclaimed)
+	if onboarded >= 0 {
+		status.Onboarded = int32(onboarded - reclaimed)
+	}
+	if reclaimed > 0 {
+		r.GetRecorder().Event(server, corev1.EventTypeNormal, fdov1beta1.ReasonVouchersReclaimed,
+			fmt.Sprintf("pruned %d vouchers of devices onboarded more than %d days ago", reclaimed, retention.Days))
+	}
+	if err != nil {
+		log.Error(err, "Voucher retention failed")
+		r.GetRecorder().Event(server, corev1.EventTypeWarning, fdov1beta1.ReasonRetentionFailed, err.Error())
+		status.LastError = err.Error()
+		if inventoryInterval < interval {
+			interval = inventoryInterval
+		}
+	}
+	nextRun := metav1.NewTime(now.Add(interval))
+	status.NextRunTime = &nextRun
+	return status, nil
+}
+
+// pruneVouchers prunes the expired vouchers of a server, and returns the number of pruned vouchers
+// and of vouchers of onboarded devices, -1 if unknown
+func pruneVouchers(ctx context.Context, r *util.ReconcilerBase, httpClient *http.Client, server *fdov1beta1.FDOOnboardingServer, url string) (int, int, error) {
+	token, err := inventoryToken(ctx, r.GetClient(), server)
+	if err != nil {
+		return 0, -1, err
+	}
+	keep := time.Duration(server.Spec.Retention.Days) * 24 * time.Hour
+	scan, err := inventory.FetchRetentionScan(ctx, httpClient, url, token, keep)
+	if err != nil {
+		return 0, -1, err
+	}
+	archive := server.Spec.Retention.Archive
+	toSecrets := server.Spec.Retention.Action == fdov1beta1.VoucherRetentionArchive && archive != nil && archive.Secrets
+	for i, guid := range scan.Expired {
+		if toSecrets {
+			if err := archiveVoucherSecret(ctx, r, httpClient, server, url, token, guid); err != nil {
+				return i, scan.Onboarded, fmt.Errorf("archive voucher %s: %w", guid, err)
+			}
+		}
+		if err := inventory.RemoveVoucher(ctx, httpClient, url, token, guid); err != nil {
+			return i, scan.Onboarded, fmt.Errorf("prune voucher %s: %w", guid, err)
+		}
+	}
+	return len(scan.Expired), scan.Onboarded, nil
+}
+
+// archiveVoucherSecret writes the voucher of a device to a secret, which is not owned by the server
+// so that the archive outlives it
+func archiveVoucherSecret(ctx context.Context, r *util.ReconcilerBase, httpClient *http.Client, server *fdov1beta1.FDOOnboardingServer, url, token, guid string) error {
+	data, err := inventory.FetchVoucher(ctx, httpClient, url, token, guid)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: archivedVoucherSecret(server.Name, guid), Namespace: server.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.GetClient(), secret, func() error {
+		secret.Labels = map[string]string{archivedFromLabel: server.Name}
+		secret.Data = map[string][]byte{ownershipVoucherSecretKey: data}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+)
+
+var _ = Describe("Voucher retention", func() {
+	var (
+		c       k8sclient.Client
+		ctx     context.Context
+		r       util.ReconcilerBase
+		server  *fdov1beta1.FDOOnboardingServer
+		url     string
+		keep    string
+		removed []string
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		c = newFakeClientBuilder(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "onboarding-inventory-token", Namespace: "fdo"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		}).Build()
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, record.NewFakeRecorder(10), nil)
+		now = time.Now()
+		removed = nil
+		server = &fdov1beta1.FDOOnboardingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo", UID: "uid"},
+			Spec: fdov1beta1.FDOOnboardingServerSpec{
+				Retention: &fdov1beta1.VoucherRetention{Days: 30, Action: fdov1beta1.VoucherRetentionDelete, StateClaimName: "retention-state"},
+			},
+		}
+		sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Header.Get("Authorization") != "Bearer s3cr3t" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			switch {
+			case req.URL.Path == inventory.RetentionPath:
+				keep = req.URL.Query().Get("keep")
+				_ = json.NewEncoder(w).Encode(inventory.RetentionScan{Onboarded: 3, Expired: []string{"guid-1", "guid-2"}})
+			case req.Method == http.MethodDelete:
+				removed = append(removed, strings.TrimPrefix(req.URL.Path, inventory.Path+"/"))
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				_, _ = w.Write([]byte("voucher of " + strings.TrimPrefix(req.URL.Path, inventory.Path+"/")))
+			}
+		}))
+		DeferCleanup(sidecar.Close)
+		url = sidecar.URL
+	})
+
+	It("should prune the expired vouchers and schedule the next run", func() {
+		status, err := reconcileRetention(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keep).To(Equal("720h0m0s"))
+		Expect(removed).To(Equal([]string{"guid-1", "guid-2"}))
+		Expect(status.LastReclaimed).To(Equal(int32(2)))
+		Expect(status.Reclaimed).To(Equal(int32(2)))
+		Expect(status.Onboarded).To(Equal(int32(1)))
+		Expect(status.LastError).To(BeEmpty())
+		Expect(status.NextRunTime.Time).To(BeTemporally("~", now.Add(time.Hour), time.Second))
+
+		server.Status.Retention = status
+		Expect(reconcileRetention(ctx, &r, nil, server, url, now.Add(time.Minute))).To(Equal(status))
+		status, err = reconcileRetention(ctx, &r, nil, server, url, now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Reclaimed).To(Equal(int32(4)))
+	})
+
+	It("should archive the vouchers to secrets before pruning them", func() {
+		server.Spec.Retention.Action = fdov1beta1.VoucherRetentionArchive
+		server.Spec.Retention.Archive = &fdov1beta1.VoucherArchive{Secrets: true}
+		_, err := reconcileRetention(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "onboarding-voucher-guid-1", Namespace: "fdo"}, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("voucher", []byte("voucher of guid-1")))
+		Expect(secret.Labels).To(HaveKeyWithValue(archivedFromLabel, "onboarding"))
+		Expect(secret.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should retry a failed run with the next inventory scan", func() {
+		Expect(c.Update(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "onboarding-inventory-token", Namespace: "fdo"},
+			Data:       map[string][]byte{"token": []byte("wrong")},
+		})).To(Succeed())
+		status, err := reconcileRetention(ctx, &r, nil, server, url, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.LastError).To(ContainSubstring("401"))
+		Expect(status.NextRunTime.Time).To(BeTemporally("~", now.Add(inventoryInterval), time.Second))
+	})
+
+	It("should let the sidecar prune the store", func() {
+		server.Spec.Retention.Action = fdov1beta1.VoucherRetentionArchive
+		server.Spec.Retention.Archive = &fdov1beta1.VoucherArchive{PersistentVolumeClaim: "archive"}
+		sidecar := withRetention(withInventoryToken(inventorySidecar("operator", nil), server), server)
+		Expect(sidecar.Args).To(Equal([]string{"voucher-inventory", "--dir", "/etc/fdo/ownership_vouchers", "--retention",
+			"--state-dir", "/var/lib/fdo/retention", "--archive-dir", "/etc/fdo/archive"}))
+		Expect(sidecar.VolumeMounts[0].ReadOnly).To(BeFalse())
+		Expect(sidecar.VolumeMounts[1].Name).To(Equal("retention-state"))
+		Expect(sidecar.Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("onboarding-inventory-token"))
+		volumes := retentionVolumes(sidecar, server)
+		Expect(volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("retention-state"))
+		Expect(volumes[1].PersistentVolumeClaim.ClaimName).To(Equal("archive"))
+
+		server.Spec.Retention = nil
+		Expect(withRetention(inventorySidecar("operator", nil), server).VolumeMounts[0].ReadOnly).To(BeTrue())
+		Expect(retentionVolumes(sidecar, server)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,47 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+// applyPodTemplate applies the user customizations of a server to its pod spec. The resources
+// are set on the server containers only, the inventory sidecar is left as the operator built it.
+func applyPodTemplate(spec *corev1.PodSpec, t *fdov1beta1.PodTemplate) {
+	if t == nil {
+		return
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == inventoryContainer {
+			continue
+		}
+		spec.Containers[i].Resources = *t.Resources.DeepCopy()
+	}
+	spec.NodeSelector = t.NodeSelector
+	spec.Tolerations = t.Tolerations
+}
+
+// routeHost returns the host requested for the route of a server, empty to let OpenShift generate one
+func routeHost(e *fdov1beta1.Expose) string {
+	if e == nil {
+		return ""
+	}
+	return e.Host
+}
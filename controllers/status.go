@@ -0,0 +1,258 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+// instanceLabel selects the pods of a single server
+const instanceLabel = "app.kubernetes.io/instance"
+
+// serverObject is a server resource with the common status
+type serverObject interface {
+	client.Object
+	GetServerStatus() *fdov1beta1.ServerStatus
+}
+
+// getPodLabels returns the labels of the pods running a server, a superset of the deployment selector
+func getPodLabels(svc FDOServiceType, name string) map[string]string {
+	labels := getLabels(svc)
+	labels[instanceLabel] = name
+	return labels
+}
+
+// manageError reports a failed reconciliation in the Degraded condition before handing the error over to the ReconcilerBase
+func manageError(ctx context.Context, r *util.ReconcilerBase, server serverObject, issue error, requeueAfter time.Duration) (ctrl.Result, error) {
+	status := server.GetServerStatus()
+	status.ObservedGeneration = server.GetGeneration()
+	setCondition(server, fdov1beta1.ConditionDegraded, metav1.ConditionTrue, fdov1beta1.ReasonReconcileFailed, issue.Error())
+	return r.ManageErrorWithRequeue(ctx, server, issue, requeueAfter)
+}
+
+// setCondition sets a condition of a server, and returns whether its status, reason or message changed
+func setCondition(server serverObject, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	conditions := &server.GetServerStatus().Conditions
+	previous := meta.FindStatusCondition(*conditions, conditionType)
+	changed := previous == nil || previous.Status != status || previous.Reason != reason || previous.Message != message
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: server.GetGeneration(),
+	})
+	return changed
+}
+
+// updateServerStatus fills in the status of a server from its deployment, route and pods, and returns
+// the image run by each container of the newest ready pod
+func updateServerStatus(ctx context.Context, c client.Client, server serverObject, svc FDOServiceType, deploy *appsv1.Deployment, route *routev1.Route) (map[string]string, error) {
+	status := server.GetServerStatus()
+	status.ObservedGeneration = server.GetGeneration()
+	status.Endpoints = routeEndpoints(route)
+	status.Replicas = 0
+	if deploy.Spec.Replicas != nil {
+		status.Replicas = *deploy.Spec.Replicas
+	}
+	status.ReadyReplicas = deploy.Status.ReadyReplicas
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(server.GetNamespace()), client.MatchingLabels(getPodLabels(svc, server.GetName()))); err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	status.Pods = nil
+	images := map[string]string{}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		status.Pods = append(status.Pods, pod.Name)
+		if len(images) == 0 && isPodReady(&pod) {
+			for _, cs := range pod.Status.ContainerStatuses {
+				images[cs.Name] = cs.Image
+			}
+		}
+	}
+	sort.Strings(status.Pods)
+
+	setDeploymentConditions(server, deploy)
+	return images, nil
+}
+
+// setDeploymentConditions sets the Available, Progressing and Degraded conditions from the state of the deployment
+func setDeploymentConditions(server serverObject, deploy *appsv1.Deployment) {
+	available := deploymentCondition(deploy, appsv1.DeploymentAvailable)
+	if available != nil && available.Status == corev1.ConditionTrue {
+		setCondition(server, fdov1beta1.ConditionAvailable, metav1.ConditionTrue, fdov1beta1.ReasonMinimumReplicasAvailable,
+			fmt.Sprintf("%d of %d pods are ready", deploy.Status.ReadyReplicas, server.GetServerStatus().Replicas))
+	} else {
+		message := "deployment has no ready pod"
+		if available != nil && available.Message != "" {
+			message = available.Message
+		}
+		setCondition(server, fdov1beta1.ConditionAvailable, metav1.ConditionFalse, fdov1beta1.ReasonDeploymentUnavailable, message)
+	}
+
+	progressing := deploymentCondition(deploy, appsv1.DeploymentProgressing)
+	switch {
+	case progressing != nil && progressing.Reason == "ProgressDeadlineExceeded":
+		setCondition(server, fdov1beta1.ConditionProgressing, metav1.ConditionFalse, fdov1beta1.ReasonProgressDeadlineExceeded, progressing.Message)
+		setCondition(server, fdov1beta1.ConditionDegraded, metav1.ConditionTrue, fdov1beta1.ReasonProgressDeadlineExceeded, progressing.Message)
+		return
+	case isRolloutComplete(deploy):
+		setCondition(server, fdov1beta1.ConditionProgressing, metav1.ConditionFalse, fdov1beta1.ReasonRolloutComplete, "all pods run the latest version of the server")
+	default:
+		setCondition(server, fdov1beta1.ConditionProgressing, metav1.ConditionTrue, fdov1beta1.ReasonRolloutInProgress,
+			fmt.Sprintf("%d of %d pods run the latest version of the server", deploy.Status.UpdatedReplicas, server.GetServerStatus().Replicas))
+	}
+	setCondition(server, fdov1beta1.ConditionDegraded, metav1.ConditionFalse, fdov1beta1.ReasonAsExpected, "")
+}
+
+// verifyKeyFiles checks that the secrets of all key files exist and hold the expected keys, and sets the
+// KeysReady and MissingKeyMaterial conditions. It returns the missing key material as secret/key pairs.
+func verifyKeyFiles(ctx context.Context, c client.Reader, server serverObject, files []keyFile) ([]string, error) {
+	missing := []string{}
+	secretMissing := false
+	for _, f := range files {
+		if f.external() {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: server.GetNamespace(), Name: f.Secret}, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if _, ok := secret.Data[f.Key]; !ok {
+			secretMissing = secretMissing || errors.IsNotFound(err)
+			missing = append(missing, fmt.Sprintf("%s/%s", f.Secret, f.Key))
+		}
+	}
+
+	if len(missing) == 0 {
+		setCondition(server, fdov1beta1.ConditionKeysReady, metav1.ConditionTrue, fdov1beta1.ReasonKeysFound, "all keys and certificates are found")
+		setCondition(server, fdov1beta1.ConditionMissingKeyMaterial, metav1.ConditionFalse, fdov1beta1.ReasonKeysFound, "")
+		return nil, nil
+	}
+	reason := fdov1beta1.ReasonKeyNotFound
+	if secretMissing {
+		reason = fdov1beta1.ReasonSecretNotFound
+	}
+	message := fmt.Sprintf("missing secret/key: %s", strings.Join(missing, ", "))
+	setCondition(server, fdov1beta1.ConditionKeysReady, metav1.ConditionFalse, reason, message)
+	setCondition(server, fdov1beta1.ConditionMissingKeyMaterial, metav1.ConditionTrue, reason, message)
+	return missing, nil
+}
+
+// manageMissingKeys reports missing key material without rolling out the server, which is
+// reconciled again once the secrets are created or updated. The warning Event is only emitted
+// when the missing key material changes, not on every reconcile.
+func manageMissingKeys(ctx context.Context, r *util.ReconcilerBase, server serverObject, missing []string) (ctrl.Result, error) {
+	message := fmt.Sprintf("missing secret/key: %s", strings.Join(missing, ", "))
+	server.GetServerStatus().ObservedGeneration = server.GetGeneration()
+	if setCondition(server, fdov1beta1.ConditionDegraded, metav1.ConditionTrue, fdov1beta1.ConditionMissingKeyMaterial, message) {
+		r.GetRecorder().Event(server, corev1.EventTypeWarning, fdov1beta1.ConditionMissingKeyMaterial, message)
+	}
+	if err := r.GetClient().Status().Update(ctx, server); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// manageSuccess updates the status of a server, which is reconciled again at next unless it is zero
+func manageSuccess(ctx context.Context, r *util.ReconcilerBase, server serverObject, next time.Time) (ctrl.Result, error) {
+	if next.IsZero() {
+		return r.ManageSuccess(ctx, server)
+	}
+	return r.ManageSuccessWithRequeue(ctx, server, time.Until(next))
+}
+
+func routeEndpoints(route *routev1.Route) []string {
+	if route == nil {
+		return nil
+	}
+	hosts := routeHosts(route)
+	endpoints := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		// Routes are not secured, see the OwnerAddresses of the owner-onboarding server
+		endpoints = append(endpoints, fmt.Sprintf("http://%s", host))
+	}
+	return endpoints
+}
+
+// routeHosts returns the host of a route followed by the hosts admitted by its routers
+func routeHosts(route *routev1.Route) []string {
+	hosts := []string{}
+	if route.Spec.Host != "" {
+		hosts = append(hosts, route.Spec.Host)
+	}
+	for _, ingress := range route.Status.Ingress {
+		if ingress.Host != "" && !containsString(hosts, ingress.Host) {
+			hosts = append(hosts, ingress.Host)
+		}
+	}
+	return hosts
+}
+
+func deploymentCondition(deploy *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deploy.Status.Conditions {
+		if deploy.Status.Conditions[i].Type == conditionType {
+			return &deploy.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isRolloutComplete(deploy *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.ObservedGeneration >= deploy.Generation &&
+		deploy.Status.UpdatedReplicas == replicas &&
+		deploy.Status.Replicas == replicas &&
+		deploy.Status.AvailableReplicas == replicas
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
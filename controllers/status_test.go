@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gomock "go.uber.org/mock/gomock"
+
+	routev1 "github.com/openshift/api/route/v1"
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/client"
+)
+
+var _ = Describe("Server status", func() {
+	var server *fdov1beta1.FDORendezvousServer
+
+	BeforeEach(func() {
+		server = &fdov1beta1.FDORendezvousServer{ObjectMeta: metav1.ObjectMeta{Name: "test-server", Namespace: "fdo", Generation: 2}}
+	})
+
+	Describe("setDeploymentConditions", func() {
+		var deploy *appsv1.Deployment
+
+		BeforeEach(func() {
+			replicas := int32(1)
+			deploy = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 3,
+					Replicas:           1,
+					UpdatedReplicas:    1,
+					ReadyReplicas:      1,
+					AvailableReplicas:  1,
+					Conditions: []appsv1.DeploymentCondition{
+						{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+						{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable"},
+					},
+				},
+			}
+		})
+
+		It("should report a rolled out deployment as available", func() {
+			setDeploymentConditions(server, deploy)
+			conditions := server.Status.Conditions
+			Expect(meta.IsStatusConditionTrue(conditions, fdov1beta1.ConditionAvailable)).To(BeTrue())
+			Expect(meta.FindStatusCondition(conditions, fdov1beta1.ConditionProgressing).Reason).To(Equal(fdov1beta1.ReasonRolloutComplete))
+			Expect(meta.IsStatusConditionFalse(conditions, fdov1beta1.ConditionDegraded)).To(BeTrue())
+			Expect(meta.FindStatusCondition(conditions, fdov1beta1.ConditionAvailable).ObservedGeneration).To(Equal(int64(2)))
+		})
+
+		It("should report a rollout in progress", func() {
+			deploy.Status.UpdatedReplicas = 0
+			deploy.Status.Replicas = 2
+			setDeploymentConditions(server, deploy)
+			Expect(meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionProgressing)).To(BeTrue())
+		})
+
+		It("should report a stuck rollout as degraded", func() {
+			deploy.Status.Conditions[0].Status = corev1.ConditionFalse
+			deploy.Status.Conditions[1] = appsv1.DeploymentCondition{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: "ReplicaSet has timed out progressing",
+			}
+			setDeploymentConditions(server, deploy)
+			conditions := server.Status.Conditions
+			Expect(meta.IsStatusConditionFalse(conditions, fdov1beta1.ConditionAvailable)).To(BeTrue())
+			degraded := meta.FindStatusCondition(conditions, fdov1beta1.ConditionDegraded)
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Reason).To(Equal(fdov1beta1.ReasonProgressDeadlineExceeded))
+		})
+	})
+
+	Describe("verifyKeyFiles", func() {
+		var (
+			gCtrl *gomock.Controller
+			c     *client.MockClient
+			ctx   context.Context
+		)
+
+		BeforeEach(func() {
+			gCtrl = gomock.NewController(GinkgoT())
+			c = client.NewMockClient(gCtrl)
+			ctx = context.TODO()
+		})
+
+		It("should list missing secrets and keys", func() {
+			c.EXPECT().Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "fdo-owner-cert"}, gomock.Any()).
+				Return(apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "fdo-owner-cert"))
+			c.EXPECT().Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "fdo-owner-key"}, gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ k8sclient.ObjectKey, obj k8sclient.Object, _ ...k8sclient.GetOption) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"tls.key": []byte("key")}
+					return nil
+				})
+			c.EXPECT().Get(ctx, k8sclient.ObjectKey{Namespace: "fdo", Name: "fdo-device-ca-cert"}, gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ k8sclient.ObjectKey, obj k8sclient.Object, _ ...k8sclient.GetOption) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"device_ca_cert.pem": []byte("cert")}
+					return nil
+				})
+
+			missing, err := verifyKeyFiles(ctx, c, server, onboardingKeyFiles(nil))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(Equal([]string{"fdo-owner-cert/owner_cert.pem", "fdo-owner-key/owner_key.der"}))
+
+			condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionMissingKeyMaterial)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(fdov1beta1.ReasonSecretNotFound))
+			Expect(condition.Message).To(ContainSubstring("fdo-owner-cert/owner_cert.pem, fdo-owner-key/owner_key.der"))
+			Expect(meta.IsStatusConditionFalse(server.Status.Conditions, fdov1beta1.ConditionKeysReady)).To(BeTrue())
+		})
+
+		It("should report a missing key", func() {
+			c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ k8sclient.ObjectKey, obj k8sclient.Object, _ ...k8sclient.GetOption) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"tls.crt": []byte("cert")}
+					return nil
+				})
+			missing, err := verifyKeyFiles(ctx, c, server, rendezvousKeyFiles(nil))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(HaveLen(1))
+			Expect(meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysReady).Reason).To(Equal(fdov1beta1.ReasonKeyNotFound))
+		})
+
+		It("should report found keys", func() {
+			c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ k8sclient.ObjectKey, obj k8sclient.Object, _ ...k8sclient.GetOption) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"manufacturer_cert.pem": []byte("cert")}
+					return nil
+				})
+			missing, err := verifyKeyFiles(ctx, c, server, rendezvousKeyFiles(nil))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionKeysReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(server.Status.Conditions, fdov1beta1.ConditionMissingKeyMaterial)).To(BeTrue())
+		})
+	})
+
+	Describe("manageMissingKeys", func() {
+		It("should only emit an Event when the missing key material changes", func() {
+			gCtrl := gomock.NewController(GinkgoT())
+			c := client.NewMockClient(gCtrl)
+			status := client.NewMockSubResourceClient(gCtrl)
+			c.EXPECT().Status().AnyTimes().Return(status)
+			status.EXPECT().Update(gomock.Any(), server, gomock.Any()).Times(3).Return(nil)
+			recorder := record.NewFakeRecorder(10)
+			r := util.NewReconcilerBase(c, scheme.Scheme, nil, recorder, nil)
+
+			_, err := manageMissingKeys(context.TODO(), &r, server, []string{"fdo-owner-cert/owner_cert.pem"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("fdo-owner-cert/owner_cert.pem")))
+			Expect(meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionDegraded).Reason).To(Equal(fdov1beta1.ConditionMissingKeyMaterial))
+
+			_, err = manageMissingKeys(context.TODO(), &r, server, []string{"fdo-owner-cert/owner_cert.pem"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).ToNot(Receive())
+
+			_, err = manageMissingKeys(context.TODO(), &r, server, []string{"fdo-owner-key/owner_key.der"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring("fdo-owner-key/owner_key.der")))
+		})
+	})
+
+	It("should list the route hosts as endpoints", func() {
+		route := &routev1.Route{
+			Spec: routev1.RouteSpec{Host: "rendezvous.example.com"},
+			Status: routev1.RouteStatus{Ingress: []routev1.RouteIngress{
+				{Host: "rendezvous.example.com"},
+				{Host: "rendezvous.apps.example.com"},
+			}},
+		}
+		Expect(routeEndpoints(route)).To(Equal([]string{"http://rendezvous.example.com", "http://rendezvous.apps.example.com"}))
+	})
+})
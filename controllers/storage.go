@@ -0,0 +1,167 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+// databaseStore is a store of an FDO server that can be kept in a database instead of a directory
+type databaseStore struct {
+	// Server selects the tables of the store in the database, e.g. Manufacturer
+	Server string
+	// EnvName is the server part of the environment variable holding the connection URL, e.g.
+	// MANUFACTURER for POSTGRES_MANUFACTURER_DATABASE_URL
+	EnvName string
+	// Dir is the directory of the store, which holds the Sqlite database
+	Dir string
+	// Migrations is the server part of the migrations of the store, e.g. manufacturing_server
+	Migrations string
+}
+
+var (
+	manufacturerVoucherStore = databaseStore{Server: "Manufacturer", EnvName: "MANUFACTURER", Dir: "/etc/fdo/ownership_vouchers/", Migrations: "manufacturing_server"}
+	ownerVoucherStore        = databaseStore{Server: "Owner", EnvName: "OWNER", Dir: "/etc/fdo/ownership_vouchers/", Migrations: "owner_onboarding_server"}
+	rendezvousStore          = databaseStore{Server: "Rendezvous", EnvName: "RV", Dir: "/etc/fdo/registered/", Migrations: "rendezvous_server"}
+)
+
+// DefaultSqliteImage migrates the Sqlite databases of the servers unless the operator is given
+// another image, which must provide sqlite3 and a shell
+const DefaultSqliteImage = "docker.io/keinos/sqlite3:3.46.1"
+
+// driver returns the driver of the store in the database, or in its directory if db is nil
+func (s databaseStore) driver(db *fdov1beta1.DatabaseStorage) *Driver {
+	db = resolveDatabase(db)
+	switch {
+	case db == nil:
+		return NewDriver(s.Dir)
+	case db.Driver == fdov1beta1.DatabaseDriverSqlite:
+		return &Driver{Sqlite: &Database{Server: s.Server}}
+	}
+	return &Driver{Postgres: &Database{Server: s.Server}}
+}
+
+// sqlitePath returns the path of the Sqlite database of the store, in its directory
+func (s databaseStore) sqlitePath() string {
+	return path.Join(s.Dir, strings.ToLower(s.Server)+".db")
+}
+
+// env returns the environment variable of the server container holding the connection URL of the
+// database, read from the connection secret, or the path of the Sqlite database
+func (s databaseStore) env(db *fdov1beta1.DatabaseStorage) []corev1.EnvVar {
+	db = resolveDatabase(db)
+	switch {
+	case db == nil:
+		return nil
+	case db.Driver == fdov1beta1.DatabaseDriverSqlite:
+		return []corev1.EnvVar{{Name: "SQLITE_" + s.EnvName + "_DATABASE_URL", Value: s.sqlitePath()}}
+	case db.ConnectionSecret == nil:
+		return nil
+	}
+	name := "POSTGRES_" + s.EnvName + "_DATABASE_URL"
+	return []corev1.EnvVar{secretEnvVar(name, db.ConnectionSecret.Name, db.ConnectionSecret.Key, "url")}
+}
+
+// migrationContainers returns the init container creating the tables of the Sqlite database of the
+// store, which the servers don't create, in the volume of the store. Each migration runs once in a
+// transaction recording it in the fdo_operator_migrations table, as the managed Postgres database
+// does. The container runs image, or DefaultSqliteImage.
+func (s databaseStore) migrationContainers(db *fdov1beta1.DatabaseStorage, image, volume string) ([]corev1.Container, error) {
+	db = resolveDatabase(db)
+	if db == nil || db.Driver != fdov1beta1.DatabaseDriverSqlite {
+		return nil, nil
+	}
+	files, err := migrationFiles("sqlite")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range files {
+		if strings.HasPrefix(name, s.Migrations+".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	script := &strings.Builder{}
+	script.WriteString(sqliteMigrateScript)
+	for _, name := range names {
+		fmt.Fprintf(script, "\nmigrate '%s' <<'EOF'\n%s\nEOF", strings.TrimSuffix(name, ".sql"), strings.TrimSpace(files[name]))
+	}
+	if image == "" {
+		image = DefaultSqliteImage
+	}
+	privilegeEscalation := false
+	return []corev1.Container{{
+		Name:    "sqlite-migrate",
+		Image:   image,
+		Command: []string{"/bin/sh", "-c", script.String()},
+		Env:     []corev1.EnvVar{{Name: "DATABASE", Value: s.sqlitePath()}},
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      volume,
+			MountPath: s.Dir,
+		}},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &privilegeEscalation,
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}}, nil
+}
+
+// sqliteMigrateScript defines the migrate function of the Sqlite migrations, which reads a
+// migration from its standard input
+const sqliteMigrateScript = `set -e
+sqlite3 "$DATABASE" 'CREATE TABLE IF NOT EXISTS fdo_operator_migrations (name text PRIMARY KEY)'
+migrate() {
+  [ -n "$(sqlite3 "$DATABASE" "SELECT 1 FROM fdo_operator_migrations WHERE name = '$1'")" ] && return 0
+  { echo 'BEGIN;'; cat; echo "INSERT INTO fdo_operator_migrations VALUES ('$1'); COMMIT;"; } | sqlite3 -bail "$DATABASE"
+}`
+
+// storeVolume returns the volume of the store directory: an empty directory if the store is in a
+// Postgres database, source otherwise, which holds the Sqlite database
+func storeVolume(db *fdov1beta1.DatabaseStorage, source corev1.VolumeSource) corev1.VolumeSource {
+	if resolveDatabase(db).IsPostgres() {
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+	return source
+}
+
+// sessionsVolume returns the volume of the sessions of a server: the claim shared by its pods if set,
+// an empty directory of each pod otherwise
+func sessionsVolume(claimName string) corev1.VolumeSource {
+	if claimName == "" {
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+	return corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName}}
+}
+
+// serverReplicas returns the number of pods of a server
+func serverReplicas(replicas *int32) *int32 {
+	n := int32(1)
+	if replicas != nil {
+		n = *replicas
+	}
+	return &n
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+)
+
+var _ = Describe("Database stores", func() {
+	postgres := &fdov1beta1.DatabaseStorage{
+		Driver:           fdov1beta1.DatabaseDriverPostgres,
+		ConnectionSecret: &fdov1beta1.SecretKeyReference{Name: "fdo-db"},
+	}
+
+	It("should keep stores in directories without a database", func() {
+		data, err := yaml.Marshal(manufacturerVoucherStore.driver(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("Directory:\n  path: /etc/fdo/ownership_vouchers/\n"))
+		Expect(manufacturerVoucherStore.env(nil)).To(BeEmpty())
+	})
+
+	It("should read the connection URL of a Postgres database from its secret", func() {
+		data, err := yaml.Marshal(rendezvousStore.driver(postgres))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("Postgres:\n  server: Rendezvous\n"))
+
+		env := rendezvousStore.env(postgres)
+		Expect(env).To(HaveLen(1))
+		Expect(env[0].Name).To(Equal("POSTGRES_RV_DATABASE_URL"))
+		Expect(env[0].ValueFrom.SecretKeyRef.Name).To(Equal("fdo-db"))
+		Expect(env[0].ValueFrom.SecretKeyRef.Key).To(Equal("url"))
+
+		volume := storeVolume(postgres, corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "registrations"}})
+		Expect(volume.EmptyDir).NotTo(BeNil())
+	})
+
+	It("should keep a Sqlite database in the volume of the store and migrate it", func() {
+		sqlite := &fdov1beta1.DatabaseStorage{Driver: fdov1beta1.DatabaseDriverSqlite}
+		data, err := yaml.Marshal(ownerVoucherStore.driver(sqlite))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("Sqlite:\n  server: Owner\n"))
+		Expect(ownerVoucherStore.env(sqlite)).To(Equal([]corev1.EnvVar{{Name: "SQLITE_OWNER_DATABASE_URL", Value: "/etc/fdo/ownership_vouchers/owner.db"}}))
+		volume := storeVolume(sqlite, corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "vouchers"}})
+		Expect(volume.PersistentVolumeClaim.ClaimName).To(Equal("vouchers"))
+
+		containers, err := ownerVoucherStore.migrationContainers(sqlite, "", "ownership-vouchers")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containers).To(HaveLen(1))
+		Expect(containers[0].Image).To(Equal(DefaultSqliteImage))
+		Expect(containers[0].VolumeMounts[0].MountPath).To(Equal("/etc/fdo/ownership_vouchers/"))
+		script := containers[0].Command[2]
+		Expect(script).To(ContainSubstring("migrate 'owner_onboarding_server.2023-10-03-152801_create_db'"))
+		Expect(script).To(ContainSubstring("CREATE TABLE owner_vouchers"))
+		Expect(script).NotTo(ContainSubstring("manufacturer_vouchers"))
+
+		Expect(ownerVoucherStore.migrationContainers(postgres, "", "ownership-vouchers")).To(BeEmpty())
+		Expect(ownerVoucherStore.migrationContainers(nil, "", "ownership-vouchers")).To(BeEmpty())
+	})
+
+	It("should run each Sqlite migration once", func() {
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			Skip("sqlite3 is not installed")
+		}
+		containers, err := manufacturerVoucherStore.migrationContainers(&fdov1beta1.DatabaseStorage{Driver: fdov1beta1.DatabaseDriverSqlite}, "", "ownership-vouchers")
+		Expect(err).NotTo(HaveOccurred())
+		database := filepath.Join(GinkgoT().TempDir(), "manufacturer.db")
+		for i := 0; i < 2; i++ {
+			cmd := exec.Command("/bin/sh", "-c", containers[0].Command[2])
+			cmd.Env = append(os.Environ(), "DATABASE="+database)
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+		}
+		out, err := exec.Command("sqlite3", database, "SELECT name FROM fdo_operator_migrations; SELECT count(*) FROM manufacturer_vouchers").CombinedOutput()
+		Expect(err).NotTo(HaveOccurred(), string(out))
+		Expect(string(out)).To(Equal("manufacturing_server.2023-10-03-152801_create_db\n0\n"))
+	})
+
+	It("should share the sessions of the pods of a server through their claim", func() {
+		Expect(*serverReplicas(nil)).To(Equal(int32(1)))
+		Expect(sessionsVolume("").EmptyDir).NotTo(BeNil())
+		Expect(sessionsVolume("fdo-sessions").PersistentVolumeClaim.ClaimName).To(Equal("fdo-sessions"))
+	})
+})
@@ -21,6 +21,13 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -29,3 +36,26 @@ func TestAPIs(t *testing.T) {
 
 	RunSpecs(t, "Controller Suite")
 }
+
+var _ = BeforeSuite(func() {
+	Expect(fdov1beta1.AddToScheme(scheme.Scheme)).To(Succeed())
+	Expect(routev1.AddToScheme(scheme.Scheme)).To(Succeed())
+})
+
+// newFakeClientBuilder returns the builder of a fake client holding objs. Like the API server, it ignores the status of
+// the FDO kinds that have a status subresource on updates.
+func newFakeClientBuilder(objs ...k8sclient.Object) *fake.ClientBuilder {
+	return fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithStatusSubresource(
+			&fdov1beta1.FDOManufacturingServer{},
+			&fdov1beta1.FDOOnboardingServer{},
+			&fdov1beta1.FDORendezvousServer{},
+			&fdov1beta1.FDOKeySet{},
+			&fdov1beta1.FDOTrustBundle{},
+			&fdov1beta1.FDOOwnershipVoucher{},
+			&fdov1beta1.FDOVoucherSync{},
+			&fdov1beta1.FDOVoucherExtension{},
+		).
+		WithObjects(objs...)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// serverTrust holds the fingerprints of the certificates of a server that are part of the FDO trust chain
+type serverTrust struct {
+	// Server is the kind and name of the server, e.g. FDOOnboardingServer/onboarding
+	Server string
+	// OwnerKey is the fingerprint of the public key of the owner certificate
+	OwnerKey string
+	// ManufacturerCert and DeviceCACert are the fingerprints of the certificates of a manufacturing server
+	ManufacturerCert string
+	DeviceCACert     string
+	// TrustedCerts are the fingerprints of the manufacturer certificates trusted by a rendezvous
+	// server, or of the device CA certificates trusted by an onboarding server
+	TrustedCerts map[string]bool
+}
+
+// readCerts reads the certificates of the PEM files of a server. Files that are missing or can't be
+// parsed are left out, they are reported by the KeysReady and KeysValid conditions.
+func readCerts(ctx context.Context, c client.Reader, namespace string, files []keyFile) (map[string][]*x509.Certificate, error) {
+	files, err := resolveTrustBundles(ctx, c, namespace, files)
+	if err != nil {
+		return nil, err
+	}
+	certs := map[string][]*x509.Certificate{}
+	for _, f := range files {
+		if path.Ext(f.File) != ".pem" {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: f.Secret}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if parsed, err := keys.ParseCerts(secret.Data[f.Key]); err == nil {
+			certs[f.File] = parsed
+		}
+	}
+	return certs, nil
+}
+
+// certFingerprints returns the set of fingerprints of certificates
+func certFingerprints(certs []*x509.Certificate) map[string]bool {
+	if len(certs) == 0 {
+		return nil
+	}
+	fingerprints := map[string]bool{}
+	for _, cert := range certs {
+		fingerprints[keys.Fingerprint(cert)] = true
+	}
+	return fingerprints
+}
+
+// ownerKeyFingerprint returns the fingerprint of the public key of an owner certificate, empty if
+// there is none
+func ownerKeyFingerprint(certs []*x509.Certificate) string {
+	if len(certs) == 0 {
+		return ""
+	}
+	fingerprint, err := keys.PublicKeyFingerprint(certs[0].PublicKey)
+	if err != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+func manufacturingTrust(ctx context.Context, c client.Reader, server *fdov1beta1.FDOManufacturingServer) (serverTrust, error) {
+	trust := serverTrust{Server: "FDOManufacturingServer/" + server.Name}
+	certs, err := readCerts(ctx, c, server.Namespace, manufacturingKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return trust, err
+	}
+	trust.OwnerKey = ownerKeyFingerprint(certs["owner_cert.pem"])
+	if manufacturer := certs["manufacturer_cert.pem"]; len(manufacturer) > 0 {
+		trust.ManufacturerCert = keys.Fingerprint(manufacturer[0])
+	}
+	if deviceCA := certs["device_ca_cert.pem"]; len(deviceCA) > 0 {
+		trust.DeviceCACert = keys.Fingerprint(deviceCA[0])
+	}
+	return trust, nil
+}
+
+func onboardingTrust(ctx context.Context, c client.Reader, server *fdov1beta1.FDOOnboardingServer) (serverTrust, error) {
+	trust := serverTrust{Server: "FDOOnboardingServer/" + server.Name}
+	certs, err := readCerts(ctx, c, server.Namespace, onboardingKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return trust, err
+	}
+	trust.OwnerKey = ownerKeyFingerprint(certs["owner_cert.pem"])
+	trust.TrustedCerts = certFingerprints(certs["device_ca_cert.pem"])
+	return trust, nil
+}
+
+func rendezvousTrust(ctx context.Context, c client.Reader, server *fdov1beta1.FDORendezvousServer) (serverTrust, error) {
+	trust := serverTrust{Server: "FDORendezvousServer/" + server.Name}
+	certs, err := readCerts(ctx, c, server.Namespace, rendezvousKeyFiles(server.Spec.Keys))
+	if err != nil {
+		return trust, err
+	}
+	trust.TrustedCerts = certFingerprints(certs["manufacturer_cert.pem"])
+	return trust, nil
+}
+
+// namespaceTrust reads the certificates of the manufacturing, onboarding and rendezvous servers of a namespace
+func namespaceTrust(ctx context.Context, c client.Reader, namespace string) (manufacturing, onboarding, rendezvous []serverTrust, err error) {
+	manufacturingServers := &fdov1beta1.FDOManufacturingServerList{}
+	if err = c.List(ctx, manufacturingServers, client.InNamespace(namespace)); err != nil {
+		return
+	}
+	for i := range manufacturingServers.Items {
+		var trust serverTrust
+		if trust, err = manufacturingTrust(ctx, c, &manufacturingServers.Items[i]); err != nil {
+			return
+		}
+		manufacturing = append(manufacturing, trust)
+	}
+
+	onboardingServers := &fdov1beta1.FDOOnboardingServerList{}
+	if err = c.List(ctx, onboardingServers, client.InNamespace(namespace)); err != nil {
+		return
+	}
+	for i := range onboardingServers.Items {
+		var trust serverTrust
+		if trust, err = onboardingTrust(ctx, c, &onboardingServers.Items[i]); err != nil {
+			return
+		}
+		onboarding = append(onboarding, trust)
+	}
+
+	rendezvousServers := &fdov1beta1.FDORendezvousServerList{}
+	if err = c.List(ctx, rendezvousServers, client.InNamespace(namespace)); err != nil {
+		return
+	}
+	for i := range rendezvousServers.Items {
+		var trust serverTrust
+		if trust, err = rendezvousTrust(ctx, c, &rendezvousServers.Items[i]); err != nil {
+			return
+		}
+		rendezvous = append(rendezvous, trust)
+	}
+	return
+}
+
+// trustMismatches compares the certificates of each manufacturing server with those of the onboarding
+// and rendezvous servers, and returns the mismatches involving the given server
+func trustMismatches(server string, manufacturing, onboarding, rendezvous []serverTrust) []string {
+	mismatches := []string{}
+	for _, m := range manufacturing {
+		for _, o := range onboarding {
+			if m.Server != server && o.Server != server {
+				continue
+			}
+			if m.OwnerKey != "" && o.OwnerKey != "" && m.OwnerKey != o.OwnerKey {
+				mismatches = append(mismatches, fmt.Sprintf("the owner certificate of %s doesn't match the owner key of %s", m.Server, o.Server))
+			}
+			if m.DeviceCACert != "" && o.TrustedCerts != nil && !o.TrustedCerts[m.DeviceCACert] {
+				mismatches = append(mismatches, fmt.Sprintf("%s doesn't trust the device CA certificate of %s", o.Server, m.Server))
+			}
+		}
+		for _, rv := range rendezvous {
+			if m.Server != server && rv.Server != server {
+				continue
+			}
+			if m.ManufacturerCert != "" && rv.TrustedCerts != nil && !rv.TrustedCerts[m.ManufacturerCert] {
+				mismatches = append(mismatches, fmt.Sprintf("%s doesn't trust the manufacturer certificate of %s", rv.Server, m.Server))
+			}
+		}
+	}
+	return mismatches
+}
+
+// checkTrust compares the certificates of a server of the given kind with those of the other servers
+// of its namespace, and sets the TrustConsistent condition
+func checkTrust(ctx context.Context, c client.Reader, server serverObject, kind string) error {
+	manufacturing, onboarding, rendezvous, err := namespaceTrust(ctx, c, server.GetNamespace())
+	if err != nil {
+		return err
+	}
+	mismatches := trustMismatches(kind+"/"+server.GetName(), manufacturing, onboarding, rendezvous)
+	if len(mismatches) > 0 {
+		setCondition(server, fdov1beta1.ConditionTrustConsistent, metav1.ConditionFalse, fdov1beta1.ReasonTrustMismatch, strings.Join(mismatches, "; "))
+	} else {
+		setCondition(server, fdov1beta1.ConditionTrustConsistent, metav1.ConditionTrue, fdov1beta1.ReasonAsExpected, "the certificates match those of the other servers of the namespace")
+	}
+	return nil
+}
+
+// requestsForNamespace maps an object to reconcile requests of the servers of the list type in its namespace
+func requestsForNamespace(ctx context.Context, c client.Reader, list client.ObjectList, obj client.Object) []reconcile.Request {
+	return requestsForList(ctx, c, list, client.InNamespace(obj.GetNamespace()))
+}
+
+// requestsForPeerSecret maps a secret to reconcile requests of the servers of the list type in its
+// namespace if a server of one of the peer list types references it, as their trust check reads it
+func requestsForPeerSecret(ctx context.Context, c client.Reader, list client.ObjectList, secret client.Object, peers ...client.ObjectList) []reconcile.Request {
+	for _, peer := range peers {
+		if len(requestsForSecret(ctx, c, peer, secret)) > 0 {
+			return requestsForNamespace(ctx, c, list, secret)
+		}
+	}
+	return nil
+}
+
+// peerChanged filters the events of peer servers to the changes of their spec, so that the status
+// updates of the servers of a namespace don't reconcile each other in a loop
+var peerChanged = builder.WithPredicates(predicate.GenerationChangedPredicate{})
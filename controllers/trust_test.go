@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("Trust consistency", func() {
+	Describe("trustMismatches", func() {
+		manufacturing := []serverTrust{{Server: "FDOManufacturingServer/m", OwnerKey: "owner", ManufacturerCert: "manufacturer", DeviceCACert: "device-ca"}}
+
+		It("should accept consistent servers", func() {
+			onboarding := []serverTrust{{Server: "FDOOnboardingServer/o", OwnerKey: "owner", TrustedCerts: map[string]bool{"old": true, "device-ca": true}}}
+			rendezvous := []serverTrust{{Server: "FDORendezvousServer/r", TrustedCerts: map[string]bool{"manufacturer": true}}}
+			Expect(trustMismatches("FDOManufacturingServer/m", manufacturing, onboarding, rendezvous)).To(BeEmpty())
+		})
+
+		It("should report mismatches to the servers involved", func() {
+			onboarding := []serverTrust{{Server: "FDOOnboardingServer/o", OwnerKey: "other", TrustedCerts: map[string]bool{"other": true}}}
+			rendezvous := []serverTrust{{Server: "FDORendezvousServer/r", TrustedCerts: map[string]bool{"other": true}}}
+			Expect(trustMismatches("FDOManufacturingServer/m", manufacturing, onboarding, rendezvous)).To(Equal([]string{
+				"the owner certificate of FDOManufacturingServer/m doesn't match the owner key of FDOOnboardingServer/o",
+				"FDOOnboardingServer/o doesn't trust the device CA certificate of FDOManufacturingServer/m",
+				"FDORendezvousServer/r doesn't trust the manufacturer certificate of FDOManufacturingServer/m",
+			}))
+			Expect(trustMismatches("FDOOnboardingServer/o", manufacturing, onboarding, rendezvous)).To(HaveLen(2))
+			Expect(trustMismatches("FDORendezvousServer/r", manufacturing, onboarding, rendezvous)).To(HaveLen(1))
+		})
+
+		It("should skip missing key material", func() {
+			onboarding := []serverTrust{{Server: "FDOOnboardingServer/o"}}
+			Expect(trustMismatches("FDOOnboardingServer/o", manufacturing, onboarding, nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("requestsForPeerSecret", func() {
+		It("should only map the secrets of peer servers", func() {
+			ctx := context.TODO()
+			c := newFakeClientBuilder(
+				&fdov1beta1.FDOManufacturingServer{ObjectMeta: metav1.ObjectMeta{Name: "manufacturing", Namespace: "fdo"}},
+				&fdov1beta1.FDOOnboardingServer{ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo"}},
+			).WithIndex(&fdov1beta1.FDOOnboardingServer{}, keySecretsIndex, func(obj k8sclient.Object) []string {
+				return keySecretNames(onboardingKeyFiles(obj.(*fdov1beta1.FDOOnboardingServer).Spec.Keys))
+			}).Build()
+			secret := func(name string) *corev1.Secret {
+				return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fdo"}}
+			}
+
+			Expect(requestsForPeerSecret(ctx, c, &fdov1beta1.FDOManufacturingServerList{}, secret("fdo-device-ca-cert"), &fdov1beta1.FDOOnboardingServerList{})).
+				To(ConsistOf(HaveField("Name", "manufacturing")))
+			Expect(requestsForPeerSecret(ctx, c, &fdov1beta1.FDOManufacturingServerList{}, secret("unrelated"), &fdov1beta1.FDOOnboardingServerList{})).
+				To(BeEmpty())
+		})
+	})
+
+	Describe("checkTrust", func() {
+		var (
+			c   k8sclient.Client
+			ctx context.Context
+		)
+
+		newCert := func(commonName string) []byte {
+			key, err := keys.GenerateKey(keys.SECP256R1)
+			Expect(err).NotTo(HaveOccurred())
+			cert, err := keys.SelfSignedCert(key, keys.Subject{CommonName: commonName}, true, time.Now(), time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			return cert
+		}
+
+		BeforeEach(func() {
+			ctx = context.TODO()
+			secret := func(name, key string, data []byte) *corev1.Secret {
+				return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fdo"}, Data: map[string][]byte{key: data}}
+			}
+			c = newFakeClientBuilder(
+				secret("fdo-owner-cert", "owner_cert.pem", newCert("Owner")),
+				secret("fdo-device-ca-cert", "device_ca_cert.pem", newCert("Device CA")),
+				secret("fdo-manufacturer-cert", "manufacturer_cert.pem", newCert("Manufacturer")),
+				secret("other-owner-cert", "owner_cert.pem", newCert("Other owner")),
+				&fdov1beta1.FDOManufacturingServer{ObjectMeta: metav1.ObjectMeta{Name: "manufacturing", Namespace: "fdo"}},
+				&fdov1beta1.FDOOnboardingServer{ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo"}},
+			).Build()
+		})
+
+		It("should accept servers sharing the default secrets", func() {
+			server := &fdov1beta1.FDOOnboardingServer{ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo"}}
+			Expect(checkTrust(ctx, c, server, "FDOOnboardingServer")).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionTrustConsistent)).To(BeTrue())
+		})
+
+		It("should report an owner certificate that doesn't match", func() {
+			manufacturing := &fdov1beta1.FDOManufacturingServer{}
+			Expect(c.Get(ctx, k8sclient.ObjectKey{Name: "manufacturing", Namespace: "fdo"}, manufacturing)).To(Succeed())
+			manufacturing.Spec.Keys = &fdov1beta1.ManufacturingKeys{OwnerCert: &fdov1beta1.SecretKeyReference{Name: "other-owner-cert"}}
+			Expect(c.Update(ctx, manufacturing)).To(Succeed())
+
+			server := &fdov1beta1.FDOOnboardingServer{ObjectMeta: metav1.ObjectMeta{Name: "onboarding", Namespace: "fdo"}}
+			Expect(checkTrust(ctx, c, server, "FDOOnboardingServer")).To(Succeed())
+			condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionTrustConsistent)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(fdov1beta1.ReasonTrustMismatch))
+			Expect(condition.Message).To(Equal("the owner certificate of FDOManufacturingServer/manufacturing doesn't match the owner key of FDOOnboardingServer/onboarding"))
+		})
+	})
+})
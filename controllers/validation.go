@@ -0,0 +1,146 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// certificateExpiryWarning is how long before the expiry of a certificate warning Events are emitted
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// manufacturingKeyTypes returns the key type required for each key file of a manufacturing server
+func manufacturingKeyTypes(server *fdov1beta1.FDOManufacturingServer) map[string]string {
+	if server.Spec.Protocols.DIUN == nil {
+		return nil
+	}
+	return map[string]string{"diun_key.der": server.Spec.Protocols.DIUN.KeyType}
+}
+
+// validateKeyFiles parses the keys and certificates of a server, and checks that each certificate is
+// valid and matches its private key, and that keys are of the required type. It sets the KeysValid
+// condition and the keys in status, and returns when the certificates have to be checked again.
+// Certificates close to expiry keep the condition true with the CertificateExpiring reason. Warning
+// Events are only emitted when the condition changes, not on every reconcile.
+func validateKeyFiles(ctx context.Context, r *util.ReconcilerBase, server serverObject, files []keyFile, keyTypes map[string]string, now time.Time) (time.Time, error) {
+	status := server.GetServerStatus()
+	status.Keys = nil
+	problems := []string{}
+	expiring := []string{}
+	var next time.Time
+	setNext := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	certs := map[string]*x509.Certificate{}
+	privateKeys := map[string]*ecdsa.PrivateKey{}
+	for _, f := range files {
+		if f.external() {
+			// only the init container of the server can read keys from external key stores
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := r.GetClient().Get(ctx, types.NamespacedName{Namespace: server.GetNamespace(), Name: f.Secret}, secret); err != nil {
+			return next, err
+		}
+		data := secret.Data[f.Key]
+		entry := fdov1beta1.KeyStatus{File: f.File, Secret: f.Secret}
+
+		switch path.Ext(f.File) {
+		case ".pem":
+			cert, err := keys.ParseCert(data)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", f.File, err))
+				break
+			}
+			certs[f.File] = cert
+			notBefore, notAfter := metav1.NewTime(cert.NotBefore), metav1.NewTime(cert.NotAfter)
+			entry.NotBefore, entry.NotAfter = &notBefore, &notAfter
+			entry.SHA256Fingerprint, entry.SHA384Fingerprint = keys.Fingerprint(cert), keys.SHA384Fingerprint(cert)
+			if f.File == "diun_cert.pem" {
+				entry.PublicKeyHash = "sha256:" + entry.SHA256Fingerprint
+			}
+			switch {
+			case now.Before(cert.NotBefore):
+				problems = append(problems, fmt.Sprintf("%s: not valid before %s", f.File, cert.NotBefore.UTC().Format(time.RFC3339)))
+				setNext(cert.NotBefore)
+			case !now.Before(cert.NotAfter):
+				problems = append(problems, fmt.Sprintf("%s: expired on %s", f.File, cert.NotAfter.UTC().Format(time.RFC3339)))
+			case now.After(cert.NotAfter.Add(-certificateExpiryWarning)):
+				expiring = append(expiring, fmt.Sprintf("certificate %s of secret %s expires on %s", f.File, f.Secret, cert.NotAfter.UTC().Format(time.RFC3339)))
+				setNext(cert.NotAfter)
+			default:
+				setNext(cert.NotAfter.Add(-certificateExpiryWarning))
+			}
+		case ".der":
+			key, err := keys.ParseKey(data)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", f.File, err))
+				break
+			}
+			privateKeys[f.File] = key
+			if want, ok := keyTypes[f.File]; ok && want != keys.KeyType(&key.PublicKey) {
+				problems = append(problems, fmt.Sprintf("%s: key type is %s instead of %s", f.File, keys.KeyType(&key.PublicKey), want))
+			}
+		}
+		status.Keys = append(status.Keys, entry)
+	}
+
+	// a private key X_key.der is paired with the certificate X_cert.pem
+	for _, f := range files {
+		key, ok := privateKeys[f.File]
+		if !ok {
+			continue
+		}
+		cert, ok := certs[strings.TrimSuffix(f.File, "_key.der")+"_cert.pem"]
+		if ok && !key.PublicKey.Equal(cert.PublicKey) {
+			problems = append(problems, fmt.Sprintf("%s: the private key doesn't match the certificate %s", f.File, cert.Subject))
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+		message := strings.Join(problems, "; ")
+		if setCondition(server, fdov1beta1.ConditionKeysValid, metav1.ConditionFalse, fdov1beta1.ReasonInvalidKeyMaterial, message) {
+			r.GetRecorder().Event(server, corev1.EventTypeWarning, fdov1beta1.ReasonInvalidKeyMaterial, message)
+		}
+	case len(expiring) > 0:
+		message := strings.Join(expiring, "; ")
+		if setCondition(server, fdov1beta1.ConditionKeysValid, metav1.ConditionTrue, fdov1beta1.ReasonCertificateExpiring, message) {
+			r.GetRecorder().Event(server, corev1.EventTypeWarning, fdov1beta1.ReasonCertificateExpiring, message)
+		}
+	default:
+		setCondition(server, fdov1beta1.ConditionKeysValid, metav1.ConditionTrue, fdov1beta1.ReasonAsExpected, "all keys and certificates are valid")
+	}
+	return next, nil
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	util "github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("validateKeyFiles", func() {
+	var (
+		c        k8sclient.Client
+		ctx      context.Context
+		recorder *record.FakeRecorder
+		r        util.ReconcilerBase
+		server   *fdov1beta1.FDOManufacturingServer
+		now      time.Time
+	)
+
+	files := []keyFile{
+		{File: "diun_cert.pem", Secret: "diun-cert", Key: "diun_cert.pem"},
+		{File: "diun_key.der", Secret: "diun-key", Key: "diun_key.der"},
+	}
+
+	setSecret := func(name, key string, data []byte) {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fdo"}, Data: map[string][]byte{key: data}}
+		if err := c.Update(ctx, secret); apierrors.IsNotFound(err) {
+			Expect(c.Create(ctx, secret)).To(Succeed())
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	// setKeyPair writes a new DIUN key pair and returns its certificate
+	setKeyPair := func(keyType string, notBefore time.Time, validity time.Duration) []byte {
+		key, err := keys.GenerateKey(keyType)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := keys.MarshalKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := keys.SelfSignedCert(key, keys.Subject{CommonName: "DIUN"}, false, notBefore, validity)
+		Expect(err).NotTo(HaveOccurred())
+		setSecret("diun-key", "diun_key.der", der)
+		setSecret("diun-cert", "diun_cert.pem", cert)
+		return cert
+	}
+
+	BeforeEach(func() {
+		c = newFakeClientBuilder().Build()
+		ctx = context.TODO()
+		recorder = record.NewFakeRecorder(10)
+		r = util.NewReconcilerBase(c, scheme.Scheme, nil, recorder, nil)
+		server = &fdov1beta1.FDOManufacturingServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-server", Namespace: "fdo"},
+			Spec: fdov1beta1.FDOManufacturingServerSpec{
+				Protocols: fdov1beta1.Protocols{DIUN: &fdov1beta1.DIUN{KeyType: keys.SECP256R1}},
+			},
+		}
+		now = time.Now()
+	})
+
+	It("should accept valid keys and report the validity of certificates", func() {
+		setKeyPair(keys.SECP256R1, now.Add(-time.Hour), 365*24*time.Hour)
+		next, err := validateKeyFiles(ctx, &r, server, files, manufacturingKeyTypes(server), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.IsStatusConditionTrue(server.Status.Conditions, fdov1beta1.ConditionKeysValid)).To(BeTrue())
+		Expect(server.Status.Keys).To(HaveLen(2))
+		Expect(server.Status.Keys[0].NotAfter).NotTo(BeNil())
+		Expect(server.Status.Keys[1].NotAfter).To(BeNil())
+		Expect(next).To(BeTemporally("~", server.Status.Keys[0].NotAfter.Add(-certificateExpiryWarning), time.Second))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should publish the fingerprints of certificates and the DIUN public key hash", func() {
+		pem := setKeyPair(keys.SECP256R1, now.Add(-time.Hour), 365*24*time.Hour)
+		_, err := validateKeyFiles(ctx, &r, server, files, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := keys.ParseCert(pem)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Status.Keys[0].SHA256Fingerprint).To(Equal(keys.Fingerprint(cert)))
+		Expect(server.Status.Keys[0].SHA384Fingerprint).To(Equal(keys.SHA384Fingerprint(cert)))
+		Expect(server.Status.Keys[0].PublicKeyHash).To(Equal("sha256:" + keys.Fingerprint(cert)))
+		Expect(server.Status.Keys[1].SHA256Fingerprint).To(BeEmpty())
+
+		Expect(fingerprintsData(server.Status.Keys)).To(Equal(map[string]string{
+			"diun_cert.sha256":  keys.Fingerprint(cert),
+			"diun_cert.sha384":  keys.SHA384Fingerprint(cert),
+			"DIUN_PUB_KEY_HASH": "sha256:" + keys.Fingerprint(cert),
+		}))
+	})
+
+	It("should reject a key of the wrong type", func() {
+		setKeyPair(keys.SECP384R1, now.Add(-time.Hour), 365*24*time.Hour)
+		_, err := validateKeyFiles(ctx, &r, server, files, manufacturingKeyTypes(server), now)
+		Expect(err).NotTo(HaveOccurred())
+		condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysValid)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("key type is SECP384R1 instead of SECP256R1"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(fdov1beta1.ReasonInvalidKeyMaterial)))
+
+		_, err = validateKeyFiles(ctx, &r, server, files, manufacturingKeyTypes(server), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should reject a private key that doesn't match the certificate", func() {
+		cert := setKeyPair(keys.SECP256R1, now.Add(-time.Hour), 365*24*time.Hour)
+		setKeyPair(keys.SECP256R1, now.Add(-time.Hour), 365*24*time.Hour)
+		setSecret("diun-cert", "diun_cert.pem", cert)
+		_, err := validateKeyFiles(ctx, &r, server, files, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysValid).Message).To(ContainSubstring("doesn't match"))
+	})
+
+	It("should reject expired and not yet valid certificates", func() {
+		setKeyPair(keys.SECP256R1, now.Add(-2*time.Hour), time.Hour)
+		_, err := validateKeyFiles(ctx, &r, server, files, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysValid).Message).To(ContainSubstring("expired"))
+
+		setKeyPair(keys.SECP256R1, now.Add(time.Hour), time.Hour)
+		next, err := validateKeyFiles(ctx, &r, server, files, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysValid).Message).To(ContainSubstring("not valid before"))
+		Expect(next).To(BeTemporally("~", now.Add(time.Hour), time.Second))
+	})
+
+	It("should warn about certificates close to expiry", func() {
+		setKeyPair(keys.SECP256R1, now.Add(-time.Hour), 10*24*time.Hour)
+		next, err := validateKeyFiles(ctx, &r, server, files, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		condition := meta.FindStatusCondition(server.Status.Conditions, fdov1beta1.ConditionKeysValid)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(fdov1beta1.ReasonCertificateExpiring))
+		Expect(recorder.Events).To(Receive(ContainSubstring(fdov1beta1.ReasonCertificateExpiring)))
+		Expect(next).To(BeTemporally("~", server.Status.Keys[0].NotAfter.Time, time.Second))
+
+		_, err = validateKeyFiles(ctx, &r, server, files, nil, now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
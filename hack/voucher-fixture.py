@@ -0,0 +1,145 @@
+#!/usr/bin/env python3
+"""Writes a voucher initialized by a manufacturer and extended once, and the key of its owner.
+
+The voucher is encoded as fido-device-onboard-rs writes it, without the code of the operator: the
+CBOR encoder is below and the keys, certificates and signatures are made by the openssl command.
+It stands in for the output of "make voucher-fixtures" where the tools of fido-device-onboard-rs
+can't be run, and is replaced by it.
+
+Usage: hack/voucher-fixture.py <directory>
+"""
+
+import hashlib
+import hmac
+import os
+import struct
+import subprocess
+import sys
+import tempfile
+
+
+def head(major, n):
+    if n < 24:
+        return bytes([major << 5 | n])
+    for extra, fmt in ((24, ">B"), (25, ">H"), (26, ">I"), (27, ">Q")):
+        if n < 1 << (8 * struct.calcsize(fmt)):
+            return bytes([major << 5 | extra]) + struct.pack(fmt, n)
+    raise ValueError(n)
+
+
+class Raw(bytes):
+    """An encoded data item, embedded as is."""
+
+
+def cbor(value):
+    if isinstance(value, Raw):
+        return bytes(value)
+    if value is None:
+        return b"\xf6"
+    if isinstance(value, int):
+        return head(0, value) if value >= 0 else head(1, -1 - value)
+    if isinstance(value, bytes):
+        return head(2, len(value)) + value
+    if isinstance(value, str):
+        data = value.encode()
+        return head(3, len(data)) + data
+    if isinstance(value, list):
+        return head(4, len(value)) + b"".join(cbor(item) for item in value)
+    if isinstance(value, dict):
+        return head(5, len(value)) + b"".join(cbor(k) + cbor(v) for k, v in value.items())
+    raise TypeError(value)
+
+
+def openssl(*args, data=None):
+    return subprocess.run(["openssl", *args], input=data, capture_output=True, check=True).stdout
+
+
+def der_integers(der):
+    """Returns the integers of a DER sequence, the r and s of an ECDSA signature."""
+    assert der[0] == 0x30
+    pos = 2 if der[1] < 0x80 else 2 + (der[1] & 0x7F)
+    values = []
+    while pos < len(der):
+        assert der[pos] == 0x02
+        n = der[pos + 1]
+        values.append(int.from_bytes(der[pos + 2 : pos + 2 + n], "big"))
+        pos += 2 + n
+    return values
+
+
+class Key:
+    def __init__(self, tmp, name, subject, issuer=None):
+        self.path = os.path.join(tmp, name + "_key.pem")
+        openssl("ecparam", "-name", "prime256v1", "-genkey", "-noout", "-out", self.path)
+        self.der = openssl("pkey", "-in", self.path, "-outform", "DER")
+        self.public = openssl("pkey", "-in", self.path, "-pubout", "-outform", "DER")
+        cert = os.path.join(tmp, name + "_cert.pem")
+        if issuer is None:
+            openssl("req", "-new", "-x509", "-key", self.path, "-subj", subject, "-days", "3650", "-out", cert)
+        else:
+            csr = openssl("req", "-new", "-key", self.path, "-subj", subject)
+            openssl("x509", "-req", "-CA", issuer.cert, "-CAkey", issuer.path, "-set_serial", "1",
+                    "-days", "3650", "-out", cert, data=csr)
+        self.cert = cert
+        self.cert_der = openssl("x509", "-in", cert, "-outform", "DER")
+
+    def sign(self, data):
+        r, s = der_integers(openssl("dgst", "-sha256", "-sign", self.path, data=data))
+        return r.to_bytes(32, "big") + s.to_bytes(32, "big")
+
+
+# FDO 1.1 values
+SECP256R1, X509, SHA256, HMAC_SHA256, ES256 = 10, 1, -16, 5, -7
+RV_DEV_PORT, RV_OWNER_PORT, RV_DNS, RV_PROTOCOL, RV_PROT_HTTP = 3, 4, 5, 12, 1
+
+
+def main(out, tmp):
+    manufacturer = Key(tmp, "manufacturer", "/O=Example/C=US/CN=Manufacturer")
+    device_ca = Key(tmp, "device_ca", "/O=Example/C=US/CN=Device CA")
+    device = Key(tmp, "device", "/CN=test-device", issuer=device_ca)
+    owner = Key(tmp, "owner", "/O=Example/C=US/CN=Owner")
+
+    guid = os.urandom(16)
+    rv_info = [[
+        [RV_DNS, cbor("rv.example.com")],
+        [RV_DEV_PORT, cbor(8082)],
+        [RV_OWNER_PORT, cbor(8082)],
+        [RV_PROTOCOL, cbor(RV_PROT_HTTP)],
+    ]]
+    chain = [device.cert_der, device_ca.cert_der]
+    header = cbor([
+        101,
+        guid,
+        rv_info,
+        "test-device",
+        [SECP256R1, X509, manufacturer.public],
+        [SHA256, hashlib.sha256(b"".join(chain)).digest()],
+    ])
+    header_hmac = [HMAC_SHA256, hmac.new(os.urandom(32), header, hashlib.sha256).digest()]
+
+    # the first entry holds the hash of the header and its HMAC, as byte string and array
+    previous = hashlib.sha256(cbor(header) + cbor(header_hmac)).digest()
+    payload = cbor([
+        [SHA256, previous],
+        [SHA256, hashlib.sha256(guid + b"test-device").digest()],
+        None,
+        [SECP256R1, X509, owner.public],
+    ])
+    protected = cbor({1: ES256})
+    signature = manufacturer.sign(cbor(["Signature1", protected, b"", payload]))
+    # entries are untagged COSE_Sign1 structures, hashed as encoded by the next entry
+    entry = Raw(cbor([protected, {}, payload, signature]))
+
+    voucher = cbor([101, header, header_hmac, chain, [entry]])
+    os.makedirs(out, exist_ok=True)
+    with open(os.path.join(out, "ownership_voucher"), "wb") as f:
+        f.write(voucher)
+    with open(os.path.join(out, "owner_key.der"), "wb") as f:
+        f.write(owner.der)
+
+
+if __name__ == "__main__":
+    if len(sys.argv) != 2:
+        sys.exit(__doc__)
+    with tempfile.TemporaryDirectory() as tmp:
+        main(sys.argv[1], tmp)
@@ -0,0 +1,178 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportPort is the port the sidecar serves the voucher export API on, and ExportPath the path of
+// the vouchers. The vouchers of a serial number are served under ExportSerialsPath.
+const (
+	ExportPort        = 8091
+	ExportPath        = "/export/vouchers"
+	ExportSerialsPath = "/export/serials"
+)
+
+// ExportList lists the vouchers served by the export API
+type ExportList struct {
+	Devices []Device `json:"devices"`
+}
+
+// ExportHandler serves the vouchers of a directory to manufacturing line systems. Requests are
+// authenticated by the bearer token stored in tokenFile, which is read again on each request so
+// that the token can be rotated without restarting the sidecar.
+//
+//   - GET ExportPath lists the vouchers, optionally filtered by ?serial= and ?since= (RFC 3339),
+//     sorted by the time they were written
+//   - GET ExportPath/<guid> returns the voucher of a device as stored
+//   - GET ExportSerialsPath/<serial> returns the latest voucher of a serial number
+func ExportHandler(dir, tokenFile string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ExportPath, func(w http.ResponseWriter, req *http.Request) {
+		if !exportAuthorized(w, req, tokenFile) {
+			return
+		}
+		serial := req.URL.Query().Get("serial")
+		var since time.Time
+		if s := req.URL.Query().Get("since"); s != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, s); err != nil {
+				http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		devices, err := exportDevices(dir, serial, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ExportList{Devices: devices})
+	})
+	mux.HandleFunc(ExportPath+"/", func(w http.ResponseWriter, req *http.Request) {
+		if !exportAuthorized(w, req, tokenFile) {
+			return
+		}
+		data, err := Read(dir, strings.TrimPrefix(req.URL.Path, ExportPath+"/"))
+		serveVoucher(w, req, data, err)
+	})
+	mux.HandleFunc(ExportSerialsPath+"/", func(w http.ResponseWriter, req *http.Request) {
+		if !exportAuthorized(w, req, tokenFile) {
+			return
+		}
+		serial := strings.TrimPrefix(req.URL.Path, ExportSerialsPath+"/")
+		if serial == "" {
+			http.NotFound(w, req)
+			return
+		}
+		devices, err := exportDevices(dir, serial, time.Time{})
+		if err != nil || len(devices) == 0 {
+			serveVoucher(w, req, nil, err)
+			return
+		}
+		w.Header().Set("X-FDO-GUID", devices[len(devices)-1].GUID)
+		data, err := Read(dir, devices[len(devices)-1].GUID)
+		serveVoucher(w, req, data, err)
+	})
+	return mux
+}
+
+// exportDevices returns the vouchers of a directory written after since, of a serial number if
+// it is not empty, from the oldest to the latest
+func exportDevices(dir, serial string, since time.Time) ([]Device, error) {
+	inventory, err := Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	devices := []Device{}
+	for _, d := range inventory.Devices {
+		if serial != "" && d.SerialNumber != serial && d.DeviceInfo != serial {
+			continue
+		}
+		if !d.ModTime.After(since) {
+			continue
+		}
+		devices = append(devices, d)
+	}
+	sort.SliceStable(devices, func(i, j int) bool { return devices[i].ModTime.Before(devices[j].ModTime) })
+	return devices, nil
+}
+
+func serveVoucher(w http.ResponseWriter, req *http.Request, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	_, _ = w.Write(data)
+}
+
+// exportAuthorized checks the bearer token of a request against the token file. Requests are
+// rejected if the token file is missing or empty.
+func exportAuthorized(w http.ResponseWriter, req *http.Request, tokenFile string) bool {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	expected, err := os.ReadFile(tokenFile)
+	expected = bytes.TrimSpace(expected)
+	if err != nil || len(expected) == 0 {
+		http.Error(w, "export token not available", http.StatusServiceUnavailable)
+		return false
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// downloadAuthorized checks that a request downloading a voucher carries the token of the operator
+// or the token of the voucher export API, and answers it otherwise. Vouchers are not served without
+// a token.
+func downloadAuthorized(w http.ResponseWriter, req *http.Request, token, tokenFile string) bool {
+	bearer, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token != "" && ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+		return true
+	}
+	if tokenFile != "" {
+		return exportAuthorized(w, req, tokenFile)
+	}
+	if token == "" {
+		http.Error(w, "voucher downloads require a token", http.StatusServiceUnavailable)
+		return false
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return false
+}
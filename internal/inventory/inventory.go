@@ -0,0 +1,333 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory lists the ownership vouchers of a directory store. It runs in a sidecar of the
+// manufacturing and onboarding servers, which serves the list to the operator, and of the
+// rendezvous servers, which serves their TO0 registrations.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+// Command is the argument of the operator binary that serves the inventory of a store
+const Command = "voucher-inventory"
+
+// Port is the port the sidecar listens on, and Path the path of the inventory
+const (
+	Port = 8090
+	Path = "/vouchers"
+)
+
+// maxVoucherSize bounds the size of a voucher read from the inventory
+const maxVoucherSize = 1 << 20
+
+// DefaultHTTPClient reads the sidecars for callers without a client. It times out so that a hung
+// sidecar can't block a reconcile of the operator.
+var DefaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Key describes a public key of a voucher
+type Key struct {
+	Type        string `json:"type"`
+	Encoding    string `json:"encoding"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Device describes the voucher of a device
+type Device struct {
+	GUID            string    `json:"guid"`
+	DeviceInfo      string    `json:"deviceInfo"`
+	SerialNumber    string    `json:"serialNumber,omitempty"`
+	ProtocolVersion int64     `json:"protocolVersion"`
+	ManufacturerKey Key       `json:"manufacturerKey"`
+	OwnerKey        Key       `json:"ownerKey"`
+	Entries         int       `json:"entries"`
+	RendezvousInfo  []string  `json:"rendezvousInfo,omitempty"`
+	ModTime         time.Time `json:"modTime"`
+}
+
+// Inventory lists the vouchers of a store, and the files that are not vouchers
+type Inventory struct {
+	Devices []Device `json:"devices"`
+	Invalid []string `json:"invalid,omitempty"`
+}
+
+// Scan parses the files of a directory store, sorted by GUID. Hidden files and directories are skipped.
+func Scan(dir string) (*Inventory, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	inventory := &Inventory{Devices: []Device{}}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		v, err := voucher.Parse(data)
+		if err != nil {
+			inventory.Invalid = append(inventory.Invalid, entry.Name())
+			continue
+		}
+		inventory.Devices = append(inventory.Devices, describe(v, info.ModTime()))
+	}
+	sort.Slice(inventory.Devices, func(i, j int) bool { return inventory.Devices[i].GUID < inventory.Devices[j].GUID })
+	return inventory, nil
+}
+
+func describe(v *voucher.Voucher, modTime time.Time) Device {
+	device := Device{
+		GUID:            v.GUID.String(),
+		DeviceInfo:      v.DeviceInfo,
+		ProtocolVersion: v.ProtocolVersion,
+		ManufacturerKey: describeKey(v.ManufacturerKey),
+		OwnerKey:        describeKey(v.OwnerKey()),
+		Entries:         len(v.Entries),
+		ModTime:         modTime.UTC(),
+	}
+	for _, directive := range v.RendezvousInfo {
+		device.RendezvousInfo = append(device.RendezvousInfo, directive.String())
+	}
+	// the serial number is in the subject of the device certificate, or is its common name
+	if certs, err := v.DeviceCertificates(); err == nil && len(certs) > 0 {
+		device.SerialNumber = certs[0].Subject.SerialNumber
+		if device.SerialNumber == "" {
+			device.SerialNumber = certs[0].Subject.CommonName
+		}
+	}
+	return device
+}
+
+func describeKey(k voucher.PublicKey) Key {
+	// the fingerprint is left out for keys in encodings that are not supported
+	fingerprint, _ := k.Fingerprint()
+	return Key{Type: k.Type.String(), Encoding: k.Encoding.String(), Fingerprint: fingerprint}
+}
+
+// Handler serves the inventory of a directory. Vouchers can be pruned from the directory if
+// retention is set. The vouchers are only downloaded by the operator, authenticated by token, and by
+// the clients of the voucher export API, authenticated by the token of tokenFile if it is set, which
+// is then required to list the inventory too.
+func Handler(dir string, retention *Retention, token, tokenFile string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if tokenFile != "" && !exportAuthorized(w, req, tokenFile) {
+			return
+		}
+		inventory, err := Scan(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(inventory)
+	})
+	mux.HandleFunc(Path+"/", func(w http.ResponseWriter, req *http.Request) {
+		guid := strings.TrimPrefix(req.URL.Path, Path+"/")
+		if req.Method == http.MethodDelete && retention != nil {
+			retention.serveRemove(w, req, guid)
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !downloadAuthorized(w, req, token, tokenFile) {
+			return
+		}
+		data, err := Read(dir, guid)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data == nil {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	})
+	if retention != nil {
+		mux.HandleFunc(RetentionPath, retention.serveScan)
+	}
+	return mux
+}
+
+// Read returns the voucher of a device as stored in a directory, or nil if there is none. Stores
+// name vouchers after their GUID, other files are only read if the voucher is not found by name.
+func Read(dir, guid string) ([]byte, error) {
+	_, data, err := find(dir, guid)
+	return data, err
+}
+
+// find returns the path and the content of the voucher of a device in a directory, an empty path
+// if there is none
+func find(dir, guid string) (string, []byte, error) {
+	guid = strings.ToLower(guid)
+	if guid == "" || strings.Trim(guid, "0123456789abcdef-") != "" {
+		return "", nil, nil
+	}
+	path := filepath.Join(dir, guid)
+	data, err := os.ReadFile(path)
+	if err == nil && hasGUID(data, guid) {
+		return path, data, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil, err
+		}
+		if hasGUID(data, guid) {
+			return path, data, nil
+		}
+	}
+	return "", nil, nil
+}
+
+func hasGUID(data []byte, guid string) bool {
+	v, err := voucher.Parse(data)
+	return err == nil && v.GUID.String() == guid
+}
+
+// Run serves the inventory of the directory of the --dir argument until the context is done
+func Run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet(Command, flag.ContinueOnError)
+	dir := flags.String("dir", "/etc/fdo/ownership_vouchers", "The directory store of the vouchers.")
+	addr := flags.String("listen", fmt.Sprintf(":%d", Port), "The address the inventory is served on.")
+	enableRetention := flags.Bool("retention", false, "Allow the operator to prune the vouchers of onboarded devices, "+
+		"authenticated by the token of the "+TokenEnv+" environment variable.")
+	archiveDir := flags.String("archive-dir", "", "The directory pruned vouchers are moved to, instead of being deleted.")
+	stateDir := flags.String("state-dir", "", "The directory recording when the devices were first seen onboarded, "+
+		"required by --retention.")
+	registrations := flags.Bool("registrations", false, "The directory holds the TO0 registrations of a rendezvous server, "+
+		"which are served instead of vouchers.")
+	exportAddr := flags.String("export-listen", fmt.Sprintf(":%d", ExportPort), "The address the voucher export API is served on.")
+	exportTokenFile := flags.String("export-token-file", "", "The file of the token authenticating the clients of the "+
+		"voucher export API. The API is only served if it is set.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	token := os.Getenv(TokenEnv)
+	var retention *Retention
+	if *enableRetention {
+		retention = &Retention{Dir: *dir, ArchiveDir: *archiveDir, StateDir: *stateDir, Token: token}
+		if retention.Token == "" {
+			return fmt.Errorf("retention requires a token in %s", TokenEnv)
+		}
+		if retention.StateDir == "" {
+			return errors.New("retention requires a state directory in --state-dir")
+		}
+	}
+	log := logf.Log.WithName(Command)
+	handler := Handler(*dir, retention, token, *exportTokenFile)
+	if *registrations {
+		handler = RegistrationsHandler(&RegistrationStore{Dir: *dir})
+	}
+	servers := []*http.Server{{Addr: *addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}}
+	if *exportTokenFile != "" {
+		servers = append(servers, &http.Server{Addr: *exportAddr, Handler: ExportHandler(*dir, *exportTokenFile), ReadHeaderTimeout: 10 * time.Second})
+		log.Info("Serving the voucher export API", "address", *exportAddr)
+	}
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			errs <- server.ListenAndServe()
+		}(server)
+	}
+	log.Info("Serving the voucher inventory", "dir", *dir, "address", *addr)
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	for _, server := range servers {
+		_ = server.Shutdown(context.Background())
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Fetch reads the inventory served at a URL, with the token of the voucher export API if it is set
+func Fetch(ctx context.Context, httpClient *http.Client, url, token string) (*Inventory, error) {
+	resp, err := do(ctx, httpClient, http.MethodGet, strings.TrimSuffix(url, "/")+Path, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GET %s: %s: %s", Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	inventory := &Inventory{}
+	if err := json.NewDecoder(resp.Body).Decode(inventory); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// FetchVoucher reads the voucher of a device served at a URL, with the token of the operator or of
+// the voucher export API
+func FetchVoucher(ctx context.Context, httpClient *http.Client, url, token, guid string) ([]byte, error) {
+	resp, err := do(ctx, httpClient, http.MethodGet, strings.TrimSuffix(url, "/")+Path+"/"+guid, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxVoucherSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s/%s: %s: %s", Path, guid, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
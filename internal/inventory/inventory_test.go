@@ -0,0 +1,269 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const guid = "01234567-89ab-cdef-0123-456789abcdef"
+
+var _ = Describe("Inventory", func() {
+	It("should describe the vouchers of a directory", func() {
+		inventory, err := Scan("testdata")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Invalid).To(Equal([]string{"not-a-voucher"}))
+		Expect(inventory.Devices).To(HaveLen(1))
+		device := inventory.Devices[0]
+		Expect(device.GUID).To(Equal("01234567-89ab-cdef-0123-456789abcdef"))
+		Expect(device.DeviceInfo).To(Equal("test-device"))
+		Expect(device.Entries).To(Equal(1))
+		Expect(device.ManufacturerKey.Encoding).To(Equal("X5CHAIN"))
+		Expect(device.OwnerKey.Type).To(Equal("SECP256R1"))
+		Expect(device.OwnerKey.Fingerprint).To(HaveLen(64))
+		Expect(device.OwnerKey.Fingerprint).NotTo(Equal(device.ManufacturerKey.Fingerprint))
+		Expect(device.RendezvousInfo).To(Equal([]string{"dns=rv.example.com device_port=8082 protocol=http", "ip_address=10.0.0.1 dev_only"}))
+	})
+
+	It("should serve the inventory", func() {
+		server := httptest.NewServer(Handler("testdata", nil, "", ""))
+		defer server.Close()
+		inventory, err := Fetch(context.TODO(), server.Client(), server.URL, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Devices).To(HaveLen(1))
+
+		_, err = Fetch(context.TODO(), server.Client(), server.URL+"/missing", "")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+})
+
+var _ = Describe("Vouchers", func() {
+	It("should serve the voucher of a device", func() {
+		server := httptest.NewServer(Handler("testdata", nil, "s3cr3t", ""))
+		defer server.Close()
+		data, err := FetchVoucher(context.TODO(), server.Client(), server.URL, "s3cr3t", "01234567-89AB-cdef-0123-456789abcdef")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix("-----BEGIN OWNERSHIP VOUCHER-----"))
+
+		_, err = FetchVoucher(context.TODO(), server.Client(), server.URL, "s3cr3t", "00000000-0000-0000-0000-000000000000")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("should only serve vouchers to the operator", func() {
+		server := httptest.NewServer(Handler("testdata", nil, "s3cr3t", ""))
+		defer server.Close()
+		_, err := FetchVoucher(context.TODO(), server.Client(), server.URL, "", guid)
+		Expect(err).To(MatchError(ContainSubstring("401")))
+		_, err = FetchVoucher(context.TODO(), server.Client(), server.URL, "wrong", guid)
+		Expect(err).To(MatchError(ContainSubstring("401")))
+
+		unauthenticated := httptest.NewServer(Handler("testdata", nil, "", ""))
+		defer unauthenticated.Close()
+		_, err = FetchVoucher(context.TODO(), unauthenticated.Client(), unauthenticated.URL, "", guid)
+		Expect(err).To(MatchError(ContainSubstring("503")))
+	})
+
+	It("should only read vouchers", func() {
+		data, err := Read("testdata", "../testdata/not-a-voucher")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+	})
+})
+
+var _ = Describe("Retention", func() {
+	var (
+		dir, archive, state string
+		retention           *Retention
+		onboarded           bool
+		now                 time.Time
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		archive = GinkgoT().TempDir()
+		state = GinkgoT().TempDir()
+		data, err := os.ReadFile(filepath.Join("testdata", guid))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, guid), data, 0o600)).To(Succeed())
+		onboarded = false
+		now = time.Now()
+		retention = &Retention{Dir: dir, StateDir: state, Token: "s3cr3t", onboarded: func(string) bool { return onboarded }}
+	})
+
+	It("should expire vouchers once their device has been onboarded for the retention period", func() {
+		scan, err := retention.Scan(time.Hour, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Onboarded).To(BeZero())
+
+		onboarded = true
+		scan, err = retention.Scan(time.Hour, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Onboarded).To(Equal(1))
+		Expect(scan.Expired).To(BeEmpty())
+
+		scan, err = retention.Scan(time.Hour, now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Expired).To(Equal([]string{guid}))
+		Expect(filepath.Join(state, "retention.json")).To(BeAnExistingFile())
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should archive or delete pruned vouchers", func() {
+		retention.ArchiveDir = archive
+		Expect(retention.Remove(guid)).To(BeTrue())
+		Expect(filepath.Join(dir, guid)).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(archive, guid)).To(BeAnExistingFile())
+		Expect(retention.Remove(guid)).To(BeFalse())
+	})
+
+	It("should only prune vouchers for the operator", func() {
+		onboarded = true
+		server := httptest.NewServer(Handler(dir, retention, "s3cr3t", ""))
+		defer server.Close()
+		_, err := FetchRetentionScan(context.TODO(), server.Client(), server.URL, "wrong", 0)
+		Expect(err).To(MatchError(ContainSubstring("401")))
+		Expect(RemoveVoucher(context.TODO(), server.Client(), server.URL, "wrong", guid)).To(MatchError(ContainSubstring("401")))
+
+		scan, err := FetchRetentionScan(context.TODO(), server.Client(), server.URL, "s3cr3t", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scan.Expired).To(Equal([]string{guid}))
+		Expect(RemoveVoucher(context.TODO(), server.Client(), server.URL, "s3cr3t", guid)).To(Succeed())
+		Expect(filepath.Join(dir, guid)).NotTo(BeAnExistingFile())
+		Expect(RemoveVoucher(context.TODO(), server.Client(), server.URL, "s3cr3t", guid)).To(Succeed())
+
+		inventory, err := Fetch(context.TODO(), server.Client(), server.URL, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Devices).To(BeEmpty())
+		Expect(inventory.Invalid).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Export", func() {
+	var (
+		server         *httptest.Server
+		dir, tokenFile string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		data, err := os.ReadFile(filepath.Join("testdata", guid))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, guid), data, 0o600)).To(Succeed())
+		tokenFile = filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0o600)).To(Succeed())
+		server = httptest.NewServer(ExportHandler(dir, tokenFile))
+		DeferCleanup(server.Close)
+	})
+
+	get := func(path, token string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.RequestURI = ""
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := server.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	It("should only serve vouchers to clients with the token", func() {
+		status, _ := get(ExportPath, "wrong")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+		status, _ = get(ExportPath+"/"+guid, "")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should require the token on the inventory while the API is served", func() {
+		inventoryServer := httptest.NewServer(Handler(dir, nil, "", tokenFile))
+		defer inventoryServer.Close()
+		resp, err := inventoryServer.Client().Get(inventoryServer.URL + Path)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		_, err = FetchVoucher(context.TODO(), inventoryServer.Client(), inventoryServer.URL, "", guid)
+		Expect(err).To(MatchError(ContainSubstring("401")))
+		_, err = Fetch(context.TODO(), inventoryServer.Client(), inventoryServer.URL, "wrong")
+		Expect(err).To(MatchError(ContainSubstring("401")))
+
+		inventory, err := Fetch(context.TODO(), inventoryServer.Client(), inventoryServer.URL, "s3cr3t")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Devices).To(HaveLen(1))
+		data, err := FetchVoucher(context.TODO(), inventoryServer.Client(), inventoryServer.URL, "s3cr3t", guid)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix("-----BEGIN OWNERSHIP VOUCHER-----"))
+	})
+
+	It("should list the vouchers written since a time", func() {
+		status, body := get(ExportPath, "s3cr3t")
+		Expect(status).To(Equal(http.StatusOK))
+		list := ExportList{}
+		Expect(json.Unmarshal([]byte(body), &list)).To(Succeed())
+		Expect(list.Devices).To(HaveLen(1))
+		Expect(list.Devices[0].GUID).To(Equal(guid))
+
+		status, body = get(ExportPath+"?since="+time.Now().Add(time.Hour).UTC().Format(time.RFC3339), "s3cr3t")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"devices":[]}`))
+
+		status, _ = get(ExportPath+"?since=yesterday", "s3cr3t")
+		Expect(status).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should serve vouchers by GUID or serial number", func() {
+		status, body := get(ExportPath+"/"+guid, "s3cr3t")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(HavePrefix("-----BEGIN OWNERSHIP VOUCHER-----"))
+
+		// the test voucher has no device certificate, its serial number is its device info
+		status, body = get(ExportSerialsPath+"/test-device", "s3cr3t")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(HavePrefix("-----BEGIN OWNERSHIP VOUCHER-----"))
+
+		status, _ = get(ExportSerialsPath+"/unknown", "s3cr3t")
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("Registrations", func() {
+	It("should describe the registrations of a rendezvous server", func() {
+		expiry := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		store := &RegistrationStore{
+			Dir:    filepath.Join("testdata", "registered"),
+			expiry: func(string) (time.Time, bool) { return expiry, true },
+		}
+		server := httptest.NewServer(RegistrationsHandler(store))
+		defer server.Close()
+		inventory, err := FetchRegistrations(context.TODO(), server.Client(), server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Invalid).To(BeEmpty())
+		Expect(inventory.Registrations).To(HaveLen(1))
+		registration := inventory.Registrations[0]
+		Expect(registration.GUID).To(Equal(guid))
+		Expect(registration.OwnerKey.Fingerprint).To(HaveLen(64))
+		Expect(registration.Addresses).To(Equal([]string{"ip_address=10.0.0.2 port=8081 protocol=http", "dns=owner.example.com port=443 protocol=https"}))
+		Expect(registration.ExpiryTime).To(HaveValue(BeTemporally("==", expiry)))
+	})
+
+	It("should only list registrations named after a GUID", func() {
+		dir := GinkgoT().TempDir()
+		data, err := os.ReadFile(filepath.Join("testdata", "registered", guid))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "not-a-guid"), data, 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, guid), []byte("garbage"), 0o600)).To(Succeed())
+		inventory, err := (&RegistrationStore{Dir: dir}).Scan()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Registrations).To(BeEmpty())
+		Expect(inventory.Invalid).To(ConsistOf("not-a-guid", guid))
+	})
+})
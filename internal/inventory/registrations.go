@@ -0,0 +1,175 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+// RegistrationsPath is the path of the inventory of the registrations of a rendezvous server
+const RegistrationsPath = "/registrations"
+
+// ttlAttr is the extended attribute holding the expiry time of the entries of directory stores,
+// in seconds since the epoch as a little-endian integer
+const ttlAttr = "user.store_ttl"
+
+// Registration describes the TO0 registration of a device held by a rendezvous server
+type Registration struct {
+	GUID     string `json:"guid"`
+	OwnerKey Key    `json:"ownerKey"`
+	// Addresses are the TO2 addresses of the owner onboarding server
+	Addresses []string  `json:"addresses,omitempty"`
+	ModTime   time.Time `json:"modTime"`
+	// ExpiryTime is the end of the registration, unset if the store doesn't record it
+	ExpiryTime *time.Time `json:"expiryTime,omitempty"`
+}
+
+// RegistrationInventory lists the registrations of a store, and the files that are not registrations
+type RegistrationInventory struct {
+	Registrations []Registration `json:"registrations"`
+	Invalid       []string       `json:"invalid,omitempty"`
+}
+
+// RegistrationStore reads the registrations of the directory store of a rendezvous server
+type RegistrationStore struct {
+	Dir string
+
+	// expiry returns the expiry time of the registration of a file, defaults to storeTTL
+	expiry func(path string) (time.Time, bool)
+}
+
+// Scan parses the registrations of the store, sorted by GUID. The files are named after the GUID
+// of their device, hidden files and directories are skipped.
+func (s *RegistrationStore) Scan() (*RegistrationInventory, error) {
+	expiry := s.expiry
+	if expiry == nil {
+		expiry = storeTTL
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	inventory := &RegistrationInventory{Registrations: []Registration{}}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(s.Dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r, err := voucher.ParseRegistration(data)
+		if err != nil || !isGUID(entry.Name()) {
+			inventory.Invalid = append(inventory.Invalid, entry.Name())
+			continue
+		}
+		registration := Registration{
+			GUID:     strings.ToLower(entry.Name()),
+			OwnerKey: describeKey(r.OwnerKey),
+			ModTime:  info.ModTime().UTC(),
+		}
+		for _, address := range r.Addresses {
+			registration.Addresses = append(registration.Addresses, address.String())
+		}
+		if t, ok := expiry(path); ok {
+			t = t.UTC()
+			registration.ExpiryTime = &t
+		}
+		inventory.Registrations = append(inventory.Registrations, registration)
+	}
+	sort.Slice(inventory.Registrations, func(i, j int) bool {
+		return inventory.Registrations[i].GUID < inventory.Registrations[j].GUID
+	})
+	return inventory, nil
+}
+
+func isGUID(name string) bool {
+	return len(name) == 36 && strings.Trim(strings.ToLower(name), "0123456789abcdef-") == ""
+}
+
+// storeTTL reads the expiry time of an entry of a directory store
+func storeTTL(path string) (time.Time, bool) {
+	value := make([]byte, 8)
+	n, err := unix.Getxattr(path, ttlAttr, value)
+	if err != nil || n != len(value) {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.LittleEndian.Uint64(value)), 0), true
+}
+
+// RegistrationsHandler serves the registrations of a store
+func RegistrationsHandler(store *RegistrationStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RegistrationsPath, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inventory, err := store.Scan()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(inventory)
+	})
+	return mux
+}
+
+// FetchRegistrations reads the registrations served at a URL
+func FetchRegistrations(ctx context.Context, httpClient *http.Client, url string) (*RegistrationInventory, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+RegistrationsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = DefaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GET %s: %s: %s", RegistrationsPath, resp.Status, strings.TrimSpace(string(body)))
+	}
+	inventory := &RegistrationInventory{}
+	if err := json.NewDecoder(resp.Body).Decode(inventory); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+// RetentionPath is the path of the retention scan, which lists the vouchers to prune
+const RetentionPath = "/retention"
+
+// TokenEnv is the environment variable holding the token of the operator, required by the requests
+// downloading or pruning vouchers
+const TokenEnv = "INVENTORY_TOKEN"
+
+// to2PerformedAttr is the extended attribute the directory store of the owner-onboarding server
+// sets on the vouchers of the devices that completed TO2
+const to2PerformedAttr = "user.store_mdkey_fdo.to2.performed"
+
+// retentionStateFile records when the devices of the store were first seen onboarded, in the state
+// directory
+const retentionStateFile = "retention.json"
+
+// RetentionScan lists the vouchers of the devices onboarded for longer than the retention period
+type RetentionScan struct {
+	// Onboarded counts the vouchers of the devices that completed TO2
+	Onboarded int `json:"onboarded"`
+	// Expired lists the GUIDs of the vouchers to prune
+	Expired []string `json:"expired"`
+}
+
+// Retention prunes the vouchers of the devices that completed onboarding from a directory store
+type Retention struct {
+	Dir string
+	// ArchiveDir receives the pruned vouchers, which are deleted if it is empty
+	ArchiveDir string
+	// StateDir holds the record of the devices seen onboarded, out of the store of the server
+	StateDir string
+	// Token authenticates the requests of the operator
+	Token string
+
+	// onboarded reports whether the device of a voucher completed TO2, defaults to to2Performed
+	onboarded func(path string) bool
+	mu        sync.Mutex
+}
+
+// Scan lists the vouchers of the devices onboarded for longer than keep. The devices are considered
+// onboarded from the first scan finding their voucher marked by the store.
+func (r *Retention) Scan(keep time.Duration, now time.Time) (*RetentionScan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	onboarded := r.onboarded
+	if onboarded == nil {
+		onboarded = to2Performed
+	}
+	since, err := r.readState()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	scan := &RetentionScan{Expired: []string{}}
+	state := map[string]time.Time{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(r.Dir, entry.Name())
+		if !onboarded(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		v, err := voucher.Parse(data)
+		if err != nil {
+			continue
+		}
+		guid := v.GUID.String()
+		scan.Onboarded++
+		state[guid] = now.UTC()
+		if t, ok := since[guid]; ok {
+			state[guid] = t
+		}
+		if !now.Before(state[guid].Add(keep)) {
+			scan.Expired = append(scan.Expired, guid)
+		}
+	}
+	return scan, r.writeState(state)
+}
+
+// Remove moves the voucher of a device to the archive directory, or deletes it. It returns false if
+// the directory holds no voucher of the device.
+func (r *Retention) Remove(guid string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path, data, err := find(r.Dir, guid)
+	if err != nil || path == "" {
+		return false, err
+	}
+	if r.ArchiveDir != "" {
+		// the archive is usually another volume, where the voucher can't be renamed to
+		if err := os.WriteFile(filepath.Join(r.ArchiveDir, filepath.Base(path)), data, 0o600); err != nil {
+			return false, err
+		}
+	}
+	return true, os.Remove(path)
+}
+
+func (r *Retention) readState() (map[string]time.Time, error) {
+	state := map[string]time.Time{}
+	data, err := os.ReadFile(filepath.Join(r.StateDir, retentionStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		// the state is only a record of the first scans, start over
+		return map[string]time.Time{}, nil
+	}
+	return state, nil
+}
+
+func (r *Retention) writeState(state map[string]time.Time) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(r.StateDir, retentionStateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(r.StateDir, retentionStateFile))
+}
+
+// authorized checks the bearer token of a request
+func (r *Retention) authorized(w http.ResponseWriter, req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (r *Retention) serveScan(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authorized(w, req) {
+		return
+	}
+	keep, err := time.ParseDuration(req.URL.Query().Get("keep"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid keep parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	scan, err := r.Scan(keep, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(scan)
+}
+
+func (r *Retention) serveRemove(w http.ResponseWriter, req *http.Request, guid string) {
+	if !r.authorized(w, req) {
+		return
+	}
+	found, err := r.Remove(guid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.NotFound(w, req)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// to2Performed reads the attribute the store sets on the vouchers of onboarded devices, a text or
+// CBOR boolean
+func to2Performed(path string) bool {
+	value := make([]byte, 16)
+	n, err := unix.Getxattr(path, to2PerformedAttr, value)
+	if err != nil {
+		return false
+	}
+	value = bytes.TrimSpace(value[:n])
+	switch string(value) {
+	case "", "false", "0", "\xf4":
+		return false
+	}
+	return true
+}
+
+// FetchRetentionScan lists the vouchers onboarded for longer than keep in the inventory served at a URL
+func FetchRetentionScan(ctx context.Context, httpClient *http.Client, baseURL, token string, keep time.Duration) (*RetentionScan, error) {
+	query := url.Values{"keep": []string{keep.String()}}
+	resp, err := do(ctx, httpClient, http.MethodPost, strings.TrimSuffix(baseURL, "/")+RetentionPath+"?"+query.Encode(), token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("POST %s: %s: %s", RetentionPath, resp.Status, strings.TrimSpace(string(body)))
+	}
+	scan := &RetentionScan{}
+	if err := json.NewDecoder(resp.Body).Decode(scan); err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
+// RemoveVoucher prunes the voucher of a device from the inventory served at a URL. A voucher that
+// is already gone is not an error.
+func RemoveVoucher(ctx context.Context, httpClient *http.Client, baseURL, token, guid string) error {
+	resp, err := do(ctx, httpClient, http.MethodDelete, strings.TrimSuffix(baseURL, "/")+Path+"/"+guid, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("DELETE %s/%s: %s: %s", Path, guid, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func do(ctx context.Context, httpClient *http.Client, method, target, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if httpClient == nil {
+		httpClient = DefaultHTTPClient
+	}
+	return httpClient.Do(req)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keys generates and parses the keys and certificates used by FDO servers
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Key types as named in the FDO server configuration
+const (
+	SECP256R1 = "SECP256R1"
+	SECP384R1 = "SECP384R1"
+)
+
+// Subject is the subject of a self-signed certificate
+type Subject struct {
+	CommonName   string
+	Organization string
+	Country      string
+}
+
+func curve(keyType string) (elliptic.Curve, error) {
+	switch keyType {
+	case SECP256R1:
+		return elliptic.P256(), nil
+	case SECP384R1:
+		return elliptic.P384(), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", keyType)
+}
+
+// KeyType returns the FDO key type of a public key, empty if it isn't an ECDSA key of a supported curve
+func KeyType(pub crypto.PublicKey) string {
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	switch ecKey.Curve {
+	case elliptic.P256():
+		return SECP256R1
+	case elliptic.P384():
+		return SECP384R1
+	}
+	return ""
+}
+
+// GenerateKey generates an ECDSA private key of the given type
+func GenerateKey(keyType string) (*ecdsa.PrivateKey, error) {
+	c, err := curve(keyType)
+	if err != nil {
+		return nil, err
+	}
+	return ecdsa.GenerateKey(c, rand.Reader)
+}
+
+// MarshalKey encodes a private key in DER (SEC 1) format, as read by FDO servers
+func MarshalKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	return x509.MarshalECPrivateKey(key)
+}
+
+// ParseKey decodes an ECDSA private key in DER format, either SEC 1 or PKCS #8
+func ParseKey(der []byte) (*ecdsa.PrivateKey, error) {
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.New("not an EC private key in DER format")
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an EC private key")
+	}
+	return ecKey, nil
+}
+
+// ParsePEMKey decodes an ECDSA private key in PEM format, either SEC 1 or PKCS #8
+func ParsePEMKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no private key found in PEM data")
+	}
+	return ParseKey(block.Bytes)
+}
+
+// SelfSignedCert issues a certificate signed by its own key and encodes it in PEM format.
+// The certificate of a CA can sign other certificates, e.g. the device certificates.
+func SelfSignedCert(key *ecdsa.PrivateKey, subject Subject, ca bool, notBefore time.Time, validity time.Duration) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   subject.CommonName,
+			Organization: []string{subject.Organization},
+			Country:      []string{subject.Country},
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if ca {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// ParseCert decodes the first certificate of a PEM bundle
+func ParseCert(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no certificate found in PEM data")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// ParseCerts parses all the certificates of PEM data, e.g. a bundle of trusted certificates
+func ParseCerts(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in PEM data")
+	}
+	return certs, nil
+}
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA384Fingerprint returns the hex encoded SHA-384 fingerprint of a certificate
+func SHA384Fingerprint(cert *x509.Certificate) string {
+	sum := sha512.Sum384(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// PublicKeyFingerprint returns the hex encoded SHA-256 hash of the DER encoded public key, which
+// doesn't change when a certificate is reissued for the same key
+func PublicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keysource fetches the private keys of FDO servers from external key stores. It runs in the
+// init container of the server pods, the keys are written to a memory-backed volume.
+package keysource
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// Command is the argument of the operator binary that fetches the keys of a server
+const Command = "fetch-keys"
+
+// ConfigEnv is the environment variable holding the keys to fetch, as JSON
+const ConfigEnv = "FDO_KEY_SOURCES"
+
+// Key is a private key to fetch
+type Key struct {
+	// Path of the file the key is written to in DER format
+	Path string `json:"path"`
+	// Source of the key
+	Source fdov1beta1.KeySource `json:"source"`
+	// TokenEnv and CACertEnv are the environment variables holding the Vault token and CA certificate
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+	CACertEnv string `json:"caCertEnv,omitempty"`
+}
+
+// Source fetches a private key from an external key store
+type Source interface {
+	Fetch(ctx context.Context) (*ecdsa.PrivateKey, error)
+}
+
+// NewSource returns the source of a key, getenv reads the environment variables of the key
+func NewSource(key Key, getenv func(string) string) (Source, error) {
+	if key.Source.Vault != nil {
+		return newVault(*key.Source.Vault, lookup(getenv, key.TokenEnv), lookup(getenv, key.CACertEnv))
+	}
+	return nil, errors.New("no key store set")
+}
+
+func lookup(getenv func(string) string, name string) string {
+	if name == "" {
+		return ""
+	}
+	return getenv(name)
+}
+
+// Run fetches the keys of a JSON configuration and writes them to their files
+func Run(ctx context.Context, config string, getenv func(string) string) error {
+	toFetch := []Key{}
+	if err := json.Unmarshal([]byte(config), &toFetch); err != nil {
+		return fmt.Errorf("invalid %s: %w", ConfigEnv, err)
+	}
+	for _, k := range toFetch {
+		source, err := NewSource(k, getenv)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k.Path, err)
+		}
+		key, err := source.Fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k.Path, err)
+		}
+		der, err := keys.MarshalKey(key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k.Path, err)
+		}
+		// only the user of the pod may read the key, the server container must run as the same user
+		if err := os.WriteFile(k.Path, der, 0400); err != nil {
+			return err
+		}
+	}
+	return nil
+}
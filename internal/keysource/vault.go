@@ -0,0 +1,226 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keysource
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// serviceAccountTokenPath is the token of the service account of the pod, used by the Kubernetes auth method
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// vault reads private keys from HashiCorp Vault through its HTTP API
+type vault struct {
+	spec   fdov1beta1.VaultKeySource
+	token  string
+	client *http.Client
+	// jwtPath is the service account token sent to the Kubernetes auth method
+	jwtPath string
+}
+
+func newVault(spec fdov1beta1.VaultKeySource, token, caCert string) (*vault, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if caCert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, errors.New("no certificate found in the Vault CA certificate")
+		}
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}
+	}
+	return &vault{spec: spec, token: token, client: client, jwtPath: serviceAccountTokenPath}, nil
+}
+
+// Fetch logs in to Vault and reads the private key from the KV or the transit secrets engine
+func (v *vault) Fetch(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	if err := v.login(ctx); err != nil {
+		return nil, err
+	}
+	if v.spec.KV != nil {
+		return v.readKV(ctx, *v.spec.KV)
+	}
+	if v.spec.Transit != nil {
+		return v.exportTransit(ctx, *v.spec.Transit)
+	}
+	return nil, errors.New("one of kv and transit must be set")
+}
+
+func (v *vault) login(ctx context.Context) error {
+	auth := v.spec.Auth.Kubernetes
+	if auth == nil {
+		if v.token == "" {
+			return errors.New("the Vault token is empty")
+		}
+		return nil
+	}
+	jwt, err := os.ReadFile(v.jwtPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the service account token: %w", err)
+	}
+	mount := auth.MountPath
+	if mount == "" {
+		mount = "kubernetes"
+	}
+	response := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	body := map[string]string{"role": auth.Role, "jwt": strings.TrimSpace(string(jwt))}
+	if err := v.request(ctx, http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", body, &response); err != nil {
+		return err
+	}
+	if response.Auth.ClientToken == "" {
+		return errors.New("Vault returned no client token")
+	}
+	v.token = response.Auth.ClientToken
+	return nil
+}
+
+func (v *vault) readKV(ctx context.Context, kv fdov1beta1.VaultKV) (*ecdsa.PrivateKey, error) {
+	mount := strings.Trim(kv.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	field := kv.Field
+	if field == "" {
+		field = "key"
+	}
+	secretPath := strings.Trim(kv.Path, "/")
+
+	var data map[string]interface{}
+	if kv.Version == 1 {
+		response := struct {
+			Data map[string]interface{} `json:"data"`
+		}{}
+		if err := v.request(ctx, http.MethodGet, mount+"/"+secretPath, nil, &response); err != nil {
+			return nil, err
+		}
+		data = response.Data
+	} else {
+		response := struct {
+			Data struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}{}
+		if err := v.request(ctx, http.MethodGet, mount+"/data/"+secretPath, nil, &response); err != nil {
+			return nil, err
+		}
+		data = response.Data.Data
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no field %s", mount, secretPath, field)
+	}
+	return parsePrivateKey(value)
+}
+
+func (v *vault) exportTransit(ctx context.Context, transit fdov1beta1.VaultTransit) (*ecdsa.PrivateKey, error) {
+	mount := strings.Trim(transit.Mount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+	response := struct {
+		Data struct {
+			Keys map[string]string `json:"keys"`
+		} `json:"data"`
+	}{}
+	if err := v.request(ctx, http.MethodGet, mount+"/export/signing-key/"+transit.Key, nil, &response); err != nil {
+		return nil, err
+	}
+	version := transit.Version
+	if version == 0 {
+		for k := range response.Data.Keys {
+			if n, err := strconv.Atoi(k); err == nil && n > version {
+				version = n
+			}
+		}
+	}
+	value, ok := response.Data.Keys[strconv.Itoa(version)]
+	if !ok {
+		return nil, fmt.Errorf("transit key %s has no exported version %d", transit.Key, version)
+	}
+	return parsePrivateKey(value)
+}
+
+// request calls the Vault API and decodes the JSON response into out
+func (v *vault) request(ctx context.Context, method, apiPath string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.spec.Address, "/")+"/v1/"+apiPath, reader)
+	if err != nil {
+		return err
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.spec.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.spec.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errs := struct {
+			Errors []string `json:"errors"`
+		}{}
+		_ = json.NewDecoder(resp.Body).Decode(&errs)
+		return fmt.Errorf("Vault %s %s: %s %s", method, apiPath, resp.Status, strings.Join(errs.Errors, "; "))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// parsePrivateKey parses a private key in PEM or base64 encoded DER format
+func parsePrivateKey(value string) (*ecdsa.PrivateKey, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return keys.ParsePEMKey([]byte(value))
+	}
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("the private key is neither in PEM nor in base64 encoded DER format")
+	}
+	return keys.ParseKey(der)
+}
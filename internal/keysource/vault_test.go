@@ -0,0 +1,235 @@
+package keysource
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+var _ = Describe("Vault", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+		pemKey   string
+		derKey   []byte
+		spec     fdov1beta1.VaultKeySource
+		jwtPath  string
+	)
+
+	BeforeEach(func() {
+		key, err := keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		derKey, err = keys.MarshalKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		sec1, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		pemKey = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+
+		jwtPath = filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(jwtPath, []byte("service-account-jwt\n"), 0600)).To(Succeed())
+
+		// responses of the Vault API, as returned by a dev server
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			var response interface{}
+			switch r.URL.Path {
+			case "/v1/auth/kubernetes/login":
+				body := map[string]string{}
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				if body["role"] != "fdo-owner" || body["jwt"] != "service-account-jwt" {
+					w.WriteHeader(http.StatusForbidden)
+					response = map[string]interface{}{"errors": []string{"permission denied"}}
+					break
+				}
+				response = map[string]interface{}{"auth": map[string]interface{}{"client_token": "client-token"}}
+			case "/v1/secret/data/fdo/owner":
+				response = map[string]interface{}{"data": map[string]interface{}{"data": map[string]interface{}{"key": pemKey}}}
+			case "/v1/kv/fdo/owner":
+				response = map[string]interface{}{"data": map[string]interface{}{"der": base64.StdEncoding.EncodeToString(derKey)}}
+			case "/v1/transit/export/signing-key/fdo-owner":
+				response = map[string]interface{}{"data": map[string]interface{}{"keys": map[string]string{"1": "garbage", "2": pemKey}}}
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				response = map[string]interface{}{"errors": []string{}}
+			}
+			Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+		}))
+		DeferCleanup(server.Close)
+
+		spec = fdov1beta1.VaultKeySource{
+			Address: server.URL,
+			Auth:    fdov1beta1.VaultAuth{Kubernetes: &fdov1beta1.VaultKubernetesAuth{Role: "fdo-owner"}},
+			KV:      &fdov1beta1.VaultKV{Path: "fdo/owner"},
+		}
+	})
+
+	fetch := func(token string) ([]byte, error) {
+		v, err := newVault(spec, token, "")
+		Expect(err).NotTo(HaveOccurred())
+		v.jwtPath = jwtPath
+		key, err := v.Fetch(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		return keys.MarshalKey(key)
+	}
+
+	It("should log in with the service account and read a KV v2 secret", func() {
+		Expect(fetch("")).To(Equal(derKey))
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1].Header.Get("X-Vault-Token")).To(Equal("client-token"))
+	})
+
+	It("should read a KV v1 secret holding a DER key with a token", func() {
+		spec.Auth = fdov1beta1.VaultAuth{TokenSecret: &fdov1beta1.SecretKeyReference{Name: "vault-token"}}
+		spec.Namespace = "fdo"
+		spec.KV = &fdov1beta1.VaultKV{Mount: "kv", Path: "fdo/owner", Field: "der", Version: 1}
+		Expect(fetch("static-token")).To(Equal(derKey))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Header.Get("X-Vault-Token")).To(Equal("static-token"))
+		Expect(requests[0].Header.Get("X-Vault-Namespace")).To(Equal("fdo"))
+	})
+
+	It("should export the latest version of a transit key", func() {
+		spec.KV = nil
+		spec.Transit = &fdov1beta1.VaultTransit{Key: "fdo-owner"}
+		Expect(fetch("")).To(Equal(derKey))
+
+		spec.Transit.Version = 1
+		_, err := fetch("")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should report the errors of Vault", func() {
+		spec.Auth.Kubernetes.Role = "other"
+		_, err := fetch("")
+		Expect(err).To(MatchError(ContainSubstring("403 Forbidden permission denied")))
+
+		spec.Auth = fdov1beta1.VaultAuth{TokenSecret: &fdov1beta1.SecretKeyReference{Name: "vault-token"}}
+		_, err = fetch("")
+		Expect(err).To(MatchError("the Vault token is empty"))
+
+		spec.KV.Field = "missing"
+		_, err = fetch("static-token")
+		Expect(err).To(MatchError("secret secret/fdo/owner has no field missing"))
+	})
+
+	It("should write the fetched keys in DER format", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "owner_key.der")
+		config, err := json.Marshal([]Key{{
+			Path:     path,
+			Source:   fdov1beta1.KeySource{Vault: &fdov1beta1.VaultKeySource{Address: server.URL, Auth: fdov1beta1.VaultAuth{TokenSecret: &fdov1beta1.SecretKeyReference{Name: "vault-token"}}, KV: spec.KV}},
+			TokenEnv: "VAULT_TOKEN_0",
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		getenv := func(name string) string {
+			return map[string]string{"VAULT_TOKEN_0": "static-token"}[name]
+		}
+		Expect(Run(context.TODO(), string(config), getenv)).To(Succeed())
+		Expect(os.ReadFile(path)).To(Equal(derKey))
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0400)))
+	})
+})
+
+// The Vault dev server checks the requests against the real API, the tests are skipped if the vault
+// binary is not installed
+var _ = Describe("Vault dev server", Ordered, func() {
+	var address string
+
+	BeforeAll(func() {
+		binary, err := exec.LookPath("vault")
+		if err != nil {
+			Skip("vault is not installed")
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		listen := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+		address = "http://" + listen
+
+		cmd := exec.Command(binary, "server", "-dev", "-dev-root-token-id=root", "-dev-listen-address="+listen)
+		// the dev server writes its token to the home directory
+		cmd.Env = append(os.Environ(), "HOME="+GinkgoT().TempDir())
+		cmd.Stdout = GinkgoWriter
+		cmd.Stderr = GinkgoWriter
+		Expect(cmd.Start()).To(Succeed())
+		DeferCleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		Eventually(func() (int, error) {
+			resp, err := http.Get(address + "/v1/sys/health")
+			if err != nil {
+				return 0, err
+			}
+			resp.Body.Close()
+			return resp.StatusCode, nil
+		}).WithTimeout(30 * time.Second).Should(Equal(http.StatusOK))
+	})
+
+	// call sends a request with the root token to set up the dev server
+	call := func(method, apiPath string, body interface{}) {
+		data, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+		req, err := http.NewRequest(method, address+"/v1/"+apiPath, bytes.NewReader(data))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("X-Vault-Token", "root")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(BeNumerically("<", 300), apiPath)
+	}
+
+	fetch := func(spec fdov1beta1.VaultKeySource) *ecdsa.PrivateKey {
+		spec.Address = address
+		spec.Auth = fdov1beta1.VaultAuth{TokenSecret: &fdov1beta1.SecretKeyReference{Name: "vault-token"}}
+		v, err := newVault(spec, "root", "")
+		Expect(err).NotTo(HaveOccurred())
+		key, err := v.Fetch(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		return key
+	}
+
+	It("should read a PEM key from a KV v2 secret", func() {
+		key, err := keys.GenerateKey(keys.SECP384R1)
+		Expect(err).NotTo(HaveOccurred())
+		sec1, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		call(http.MethodPost, "secret/data/fdo/owner", map[string]interface{}{
+			"data": map[string]string{"key": string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))},
+		})
+		Expect(fetch(fdov1beta1.VaultKeySource{KV: &fdov1beta1.VaultKV{Path: "fdo/owner"}}).Equal(key)).To(BeTrue())
+	})
+
+	It("should export the latest version of an exportable transit key", func() {
+		call(http.MethodPost, "sys/mounts/transit", map[string]string{"type": "transit"})
+		call(http.MethodPost, "transit/keys/fdo-owner", map[string]interface{}{"type": "ecdsa-p256", "exportable": true})
+		first := fetch(fdov1beta1.VaultKeySource{Transit: &fdov1beta1.VaultTransit{Key: "fdo-owner"}})
+		Expect(first.Curve.Params().Name).To(Equal("P-256"))
+
+		call(http.MethodPost, "transit/keys/fdo-owner/rotate", map[string]string{})
+		latest := fetch(fdov1beta1.VaultKeySource{Transit: &fdov1beta1.VaultTransit{Key: "fdo-owner"}})
+		Expect(latest.Equal(first)).To(BeFalse())
+		Expect(fetch(fdov1beta1.VaultKeySource{Transit: &fdov1beta1.VaultTransit{Key: "fdo-owner", Version: 1}}).Equal(first)).To(BeTrue())
+	})
+})
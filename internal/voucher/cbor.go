@@ -0,0 +1,346 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package voucher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CBOR major types (RFC 8949)
+const (
+	majorUnsigned byte = iota
+	majorNegative
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+// maxItems bounds the length of the arrays and maps of a voucher, which are small
+const maxItems = 1 << 16
+
+// maxDepth bounds the nesting of the arrays, maps and tags of a voucher, which are a few levels deep
+const maxDepth = 32
+
+// Tag is a tagged CBOR data item
+type Tag struct {
+	Number  uint64
+	Content any
+}
+
+// RawMessage is an encoded CBOR data item, encoded as is
+type RawMessage []byte
+
+var errTruncated = errors.New("cbor: unexpected end of data")
+
+// decoder reads CBOR data items into int64, []byte, string, []any, map[any]any, Tag, bool, float64
+// and nil values. Indefinite lengths are not used by FDO and not supported.
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// decode decodes a single data item
+func decode(data []byte) (any, error) {
+	d := &decoder{data: data}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d bytes after the data item", len(data)-d.pos)
+	}
+	return v, nil
+}
+
+// decodeArray decodes an array and returns its items encoded, so that they can be hashed and
+// decoded later
+func decodeArray(data []byte) ([]RawMessage, error) {
+	d := &decoder{data: data}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorArray {
+		return nil, fmt.Errorf("cbor: expected an array, found major type %d", major)
+	}
+	if n > maxItems {
+		return nil, fmt.Errorf("cbor: array of %d items", n)
+	}
+	items := make([]RawMessage, 0, d.capacity(n))
+	for i := uint64(0); i < n; i++ {
+		item, err := d.raw()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d bytes after the array", len(data)-d.pos)
+	}
+	return items, nil
+}
+
+func (d *decoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errTruncated
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, errTruncated
+	}
+	buf := make([]byte, 8)
+	copy(buf[8-size:], d.data[d.pos:d.pos+size])
+	d.pos += size
+	return major, binary.BigEndian.Uint64(buf), nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// capacity returns the capacity to allocate for n items, which can't exceed the remaining bytes
+// since each item takes at least one
+func (d *decoder) capacity(n uint64) int {
+	if remaining := uint64(len(d.data) - d.pos); n > remaining {
+		return int(remaining)
+	}
+	return int(n)
+}
+
+func (d *decoder) raw() (RawMessage, error) {
+	start := d.pos
+	if _, err := d.value(); err != nil {
+		return nil, err
+	}
+	return RawMessage(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) value() (any, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major == majorArray || major == majorMap || major == majorTag {
+		if d.depth == maxDepth {
+			return nil, fmt.Errorf("cbor: data items nested more than %d levels deep", maxDepth)
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+	}
+	switch major {
+	case majorUnsigned:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer %d out of range", n)
+		}
+		return int64(n), nil
+	case majorNegative:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer -1-%d out of range", n)
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		b, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case majorText:
+		b, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		if n > maxItems {
+			return nil, fmt.Errorf("cbor: array of %d items", n)
+		}
+		items := make([]any, 0, d.capacity(n))
+		for i := uint64(0); i < n; i++ {
+			item, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case majorMap:
+		if n > maxItems {
+			return nil, fmt.Errorf("cbor: map of %d items", n)
+		}
+		// each entry takes at least two bytes
+		m := make(map[any]any, d.capacity(n)/2)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key %T", key)
+			}
+			if m[key], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorTag:
+		content, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: n, Content: content}, nil
+	default:
+		switch n {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		if d.data[d.pos-1]&0x1f == 26 {
+			return float64(math.Float32frombits(uint32(n))), nil
+		}
+		if d.data[d.pos-1]&0x1f == 27 {
+			return math.Float64frombits(n), nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+	}
+}
+
+// encode encodes int, int64, uint64, []byte, string, []any, map[any]any, Tag, RawMessage, bool and
+// nil values. Map keys are sorted as required by the deterministic encoding of RFC 8949.
+func encode(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := encodeTo(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func encodeTo(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | 22)
+	case bool:
+		if v {
+			buf.WriteByte(majorSimple<<5 | 21)
+		} else {
+			buf.WriteByte(majorSimple<<5 | 20)
+		}
+	case int:
+		return encodeTo(buf, int64(v))
+	case int64:
+		if v < 0 {
+			encodeHead(buf, majorNegative, uint64(-1-v))
+		} else {
+			encodeHead(buf, majorUnsigned, uint64(v))
+		}
+	case uint64:
+		encodeHead(buf, majorUnsigned, v)
+	case []byte:
+		encodeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		encodeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case RawMessage:
+		buf.Write(v)
+	case []any:
+		encodeHead(buf, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeTo(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		entries := make([][2][]byte, 0, len(v))
+		for key, value := range v {
+			k, err := encode(key)
+			if err != nil {
+				return err
+			}
+			val, err := encode(value)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, [2][]byte{k, val})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i][0], entries[j][0]) < 0 })
+		encodeHead(buf, majorMap, uint64(len(entries)))
+		for _, entry := range entries {
+			buf.Write(entry[0])
+			buf.Write(entry[1])
+		}
+	case Tag:
+		encodeHead(buf, majorTag, v.Number)
+		return encodeTo(buf, v.Content)
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", v)
+	}
+	return nil
+}
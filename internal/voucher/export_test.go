@@ -0,0 +1,10 @@
+package voucher
+
+// Encode, Decode and ErrTruncated expose the CBOR codec to the tests
+var (
+	Encode       = encode
+	Decode       = decode
+	ErrTruncated = errTruncated
+)
+
+const COSESign1Tag = coseSign1Tag
@@ -0,0 +1,282 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package voucher
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// Hash types of the FDO specification
+const (
+	HashTypeSHA256 int64 = -16
+	HashTypeSHA384 int64 = -43
+)
+
+// COSE algorithms of the entry signatures, and the label of the algorithm in COSE headers
+const (
+	coseAlgES256    = -7
+	coseAlgES384    = -35
+	coseHeaderAlg   = 1
+	coseSigContext1 = "Signature1"
+)
+
+// NewX509PublicKey returns an EC public key in the X509 encoding, as owners usually provide it
+func NewX509PublicKey(pub *ecdsa.PublicKey) (PublicKey, error) {
+	var keyType KeyType
+	switch pub.Curve {
+	case elliptic.P256():
+		keyType = KeyTypeSECP256R1
+	case elliptic.P384():
+		keyType = KeyTypeSECP384R1
+	default:
+		return PublicKey{}, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	body, err := encode(der)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	return PublicKey{Type: keyType, Encoding: KeyEncodingX509, Body: body}, nil
+}
+
+// Marshal encodes the voucher in CBOR
+func (v *Voucher) Marshal() ([]byte, error) {
+	entries := make([]any, 0, len(v.Entries))
+	for _, entry := range v.Entries {
+		entries = append(entries, entry.raw)
+	}
+	return encode([]any{v.ProtocolVersion, []byte(v.header), v.hmac, v.certChain, entries})
+}
+
+// MarshalPEM encodes the voucher in PEM format, as FDO servers store it
+func (v *Voucher) MarshalPEM() ([]byte, error) {
+	data, err := v.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PEMType, Bytes: data}), nil
+}
+
+// Extend appends an entry transferring the ownership of the device to the next owner. The entry is
+// signed with the private key of the current owner.
+func (v *Voucher) Extend(owner *ecdsa.PrivateKey, next PublicKey) error {
+	current := v.OwnerKey()
+	expected, err := current.Fingerprint()
+	if err != nil {
+		return fmt.Errorf("invalid owner key: %w", err)
+	}
+	if fingerprint, err := keys.PublicKeyFingerprint(&owner.PublicKey); err != nil || fingerprint != expected {
+		return errors.New("the private key doesn't match the owner key of the voucher")
+	}
+	if next.Type != current.Type {
+		return fmt.Errorf("the next owner key is of type %s instead of %s", next.Type, current.Type)
+	}
+	if _, err := next.PublicKey(); err != nil {
+		return fmt.Errorf("invalid next owner key: %w", err)
+	}
+	hashType, alg, err := algorithms(current.Type)
+	if err != nil {
+		return err
+	}
+	previousHash, err := hashOf(hashType, v.previousEntryData(len(v.Entries)))
+	if err != nil {
+		return err
+	}
+	headerHash, err := hashOf(hashType, v.headerInfo())
+	if err != nil {
+		return err
+	}
+	nextKey := []any{int64(next.Type), int64(next.Encoding), next.Body}
+	fields := []any{[]any{previousHash.Type, previousHash.Value}, []any{headerHash.Type, headerHash.Value}}
+	// FDO 1.1 added the extra field before the public key
+	if v.ProtocolVersion >= 101 {
+		fields = append(fields, nil)
+	}
+	payload, err := encode(append(fields, nextKey))
+	if err != nil {
+		return err
+	}
+	protected, err := encode(map[any]any{int64(coseHeaderAlg): int64(alg)})
+	if err != nil {
+		return err
+	}
+	signature, err := sign(owner, hashType, protected, payload)
+	if err != nil {
+		return err
+	}
+	var sign1 any = Tag{Number: coseSign1Tag, Content: []any{protected, map[any]any{}, payload, signature}}
+	cose, err := encode(sign1)
+	if err != nil {
+		return err
+	}
+	raw := RawMessage(cose)
+	// follow the style of the existing entries, wrapped in a byte string or not
+	if n := len(v.Entries); n > 0 && len(v.Entries[n-1].raw) != len(v.Entries[n-1].cose) {
+		if raw, err = encode(cose); err != nil {
+			return err
+		}
+	}
+	entry, err := parseEntry(raw)
+	if err != nil {
+		return err
+	}
+	v.Entries = append(v.Entries, entry)
+	return nil
+}
+
+// VerifyEntries checks that the entries of the voucher form a chain, each entry being signed by the
+// owner of the previous one and holding its hash
+func (v *Voucher) VerifyEntries() error {
+	signer := v.ManufacturerKey
+	for i, entry := range v.Entries {
+		previousHash, err := hashOf(entry.PreviousHash.Type, v.previousEntryData(i))
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		if !previousHash.equal(entry.PreviousHash) {
+			return fmt.Errorf("entry %d: invalid previous entry hash", i)
+		}
+		headerHash, err := hashOf(entry.HeaderHash.Type, v.headerInfo())
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		if !headerHash.equal(entry.HeaderHash) {
+			return fmt.Errorf("entry %d: invalid header hash", i)
+		}
+		if err := entry.Verify(signer); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		signer = entry.PublicKey
+	}
+	return nil
+}
+
+// Verify checks the signature of the entry with the key of the previous owner
+func (e Entry) Verify(signer PublicKey) error {
+	key, err := signer.PublicKey()
+	if err != nil {
+		return fmt.Errorf("invalid signer key: %w", err)
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported signer key %T", key)
+	}
+	hashType, _, err := algorithms(signer.Type)
+	if err != nil {
+		return err
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	if len(e.Signature) != 2*size {
+		return errors.New("invalid signature length")
+	}
+	digest, err := sigStructureDigest(hashType, e.protected, e.payload)
+	if err != nil {
+		return err
+	}
+	r := new(big.Int).SetBytes(e.Signature[:size])
+	s := new(big.Int).SetBytes(e.Signature[size:])
+	if !ecdsa.Verify(pub, digest, r, s) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// previousEntryData returns the data hashed in the entry i, the header and its HMAC for the first
+// entry and the previous entry for the others
+func (v *Voucher) previousEntryData(i int) []byte {
+	if i == 0 {
+		header, _ := encode([]byte(v.header))
+		return append(header, v.hmac...)
+	}
+	return v.Entries[i-1].cose
+}
+
+// headerInfo returns the data of the header hash of the entries, the GUID and the device info
+func (v *Voucher) headerInfo() []byte {
+	return append(append([]byte{}, v.GUID[:]...), v.DeviceInfo...)
+}
+
+func (h Hash) equal(other Hash) bool {
+	return h.Type == other.Type && string(h.Value) == string(other.Value)
+}
+
+// algorithms returns the hash type and COSE algorithm of the signatures of a key type
+func algorithms(keyType KeyType) (int64, int, error) {
+	switch keyType {
+	case KeyTypeSECP256R1:
+		return HashTypeSHA256, coseAlgES256, nil
+	case KeyTypeSECP384R1:
+		return HashTypeSHA384, coseAlgES384, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported key type %s", keyType)
+}
+
+func hashOf(hashType int64, data []byte) (Hash, error) {
+	switch hashType {
+	case HashTypeSHA256:
+		sum := sha256.Sum256(data)
+		return Hash{Type: hashType, Value: sum[:]}, nil
+	case HashTypeSHA384:
+		sum := sha512.Sum384(data)
+		return Hash{Type: hashType, Value: sum[:]}, nil
+	}
+	return Hash{}, fmt.Errorf("unsupported hash type %d", hashType)
+}
+
+// sigStructureDigest hashes the COSE Sig_structure of a COSE_Sign1 structure
+func sigStructureDigest(hashType int64, protected, payload []byte) ([]byte, error) {
+	data, err := encode([]any{coseSigContext1, protected, []byte{}, payload})
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashOf(hashType, data)
+	if err != nil {
+		return nil, err
+	}
+	return hash.Value, nil
+}
+
+// sign computes a COSE ECDSA signature, the concatenation of r and s
+func sign(key *ecdsa.PrivateKey, hashType int64, protected, payload []byte) ([]byte, error) {
+	digest, err := sigStructureDigest(hashType, protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return nil, err
+	}
+	size := (key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package voucher
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+)
+
+// KeyType is the type of a public key of a voucher
+type KeyType int64
+
+const (
+	KeyTypeRSA2048Restr KeyType = 1
+	KeyTypeRSAPKCS      KeyType = 5
+	KeyTypeRSAPSS       KeyType = 6
+	KeyTypeSECP256R1    KeyType = 10
+	KeyTypeSECP384R1    KeyType = 11
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeRSA2048Restr:
+		return "RSA2048RESTR"
+	case KeyTypeRSAPKCS:
+		return "RSAPKCS"
+	case KeyTypeRSAPSS:
+		return "RSAPSS"
+	case KeyTypeSECP256R1:
+		return "SECP256R1"
+	case KeyTypeSECP384R1:
+		return "SECP384R1"
+	}
+	return fmt.Sprintf("KeyType(%d)", int64(t))
+}
+
+// KeyEncoding is the encoding of a public key of a voucher
+type KeyEncoding int64
+
+const (
+	KeyEncodingCrypto  KeyEncoding = 0
+	KeyEncodingX509    KeyEncoding = 1
+	KeyEncodingX5Chain KeyEncoding = 2
+	KeyEncodingCOSEKey KeyEncoding = 3
+)
+
+func (e KeyEncoding) String() string {
+	switch e {
+	case KeyEncodingCrypto:
+		return "Crypto"
+	case KeyEncodingX509:
+		return "X509"
+	case KeyEncodingX5Chain:
+		return "X5CHAIN"
+	case KeyEncodingCOSEKey:
+		return "COSEKEY"
+	}
+	return fmt.Sprintf("KeyEncoding(%d)", int64(e))
+}
+
+// PublicKey is a public key of a voucher, the key of the manufacturer or of an owner
+type PublicKey struct {
+	Type     KeyType
+	Encoding KeyEncoding
+	// Body is the encoded key, its format depends on the encoding
+	Body RawMessage
+}
+
+func parsePublicKey(raw RawMessage) (PublicKey, error) {
+	fields, err := decodeArray(raw)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	if len(fields) != 3 {
+		return PublicKey{}, fmt.Errorf("%d fields instead of 3", len(fields))
+	}
+	var keyType, encoding int64
+	if err := decodeItem(fields[0], &keyType); err != nil {
+		return PublicKey{}, fmt.Errorf("invalid key type: %w", err)
+	}
+	if err := decodeItem(fields[1], &encoding); err != nil {
+		return PublicKey{}, fmt.Errorf("invalid key encoding: %w", err)
+	}
+	return PublicKey{Type: KeyType(keyType), Encoding: KeyEncoding(encoding), Body: fields[2]}, nil
+}
+
+// Certificates parses the certificate chain of a key in X5CHAIN encoding
+func (k PublicKey) Certificates() ([]*x509.Certificate, error) {
+	if k.Encoding != KeyEncodingX5Chain {
+		return nil, fmt.Errorf("key encoding %s holds no certificates", k.Encoding)
+	}
+	chain, err := parseCertChain(k.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificates(chain)
+}
+
+// PublicKey returns the key, it supports the X509, X5CHAIN and COSEKEY encodings
+func (k PublicKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Encoding {
+	case KeyEncodingX509:
+		var der []byte
+		if err := decodeItem(k.Body, &der); err != nil {
+			return nil, err
+		}
+		return x509.ParsePKIXPublicKey(der)
+	case KeyEncodingX5Chain:
+		certs, err := k.Certificates()
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) == 0 {
+			return nil, errors.New("empty certificate chain")
+		}
+		return certs[0].PublicKey, nil
+	case KeyEncodingCOSEKey:
+		return parseCOSEKey(k.Body)
+	}
+	return nil, fmt.Errorf("unsupported key encoding %s", k.Encoding)
+}
+
+// Fingerprint returns the SHA-256 hash of the key in PKIX format, in hexadecimal. The fingerprint
+// of a key doesn't depend on its encoding.
+func (k PublicKey) Fingerprint() (string, error) {
+	key, err := k.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	return keys.PublicKeyFingerprint(key)
+}
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType   = 1
+	coseKeyTypeEC = 2
+	coseKeyCurve  = -1
+	coseKeyX      = -2
+	coseKeyY      = -3
+	coseCurveP256 = 1
+	coseCurveP384 = 2
+)
+
+func parseCOSEKey(raw RawMessage) (*ecdsa.PublicKey, error) {
+	value, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	params, ok := value.(map[any]any)
+	if !ok || params[int64(coseKeyType)] != int64(coseKeyTypeEC) {
+		return nil, errors.New("not an EC2 COSE key")
+	}
+	var curve elliptic.Curve
+	switch params[int64(coseKeyCurve)] {
+	case int64(coseCurveP256):
+		curve = elliptic.P256()
+	case int64(coseCurveP384):
+		curve = elliptic.P384()
+	default:
+		return nil, fmt.Errorf("unsupported COSE curve %v", params[int64(coseKeyCurve)])
+	}
+	x, ok1 := params[int64(coseKeyX)].([]byte)
+	y, ok2 := params[int64(coseKeyY)].([]byte)
+	if !ok1 || !ok2 {
+		return nil, errors.New("missing coordinates")
+	}
+	point := append([]byte{4}, append(x, y...)...)
+	px, py := elliptic.Unmarshal(curve, point)
+	if px == nil {
+		return nil, errors.New("invalid point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: px, Y: py}, nil
+}
+
+func parseCertificates(chain [][]byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, len(chain))
+	for _, der := range chain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package voucher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TO2Address is an address of the owner onboarding server of a device, as registered in TO0
+type TO2Address struct {
+	// IPAddress or DNS name of the server, either can be empty
+	IPAddress net.IP
+	DNS       string
+	Port      int64
+	// Protocol is the transport protocol, e.g. TransportHTTP
+	Protocol TransportProtocol
+}
+
+// TransportProtocol is the transport protocol of a TO2 address
+type TransportProtocol int64
+
+const (
+	TransportTCP TransportProtocol = iota + 1
+	TransportTLS
+	TransportHTTP
+	TransportCoAP
+	TransportHTTPS
+	TransportCoAPS
+)
+
+// transportProtocols are the names of the transport protocols
+var transportProtocols = []string{"tcp", "tls", "http", "coap", "https", "coaps"}
+
+func (p TransportProtocol) String() string {
+	if p >= TransportTCP && int(p) <= len(transportProtocols) {
+		return transportProtocols[p-1]
+	}
+	return fmt.Sprintf("TransportProtocol(%d)", int64(p))
+}
+
+// String formats an address like the rendezvous directives, e.g. "dns=owner.example.com port=8081 protocol=http"
+func (a TO2Address) String() string {
+	parts := []string{}
+	if a.IPAddress != nil {
+		parts = append(parts, "ip_address="+a.IPAddress.String())
+	}
+	if a.DNS != "" {
+		parts = append(parts, "dns="+a.DNS)
+	}
+	parts = append(parts, fmt.Sprintf("port=%d", a.Port), "protocol="+a.Protocol.String())
+	return strings.Join(parts, " ")
+}
+
+// Registration is the TO0 registration of a device held by a rendezvous server: the key of the
+// owner, which signed the registration, and the addresses the device reaches its owner at
+type Registration struct {
+	OwnerKey  PublicKey
+	Addresses []TO2Address
+}
+
+// ParseRegistration decodes a registration as stored by FDO rendezvous servers, an array of the
+// owner key and of the COSE_Sign1 to1d blob whose payload lists the TO2 addresses
+func ParseRegistration(data []byte) (*Registration, error) {
+	items, err := decodeArray(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registration: %w", err)
+	}
+	if len(items) != 2 {
+		return nil, fmt.Errorf("invalid registration: %d fields instead of 2", len(items))
+	}
+	r := &Registration{}
+	if r.OwnerKey, err = parsePublicKey(items[0]); err != nil {
+		return nil, fmt.Errorf("invalid owner key: %w", err)
+	}
+	_, _, payload, _, err := parseSign1(items[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid to1d: %w", err)
+	}
+	fields, err := decodeArray(payload)
+	if err != nil || len(fields) != 2 {
+		return nil, errors.New("invalid to1d: not an array of the addresses and the to0d hash")
+	}
+	addresses, err := decodeArray(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid TO2 addresses: %w", err)
+	}
+	for i, raw := range addresses {
+		address, err := parseTO2Address(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TO2 address %d: %w", i, err)
+		}
+		r.Addresses = append(r.Addresses, address)
+	}
+	return r, nil
+}
+
+func parseTO2Address(raw RawMessage) (TO2Address, error) {
+	fields, err := decodeArray(raw)
+	if err != nil {
+		return TO2Address{}, err
+	}
+	if len(fields) != 4 {
+		return TO2Address{}, fmt.Errorf("%d fields instead of 4", len(fields))
+	}
+	address := TO2Address{}
+	if !isNull(fields[0]) {
+		var ip []byte
+		if err := decodeItem(fields[0], &ip); err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+			return TO2Address{}, errors.New("invalid IP address")
+		}
+		address.IPAddress = net.IP(ip)
+	}
+	if !isNull(fields[1]) {
+		if err := decodeItem(fields[1], &address.DNS); err != nil {
+			return TO2Address{}, fmt.Errorf("invalid DNS name: %w", err)
+		}
+	}
+	if err := decodeItem(fields[2], &address.Port); err != nil {
+		return TO2Address{}, fmt.Errorf("invalid port: %w", err)
+	}
+	var protocol int64
+	if err := decodeItem(fields[3], &protocol); err != nil {
+		return TO2Address{}, fmt.Errorf("invalid protocol: %w", err)
+	}
+	address.Protocol = TransportProtocol(protocol)
+	return address, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package voucher
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// RendezvousVariable is a variable of a rendezvous instruction
+type RendezvousVariable int64
+
+const (
+	RendezvousDevOnly RendezvousVariable = iota
+	RendezvousOwnerOnly
+	RendezvousIPAddress
+	RendezvousDevPort
+	RendezvousOwnerPort
+	RendezvousDNS
+	RendezvousServerCertHash
+	RendezvousClientCertHash
+	RendezvousUserInput
+	RendezvousWifiSSID
+	RendezvousWifiPassword
+	RendezvousMedium
+	RendezvousProtocol
+	RendezvousDelaySec
+	RendezvousBypass
+	RendezvousExtended
+)
+
+// rendezvousVariables are the names of the variables in the configuration of FDO servers
+var rendezvousVariables = []string{
+	"dev_only", "owner_only", "ip_address", "device_port", "owner_port", "dns", "server_cert_hash",
+	"client_cert_hash", "user_input", "wifi_ssid", "wifi_pw", "medium", "protocol", "delaysec", "bypass",
+	"ext_rv",
+}
+
+// rendezvousProtocols are the names of the values of the protocol variable
+var rendezvousProtocols = []string{"rest", "http", "https", "tcp", "tls", "coap_tcp", "coap_udp"}
+
+func (v RendezvousVariable) String() string {
+	if v >= 0 && int(v) < len(rendezvousVariables) {
+		return rendezvousVariables[v]
+	}
+	return fmt.Sprintf("RendezvousVariable(%d)", int64(v))
+}
+
+// RendezvousInstruction sets a variable of a rendezvous directive
+type RendezvousInstruction struct {
+	Variable RendezvousVariable
+	// Value is the decoded value, nil for flags such as dev_only
+	Value any
+}
+
+func (i RendezvousInstruction) String() string {
+	if i.Value == nil {
+		return i.Variable.String()
+	}
+	value := fmt.Sprint(i.Value)
+	switch v := i.Value.(type) {
+	case []byte:
+		value = hex.EncodeToString(v)
+		if i.Variable == RendezvousIPAddress && (len(v) == net.IPv4len || len(v) == net.IPv6len) {
+			value = net.IP(v).String()
+		}
+	case int64:
+		if i.Variable == RendezvousProtocol && v >= 0 && int(v) < len(rendezvousProtocols) {
+			value = rendezvousProtocols[v]
+		}
+	case []any:
+		// hashes are encoded as [type, hash]
+		if len(v) == 2 {
+			if hash, ok := v[1].([]byte); ok {
+				value = hex.EncodeToString(hash)
+			}
+		}
+	}
+	return i.Variable.String() + "=" + value
+}
+
+// RendezvousDirective is a set of instructions telling a device how to reach a rendezvous server
+type RendezvousDirective []RendezvousInstruction
+
+// String formats a directive as space separated instructions, e.g. "dns=rv.example.com device_port=80"
+func (d RendezvousDirective) String() string {
+	instructions := make([]string, 0, len(d))
+	for _, i := range d {
+		instructions = append(instructions, i.String())
+	}
+	return strings.Join(instructions, " ")
+}
+
+func parseRendezvousInfo(raw RawMessage) ([]RendezvousDirective, error) {
+	value, err := decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	directives, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T", value)
+	}
+	info := make([]RendezvousDirective, 0, len(directives))
+	for _, d := range directives {
+		instructions, ok := d.([]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected directive %T", d)
+		}
+		directive := make(RendezvousDirective, 0, len(instructions))
+		for _, i := range instructions {
+			instruction, err := parseRendezvousInstruction(i)
+			if err != nil {
+				return nil, err
+			}
+			directive = append(directive, instruction)
+		}
+		info = append(info, directive)
+	}
+	return info, nil
+}
+
+func parseRendezvousInstruction(value any) (RendezvousInstruction, error) {
+	fields, ok := value.([]any)
+	if !ok || len(fields) < 1 || len(fields) > 2 {
+		return RendezvousInstruction{}, fmt.Errorf("unexpected instruction %v", value)
+	}
+	variable, ok := fields[0].(int64)
+	if !ok {
+		return RendezvousInstruction{}, fmt.Errorf("unexpected variable %v", fields[0])
+	}
+	instruction := RendezvousInstruction{Variable: RendezvousVariable(variable)}
+	if len(fields) == 2 {
+		instruction.Value = fields[1]
+		// values are CBOR data items wrapped in a byte string since FDO 1.1
+		if wrapped, ok := fields[1].([]byte); ok {
+			if decoded, err := decode(wrapped); err == nil {
+				instruction.Value = decoded
+			}
+		}
+	}
+	return instruction, nil
+}
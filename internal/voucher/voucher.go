@@ -0,0 +1,317 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package voucher decodes FDO ownership vouchers, as defined in the FIDO Device Onboard specification:
+// the CBOR header of a voucher, its rendezvous information and its chain of COSE signed entries.
+package voucher
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// PEMType is the type of the PEM blocks holding ownership vouchers
+const PEMType = "OWNERSHIP VOUCHER"
+
+// coseSign1Tag is the CBOR tag of COSE_Sign1 structures
+const coseSign1Tag = 18
+
+// GUID identifies a device
+type GUID [16]byte
+
+// String formats a GUID as an UUID, like FDO servers do
+func (g GUID) String() string {
+	h := hex.EncodeToString(g[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// Hash is a hash or an HMAC of a voucher
+type Hash struct {
+	Type  int64
+	Value []byte
+}
+
+// Voucher is a decoded ownership voucher
+type Voucher struct {
+	// ProtocolVersion is the FDO protocol version of the voucher, e.g. 101
+	ProtocolVersion int64
+	// GUID of the device
+	GUID GUID
+	// DeviceInfo describes the device, set by the manufacturer
+	DeviceInfo string
+	// RendezvousInfo lists the directives telling the device how to reach rendezvous servers
+	RendezvousInfo []RendezvousDirective
+	// ManufacturerKey is the public key of the manufacturer, which signs the first entry
+	ManufacturerKey PublicKey
+	// DeviceCertChainHash is the hash of the certificate chain of the device, if any
+	DeviceCertChainHash *Hash
+	// HeaderHMAC is the HMAC of the header computed by the device
+	HeaderHMAC Hash
+	// DeviceCertChain is the certificate chain of the device in DER format, if any
+	DeviceCertChain [][]byte
+	// Entries transfer the ownership of the device from the manufacturer to the owner
+	Entries []Entry
+
+	// header is the encoded header, hmac is the encoded HMAC and certChain the encoded device
+	// certificate chain
+	header    RawMessage
+	hmac      RawMessage
+	certChain RawMessage
+}
+
+// Entry is an entry of a voucher, signed by the owner of the previous entry
+type Entry struct {
+	// PreviousHash is the hash of the previous entry, or of the header and its HMAC
+	PreviousHash Hash
+	// HeaderHash is the hash of the GUID and the device info
+	HeaderHash Hash
+	// PublicKey is the public key of the owner the entry transfers the device to
+	PublicKey PublicKey
+	// Signature is the COSE signature of the entry
+	Signature []byte
+
+	// raw is the entry as encoded in the voucher, cose the encoded COSE_Sign1 structure
+	raw  RawMessage
+	cose RawMessage
+	// protected and payload are the signed protected header and payload
+	protected []byte
+	payload   []byte
+}
+
+// Parse decodes an ownership voucher in PEM or CBOR format
+func Parse(data []byte) (*Voucher, error) {
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("-----BEGIN")) {
+		block, _ := pem.Decode(trimmed)
+		if block == nil || block.Type != PEMType {
+			return nil, fmt.Errorf("no %s PEM block found", PEMType)
+		}
+		data = block.Bytes
+	}
+	items, err := decodeArray(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid voucher: %w", err)
+	}
+	if len(items) != 5 {
+		return nil, fmt.Errorf("invalid voucher: %d fields instead of 5", len(items))
+	}
+	v := &Voucher{hmac: items[2], certChain: items[3]}
+	if err := decodeItem(items[0], &v.ProtocolVersion); err != nil {
+		return nil, fmt.Errorf("invalid protocol version: %w", err)
+	}
+	if err := decodeItem(items[1], &v.header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if err := v.parseHeader(); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if v.HeaderHMAC, err = parseHash(items[2]); err != nil {
+		return nil, fmt.Errorf("invalid header HMAC: %w", err)
+	}
+	if v.DeviceCertChain, err = parseCertChain(items[3]); err != nil {
+		return nil, fmt.Errorf("invalid device certificate chain: %w", err)
+	}
+	entries, err := decodeArray(items[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid entries: %w", err)
+	}
+	for i, raw := range entries {
+		entry, err := parseEntry(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %d: %w", i, err)
+		}
+		v.Entries = append(v.Entries, entry)
+	}
+	return v, nil
+}
+
+// OwnerKey returns the public key of the current owner of the device, the key of the last entry
+func (v *Voucher) OwnerKey() PublicKey {
+	if len(v.Entries) == 0 {
+		return v.ManufacturerKey
+	}
+	return v.Entries[len(v.Entries)-1].PublicKey
+}
+
+// DeviceCertificates parses the certificate chain of the device
+func (v *Voucher) DeviceCertificates() ([]*x509.Certificate, error) {
+	return parseCertificates(v.DeviceCertChain)
+}
+
+func (v *Voucher) parseHeader() error {
+	fields, err := decodeArray(v.header)
+	if err != nil {
+		return err
+	}
+	if len(fields) != 6 {
+		return fmt.Errorf("%d fields instead of 6", len(fields))
+	}
+	var guid []byte
+	if err := decodeItem(fields[1], &guid); err != nil || len(guid) != len(v.GUID) {
+		return errors.New("invalid GUID")
+	}
+	copy(v.GUID[:], guid)
+	if v.RendezvousInfo, err = parseRendezvousInfo(fields[2]); err != nil {
+		return fmt.Errorf("invalid rendezvous info: %w", err)
+	}
+	if err := decodeItem(fields[3], &v.DeviceInfo); err != nil {
+		return fmt.Errorf("invalid device info: %w", err)
+	}
+	if v.ManufacturerKey, err = parsePublicKey(fields[4]); err != nil {
+		return fmt.Errorf("invalid manufacturer key: %w", err)
+	}
+	if !isNull(fields[5]) {
+		hash, err := parseHash(fields[5])
+		if err != nil {
+			return fmt.Errorf("invalid device certificate chain hash: %w", err)
+		}
+		v.DeviceCertChainHash = &hash
+	}
+	return nil
+}
+
+// parseCertChain parses a device certificate chain, an array of certificates either as is or
+// wrapped in a byte string, or null
+func parseCertChain(raw RawMessage) ([][]byte, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	var wrapped []byte
+	if decodeItem(raw, &wrapped) == nil {
+		raw = wrapped
+	}
+	items, err := decodeArray(raw)
+	if err != nil {
+		return nil, err
+	}
+	certs := make([][]byte, 0, len(items))
+	for _, item := range items {
+		var der []byte
+		if err := decodeItem(item, &der); err != nil {
+			return nil, err
+		}
+		certs = append(certs, der)
+	}
+	return certs, nil
+}
+
+// parseEntry parses a COSE_Sign1 entry, which may be tagged or wrapped in a byte string
+func parseEntry(raw RawMessage) (Entry, error) {
+	entry := Entry{raw: raw}
+	var err error
+	if entry.cose, entry.protected, entry.payload, entry.Signature, err = parseSign1(raw); err != nil {
+		return entry, err
+	}
+	fields, err := decodeArray(entry.payload)
+	if err != nil {
+		return entry, fmt.Errorf("invalid payload: %w", err)
+	}
+	// FDO 1.1 added the extra field before the public key
+	if len(fields) != 3 && len(fields) != 4 {
+		return entry, fmt.Errorf("payload of %d fields instead of 3 or 4", len(fields))
+	}
+	if entry.PreviousHash, err = parseHash(fields[0]); err != nil {
+		return entry, fmt.Errorf("invalid previous entry hash: %w", err)
+	}
+	if entry.HeaderHash, err = parseHash(fields[1]); err != nil {
+		return entry, fmt.Errorf("invalid header hash: %w", err)
+	}
+	if entry.PublicKey, err = parsePublicKey(fields[len(fields)-1]); err != nil {
+		return entry, fmt.Errorf("invalid public key: %w", err)
+	}
+	return entry, nil
+}
+
+// parseSign1 parses a COSE_Sign1 structure, which may be tagged or wrapped in a byte string. It
+// returns the structure without the byte string, its protected header, payload and signature.
+func parseSign1(raw RawMessage) (cose RawMessage, protected, payload, signature []byte, err error) {
+	cose = raw
+	var wrapped []byte
+	if decodeItem(raw, &wrapped) == nil {
+		cose = wrapped
+	}
+	value, err := decode(cose)
+	if err != nil {
+		return cose, nil, nil, nil, err
+	}
+	if tag, ok := value.(Tag); ok && tag.Number == coseSign1Tag {
+		value = tag.Content
+	}
+	sign1, ok := value.([]any)
+	if !ok || len(sign1) != 4 {
+		return cose, nil, nil, nil, errors.New("not a COSE_Sign1 structure")
+	}
+	if protected, ok = sign1[0].([]byte); !ok {
+		return cose, nil, nil, nil, errors.New("missing protected header")
+	}
+	if payload, ok = sign1[2].([]byte); !ok {
+		return cose, nil, nil, nil, errors.New("missing payload")
+	}
+	if signature, ok = sign1[3].([]byte); !ok {
+		return cose, nil, nil, nil, errors.New("missing signature")
+	}
+	return cose, protected, payload, signature, nil
+}
+
+func parseHash(raw RawMessage) (Hash, error) {
+	value, err := decode(raw)
+	if err != nil {
+		return Hash{}, err
+	}
+	fields, ok := value.([]any)
+	if !ok || len(fields) != 2 {
+		return Hash{}, errors.New("not a hash")
+	}
+	hashType, ok1 := fields[0].(int64)
+	hash, ok2 := fields[1].([]byte)
+	if !ok1 || !ok2 {
+		return Hash{}, errors.New("not a hash")
+	}
+	return Hash{Type: hashType, Value: hash}, nil
+}
+
+func isNull(raw RawMessage) bool {
+	return len(raw) == 1 && (raw[0] == majorSimple<<5|22 || raw[0] == majorSimple<<5|23)
+}
+
+// decodeItem decodes an encoded data item into a pointer to an int64, a string, a byte slice or a
+// RawMessage, the latter being the content of a byte string
+func decodeItem(raw RawMessage, out any) error {
+	value, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	ok := false
+	switch out := out.(type) {
+	case *int64:
+		*out, ok = value.(int64)
+	case *string:
+		*out, ok = value.(string)
+	case *[]byte:
+		*out, ok = value.([]byte)
+	case *RawMessage:
+		var b []byte
+		b, ok = value.([]byte)
+		*out = b
+	}
+	if !ok {
+		return fmt.Errorf("unexpected %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package voucher_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/fdo-rs/fdo-operator/internal/keys"
+	"github.com/fdo-rs/fdo-operator/internal/voucher"
+)
+
+// testVoucher builds the CBOR encoding of a voucher, owned by the given keys in turn
+func testVoucher(manufacturer *ecdsa.PrivateKey, owners ...*ecdsa.PrivateKey) []byte {
+	certPEM, err := keys.SelfSignedCert(manufacturer, keys.Subject{CommonName: "Manufacturer"}, false, time.Now(), time.Hour)
+	Expect(err).NotTo(HaveOccurred())
+	block, _ := pem.Decode(certPEM)
+	mustEncode := func(v any) []byte {
+		data, err := voucher.Encode(v)
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+	rvInfo := []any{
+		[]any{
+			[]any{int64(voucher.RendezvousDNS), mustEncode("rv.example.com")},
+			[]any{int64(voucher.RendezvousDevPort), mustEncode(8082)},
+			[]any{int64(voucher.RendezvousProtocol), mustEncode(1)},
+		},
+		[]any{
+			[]any{int64(voucher.RendezvousIPAddress), mustEncode([]byte{10, 0, 0, 1})},
+			[]any{int64(voucher.RendezvousDevOnly)},
+		},
+	}
+	header := mustEncode([]any{
+		101,
+		[]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef},
+		rvInfo,
+		"test-device",
+		[]any{int64(voucher.KeyTypeSECP256R1), int64(voucher.KeyEncodingX5Chain), []any{block.Bytes}},
+		nil,
+	})
+	entries := []any{}
+	for _, owner := range owners {
+		der, err := x509.MarshalPKIXPublicKey(&owner.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		payload := mustEncode([]any{
+			[]any{-16, make([]byte, 32)},
+			[]any{-16, make([]byte, 32)},
+			nil,
+			[]any{int64(voucher.KeyTypeSECP256R1), int64(voucher.KeyEncodingX509), der},
+		})
+		entries = append(entries, voucher.Tag{Number: voucher.COSESign1Tag, Content: []any{mustEncode(map[any]any{int64(1): int64(-7)}), map[any]any{}, payload, make([]byte, 64)}})
+	}
+	return mustEncode([]any{101, header, []any{5, make([]byte, 32)}, nil, entries})
+}
+
+var _ = Describe("CBOR", func() {
+	It("should decode what it encodes", func() {
+		value := []any{int64(-7), int64(1 << 40), "text", []byte{1, 2}, map[any]any{int64(1): true, "a": nil}, voucher.Tag{Number: 18, Content: []any{}}}
+		data, err := voucher.Encode(value)
+		Expect(err).NotTo(HaveOccurred())
+		decoded, err := voucher.Decode(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(value))
+	})
+
+	It("should reject truncated data", func() {
+		_, err := voucher.Decode([]byte{0x82, 0x01})
+		Expect(err).To(MatchError(voucher.ErrTruncated))
+		_, err = voucher.Decode([]byte{0x5a, 0xff, 0xff, 0xff, 0xff})
+		Expect(err).To(MatchError(voucher.ErrTruncated))
+	})
+
+	It("should reject deeply nested data without allocating its announced lengths", func() {
+		// 200 nested arrays of 65535 items each
+		data := bytes.Repeat([]byte{0x99, 0xff, 0xff}, 200)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := voucher.Decode(data)
+		runtime.ReadMemStats(&after)
+		Expect(err).To(MatchError(ContainSubstring("nested more than 32 levels")))
+		Expect(after.TotalAlloc - before.TotalAlloc).To(BeNumerically("<", 1<<20))
+
+		_, err = voucher.Decode(data[:3])
+		Expect(err).To(MatchError(voucher.ErrTruncated))
+	})
+})
+
+var _ = Describe("Voucher", func() {
+	var manufacturer, owner *ecdsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		manufacturer, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		owner, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should decode the header of a voucher", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.ProtocolVersion).To(Equal(int64(101)))
+		Expect(v.GUID.String()).To(Equal("01234567-89ab-cdef-0123-456789abcdef"))
+		Expect(v.DeviceInfo).To(Equal("test-device"))
+		Expect(v.DeviceCertChainHash).To(BeNil())
+		Expect(v.HeaderHMAC.Type).To(Equal(int64(5)))
+		Expect(v.Entries).To(BeEmpty())
+
+		Expect(v.ManufacturerKey.Type).To(Equal(voucher.KeyTypeSECP256R1))
+		Expect(v.ManufacturerKey.Encoding.String()).To(Equal("X5CHAIN"))
+		certs, err := v.ManufacturerKey.Certificates()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certs[0].Subject.CommonName).To(Equal("Manufacturer"))
+		fingerprint, err := v.ManufacturerKey.Fingerprint()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys.PublicKeyFingerprint(&manufacturer.PublicKey)).To(Equal(fingerprint))
+		Expect(v.OwnerKey()).To(Equal(v.ManufacturerKey))
+	})
+
+	It("should decode the rendezvous info of a voucher", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.RendezvousInfo).To(HaveLen(2))
+		Expect(v.RendezvousInfo[0].String()).To(Equal("dns=rv.example.com device_port=8082 protocol=http"))
+		Expect(v.RendezvousInfo[1].String()).To(Equal("ip_address=10.0.0.1 dev_only"))
+	})
+
+	It("should decode the entries of a voucher in PEM format", func() {
+		data := pem.EncodeToMemory(&pem.Block{Type: voucher.PEMType, Bytes: testVoucher(manufacturer, owner)})
+		v, err := voucher.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Entries).To(HaveLen(1))
+		Expect(v.Entries[0].PreviousHash.Type).To(Equal(int64(-16)))
+		Expect(v.OwnerKey().Encoding).To(Equal(voucher.KeyEncodingX509))
+		fingerprint, err := v.OwnerKey().Fingerprint()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys.PublicKeyFingerprint(&owner.PublicKey)).To(Equal(fingerprint))
+	})
+
+	It("should reject data that is not a voucher", func() {
+		_, err := voucher.Parse([]byte("-----BEGIN CERTIFICATE-----\nAA==\n-----END CERTIFICATE-----\n"))
+		Expect(err).To(MatchError(ContainSubstring("no OWNERSHIP VOUCHER PEM block")))
+		data, err := voucher.Encode([]any{101, []byte{}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = voucher.Parse(data)
+		Expect(err).To(MatchError(ContainSubstring("2 fields instead of 5")))
+	})
+})
+
+var _ = Describe("Extend", func() {
+	var manufacturer, owner, next *ecdsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		manufacturer, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		owner, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		next, err = keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should append entries signed by the current owner", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer))
+		Expect(err).NotTo(HaveOccurred())
+		ownerKey, err := voucher.NewX509PublicKey(&owner.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(manufacturer, ownerKey)).To(Succeed())
+		nextKey, err := voucher.NewX509PublicKey(&next.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(owner, nextKey)).To(Succeed())
+		Expect(v.VerifyEntries()).To(Succeed())
+
+		data, err := v.MarshalPEM()
+		Expect(err).NotTo(HaveOccurred())
+		extended, err := voucher.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extended.GUID).To(Equal(v.GUID))
+		Expect(extended.Entries).To(HaveLen(2))
+		Expect(extended.Entries[1].PreviousHash.Type).To(Equal(voucher.HashTypeSHA256))
+		Expect(extended.VerifyEntries()).To(Succeed())
+		fingerprint, err := extended.OwnerKey().Fingerprint()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys.PublicKeyFingerprint(&next.PublicKey)).To(Equal(fingerprint))
+	})
+
+	It("should reject a key that doesn't own the voucher", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer, owner))
+		Expect(err).NotTo(HaveOccurred())
+		nextKey, err := voucher.NewX509PublicKey(&next.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(manufacturer, nextKey)).To(MatchError(ContainSubstring("doesn't match the owner key")))
+		Expect(v.Entries).To(HaveLen(1))
+	})
+
+	It("should reject a next owner key of another type", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer))
+		Expect(err).NotTo(HaveOccurred())
+		p384, err := keys.GenerateKey(keys.SECP384R1)
+		Expect(err).NotTo(HaveOccurred())
+		nextKey, err := voucher.NewX509PublicKey(&p384.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(manufacturer, nextKey)).To(MatchError(ContainSubstring("instead of SECP256R1")))
+	})
+
+	It("should detect entries with invalid signatures", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer, owner))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.VerifyEntries()).To(MatchError(ContainSubstring("entry 0")))
+	})
+
+	It("should sign entries that verify without the codec of the package", func() {
+		v, err := voucher.Parse(testVoucher(manufacturer))
+		Expect(err).NotTo(HaveOccurred())
+		ownerKey, err := voucher.NewX509PublicKey(&owner.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(manufacturer, ownerKey)).To(Succeed())
+		nextKey, err := voucher.NewX509PublicKey(&next.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(owner, nextKey)).To(Succeed())
+		data, err := v.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+
+		raw := walkVoucher(data)
+		Expect(raw.entries).To(HaveLen(2))
+		first := parseTestSign1(raw.entries[0])
+		Expect(first.protected).To(Equal([]byte{0xa1, 0x01, 0x26}))
+		Expect(verifyTestSign1(&manufacturer.PublicKey, first)).To(BeTrue())
+		Expect(previousHash(first.payload)).To(Equal(sha256.Sum256(append(append([]byte{}, raw.header...), raw.hmac...))))
+		second := parseTestSign1(raw.entries[1])
+		Expect(verifyTestSign1(&owner.PublicKey, second)).To(BeTrue())
+		Expect(verifyTestSign1(&manufacturer.PublicKey, second)).To(BeFalse())
+		Expect(previousHash(second.payload)).To(Equal(sha256.Sum256(raw.entries[0])))
+
+		second.payload[len(second.payload)-1] ^= 0xff
+		Expect(verifyTestSign1(&owner.PublicKey, second)).To(BeFalse())
+	})
+
+	// The fixture is a voucher initialized by a manufacturer and extended once, and the key of its
+	// owner, written by "make voucher-fixtures" with the tools of fido-device-onboard-rs or by
+	// hack/voucher-fixture.py where they can't be run.
+	It("should extend a voucher of fido-device-onboard-rs", func() {
+		dir := filepath.Join("testdata", "fdo-rs")
+		data, err := os.ReadFile(filepath.Join(dir, "ownership_voucher"))
+		Expect(err).NotTo(HaveOccurred(), "run make voucher-fixtures")
+		der, err := os.ReadFile(filepath.Join(dir, "owner_key.der"))
+		Expect(err).NotTo(HaveOccurred())
+		fixtureOwner, err := keys.ParseKey(der)
+		Expect(err).NotTo(HaveOccurred())
+
+		v, err := voucher.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Entries).NotTo(BeEmpty())
+		Expect(v.VerifyEntries()).To(Succeed())
+		nextKey, err := voucher.NewX509PublicKey(&next.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Extend(fixtureOwner, nextKey)).To(Succeed())
+		extended, err := v.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+
+		raw := walkVoucher(extended)
+		n := len(raw.entries)
+		last := parseTestSign1(raw.entries[n-1])
+		Expect(verifyTestSign1(&fixtureOwner.PublicKey, last)).To(BeTrue())
+		Expect(previousHash(last.payload)).To(Equal(sha256.Sum256(raw.entries[n-2])))
+	})
+})
+
+// testReader reads CBOR data item by item, independently of the codec of the package, to check
+// what the package encodes against RFC 8949 and RFC 9052
+type testReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *testReader) head() (byte, uint64) {
+	b := r.data[r.pos]
+	r.pos++
+	major, n := b>>5, uint64(b&0x1f)
+	if n >= 24 {
+		size := 1 << (n - 24)
+		n = 0
+		for _, c := range r.data[r.pos : r.pos+size] {
+			n = n<<8 | uint64(c)
+		}
+		r.pos += size
+	}
+	return major, n
+}
+
+func (r *testReader) skip() {
+	major, n := r.head()
+	switch major {
+	case 2, 3:
+		r.pos += int(n)
+	case 4, 5:
+		if major == 5 {
+			n *= 2
+		}
+		for i := uint64(0); i < n; i++ {
+			r.skip()
+		}
+	case 6:
+		r.skip()
+	}
+}
+
+// item returns the encoding of the next data item
+func (r *testReader) item() []byte {
+	start := r.pos
+	r.skip()
+	return r.data[start:r.pos]
+}
+
+func (r *testReader) bstr() []byte {
+	major, n := r.head()
+	Expect(major).To(Equal(byte(2)))
+	r.pos += int(n)
+	return r.data[r.pos-int(n) : r.pos]
+}
+
+type testVoucherItems struct {
+	header, hmac []byte
+	// entries holds the COSE_Sign1 structures, unwrapped from their byte strings
+	entries [][]byte
+}
+
+func walkVoucher(data []byte) testVoucherItems {
+	r := &testReader{data: data}
+	major, n := r.head()
+	Expect(major).To(Equal(byte(4)))
+	Expect(n).To(Equal(uint64(5)))
+	r.skip()
+	var items testVoucherItems
+	items.header = r.item()
+	items.hmac = r.item()
+	r.skip()
+	major, n = r.head()
+	Expect(major).To(Equal(byte(4)))
+	for i := uint64(0); i < n; i++ {
+		entry := r.item()
+		if entry[0]>>5 == 2 {
+			entry = (&testReader{data: entry}).bstr()
+		}
+		items.entries = append(items.entries, entry)
+	}
+	return items
+}
+
+type testSign1 struct {
+	protected, payload, signature []byte
+}
+
+func parseTestSign1(data []byte) testSign1 {
+	r := &testReader{data: data}
+	major, n := r.head()
+	if major == 6 {
+		Expect(n).To(Equal(uint64(voucher.COSESign1Tag)))
+		major, n = r.head()
+	}
+	Expect(major).To(Equal(byte(4)))
+	Expect(n).To(Equal(uint64(4)))
+	var s testSign1
+	s.protected = r.bstr()
+	r.skip()
+	s.payload = r.bstr()
+	s.signature = r.bstr()
+	return s
+}
+
+// verifyTestSign1 checks an ES256 signature over the Sig_structure of RFC 9052, section 4.4:
+// ["Signature1", protected, external_aad, payload] with an empty external_aad
+func verifyTestSign1(pub *ecdsa.PublicKey, s testSign1) bool {
+	bstr := func(b []byte) []byte {
+		var head []byte
+		switch n := len(b); {
+		case n < 24:
+			head = []byte{0x40 | byte(n)}
+		case n < 1<<8:
+			head = []byte{0x58, byte(n)}
+		default:
+			head = []byte{0x59, byte(n >> 8), byte(n)}
+		}
+		return append(head, b...)
+	}
+	structure := append([]byte{0x84, 0x6a}, "Signature1"...)
+	structure = append(structure, bstr(s.protected)...)
+	structure = append(structure, 0x40)
+	structure = append(structure, bstr(s.payload)...)
+	digest := sha256.Sum256(structure)
+	half := len(s.signature) / 2
+	r, ss := new(big.Int).SetBytes(s.signature[:half]), new(big.Int).SetBytes(s.signature[half:])
+	return ecdsa.Verify(pub, digest[:], r, ss)
+}
+
+// previousHash returns the value of the first hash of the payload of an entry
+func previousHash(payload []byte) [sha256.Size]byte {
+	r := &testReader{data: payload}
+	r.head()
+	r.head()
+	r.skip()
+	return [sha256.Size]byte(r.bstr())
+}
+
+// testRegistration builds the CBOR encoding of a registration of a rendezvous server
+func testRegistration(owner *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(&owner.PublicKey)
+	Expect(err).NotTo(HaveOccurred())
+	payload, err := voucher.Encode([]any{
+		[]any{
+			[]any{[]byte{10, 0, 0, 2}, nil, 8081, int64(voucher.TransportHTTP)},
+			[]any{nil, "owner.example.com", 443, int64(voucher.TransportHTTPS)},
+		},
+		[]any{-16, make([]byte, 32)},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	data, err := voucher.Encode([]any{
+		[]any{int64(voucher.KeyTypeSECP256R1), int64(voucher.KeyEncodingX509), der},
+		voucher.Tag{Number: voucher.COSESign1Tag, Content: []any{[]byte{0xa1, 0x01, 0x26}, map[any]any{}, payload, make([]byte, 64)}},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return data
+}
+
+var _ = Describe("Registration", func() {
+	It("should decode the owner key and the TO2 addresses of a registration", func() {
+		owner, err := keys.GenerateKey(keys.SECP256R1)
+		Expect(err).NotTo(HaveOccurred())
+		r, err := voucher.ParseRegistration(testRegistration(owner))
+		Expect(err).NotTo(HaveOccurred())
+		fingerprint, err := keys.PublicKeyFingerprint(&owner.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.OwnerKey.Fingerprint()).To(Equal(fingerprint))
+		Expect(r.Addresses).To(HaveLen(2))
+		Expect(r.Addresses[0].String()).To(Equal("ip_address=10.0.0.2 port=8081 protocol=http"))
+		Expect(r.Addresses[1].String()).To(Equal("dns=owner.example.com port=443 protocol=https"))
+	})
+
+	It("should reject data that is not a registration", func() {
+		_, err := voucher.ParseRegistration([]byte{0x80})
+		Expect(err).To(MatchError(ContainSubstring("0 fields instead of 2")))
+	})
+})
+
+// FuzzDecode checks that the decoder rejects malformed data without panicking
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte{0x82, 0x01, 0x02})
+	f.Add(bytes.Repeat([]byte{0x99, 0xff, 0xff}, 200))
+	f.Add([]byte{0xd2, 0x84, 0x40, 0xa0, 0x40, 0x40})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = voucher.Decode(data)
+		_, _ = voucher.Parse(data)
+	})
+}
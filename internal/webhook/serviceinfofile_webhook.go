@@ -0,0 +1,142 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
+	"github.com/fdo-rs/fdo-operator/controllers"
+)
+
+const serviceInfoFileWebhookPath = "/validate-v1-serviceinfo-file"
+
+// log is for logging in this package.
+var serviceinfofilelog = logf.Log.WithName("serviceinfo-file-resource")
+
+//+kubebuilder:webhook:path=/validate-v1-serviceinfo-file,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=configmaps;secrets,verbs=create;update,versions=v1,name=vserviceinfofile.kb.io,admissionReviewVersions=v1
+//+kubebuilder:rbac:groups=fdo.redhat.com,resources=fdoonboardingservers,verbs=get;list;watch
+
+// ServiceInfoFileValidator validates ConfigMaps and Secrets labeled as serviceinfo files of an onboarding server
+type ServiceInfoFileValidator struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+// SetupServiceInfoFileWebhookWithManager registers the serviceinfo file webhook with the manager's webhook server
+func SetupServiceInfoFileWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(serviceInfoFileWebhookPath, &webhook.Admission{
+		Handler: &ServiceInfoFileValidator{
+			Client:  mgr.GetClient(),
+			decoder: admission.NewDecoder(mgr.GetScheme()),
+		},
+	})
+	return nil
+}
+
+var _ admission.Handler = &ServiceInfoFileValidator{}
+
+// Handle implements admission.Handler
+func (v *ServiceInfoFileValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var (
+		obj  metav1.Object
+		keys []string
+		gk   schema.GroupKind
+	)
+	switch req.Kind.Kind {
+	case "ConfigMap":
+		cm := &corev1.ConfigMap{}
+		if err := v.decoder.Decode(req, cm); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		for k := range cm.BinaryData {
+			keys = append(keys, k)
+		}
+		for k := range cm.Data {
+			keys = append(keys, k)
+		}
+		obj, gk = cm, corev1.SchemeGroupVersion.WithKind("ConfigMap").GroupKind()
+	case "Secret":
+		secret := &corev1.Secret{}
+		if err := v.decoder.Decode(req, secret); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+		for k := range secret.StringData {
+			keys = append(keys, k)
+		}
+		obj, gk = secret, corev1.SchemeGroupVersion.WithKind("Secret").GroupKind()
+	default:
+		return admission.Allowed("")
+	}
+
+	owner, ok := obj.GetLabels()[controllers.FileOwnerLabel]
+	if !ok {
+		return admission.Allowed("")
+	}
+	serviceinfofilelog.Info("validate", "kind", gk.Kind, "name", obj.GetName(), "owner", owner)
+
+	_, allErrs := controllers.ParseServiceInfoFile(obj, keys)
+	if len(allErrs) > 0 {
+		status := apierrors.NewInvalid(gk, obj.GetName(), allErrs).Status()
+		return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+	}
+
+	// A missing owner is only a warning, see checkOwner
+	warning, err := v.checkOwner(ctx, req.Namespace, owner)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if warning != "" {
+		return admission.Allowed("").WithWarnings(warning)
+	}
+	return admission.Allowed("")
+}
+
+// checkOwner returns a warning if the onboarding server owning a file does not exist.
+//
+// A file with a missing owner is deliberately allowed rather than rejected: the files are usually
+// applied together with their server, and kustomize, Helm and Argo CD all order ConfigMaps and
+// Secrets before custom resources. Rejecting the files would make such a bundle fail on its first
+// apply. The controller picks the files up by their owner label once the server exists, so nothing
+// is lost by accepting them early.
+func (v *ServiceInfoFileValidator) checkOwner(ctx context.Context, namespace, owner string) (string, error) {
+	ownerPath := field.NewPath("metadata", "labels").Key(controllers.FileOwnerLabel)
+	server := &fdov1beta1.FDOOnboardingServer{}
+	err := v.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner}, server)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("%s: FDOOnboardingServer %q not found in namespace %q, the file will not be used until it is created", ownerPath, owner, namespace), nil
+	}
+	return "", err
+}
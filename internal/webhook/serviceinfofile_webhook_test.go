@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gomock "go.uber.org/mock/gomock"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/fdo-rs/fdo-operator/controllers"
+	"github.com/fdo-rs/fdo-operator/internal/client"
+)
+
+var _ = Describe("ServiceInfoFileValidator", func() {
+	var (
+		gCtrl *gomock.Controller
+		c     *client.MockClient
+		ctx   context.Context
+		v     *ServiceInfoFileValidator
+		cm    *corev1.ConfigMap
+	)
+
+	request := func(obj runtime.Object, kind string) admission.Request {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: kind},
+			Namespace: "fdo",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	BeforeEach(func() {
+		gCtrl = gomock.NewController(GinkgoT())
+		c = client.NewMockClient(gCtrl)
+		ctx = context.TODO()
+		v = &ServiceInfoFileValidator{Client: c, decoder: admission.NewDecoder(scheme.Scheme)}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "motd",
+				Namespace: "fdo",
+				Labels:    map[string]string{controllers.FileOwnerLabel: "onboarding-server"},
+				Annotations: map[string]string{
+					controllers.FileKey:        "motd",
+					controllers.PathKey:        "/etc/motd",
+					controllers.PermissionsKey: "644",
+				},
+			},
+			BinaryData: map[string][]byte{"motd": []byte("Onboarded by FDO")},
+		}
+	})
+
+	It("should allow a valid file of an existing server", func() {
+		c.EXPECT().Get(ctx, types.NamespacedName{Namespace: "fdo", Name: "onboarding-server"}, gomock.Any()).Return(nil)
+		res := v.Handle(ctx, request(cm, "ConfigMap"))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Warnings).To(BeEmpty())
+	})
+
+	It("should warn if the owner server does not exist", func() {
+		c.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).
+			Return(apierrors.NewNotFound(schema.GroupResource{Group: "fdo.redhat.com", Resource: "fdoonboardingservers"}, "onboarding-server"))
+		res := v.Handle(ctx, request(cm, "ConfigMap"))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Warnings).To(HaveLen(1))
+	})
+
+	It("should reject a relative path, invalid permissions and a missing file", func() {
+		cm.Annotations[controllers.PathKey] = "etc/motd"
+		cm.Annotations[controllers.PermissionsKey] = "rw-r--r--"
+		cm.Annotations[controllers.FileKey] = "issue"
+		res := v.Handle(ctx, request(cm, "ConfigMap"))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Result.Details.Causes).To(HaveLen(3))
+	})
+
+	It("should validate secrets", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: cm.ObjectMeta,
+			StringData: map[string]string{"issue": "secret"},
+		}
+		res := v.Handle(ctx, request(secret, "Secret"))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Result.Message).To(ContainSubstring(controllers.FileKey))
+	})
+
+	It("should ignore objects that are not serviceinfo files", func() {
+		delete(cm.Labels, controllers.FileOwnerLabel)
+		cm.Annotations[controllers.PathKey] = "etc/motd"
+		res := v.Handle(ctx, request(cm, "ConfigMap"))
+		Expect(res.Allowed).To(BeTrue())
+	})
+})
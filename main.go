@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
@@ -34,9 +35,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	fdov1alpha1 "github.com/fdo-rs/fdo-operator/api/v1alpha1"
+	fdov1beta1 "github.com/fdo-rs/fdo-operator/api/v1beta1"
 	"github.com/fdo-rs/fdo-operator/controllers"
+	"github.com/fdo-rs/fdo-operator/internal/inventory"
+	"github.com/fdo-rs/fdo-operator/internal/keysource"
+	fdowebhook "github.com/fdo-rs/fdo-operator/internal/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -49,20 +55,52 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(fdov1alpha1.AddToScheme(scheme))
+	utilruntime.Must(fdov1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 
 	utilruntime.Must(routev1.AddToScheme(scheme))
 }
 
 func main() {
+	// the init containers of the servers run the operator binary to fetch keys from key stores
+	if len(os.Args) > 1 && os.Args[1] == keysource.Command {
+		ctrl.SetLogger(zap.New())
+		if err := keysource.Run(context.Background(), os.Getenv(keysource.ConfigEnv), os.Getenv); err != nil {
+			setupLog.Error(err, "unable to fetch keys")
+			os.Exit(1)
+		}
+		return
+	}
+	// the sidecars of the servers run the operator binary to serve the inventory of their vouchers
+	if len(os.Args) > 1 && os.Args[1] == inventory.Command {
+		ctrl.SetLogger(zap.New())
+		if err := inventory.Run(ctrl.SetupSignalHandler(), os.Args[2:]); err != nil {
+			setupLog.Error(err, "unable to serve the voucher inventory")
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var operatorImage string
+	var databaseImage string
+	var sqliteImage string
+	var allowCrossNamespaceVoucherSync bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&operatorImage, "operator-image", os.Getenv("OPERATOR_IMAGE"),
+		"The image of the operator, run by the servers to fetch keys from external key stores.")
+	flag.StringVar(&databaseImage, "database-image", os.Getenv("DATABASE_IMAGE"),
+		"The Postgres image of the databases managed by the operator, defaults to "+controllers.DefaultDatabaseImage+".")
+	flag.StringVar(&sqliteImage, "sqlite-image", os.Getenv("SQLITE_IMAGE"),
+		"The image migrating the Sqlite databases of the servers, defaults to "+controllers.DefaultSqliteImage+".")
+	flag.BoolVar(&allowCrossNamespaceVoucherSync, "allow-cross-namespace-voucher-sync", false,
+		"Allow voucher syncs to push vouchers to the onboarding servers of other namespaces.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -102,6 +140,9 @@ func main() {
 	if err = (&controllers.FDORendezvousServerReconciler{
 		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdorendezvousserver_controller"), mgr.GetAPIReader()),
 		Log:            ctrl.Log.WithName("controllers").WithName("FDORendezvousServer"),
+		OperatorImage:  operatorImage,
+		DatabaseImage:  databaseImage,
+		SqliteImage:    sqliteImage,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FDORendezvousServer")
 		os.Exit(1)
@@ -109,6 +150,9 @@ func main() {
 	if err = (&controllers.FDOOnboardingServerReconciler{
 		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdoonboardingserver_controller"), mgr.GetAPIReader()),
 		Log:            ctrl.Log.WithName("controllers").WithName("FDOOnboardingServer"),
+		OperatorImage:  operatorImage,
+		DatabaseImage:  databaseImage,
+		SqliteImage:    sqliteImage,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FDOOnboardingServer")
 		os.Exit(1)
@@ -116,10 +160,70 @@ func main() {
 	if err = (&controllers.FDOManufacturingServerReconciler{
 		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdomanufacturingserver_controller"), mgr.GetAPIReader()),
 		Log:            ctrl.Log.WithName("controllers").WithName("FDOManufacturingServer"),
+		OperatorImage:  operatorImage,
+		DatabaseImage:  databaseImage,
+		SqliteImage:    sqliteImage,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FDOManufacturingServer")
 		os.Exit(1)
 	}
+	if err = (&controllers.FDOKeySetReconciler{
+		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdokeyset_controller"), mgr.GetAPIReader()),
+		Log:            ctrl.Log.WithName("controllers").WithName("FDOKeySet"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FDOKeySet")
+		os.Exit(1)
+	}
+	if err = (&controllers.FDOTrustBundleReconciler{
+		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdotrustbundle_controller"), mgr.GetAPIReader()),
+		Log:            ctrl.Log.WithName("controllers").WithName("FDOTrustBundle"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FDOTrustBundle")
+		os.Exit(1)
+	}
+	if err = (&controllers.FDOOwnershipVoucherReconciler{
+		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdoownershipvoucher_controller"), mgr.GetAPIReader()),
+		Log:            ctrl.Log.WithName("controllers").WithName("FDOOwnershipVoucher"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FDOOwnershipVoucher")
+		os.Exit(1)
+	}
+	if err = (&controllers.FDOVoucherSyncReconciler{
+		ReconcilerBase:             util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdovouchersync_controller"), mgr.GetAPIReader()),
+		Log:                        ctrl.Log.WithName("controllers").WithName("FDOVoucherSync"),
+		AllowCrossNamespaceTargets: allowCrossNamespaceVoucherSync,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FDOVoucherSync")
+		os.Exit(1)
+	}
+	if err = (&controllers.FDOVoucherExtensionReconciler{
+		ReconcilerBase: util.NewReconcilerBase(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), mgr.GetEventRecorderFor("fdovoucherextension_controller"), mgr.GetAPIReader()),
+		Log:            ctrl.Log.WithName("controllers").WithName("FDOVoucherExtension"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "FDOVoucherExtension")
+		os.Exit(1)
+	}
+	// The CRDs convert v1alpha1 objects through the conversion webhook, so it is served even when
+	// ENABLE_WEBHOOKS=false disables the defaulting and validating webhooks
+	mgr.GetWebhookServer().Register("/convert", conversion.NewWebhookHandler(mgr.GetScheme()))
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&fdov1beta1.FDORendezvousServer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FDORendezvousServer")
+			os.Exit(1)
+		}
+		if err = (&fdov1beta1.FDOOnboardingServer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FDOOnboardingServer")
+			os.Exit(1)
+		}
+		if err = (&fdov1beta1.FDOManufacturingServer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FDOManufacturingServer")
+			os.Exit(1)
+		}
+		if err = fdowebhook.SetupServiceInfoFileWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ServiceInfoFile")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {